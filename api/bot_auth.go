@@ -22,14 +22,15 @@ type BotAuthConfig struct {
 	MaxTimeDrift  int64 // 最大时间差（秒）
 }
 
+
 // BotAuthMiddleware Bot认证中间件
 func BotAuthMiddleware(config BotAuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 验证Bot Token
 		botToken := c.GetHeader("X-Bot-Token")
-		log.Printf("BotAuth: Received X-Bot-Token: %s, Expected: %s", botToken, config.BotToken)
+		ctx := c.Request.Context()
 		if botToken != config.BotToken {
-			log.Printf("BotAuth: Invalid bot token - received: %s, expected: %s", botToken, config.BotToken)
+			apiLog.Warnf(ctx, "BotAuth: Invalid bot token from %s", c.ClientIP())
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid bot token",
 			})
@@ -39,9 +40,8 @@ func BotAuthMiddleware(config BotAuthConfig) gin.HandlerFunc {
 
 		// 验证时间戳
 		timestampStr := c.GetHeader("X-Bot-Timestamp")
-		log.Printf("BotAuth: Received X-Bot-Timestamp: %s", timestampStr)
 		if timestampStr == "" {
-			log.Printf("BotAuth: Missing timestamp header")
+			apiLog.Warnf(ctx, "BotAuth: Missing timestamp header")
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Missing timestamp",
 			})
@@ -51,7 +51,7 @@ func BotAuthMiddleware(config BotAuthConfig) gin.HandlerFunc {
 
 		timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
 		if err != nil {
-			log.Printf("BotAuth: Invalid timestamp format: %s", timestampStr)
+			apiLog.Warnf(ctx, "BotAuth: Invalid timestamp format: %s", timestampStr)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid timestamp format",
 			})
@@ -62,9 +62,9 @@ func BotAuthMiddleware(config BotAuthConfig) gin.HandlerFunc {
 		// 检查时间戳是否在允许范围内（防重放攻击）
 		currentTime := time.Now().Unix()
 		timeDiff := abs64(currentTime - timestamp)
-		log.Printf("BotAuth: Time difference: %d seconds (max allowed: %d)", timeDiff, config.MaxTimeDrift)
+		apiLog.Debugf(ctx, "BotAuth: Time difference: %d seconds (max allowed: %d)", timeDiff, config.MaxTimeDrift)
 		if timeDiff > config.MaxTimeDrift {
-			log.Printf("BotAuth: Request timestamp too old or too far in future - timeDiff: %d, timestamp: %d, current: %d", timeDiff, timestamp, currentTime)
+			apiLog.Warnf(ctx, "BotAuth: Request timestamp too old or too far in future - timeDiff: %d, timestamp: %d, current: %d", timeDiff, timestamp, currentTime)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Request timestamp too old or too far in the future",
 			})
@@ -74,9 +74,8 @@ func BotAuthMiddleware(config BotAuthConfig) gin.HandlerFunc {
 
 		// 验证签名
 		signature := c.GetHeader("X-Bot-Signature")
-		log.Printf("BotAuth: Received X-Bot-Signature: %s", signature)
 		if signature == "" {
-			log.Printf("BotAuth: Missing signature header")
+			apiLog.Warnf(ctx, "BotAuth: Missing signature header")
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Missing signature",
 			})
@@ -96,14 +95,10 @@ func BotAuthMiddleware(config BotAuthConfig) gin.HandlerFunc {
 			}
 		}
 
-		log.Printf("BotAuth: Request body: %s", body)
 		// 计算期望的签名
 		expectedSignature := generateSignature(config.ApiSecret, timestamp, body)
-		log.Printf("BotAuth: Expected signature: %s", expectedSignature)
-		log.Printf("BotAuth: Received signature: %s", signature)
-		log.Printf("BotAuth: Signature match: %t", hmac.Equal([]byte(signature), []byte(expectedSignature)))
 		if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
-			log.Printf("BotAuth: Invalid signature - received: %s, expected: %s", signature, expectedSignature)
+			apiLog.Warnf(ctx, "BotAuth: Invalid signature from %s (body %d bytes)", c.ClientIP(), len(body))
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid signature",
 			})
@@ -115,7 +110,7 @@ func BotAuthMiddleware(config BotAuthConfig) gin.HandlerFunc {
 		telegramUserID := c.GetHeader("X-Telegram-User-ID")
 		if telegramUserID != "" {
 			c.Set("telegram_user_id", telegramUserID)
-			log.Printf("Bot request from Telegram User ID: %s", telegramUserID)
+			apiLog.Debugf(ctx, "Bot request from Telegram User ID: %s", telegramUserID)
 		}
 
 		// 认证通过，继续处理请求
@@ -1,14 +1,23 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"nofx/auth"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/logging"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/metrics"
+	"nofx/pool"
 	"nofx/trader"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +25,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// apiLog API服务日志
+var apiLog = logging.For("api")
+
 // Server HTTP API服务器
 type Server struct {
 	router        *gin.Engine
@@ -68,6 +80,9 @@ func (s *Server) setupRoutes() {
 	// 健康检查
 	s.router.Any("/health", s.handleHealth)
 
+	// Prometheus 指标（设置 METRICS_TOKEN 后需携带 Authorization: Bearer <token>）
+	s.router.GET("/metrics", s.handleMetrics)
+
 	// API路由组
 	api := s.router.Group("/api")
 	{
@@ -98,6 +113,29 @@ func (s *Server) setupRoutes() {
 			protected.POST("/traders/:id/start", s.handleStartTrader)
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
+			protected.PUT("/traders/:id/indicators", updateTraderSetting(s, config.SettingIndicatorSpec, (*trader.AutoTrader).SetIndicatorSpec))
+			protected.PUT("/traders/:id/margin-policy", updateTraderSetting(s, config.SettingMarginPolicy, (*trader.AutoTrader).SetMarginPolicy))
+			protected.PUT("/traders/:id/exposure-limits", updateTraderSetting(s, config.SettingExposureLimits, (*trader.AutoTrader).SetExposureLimits))
+			protected.PUT("/traders/:id/risk-reward", updateTraderSetting(s, config.SettingRiskRewardRules, (*trader.AutoTrader).SetRiskRewardRules))
+			protected.GET("/traders/:id/risk-policy", s.handleGetTraderRiskPolicy)
+			protected.PUT("/traders/:id/risk-policy", updateTraderSetting(s, config.SettingRiskPolicy, (*trader.AutoTrader).SetRiskPolicy))
+			protected.PUT("/traders/:id/prompt-template", s.handleUpdateTraderPromptTemplate)
+			protected.GET("/traders/:id/prompt-preview", s.handlePreviewTraderPrompt)
+			protected.GET("/traders/:id/coin-universe", s.handleGetTraderCoinUniverse)
+			protected.PUT("/traders/:id/coin-universe", updateTraderSetting(s, config.SettingCoinUniverse, (*trader.AutoTrader).SetCoinUniverse))
+			protected.GET("/traders/:id/schedule", s.handleGetTraderSchedule)
+			protected.PUT("/traders/:id/schedule", updateTraderSetting(s, config.SettingSchedule, (*trader.AutoTrader).SetSchedule))
+
+			// 提示词模板（保存即生成新版本）
+			protected.GET("/prompt-templates", s.handleGetPromptTemplates)
+			protected.POST("/prompt-templates", s.handleCreatePromptTemplate)
+
+			// 提示词A/B实验
+			protected.GET("/experiments", s.handleGetExperiments)
+			protected.POST("/experiments", s.handleCreateExperiment)
+			protected.GET("/experiments/:id/report", s.handleGetExperimentReport)
+			protected.POST("/experiments/:id/stop", s.handleStopExperiment)
+			protected.DELETE("/experiments/:id", s.handleDeleteExperiment)
 
 			// AI模型管理（完整的CRUD）
 			protected.GET("/models", s.handleGetModelConfigs)
@@ -117,6 +155,7 @@ func (s *Server) setupRoutes() {
 			// 获取支持的类型列表（用于前端下拉选择）- 公开访问
 			s.router.GET("/api/models/supported-types", s.handleGetSupportedModelTypes)
 			s.router.GET("/api/exchanges/supported-types", s.handleGetSupportedExchangeTypes)
+			s.router.GET("/api/indicators/supported", s.handleGetSupportedIndicators)
 
 			// 公开竞赛总览（所有用户）
 			s.router.GET("/api/competition/public", s.handlePublicCompetition)
@@ -139,6 +178,17 @@ func (s *Server) handleHealth(c *gin.Context) {
 	})
 }
 
+// handleMetrics Prometheus 指标
+func (s *Server) handleMetrics(c *gin.Context) {
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+	metrics.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
 // handleGetSystemConfig 获取系统配置（客户端需要知道的配置）
 func (s *Server) handleGetSystemConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -229,7 +279,7 @@ func (s *Server) getTraderFromQuery(c *gin.Context) (string, string, error) {
 	// 确保用户的交易员已加载到内存中
 	err := s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
-		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+		apiLog.Warnf(c.Request.Context(), "⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
 	}
 
 	if traderID == "" {
@@ -252,7 +302,7 @@ func (s *Server) getTraderFromQuery(c *gin.Context) (string, string, error) {
 }
 
 // getTraderWithFallback 尝试从内存获取trader，如果不存在则从数据库验证
-func (s *Server) getTraderWithFallback(userID, traderID string) (*trader.AutoTrader, error) {
+func (s *Server) getTraderWithFallback(ctx context.Context, userID, traderID string) (*trader.AutoTrader, error) {
 	// 首先尝试从内存获取
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err == nil {
@@ -260,7 +310,7 @@ func (s *Server) getTraderWithFallback(userID, traderID string) (*trader.AutoTra
 	}
 
 	// 如果内存中找不到，检查数据库中是否存在
-	log.Printf("⚠️ Trader %s 不在内存中，检查数据库...", traderID)
+	apiLog.Warnf(ctx, "⚠️ Trader %s 不在内存中，检查数据库...", traderID)
 	traderConfigs, dbErr := s.database.GetTraders(userID)
 	if dbErr != nil {
 		return nil, fmt.Errorf("数据库查询失败: %v", dbErr)
@@ -280,7 +330,7 @@ func (s *Server) getTraderWithFallback(userID, traderID string) (*trader.AutoTra
 	}
 
 	// Trader存在但未运行
-	log.Printf("✓ Trader %s 存在但未运行", traderID)
+	apiLog.Infof(ctx, "✓ Trader %s 存在但未运行", traderID)
 	return nil, fmt.Errorf("trader存在但未运行")
 }
 
@@ -299,7 +349,7 @@ func getTraderWithFallback(traderManager *manager.TraderManager, database *confi
 	// 确保用户的交易员已加载到内存中
 	err := traderManager.LoadUserTraders(database, userID)
 	if err != nil {
-		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+		apiLog.Warnf(c.Request.Context(), "⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
 	}
 
 	if traderID == "" {
@@ -325,7 +375,7 @@ func getTraderWithFallback(traderManager *manager.TraderManager, database *confi
 	_, err = traderManager.GetTrader(traderID)
 	if err != nil {
 		// 如果内存中找不到，检查数据库中是否存在
-		log.Printf("⚠️ Trader %s 不在内存中，检查数据库...", traderID)
+		apiLog.Warnf(c.Request.Context(), "⚠️ Trader %s 不在内存中，检查数据库...", traderID)
 		traderConfigs, dbErr := database.GetTraders(userID)
 		if dbErr != nil {
 			return userID, "", fmt.Errorf("数据库查询失败: %v", dbErr)
@@ -344,7 +394,7 @@ func getTraderWithFallback(traderManager *manager.TraderManager, database *confi
 			return userID, "", fmt.Errorf("trader ID '%s' 不存在", traderID)
 		}
 
-		log.Printf("✓ Trader %s 存在但未运行", traderID)
+		apiLog.Infof(c.Request.Context(), "✓ Trader %s 存在但未运行", traderID)
 	}
 
 	return userID, traderID, nil
@@ -352,13 +402,22 @@ func getTraderWithFallback(traderManager *manager.TraderManager, database *confi
 
 // AI交易员管理相关结构体
 type CreateTraderRequest struct {
-	Name               string  `json:"name" binding:"required"`
-	AIModelID          string  `json:"ai_model_id" binding:"required"`
-	ExchangeID         string  `json:"exchange_id" binding:"required"`
-	InitialBalance     float64 `json:"initial_balance"`
-	CustomPrompt       string  `json:"custom_prompt"`
-	OverrideBasePrompt bool    `json:"override_base_prompt"`
-	IsCrossMargin      *bool   `json:"is_cross_margin"` // 指针类型，nil表示使用默认值true
+	Name                string                    `json:"name" binding:"required"`
+	AIModelID           string                    `json:"ai_model_id" binding:"required"`
+	ExchangeID          string                    `json:"exchange_id" binding:"required"`
+	InitialBalance      float64                   `json:"initial_balance"`
+	CustomPrompt        string                    `json:"custom_prompt"`
+	OverrideBasePrompt  bool                      `json:"override_base_prompt"`
+	IsCrossMargin       *bool                     `json:"is_cross_margin"`       // 指针类型，nil表示使用默认值true
+	IndicatorSpec       *market.IndicatorSpec     `json:"indicator_spec"`        // 指标配置，nil表示使用默认的3分钟+4小时数据
+	MarginPolicy        *trader.MarginPolicy      `json:"margin_policy"`         // 保证金分配策略，nil表示使用默认的90%组合预算
+	ExposureLimits      *decision.ExposureLimits  `json:"exposure_limits"`       // 组合敞口限制，nil表示使用默认限制
+	RiskRewardRules     *decision.RiskRewardRules `json:"risk_reward_rules"`     // 止损止盈校验阈值，nil表示使用默认阈值
+	RiskPolicy          *decision.RiskPolicy      `json:"risk_policy"`           // 风控策略，nil表示按系统杠杆配置使用默认策略
+	PromptTemplate      string                    `json:"prompt_template"`       // 提示词模板（name 或 name@version），为空使用默认模板
+	CoinUniverse        *pool.Universe            `json:"coin_universe"`         // 币种范围，nil表示使用 AI500前20 + OI Top
+	ScanIntervalMinutes int                       `json:"scan_interval_minutes"` // 扫描间隔（分钟），0表示默认3分钟
+	Schedule            *trader.Schedule          `json:"schedule"`              // 周期调度，nil表示按扫描间隔执行
 }
 
 // AI模型管理相关结构体
@@ -378,31 +437,53 @@ type UpdateModelRequest struct {
 }
 
 // 交易所管理相关结构体
+
+// ExchangeCredentialsInput 交易所凭证输入（支持新版credentials对象和旧版平铺字段）
+type ExchangeCredentialsInput struct {
+	Credentials           map[string]string `json:"credentials"` // 键名见 /api/exchanges/supported-types
+	APIKey                string            `json:"apiKey"`
+	SecretKey             string            `json:"secretKey"`
+	HyperliquidWalletAddr string            `json:"hyperliquidWalletAddr"`
+	AsterUser             string            `json:"asterUser"`
+	AsterSigner           string            `json:"asterSigner"`
+	AsterPrivateKey       string            `json:"asterPrivateKey"`
+	Passphrase            string            `json:"passphrase"` // OKX API密码
+}
+
+// raw 合并新版和旧版字段（新版优先，由交易所注册表完成键名映射）
+func (in *ExchangeCredentialsInput) raw() map[string]string {
+	raw := map[string]string{
+		"apiKey":                in.APIKey,
+		"secretKey":             in.SecretKey,
+		"hyperliquidWalletAddr": in.HyperliquidWalletAddr,
+		"asterUser":             in.AsterUser,
+		"asterSigner":           in.AsterSigner,
+		"asterPrivateKey":       in.AsterPrivateKey,
+		"passphrase":            in.Passphrase,
+	}
+	for k, v := range in.Credentials {
+		if v != "" {
+			raw[k] = v
+		}
+	}
+	return raw
+}
+
 type CreateExchangeRequest struct {
-	Name                  string `json:"name" binding:"required"`
-	Type                  string `json:"type" binding:"required"` // binance, hyperliquid, aster 等
-	Enabled               bool   `json:"enabled"`
-	APIKey                string `json:"apiKey"`
-	SecretKey             string `json:"secretKey"`
-	Testnet               bool   `json:"testnet"`
-	HyperliquidWalletAddr string `json:"hyperliquidWalletAddr"`
-	AsterUser             string `json:"asterUser"`
-	AsterSigner           string `json:"asterSigner"`
-	AsterPrivateKey       string `json:"asterPrivateKey"`
-	Description           string `json:"description"`
+	Name    string `json:"name" binding:"required"`
+	Type    string `json:"type" binding:"required"` // 交易所注册表中的ID，如 binance, hyperliquid, aster, bybit, okx
+	Enabled bool   `json:"enabled"`
+	Testnet bool   `json:"testnet"`
+	ExchangeCredentialsInput
+	Description string `json:"description"`
 }
 
 type UpdateExchangeRequest struct {
-	Name                  string `json:"name" binding:"required"`
-	Enabled               bool   `json:"enabled"`
-	APIKey                string `json:"apiKey"`
-	SecretKey             string `json:"secretKey"`
-	Testnet               bool   `json:"testnet"`
-	HyperliquidWalletAddr string `json:"hyperliquidWalletAddr"`
-	AsterUser             string `json:"asterUser"`
-	AsterSigner           string `json:"asterSigner"`
-	AsterPrivateKey       string `json:"asterPrivateKey"`
-	Description           string `json:"description"`
+	Name    string `json:"name" binding:"required"`
+	Enabled bool   `json:"enabled"`
+	Testnet bool   `json:"testnet"`
+	ExchangeCredentialsInput
+	Description string `json:"description"`
 }
 
 type ModelConfig struct {
@@ -434,14 +515,9 @@ type UpdateModelConfigRequest struct {
 
 type UpdateExchangeConfigRequest struct {
 	Exchanges map[string]struct {
-		Enabled               bool   `json:"enabled"`
-		APIKey                string `json:"apiKey"`
-		SecretKey             string `json:"secretKey"`
-		Testnet               bool   `json:"testnet"`
-		HyperliquidWalletAddr string `json:"hyperliquidWalletAddr"`
-		AsterUser             string `json:"asterUser"`
-		AsterSigner           string `json:"asterSigner"`
-		AsterPrivateKey       string `json:"asterPrivateKey"`
+		Enabled bool `json:"enabled"`
+		Testnet bool `json:"testnet"`
+		ExchangeCredentialsInput
 	} `json:"exchanges"`
 }
 
@@ -463,6 +539,64 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		isCrossMargin = *req.IsCrossMargin
 	}
 
+	indicatorSpec, err := config.EncodeTraderSetting(config.SettingIndicatorSpec, req.IndicatorSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	marginPolicy, err := config.EncodeTraderSetting(config.SettingMarginPolicy, req.MarginPolicy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exposureLimits, err := config.EncodeTraderSetting(config.SettingExposureLimits, req.ExposureLimits)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	riskRewardRules, err := config.EncodeTraderSetting(config.SettingRiskRewardRules, req.RiskRewardRules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	riskPolicy, err := config.EncodeTraderSetting(config.SettingRiskPolicy, req.RiskPolicy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coinUniverse, err := config.EncodeTraderSetting(config.SettingCoinUniverse, req.CoinUniverse)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := config.EncodeTraderSetting(config.SettingSchedule, req.Schedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scanIntervalMinutes := req.ScanIntervalMinutes
+	if scanIntervalMinutes == 0 {
+		scanIntervalMinutes = 3 // 默认3分钟
+	}
+	if scanIntervalMinutes < 1 || scanIntervalMinutes > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "扫描间隔必须在1-1440分钟之间"})
+		return
+	}
+
+	if req.PromptTemplate != "" {
+		if _, err := manager.ResolvePromptTemplate(s.database, userID, req.PromptTemplate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 创建交易员配置（保留所有原有字段）
 	trader := &config.TraderRecord{
 		ID:                  traderID,
@@ -473,17 +607,25 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		Description:         fmt.Sprintf("初始余额: %.2f USDT", req.InitialBalance),
 		Enabled:             false, // 默认不启用
 		InitialBalance:      req.InitialBalance,
-		ScanIntervalMinutes: 3, // 默认3分钟
+		ScanIntervalMinutes: scanIntervalMinutes,
 		IsRunning:           false,
 		CustomPrompt:        req.CustomPrompt,
 		OverrideBasePrompt:  req.OverrideBasePrompt,
 		IsCrossMargin:       isCrossMargin,
+		IndicatorSpec:       indicatorSpec,
+		MarginPolicy:        marginPolicy,
+		ExposureLimits:      exposureLimits,
+		RiskRewardRules:     riskRewardRules,
+		RiskPolicy:          riskPolicy,
+		PromptTemplate:      req.PromptTemplate,
+		CoinUniverse:        coinUniverse,
+		Schedule:            schedule,
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
 	}
 
 	// 保存到数据库
-	err := s.database.CreateTrader(trader)
+	err = s.database.CreateTrader(trader)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建交易员失败: %v", err)})
 		return
@@ -492,11 +634,11 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	// 立即将新交易员加载到TraderManager中
 	loadErr := s.traderManager.LoadUserTraders(s.database, userID)
 	if loadErr != nil {
-		log.Printf("⚠️ 加载用户交易员到内存失败: %v", loadErr)
+		apiLog.Warnf(c.Request.Context(), "⚠️ 加载用户交易员到内存失败: %v", loadErr)
 		// 这里不返回错误，因为交易员已经成功创建到数据库
 	}
 
-	log.Printf("✓ 创建交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
+	apiLog.Infof(c.Request.Context(), "✓ 创建交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"trader_id":   traderID,
@@ -521,7 +663,7 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 	// 从内存中移除交易员（会自动停止运行中的交易员）
 	s.traderManager.RemoveTrader(traderID)
 
-	log.Printf("✓ 交易员已删除: %s", traderID)
+	apiLog.Infof(c.Request.Context(), "✓ 交易员已删除: %s", traderID)
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已删除"})
 }
 
@@ -540,12 +682,12 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
 		// 如果trader不存在于内存中，需要从数据库加载并启动
-		log.Printf("🔄 交易员 %s 不在内存中，从数据库加载并启动", traderID)
+		apiLog.Infof(c.Request.Context(), "🔄 交易员 %s 不在内存中，从数据库加载并启动", traderID)
 
 		// 确保用户的交易员已加载到内存中
 		err = s.traderManager.LoadUserTraders(s.database, userID)
 		if err != nil {
-			log.Printf("❌ 加载用户交易员失败: %v", err)
+			apiLog.Errorf(c.Request.Context(), "❌ 加载用户交易员失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "加载交易员失败"})
 			return
 		}
@@ -559,27 +701,25 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 	}
 
 	// 检查交易员是否已经在运行
-	status := trader.GetStatus()
-	if isRunning, ok := status["is_running"].(bool); ok && isRunning {
+	if trader.IsRunning() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员已在运行中"})
 		return
 	}
 
-	// 启动交易员
-	go func() {
-		log.Printf("▶️  启动交易员 %s (%s)", traderID, trader.GetName())
-		if err := trader.Run(); err != nil {
-			log.Printf("❌ 交易员 %s 运行错误: %v", trader.GetName(), err)
-		}
-	}()
+	// 在监督下启动交易员（异常退出时自动重启）
+	apiLog.Infof(c.Request.Context(), "▶️  启动交易员 %s (%s)", traderID, trader.GetName())
+	if err := s.traderManager.StartTrader(traderID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 更新数据库中的运行状态
 	err = s.database.UpdateTraderStatus(userID, traderID, true)
 	if err != nil {
-		log.Printf("⚠️  更新交易员状态失败: %v", err)
+		apiLog.Warnf(c.Request.Context(), "⚠️  更新交易员状态失败: %v", err)
 	}
 
-	log.Printf("✓ 交易员 %s 已启动", trader.GetName())
+	apiLog.Infof(c.Request.Context(), "✓ 交易员 %s 已启动", trader.GetName())
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已启动"})
 }
 
@@ -602,23 +742,19 @@ func (s *Server) handleStopTrader(c *gin.Context) {
 		return
 	}
 
-	// 检查交易员是否正在运行
-	status := trader.GetStatus()
-	if isRunning, ok := status["is_running"].(bool); ok && !isRunning {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员已停止"})
+	// 停止交易员（包括正在等待自动重启的交易员）
+	if err := s.traderManager.StopTrader(traderID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 停止交易员
-	trader.Stop()
-
 	// 更新数据库中的运行状态
 	err = s.database.UpdateTraderStatus(userID, traderID, false)
 	if err != nil {
-		log.Printf("⚠️  更新交易员状态失败: %v", err)
+		apiLog.Warnf(c.Request.Context(), "⚠️  更新交易员状态失败: %v", err)
 	}
 
-	log.Printf("⏹  交易员 %s 已停止", trader.GetName())
+	apiLog.Infof(c.Request.Context(), "⏹  交易员 %s 已停止", trader.GetName())
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已停止"})
 }
 
@@ -639,6 +775,10 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 
 	// 更新数据库
 	err := s.database.UpdateTraderCustomPrompt(userID, traderID, req.CustomPrompt, req.OverrideBasePrompt)
+	if errors.Is(err, config.ErrTraderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新自定义prompt失败: %v", err)})
 		return
@@ -649,23 +789,503 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	if err == nil {
 		trader.SetCustomPrompt(req.CustomPrompt)
 		trader.SetOverrideBasePrompt(req.OverrideBasePrompt)
-		log.Printf("✓ 已更新交易员 %s 的自定义prompt (覆盖基础=%v)", trader.GetName(), req.OverrideBasePrompt)
+		apiLog.Infof(c.Request.Context(), "✓ 已更新交易员 %s 的自定义prompt (覆盖基础=%v)", trader.GetName(), req.OverrideBasePrompt)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "自定义prompt已更新"})
 }
 
+// updateTraderSetting 生成更新交易员单项配置的处理函数
+// 请求体为 {"<配置列名>": 配置对象}，null 或缺省表示恢复默认；交易员不属于当前用户时返回404
+func updateTraderSetting[T any, PT config.SettingValue[T]](s *Server, setting config.TraderSetting, apply func(*trader.AutoTrader, PT)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traderID := c.Param("id")
+		userID := c.GetString("user_id")
+
+		var req map[string]json.RawMessage
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var value PT
+		if raw, ok := req[string(setting)]; ok && string(raw) != "null" {
+			value = PT(new(T))
+			if err := json.Unmarshal(raw, value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("解析%s失败: %v", setting.Label(), err)})
+				return
+			}
+		}
+
+		encoded, err := config.EncodeTraderSetting(setting, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 更新数据库（同时校验交易员归属）
+		if err := s.database.UpdateTraderSetting(userID, traderID, setting, encoded); err != nil {
+			if errors.Is(err, config.ErrTraderNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新%s失败: %v", setting.Label(), err)})
+			return
+		}
+
+		// 如果trader在内存中，立即生效（下一个周期使用）
+		if at, err := s.traderManager.GetTrader(traderID); err == nil {
+			apply(at, value)
+			apiLog.Infof(c.Request.Context(), "✓ 已更新交易员 %s 的%s", at.GetName(), setting.Label())
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": setting.Label() + "已更新", string(setting): value})
+	}
+}
+
+// ownedTrader 获取当前用户的交易员配置，不存在或不属于该用户时写入404响应
+func (s *Server) ownedTrader(c *gin.Context, userID, traderID string) (*config.TraderRecord, bool) {
+	traderCfg, err := s.database.GetTrader(userID, traderID)
+	if errors.Is(err, config.ErrTraderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return traderCfg, true
+}
+
+// handleGetTraderRiskPolicy 获取交易员当前生效的风控策略
+func (s *Server) handleGetTraderRiskPolicy(c *gin.Context) {
+	traderID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	traderCfg, ok := s.ownedTrader(c, userID, traderID)
+	if !ok {
+		return
+	}
+
+	// 运行中的交易员返回内存中的策略（包含默认值）
+	if at, err := s.traderManager.GetTrader(traderID); err == nil {
+		c.JSON(http.StatusOK, gin.H{"risk_policy": at.GetRiskPolicy(), "is_default": traderCfg.RiskPolicy == ""})
+		return
+	}
+
+	policy, err := config.DecodeTraderSetting[decision.RiskPolicy](traderCfg, config.SettingRiskPolicy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if policy != nil {
+		c.JSON(http.StatusOK, gin.H{"risk_policy": policy, "is_default": false})
+		return
+	}
+
+	// 未配置时按系统杠杆配置生成默认策略
+	btcEthLeverage, altcoinLeverage := 5, 5
+	if v, _ := s.database.GetSystemConfig("btc_eth_leverage"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			btcEthLeverage = n
+		}
+	}
+	if v, _ := s.database.GetSystemConfig("altcoin_leverage"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			altcoinLeverage = n
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"risk_policy": decision.DefaultRiskPolicy(btcEthLeverage, altcoinLeverage), "is_default": true})
+}
+
+// handleGetTraderCoinUniverse 获取交易员当前生效的币种范围
+func (s *Server) handleGetTraderCoinUniverse(c *gin.Context) {
+	traderID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	traderCfg, ok := s.ownedTrader(c, userID, traderID)
+	if !ok {
+		return
+	}
+
+	// 运行中的交易员返回内存中的配置（包含默认值）
+	if at, err := s.traderManager.GetTrader(traderID); err == nil {
+		c.JSON(http.StatusOK, gin.H{"coin_universe": at.GetCoinUniverse(), "is_default": traderCfg.CoinUniverse == ""})
+		return
+	}
+
+	universe, err := config.DecodeTraderSetting[pool.Universe](traderCfg, config.SettingCoinUniverse)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if universe != nil {
+		c.JSON(http.StatusOK, gin.H{"coin_universe": universe, "is_default": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"coin_universe": pool.DefaultUniverse(), "is_default": true})
+}
+
+// handleGetTraderSchedule 获取交易员当前生效的周期调度
+func (s *Server) handleGetTraderSchedule(c *gin.Context) {
+	traderID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	traderCfg, ok := s.ownedTrader(c, userID, traderID)
+	if !ok {
+		return
+	}
+
+	// 运行中的交易员返回内存中的配置和下一周期时间
+	if at, err := s.traderManager.GetTrader(traderID); err == nil {
+		status := at.GetStatus()
+		c.JSON(http.StatusOK, gin.H{
+			"schedule":              at.GetSchedule(),
+			"is_default":            traderCfg.Schedule == "",
+			"scan_interval_minutes": traderCfg.ScanIntervalMinutes,
+			"next_cycle":            status["next_cycle"],
+			"trading_gate":          status["trading_gate"],
+		})
+		return
+	}
+
+	schedule, err := config.DecodeTraderSetting[trader.Schedule](traderCfg, config.SettingSchedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if schedule != nil {
+		c.JSON(http.StatusOK, gin.H{"schedule": schedule, "is_default": false, "scan_interval_minutes": traderCfg.ScanIntervalMinutes})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": trader.DefaultSchedule(), "is_default": true, "scan_interval_minutes": traderCfg.ScanIntervalMinutes})
+}
+
+// handleUpdateTraderPromptTemplate 更新交易员使用的提示词模板
+func (s *Server) handleUpdateTraderPromptTemplate(c *gin.Context) {
+	traderID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	var req struct {
+		PromptTemplate string `json:"prompt_template"` // name 表示始终使用最新版本，name@version 固定版本，为空恢复默认
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tpl *decision.PromptTemplate
+	if req.PromptTemplate != "" {
+		var err error
+		tpl, err = manager.ResolvePromptTemplate(s.database, userID, req.PromptTemplate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 更新数据库（同时校验交易员归属）
+	err := s.database.UpdateTraderSetting(userID, traderID, config.SettingPromptTemplate, req.PromptTemplate)
+	if errors.Is(err, config.ErrTraderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新提示词模板失败: %v", err)})
+		return
+	}
+
+	// 如果trader在内存中，立即生效（下一个周期使用）
+	at, err := s.traderManager.GetTrader(traderID)
+	if err == nil {
+		at.SetPromptTemplate(tpl)
+		apiLog.Infof(c.Request.Context(), "✓ 已更新交易员 %s 的提示词模板: %s", at.GetName(), at.GetPromptTemplate().Ref())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "提示词模板已更新", "prompt_template": req.PromptTemplate})
+}
+
+// handlePreviewTraderPrompt 按交易员当前账户和行情渲染提示词（不调用AI）
+// 可通过 ?template=name@version 预览其他模板的效果
+func (s *Server) handlePreviewTraderPrompt(c *gin.Context) {
+	traderID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	if _, ok := s.ownedTrader(c, userID, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员未加载，无法预览"})
+		return
+	}
+
+	tpl := at.GetPromptTemplate()
+	if ref := c.Query("template"); ref != "" {
+		tpl, err = manager.ResolvePromptTemplate(s.database, userID, ref)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	systemPrompt, userPrompt, err := at.PreviewPrompts(c.Request.Context(), tpl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("渲染提示词失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prompt_template": tpl.Ref(),
+		"system_prompt":   systemPrompt,
+		"user_prompt":     userPrompt,
+	})
+}
+
+// handleGetPromptTemplates 获取内置模板和用户保存的所有模板版本
+func (s *Server) handleGetPromptTemplates(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	templates, err := s.database.GetPromptTemplates(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取提示词模板失败: %v", err)})
+		return
+	}
+	if templates == nil {
+		templates = []*config.PromptTemplateRecord{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"builtin":   decision.BuiltinPromptTemplates(),
+		"templates": templates,
+	})
+}
+
+// handleCreatePromptTemplate 保存提示词模板（同名模板生成新版本，旧版本保留）
+func (s *Server) handleCreatePromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		Name           string `json:"name" binding:"required"`
+		Language       string `json:"language"`
+		SystemTemplate string `json:"system_template" binding:"required"`
+		UserTemplate   string `json:"user_template" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tpl := &decision.PromptTemplate{
+		Name:     req.Name,
+		Language: req.Language,
+		System:   req.SystemTemplate,
+		User:     req.UserTemplate,
+	}
+	if err := tpl.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("模板无效: %v", err)})
+		return
+	}
+	if _, ok := decision.GetBuiltinPromptTemplate(tpl.Name); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s 是内置模板名称，请使用其他名称", tpl.Name)})
+		return
+	}
+
+	record := &config.PromptTemplateRecord{
+		UserID:         userID,
+		Name:           tpl.Name,
+		Language:       tpl.Language,
+		SystemTemplate: tpl.System,
+		UserTemplate:   tpl.User,
+	}
+	if err := s.database.CreatePromptTemplate(record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	apiLog.Infof(c.Request.Context(), "✓ 用户 %s 保存提示词模板 %s@%d", userID, record.Name, record.Version)
+	c.JSON(http.StatusOK, gin.H{"message": "提示词模板已保存", "template": record})
+}
+
+// handleGetExperiments 获取用户的所有A/B实验
+func (s *Server) handleGetExperiments(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	experiments, err := s.database.GetExperiments(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取实验列表失败: %v", err)})
+		return
+	}
+	if experiments == nil {
+		experiments = []*config.ExperimentRecord{}
+	}
+	c.JSON(http.StatusOK, experiments)
+}
+
+// handleCreateExperiment 创建A/B实验（各分组交易员必须使用同一种交易所和AI模型，第一个分组为对照组）
+func (s *Server) handleCreateExperiment(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		Name        string                  `json:"name" binding:"required"`
+		Description string                  `json:"description"`
+		Arms        []manager.ExperimentArm `json:"arms" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Arms) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "实验至少需要2个分组"})
+		return
+	}
+
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员列表失败: %v", err)})
+		return
+	}
+	traderMap := make(map[string]*config.TraderRecord)
+	for _, t := range traders {
+		traderMap[t.ID] = t
+	}
+	aiModels, err := s.database.GetAIModels(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI模型配置失败: %v", err)})
+		return
+	}
+	modelProvider := make(map[string]string)
+	for _, model := range aiModels {
+		modelProvider[model.ID] = model.Provider
+	}
+
+	// 校验分组：交易员存在、不重复，且交易所类型和AI模型一致（只有提示词或参数不同）
+	var exchangeType, aiProvider string
+	seen := make(map[string]bool)
+	for i := range req.Arms {
+		arm := &req.Arms[i]
+		traderCfg, ok := traderMap[arm.TraderID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易员 %s 不存在", arm.TraderID)})
+			return
+		}
+		if seen[arm.TraderID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易员 %s 重复出现在多个分组", arm.TraderID)})
+			return
+		}
+		seen[arm.TraderID] = true
+		if arm.Label == "" {
+			arm.Label = traderCfg.Name
+		}
+
+		exchange, err := s.database.GetExchange(userID, traderCfg.ExchangeID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易员 %s 的交易所配置不存在", traderCfg.Name)})
+			return
+		}
+		if i == 0 {
+			exchangeType, aiProvider = exchange.Type, modelProvider[traderCfg.AIModelID]
+			continue
+		}
+		if exchange.Type != exchangeType || modelProvider[traderCfg.AIModelID] != aiProvider {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易员 %s 的交易所或AI模型与对照组不一致，实验分组只应在提示词或参数上不同", traderCfg.Name)})
+			return
+		}
+	}
+
+	armsJSON, err := json.Marshal(req.Arms)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("序列化实验分组失败: %v", err)})
+		return
+	}
+
+	now := time.Now()
+	exp := &config.ExperimentRecord{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Arms:        string(armsJSON),
+		StartTime:   now,
+		CreatedAt:   now,
+	}
+	if err := s.database.CreateExperiment(exp); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建实验失败: %v", err)})
+		return
+	}
+
+	apiLog.Infof(c.Request.Context(), "✓ 用户 %s 创建A/B实验 %s（%d个分组）", userID, exp.Name, len(req.Arms))
+	c.JSON(http.StatusOK, exp)
+}
+
+// handleGetExperimentReport 获取实验报告（各分组指标和与对照组的显著性比较）
+func (s *Server) handleGetExperimentReport(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	exp, err := s.database.GetExperiment(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "实验不存在"})
+		return
+	}
+
+	report, err := s.traderManager.GetExperimentReport(exp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("生成实验报告失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleStopExperiment 结束实验（固定统计窗口）
+func (s *Server) handleStopExperiment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	experimentID := c.Param("id")
+
+	if err := s.database.StopExperiment(userID, experimentID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("结束实验失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "实验已结束"})
+}
+
+// handleDeleteExperiment 删除实验（不影响交易员和决策日志）
+func (s *Server) handleDeleteExperiment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	experimentID := c.Param("id")
+
+	if err := s.database.DeleteExperiment(userID, experimentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除实验失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "实验已删除"})
+}
+
+// handleGetSupportedIndicators 获取支持的K线周期、指标和输出格式
+func (s *Server) handleGetSupportedIndicators(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"intervals":  market.SupportedIntervals(),
+		"indicators": market.SupportedIndicators(),
+		"formatters": market.Formatters(),
+	})
+}
+
 // handleGetModelConfigs 获取AI模型配置
 func (s *Server) handleGetModelConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
-	log.Printf("🔍 查询用户 %s 的AI模型配置", userID)
+	apiLog.Infof(c.Request.Context(), "🔍 查询用户 %s 的AI模型配置", userID)
 	models, err := s.database.GetAIModels(userID)
 	if err != nil {
-		log.Printf("❌ 获取AI模型配置失败: %v", err)
+		apiLog.Errorf(c.Request.Context(), "❌ 获取AI模型配置失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI模型配置失败: %v", err)})
 		return
 	}
-	log.Printf("✅ 找到 %d 个AI模型配置", len(models))
+	apiLog.Infof(c.Request.Context(), "✅ 找到 %d 个AI模型配置", len(models))
 
 	c.JSON(http.StatusOK, models)
 }
@@ -689,23 +1309,23 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 		}
 	}
 
-	log.Printf("✓ AI模型配置已更新: %+v", req.Models)
+	apiLog.Infof(c.Request.Context(), "✓ AI模型配置已更新: %+v", req.Models)
 	c.JSON(http.StatusOK, gin.H{"message": "模型配置已更新"})
 }
 
 // handleGetExchangeConfigs 获取交易所配置
 func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
-	log.Printf("🔍 查询用户 %s 的交易所配置", userID)
+	apiLog.Infof(c.Request.Context(), "🔍 查询用户 %s 的交易所配置", userID)
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
-		log.Printf("❌ 获取交易所配置失败: %v", err)
+		apiLog.Errorf(c.Request.Context(), "❌ 获取交易所配置失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易所配置失败: %v", err)})
 		return
 	}
-	log.Printf("✅ 找到 %d 个交易所配置", len(exchanges))
+	apiLog.Infof(c.Request.Context(), "✅ 找到 %d 个交易所配置", len(exchanges))
 
-	c.JSON(http.StatusOK, exchanges)
+	c.JSON(http.StatusOK, exchangeResponses(exchanges))
 }
 
 // handleUpdateExchangeConfigs 更新交易所配置
@@ -719,15 +1339,26 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 
 	// 更新每个交易所的配置
 	for exchangeID, exchangeData := range req.Exchanges {
+		existing, err := s.database.GetExchange(userID, exchangeID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("交易所 %s 不存在", exchangeID)})
+			return
+		}
+		credentials, err := normalizeExchangeCredentials(existing.Type, exchangeData.Enabled, exchangeData.raw(), existing.Credentials)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易所 %s 配置无效: %v", exchangeID, err)})
+			return
+		}
+
 		// 使用交易所ID作为名称（临时方案，后续前端会传递名称）
-		err := s.database.UpdateExchange(userID, exchangeID, exchangeID, exchangeData.Enabled, exchangeData.APIKey, exchangeData.SecretKey, exchangeData.Testnet, exchangeData.HyperliquidWalletAddr, exchangeData.AsterUser, exchangeData.AsterSigner, exchangeData.AsterPrivateKey)
+		err = s.database.UpdateExchange(userID, exchangeID, exchangeID, exchangeData.Enabled, exchangeData.Testnet, credentials)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所 %s 失败: %v", exchangeID, err)})
 			return
 		}
 	}
 
-	log.Printf("✓ 交易所配置已更新: %+v", req.Exchanges)
+	apiLog.Infof(c.Request.Context(), "✓ 交易所配置已更新: %d 个", len(req.Exchanges))
 	c.JSON(http.StatusOK, gin.H{"message": "交易所配置已更新"})
 }
 
@@ -744,11 +1375,13 @@ func (s *Server) handleTraderList(c *gin.Context) {
 	for _, trader := range traders {
 		// 获取实时运行状态
 		isRunning := trader.IsRunning // 使用IsRunning字段
+		healthStatus := trader.HealthStatus
+		lastError := trader.LastError
 		if at, err := s.traderManager.GetTrader(trader.ID); err == nil {
-			status := at.GetStatus()
-			if running, ok := status["is_running"].(bool); ok {
-				isRunning = running
-			}
+			isRunning = at.IsRunning()
+			health := at.Health()
+			healthStatus = string(health.Status)
+			lastError = health.LastError
 		}
 
 		result = append(result, map[string]interface{}{
@@ -758,6 +1391,8 @@ func (s *Server) handleTraderList(c *gin.Context) {
 			"exchange_id":     trader.ExchangeID,
 			"is_running":      isRunning,
 			"initial_balance": trader.InitialBalance,
+			"health_status":   healthStatus,
+			"last_error":      lastError,
 		})
 	}
 
@@ -775,7 +1410,7 @@ func (s *Server) handleStatus(c *gin.Context) {
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
 		// 如果内存中找不到trader，检查数据库中是否存在
-		log.Printf("⚠️ Trader %s 不在内存中，检查数据库...", traderID)
+		apiLog.Warnf(c.Request.Context(), "⚠️ Trader %s 不在内存中，检查数据库...", traderID)
 		traderConfigs, dbErr := s.database.GetTraders(userID)
 		if dbErr != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("数据库查询失败: %v", dbErr)})
@@ -799,12 +1434,17 @@ func (s *Server) handleStatus(c *gin.Context) {
 		}
 
 		// Trader存在但未运行，返回基本状态信息
-		log.Printf("✓ Trader %s 存在但未运行，返回基本状态", traderID)
+		apiLog.Infof(c.Request.Context(), "✓ Trader %s 存在但未运行，返回基本状态", traderID)
 		status := map[string]interface{}{
-			"trader_id":       traderConfig.ID,
-			"trader_name":     traderConfig.Name,
-			"ai_model":        "", // 需要从AI模型配置获取
-			"is_running":      false,
+			"trader_id":   traderConfig.ID,
+			"trader_name": traderConfig.Name,
+			"ai_model":    "", // 需要从AI模型配置获取
+			"is_running":  false,
+			"state":       "stopped",
+			"health": map[string]interface{}{
+				"status":     traderConfig.HealthStatus,
+				"last_error": traderConfig.LastError,
+			},
 			"start_time":      "",
 			"runtime_minutes": 0,
 			"call_count":      0,
@@ -850,17 +1490,17 @@ func (s *Server) handleAccount(c *gin.Context) {
 		return
 	}
 
-	log.Printf("📊 收到账户信息请求 [%s]", trader.GetName())
-	account, err := trader.GetAccountInfo()
+	apiLog.Infof(c.Request.Context(), "📊 收到账户信息请求 [%s]", trader.GetName())
+	account, err := trader.GetAccountInfo(c.Request.Context())
 	if err != nil {
-		log.Printf("❌ 获取账户信息失败 [%s]: %v", trader.GetName(), err)
+		apiLog.Errorf(c.Request.Context(), "❌ 获取账户信息失败 [%s]: %v", trader.GetName(), err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取账户信息失败: %v", err),
 		})
 		return
 	}
 
-	log.Printf("✓ 返回账户信息 [%s]: 净值=%.2f, 可用=%.2f, 盈亏=%.2f (%.2f%%)",
+	apiLog.Infof(c.Request.Context(), "✓ 返回账户信息 [%s]: 净值=%.2f, 可用=%.2f, 盈亏=%.2f (%.2f%%)",
 		trader.GetName(),
 		account["total_equity"],
 		account["available_balance"],
@@ -885,7 +1525,7 @@ func (s *Server) handlePositions(c *gin.Context) {
 		return
 	}
 
-	positions, err := trader.GetPositions()
+	positions, err := trader.GetPositions(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取持仓列表失败: %v", err),
@@ -996,7 +1636,7 @@ func (s *Server) handleCompetition(c *gin.Context) {
 	// 确保用户的交易员已加载到内存中
 	err := s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
-		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+		apiLog.Warnf(c.Request.Context(), "⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
 	}
 
 	// 使用带数据库访问的方法获取竞赛数据（包含交易所信息）
@@ -1033,7 +1673,7 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		return
 	}
 
-	trader, err := s.getTraderWithFallback(userID, traderID)
+	trader, err := s.getTraderWithFallback(c.Request.Context(), userID, traderID)
 	if err != nil {
 		if strings.Contains(err.Error(), "不存在") {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -1301,9 +1941,9 @@ func (s *Server) handleCompleteRegistration(c *gin.Context) {
 	}
 
 	// 初始化用户的默认模型和交易所配置
-	err = s.initUserDefaultConfigs(user.ID)
+	err = s.initUserDefaultConfigs(c.Request.Context(), user.ID)
 	if err != nil {
-		log.Printf("初始化用户默认配置失败: %v", err)
+		apiLog.Warnf(c.Request.Context(), "初始化用户默认配置失败: %v", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -1399,10 +2039,10 @@ func (s *Server) handleVerifyOTP(c *gin.Context) {
 }
 
 // initUserDefaultConfigs 为新用户初始化默认的模型和交易所配置
-func (s *Server) initUserDefaultConfigs(userID string) error {
+func (s *Server) initUserDefaultConfigs(ctx context.Context, userID string) error {
 	// 注释掉自动创建默认配置，让用户手动添加
 	// 这样新用户注册后不会自动有配置项
-	log.Printf("用户 %s 注册完成，等待手动配置AI模型和交易所", userID)
+	apiLog.Infof(ctx, "用户 %s 注册完成，等待手动配置AI模型和交易所", userID)
 	return nil
 }
 
@@ -1411,7 +2051,7 @@ func (s *Server) handleGetSupportedModels(c *gin.Context) {
 	// 返回系统支持的AI模型（从default用户获取）
 	models, err := s.database.GetAIModels("default")
 	if err != nil {
-		log.Printf("❌ 获取支持的AI模型失败: %v", err)
+		apiLog.Errorf(c.Request.Context(), "❌ 获取支持的AI模型失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取支持的AI模型失败"})
 		return
 	}
@@ -1424,12 +2064,12 @@ func (s *Server) handleGetSupportedExchanges(c *gin.Context) {
 	// 返回系统支持的交易所（从default用户获取）
 	exchanges, err := s.database.GetExchanges("default")
 	if err != nil {
-		log.Printf("❌ 获取支持的交易所失败: %v", err)
+		apiLog.Errorf(c.Request.Context(), "❌ 获取支持的交易所失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取支持的交易所失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, exchanges)
+	c.JSON(http.StatusOK, exchangeResponses(exchanges))
 }
 
 // handleCreateModel 创建AI模型
@@ -1454,7 +2094,7 @@ func (s *Server) handleCreateModel(c *gin.Context) {
 		return
 	}
 
-	log.Printf("✓ 创建AI模型成功: %s (类型: %s)", req.Name, req.Provider)
+	apiLog.Infof(c.Request.Context(), "✓ 创建AI模型成功: %s (类型: %s)", req.Name, req.Provider)
 	c.JSON(http.StatusCreated, gin.H{
 		"model_id":   modelID,
 		"model_name": req.Name,
@@ -1483,7 +2123,7 @@ func (s *Server) handleUpdateModel(c *gin.Context) {
 		return
 	}
 
-	log.Printf("✓ 更新AI模型成功: %s", modelID)
+	apiLog.Infof(c.Request.Context(), "✓ 更新AI模型成功: %s", modelID)
 	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
 }
 
@@ -1502,7 +2142,7 @@ func (s *Server) handleDeleteModel(c *gin.Context) {
 		return
 	}
 
-	log.Printf("✓ 删除AI模型成功: %s", modelID)
+	apiLog.Infof(c.Request.Context(), "✓ 删除AI模型成功: %s", modelID)
 	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
 }
 
@@ -1522,14 +2162,19 @@ func (s *Server) handleCreateExchange(c *gin.Context) {
 	// 生成交易所ID
 	exchangeID := fmt.Sprintf("%s_%s_%d", req.Type, strings.ToLower(strings.ReplaceAll(req.Name, " ", "_")), time.Now().Unix())
 
-	_, err := s.database.CreateExchangeWithDescription(userID, req.Name, req.Type, req.Enabled, req.APIKey, req.SecretKey, req.Testnet,
-		req.HyperliquidWalletAddr, req.AsterUser, req.AsterSigner, req.AsterPrivateKey, req.Description)
+	credentials, err := normalizeExchangeCredentials(req.Type, req.Enabled, req.raw(), nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = s.database.CreateExchangeWithDescription(userID, req.Name, req.Type, req.Enabled, req.Testnet, credentials, req.Description)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建交易所失败: %v", err)})
 		return
 	}
 
-	log.Printf("✓ 创建交易所成功: %s (类型: %s)", req.Name, req.Type)
+	apiLog.Infof(c.Request.Context(), "✓ 创建交易所成功: %s (类型: %s)", req.Name, req.Type)
 	c.JSON(http.StatusCreated, gin.H{
 		"exchange_id":   exchangeID,
 		"exchange_name": req.Name,
@@ -1552,14 +2197,25 @@ func (s *Server) handleUpdateExchange(c *gin.Context) {
 		return
 	}
 
-	err := s.database.UpdateExchangeWithDescription(userID, exchangeID, req.Name, req.Enabled, req.APIKey, req.SecretKey, req.Testnet,
-		req.HyperliquidWalletAddr, req.AsterUser, req.AsterSigner, req.AsterPrivateKey, req.Description)
+	existing, err := s.database.GetExchange(userID, exchangeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	credentials, err := normalizeExchangeCredentials(existing.Type, req.Enabled, req.raw(), existing.Credentials)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = s.database.UpdateExchangeWithDescription(userID, exchangeID, req.Name, req.Enabled, req.Testnet, credentials, req.Description)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所失败: %v", err)})
 		return
 	}
 
-	log.Printf("✓ 更新交易所成功: %s", exchangeID)
+	apiLog.Infof(c.Request.Context(), "✓ 更新交易所成功: %s", exchangeID)
 	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
 }
 
@@ -1578,7 +2234,7 @@ func (s *Server) handleDeleteExchange(c *gin.Context) {
 		return
 	}
 
-	log.Printf("✓ 删除交易所成功: %s", exchangeID)
+	apiLog.Infof(c.Request.Context(), "✓ 删除交易所成功: %s", exchangeID)
 	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
 }
 
@@ -1603,78 +2259,154 @@ func (s *Server) handleGetSupportedModelTypes(c *gin.Context) {
 	})
 }
 
-// handleGetSupportedExchangeTypes 获取支持的交易所类型列表
+// handleGetSupportedExchangeTypes 获取支持的交易所类型列表（含凭证字段定义和能力标记）
 func (s *Server) handleGetSupportedExchangeTypes(c *gin.Context) {
-	types, err := s.database.GetSystemConfig("exchange_types")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取支持的交易所类型失败"})
-		return
-	}
-
-	// 解析JSON字符串
-	var exchangeTypes []string
-	if err := json.Unmarshal([]byte(types), &exchangeTypes); err != nil {
-		// 如果解析失败，返回默认值
-		exchangeTypes = []string{"binance", "hyperliquid", "aster", "okx", "dydx"}
+	adapters := trader.ListExchangeAdapters()
+	exchangeTypes := make([]string, 0, len(adapters))
+	for _, adapter := range adapters {
+		exchangeTypes = append(exchangeTypes, adapter.ID)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"supported_types": exchangeTypes,
+		"exchanges":       adapters,
 		"count":           len(exchangeTypes),
 	})
 }
 
+// normalizeExchangeCredentials 按交易所注册表的凭证定义整理凭证，启用时校验必填项和格式
+// existing 为已保存的凭证：敏感字段提交为空或为脱敏后的值时沿用已保存的值
+func normalizeExchangeCredentials(exchangeType string, enabled bool, raw map[string]string, existing map[string]string) (map[string]string, error) {
+	adapter, err := trader.GetExchangeAdapter(exchangeType)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := adapter.NormalizeCredentials(raw)
+	for _, field := range adapter.Fields {
+		if !field.Secret {
+			continue
+		}
+		saved := existing[field.Key]
+		if value := credentials[field.Key]; saved != "" && (value == "" || value == maskSecret(saved)) {
+			credentials[field.Key] = saved
+		}
+	}
+	if enabled {
+		if err := adapter.ValidateCredentials(credentials); err != nil {
+			return nil, err
+		}
+	}
+	return credentials, nil
+}
+
+// exchangeResponses 构建交易所配置响应（额外输出旧版平铺字段，兼容现有前端）
+// 敏感字段（Secret/私钥等）只返回脱敏后的值，提交更新时原样传回表示不修改
+func exchangeResponses(exchanges []*config.ExchangeConfig) []gin.H {
+	result := make([]gin.H, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		credentials := make(map[string]string, len(exchange.Credentials))
+		item := gin.H{
+			"id":          exchange.ID,
+			"user_id":     exchange.UserID,
+			"name":        exchange.Name,
+			"type":        exchange.Type,
+			"enabled":     exchange.Enabled,
+			"testnet":     exchange.Testnet,
+			"credentials": credentials,
+			"description": exchange.Description,
+			"created_at":  exchange.CreatedAt,
+			"updated_at":  exchange.UpdatedAt,
+		}
+		adapter, err := trader.GetExchangeAdapter(exchange.Type)
+		if err != nil {
+			// 未注册的交易所无法区分敏感字段，全部脱敏
+			for key, value := range exchange.Credentials {
+				credentials[key] = maskSecret(value)
+			}
+			result = append(result, item)
+			continue
+		}
+		for _, field := range adapter.Fields {
+			value := exchange.Credentials[field.Key]
+			if value == "" {
+				value = exchange.Credentials[field.LegacyKey]
+			}
+			if field.Secret {
+				value = maskSecret(value)
+			}
+			credentials[field.Key] = value
+			if field.LegacyKey != "" {
+				item[field.LegacyKey] = value
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// maskSecret 敏感值脱敏（只保留末4位用于辨认）
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "********"
+	}
+	return "********" + value[len(value)-4:]
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
+	ctx := context.Background()
 	addr := fmt.Sprintf(":%d", s.port)
-	log.Printf("🌐 API服务器启动在 http://localhost%s", addr)
-	log.Printf("📊 API文档:")
-	log.Printf("  • GET  /health               - 健康检查")
-	log.Printf("  • GET  /api/traders          - AI交易员列表")
-	log.Printf("  • POST /api/traders          - 创建新的AI交易员")
-	log.Printf("  • DELETE /api/traders/:id    - 删除AI交易员")
-	log.Printf("  • POST /api/traders/:id/start - 启动AI交易员")
-	log.Printf("  • POST /api/traders/:id/stop  - 停止AI交易员")
-	log.Printf("  • GET  /api/models           - 获取AI模型配置")
-	log.Printf("  • POST /api/models           - 创建新的AI模型")
-	log.Printf("  • PUT  /api/models/:id       - 更新AI模型配置")
-	log.Printf("  • DELETE /api/models/:id     - 删除AI模型")
-	log.Printf("  • GET  /api/models/supported-types - 获取支持的AI模型类型")
-	log.Printf("  • GET  /api/exchanges        - 获取交易所配置")
-	log.Printf("  • POST /api/exchanges        - 创建新的交易所")
-	log.Printf("  • PUT  /api/exchanges/:id     - 更新交易所配置")
-	log.Printf("  • DELETE /api/exchanges/:id   - 删除交易所")
-	log.Printf("  • GET  /api/exchanges/supported-types - 获取支持的交易所类型")
-	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
-	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
-	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
-	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
-	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
-	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
-	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据")
-	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
-	log.Println()
+	apiLog.Infof(ctx, "🌐 API服务器启动在 http://localhost%s", addr)
+	apiLog.Infof(ctx, "📊 API文档:")
+	apiLog.Infof(ctx, "  • GET  /health               - 健康检查")
+	apiLog.Infof(ctx, "  • GET  /api/traders          - AI交易员列表")
+	apiLog.Infof(ctx, "  • POST /api/traders          - 创建新的AI交易员")
+	apiLog.Infof(ctx, "  • DELETE /api/traders/:id    - 删除AI交易员")
+	apiLog.Infof(ctx, "  • POST /api/traders/:id/start - 启动AI交易员")
+	apiLog.Infof(ctx, "  • POST /api/traders/:id/stop  - 停止AI交易员")
+	apiLog.Infof(ctx, "  • GET  /api/models           - 获取AI模型配置")
+	apiLog.Infof(ctx, "  • POST /api/models           - 创建新的AI模型")
+	apiLog.Infof(ctx, "  • PUT  /api/models/:id       - 更新AI模型配置")
+	apiLog.Infof(ctx, "  • DELETE /api/models/:id     - 删除AI模型")
+	apiLog.Infof(ctx, "  • GET  /api/models/supported-types - 获取支持的AI模型类型")
+	apiLog.Infof(ctx, "  • GET  /api/exchanges        - 获取交易所配置")
+	apiLog.Infof(ctx, "  • POST /api/exchanges        - 创建新的交易所")
+	apiLog.Infof(ctx, "  • PUT  /api/exchanges/:id     - 更新交易所配置")
+	apiLog.Infof(ctx, "  • DELETE /api/exchanges/:id   - 删除交易所")
+	apiLog.Infof(ctx, "  • GET  /api/exchanges/supported-types - 获取支持的交易所类型")
+	apiLog.Infof(ctx, "  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
+	apiLog.Infof(ctx, "  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
+	apiLog.Infof(ctx, "  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
+	apiLog.Infof(ctx, "  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
+	apiLog.Infof(ctx, "  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
+	apiLog.Infof(ctx, "  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
+	apiLog.Infof(ctx, "  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据")
+	apiLog.Infof(ctx, "  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
 
 	return s.router.Run(addr)
 }
 
 // setupBotRoutes 设置Bot API路由
 func (s *Server) setupBotRoutes() {
+	ctx := context.Background()
 	botConfig := GetBotAuthConfig()
 	botGroup := s.router.Group("/api/bot")
 	botGroup.Use(BotAuthMiddleware(botConfig))
 
-	log.Printf("🤖 Bot API endpoints (with authentication):")
-	log.Printf("  • GET  /api/bot/health           - 健康检查")
-	log.Printf("  • GET  /api/bot/traders          - 获取交易员列表")
-	log.Printf("  • POST /api/bot/traders          - 创建交易员")
-	log.Printf("  • POST /api/bot/traders/:id/start - 启动交易员")
-	log.Printf("  • POST /api/bot/traders/:id/stop  - 停止交易员")
-	log.Printf("  • GET  /api/bot/traders/:id/status - 获取交易员状态")
-	log.Printf("  • GET  /api/bot/ai-models        - 获取AI模型列表")
-	log.Printf("  • POST /api/bot/ai-models        - 创建AI模型")
-	log.Printf("  • GET  /api/bot/exchanges        - 获取交易所列表")
-	log.Println()
+	apiLog.Infof(ctx, "🤖 Bot API endpoints (with authentication):")
+	apiLog.Infof(ctx, "  • GET  /api/bot/health           - 健康检查")
+	apiLog.Infof(ctx, "  • GET  /api/bot/traders          - 获取交易员列表")
+	apiLog.Infof(ctx, "  • POST /api/bot/traders          - 创建交易员")
+	apiLog.Infof(ctx, "  • POST /api/bot/traders/:id/start - 启动交易员")
+	apiLog.Infof(ctx, "  • POST /api/bot/traders/:id/stop  - 停止交易员")
+	apiLog.Infof(ctx, "  • GET  /api/bot/traders/:id/status - 获取交易员状态")
+	apiLog.Infof(ctx, "  • GET  /api/bot/ai-models        - 获取AI模型列表")
+	apiLog.Infof(ctx, "  • POST /api/bot/ai-models        - 创建AI模型")
+	apiLog.Infof(ctx, "  • GET  /api/bot/exchanges        - 获取交易所列表")
 
 	// 健康检查
 	botGroup.GET("/health", func(c *gin.Context) {
@@ -1742,7 +2474,7 @@ func (s *Server) setupBotRoutes() {
 		traderID := c.Param("id")
 		telegramUserID := c.GetString("telegram_user_id")
 
-		log.Printf("Bot request to start trader %s by user %s", traderID, telegramUserID)
+		apiLog.Infof(c.Request.Context(), "Bot request to start trader %s by user %s", traderID, telegramUserID)
 
 		// TODO: 验证trader属于该Telegram用户
 		// TODO: 实现启动trader逻辑
@@ -1757,7 +2489,7 @@ func (s *Server) setupBotRoutes() {
 		traderID := c.Param("id")
 		telegramUserID := c.GetString("telegram_user_id")
 
-		log.Printf("Bot request to stop trader %s by user %s", traderID, telegramUserID)
+		apiLog.Infof(c.Request.Context(), "Bot request to stop trader %s by user %s", traderID, telegramUserID)
 
 		// TODO: 验证trader属于该Telegram用户
 		// TODO: 实现停止trader逻辑
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"nofx/logging"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 数据库后端类型
+const (
+	BackendSQLite   = "sqlite"   // 内嵌 SQLite（单机、离线、测试）
+	BackendPostgres = "postgres" // 任意 PostgreSQL（标准DSN）
+	BackendSupabase = "supabase" // Supabase（PostgreSQL 的一种，强制SSL）
+)
+
+// configLog 配置和数据库日志
+var configLog = logging.For("config")
+
+// defaultCredentialsKeyFile 默认的凭证加密密钥文件（与数据库分开保存）
+const defaultCredentialsKeyFile = "secrets/credentials.key"
+
+// DatabaseOptions 数据库连接选项
+type DatabaseOptions struct {
+	Backend string // sqlite / postgres / supabase
+	DSN     string // SQLite 文件路径或 PostgreSQL 连接字符串
+
+	// AutoMigrate 启动时自动执行未执行的迁移（DATABASE_AUTO_MIGRATE=false 关闭，
+	// 关闭后存在未执行的迁移时拒绝启动，需先运行 nofx migrate up）
+	AutoMigrate bool
+
+	// CredentialsKeyFile 未设置 CREDENTIALS_ENCRYPTION_KEY 时使用的凭证加密密钥文件（不存在时自动生成）
+	CredentialsKeyFile string
+}
+
+// ResolveDatabaseOptions 根据环境变量确定数据库后端
+// DATABASE_BACKEND 显式指定后端；否则 DATABASE_URL 为空时使用 SQLite 文件 dbPath，
+// 包含 "supabase" 的连接串视为 Supabase，其余视为普通 PostgreSQL
+func ResolveDatabaseOptions(dbPath string) DatabaseOptions {
+	dbURL := strings.TrimSpace(os.Getenv("DATABASE_URL"))
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("DATABASE_BACKEND")))
+
+	if backend == "" {
+		switch {
+		case dbURL == "":
+			backend = BackendSQLite
+		case strings.Contains(dbURL, "supabase"):
+			backend = BackendSupabase
+		default:
+			backend = BackendPostgres
+		}
+	}
+
+	dsn := dbURL
+	if backend == BackendSQLite && (dsn == "" || isPostgresDSN(dsn)) {
+		dsn = dbPath
+	}
+	autoMigrate := true
+	if v := strings.TrimSpace(os.Getenv("DATABASE_AUTO_MIGRATE")); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			autoMigrate = parsed
+		} else {
+			configLog.Warnf(context.Background(), "⚠️  DATABASE_AUTO_MIGRATE=%s 无效，使用默认值 true", v)
+		}
+	}
+
+	keyFile := strings.TrimSpace(os.Getenv("CREDENTIALS_ENCRYPTION_KEY_FILE"))
+	if keyFile == "" {
+		keyFile = defaultCredentialsKeyFile
+	}
+
+	return DatabaseOptions{Backend: backend, DSN: dsn, AutoMigrate: autoMigrate, CredentialsKeyFile: keyFile}
+}
+
+// isPostgresDSN 是否为 PostgreSQL 连接字符串
+func isPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// openBackend 按后端类型打开数据库连接并测试连通性
+func openBackend(opts DatabaseOptions) (*sql.DB, error) {
+	switch opts.Backend {
+	case BackendSQLite:
+		return openSQLite(opts.DSN)
+	case BackendPostgres, BackendSupabase:
+		return openPostgres(opts)
+	default:
+		return nil, fmt.Errorf("不支持的数据库后端: %s（支持 %s/%s/%s）", opts.Backend, BackendSQLite, BackendPostgres, BackendSupabase)
+	}
+}
+
+// sqliteDriverName 查找已注册的 SQLite 驱动（modernc.org/sqlite 注册为 sqlite，mattn/go-sqlite3 注册为 sqlite3）
+func sqliteDriverName() (string, error) {
+	for _, name := range sql.Drivers() {
+		if name == "sqlite" || name == "sqlite3" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("当前程序未编译 SQLite 驱动（使用了 -tags nosqlite 构建）：请设置 DATABASE_URL 使用 PostgreSQL，或去掉 nosqlite 标签重新构建")
+}
+
+// openSQLite 打开内嵌 SQLite 数据库
+func openSQLite(path string) (*sql.DB, error) {
+	ctx := context.Background()
+	if path == "" {
+		return nil, fmt.Errorf("SQLite 数据库文件路径为空")
+	}
+
+	driver, err := sqliteDriverName()
+	if err != nil {
+		return nil, err
+	}
+
+	configLog.Infof(ctx, "📋 使用内嵌 SQLite 数据库: %s", path)
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		return nil, fmt.Errorf("打开 SQLite 数据库失败: %w", err)
+	}
+
+	// SQLite 同一时间只允许一个写入者，单连接避免 database is locked，也让下面的 PRAGMA 对所有查询生效
+	db.SetMaxOpenConns(1)
+
+	pragmas := []string{
+		`PRAGMA foreign_keys = ON`,
+		`PRAGMA busy_timeout = 5000`,
+		`PRAGMA journal_mode = WAL`,
+	}
+	for _, pragma := range pragmas {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("设置 SQLite 参数失败 [%s]: %w", pragma, err)
+		}
+	}
+
+	configLog.Infof(ctx, "✅ SQLite 数据库打开成功")
+	return db, nil
+}
+
+// openPostgres 连接 PostgreSQL（Supabase 未指定 sslmode 时强制 require）
+func openPostgres(opts DatabaseOptions) (*sql.DB, error) {
+	ctx := context.Background()
+	dbURL := opts.DSN
+	if dbURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL 环境变量未设置，%s 后端需要 PostgreSQL 连接字符串", opts.Backend)
+	}
+
+	name := "PostgreSQL"
+	if opts.Backend == BackendSupabase {
+		name = "Supabase PostgreSQL"
+	}
+	configLog.Infof(ctx, "📋 连接到 %s 数据库", name)
+
+	// 将 postgresql:// 转换为 postgres:// 以便正确解析
+	if strings.HasPrefix(dbURL, "postgresql://") {
+		dbURL = strings.Replace(dbURL, "postgresql://", "postgres://", 1)
+	}
+
+	// 只补充连接串中未指定的参数
+	var params []string
+	if opts.Backend == BackendSupabase && !strings.Contains(dbURL, "sslmode=") {
+		params = append(params, "sslmode=require")
+	}
+	if !strings.Contains(dbURL, "connect_timeout=") {
+		params = append(params, "connect_timeout=30")
+	}
+	if len(params) > 0 {
+		separator := "?"
+		if strings.Contains(dbURL, "?") {
+			separator = "&"
+		}
+		dbURL += separator + strings.Join(params, "&")
+	}
+
+	configLog.Infof(ctx, "🔗 使用连接字符串: %s", maskDSN(dbURL))
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("连接 %s 失败: %w", name, err)
+	}
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		db.Close()
+		// 提供更详细的错误诊断
+		if strings.Contains(err.Error(), "network is unreachable") {
+			return nil, fmt.Errorf("%s 连接测试失败: 网络不可达。请检查:\n1. DATABASE_URL 是否正确配置\n2. 数据库服务是否正常运行\n3. 网络连接是否正常\n详细错误: %w", name, err)
+		} else if strings.Contains(err.Error(), "connection refused") {
+			return nil, fmt.Errorf("%s 连接测试失败: 连接被拒绝。请检查:\n1. 数据库服务状态\n2. 连接字符串中的端口和主机\n3. 防火墙设置\n详细错误: %w", name, err)
+		} else if strings.Contains(err.Error(), "password authentication failed") {
+			return nil, fmt.Errorf("%s 连接测试失败: 密码认证失败。请检查 DATABASE_URL 中的密码是否正确\n详细错误: %w", name, err)
+		} else if strings.Contains(err.Error(), "SSL is not enabled") {
+			return nil, fmt.Errorf("%s 连接测试失败: 服务器未启用SSL。本地数据库请在 DATABASE_URL 中添加 sslmode=disable\n详细错误: %w", name, err)
+		}
+		return nil, fmt.Errorf("%s 连接测试失败: %w", name, err)
+	}
+
+	configLog.Infof(ctx, "✅ %s 数据库连接成功", name)
+	return db, nil
+}
+
+// maskDSN 隐藏连接字符串中的密码
+func maskDSN(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return dsn
+	}
+	scheme := ""
+	if i := strings.Index(dsn, "://"); i >= 0 && i < at {
+		scheme = dsn[:i+3]
+	}
+	user := strings.SplitN(strings.TrimPrefix(dsn[:at], scheme), ":", 2)[0]
+	return scheme + user + ":***@" + dsn[at+1:]
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -14,22 +15,12 @@ type TraderConfig struct {
 	Enabled bool   `json:"enabled"` // 是否启用该trader
 	AIModel string `json:"ai_model"` // "qwen" or "deepseek"
 
-	// 交易平台选择（二选一）
-	Exchange string `json:"exchange"` // "binance" or "hyperliquid"
+	// 交易平台（可选值由 trader 包中的交易所注册表决定）
+	Exchange string `json:"exchange"` // 如 "binance", "hyperliquid", "aster", "bybit", "okx"
 
-	// 币安配置
-	BinanceAPIKey    string `json:"binance_api_key,omitempty"`
-	BinanceSecretKey string `json:"binance_secret_key,omitempty"`
-
-	// Hyperliquid配置
-	HyperliquidPrivateKey string `json:"hyperliquid_private_key,omitempty"`
-	HyperliquidWalletAddr string `json:"hyperliquid_wallet_addr,omitempty"`
-	HyperliquidTestnet    bool   `json:"hyperliquid_testnet,omitempty"`
-
-	// Aster配置
-	AsterUser       string `json:"aster_user,omitempty"`        // Aster主钱包地址
-	AsterSigner     string `json:"aster_signer,omitempty"`      // Aster API钱包地址
-	AsterPrivateKey string `json:"aster_private_key,omitempty"` // Aster API钱包私钥
+	// 交易所凭证（键名见各交易所注册的凭证字段，如 api_key、secret_key）
+	ExchangeCredentials map[string]string `json:"exchange_credentials,omitempty"`
+	ExchangeTestnet     bool              `json:"exchange_testnet,omitempty"`
 
 	// AI配置
 	QwenKey     string `json:"qwen_key,omitempty"`
@@ -126,27 +117,12 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("trader[%d]: ai_model必须是 'qwen', 'deepseek' 或 'custom'", i)
 		}
 
-		// 验证交易平台配置
+		// 验证交易平台配置（交易平台是否支持及凭证校验由交易所注册表在创建交易器时完成）
 		if trader.Exchange == "" {
 			trader.Exchange = "binance" // 默认使用币安
 		}
-		if trader.Exchange != "binance" && trader.Exchange != "hyperliquid" && trader.Exchange != "aster" {
-			return fmt.Errorf("trader[%d]: exchange必须是 'binance', 'hyperliquid' 或 'aster'", i)
-		}
-
-		// 根据平台验证对应的密钥
-		if trader.Exchange == "binance" {
-			if trader.BinanceAPIKey == "" || trader.BinanceSecretKey == "" {
-				return fmt.Errorf("trader[%d]: 使用币安时必须配置binance_api_key和binance_secret_key", i)
-			}
-		} else if trader.Exchange == "hyperliquid" {
-			if trader.HyperliquidPrivateKey == "" {
-				return fmt.Errorf("trader[%d]: 使用Hyperliquid时必须配置hyperliquid_private_key", i)
-			}
-		} else if trader.Exchange == "aster" {
-			if trader.AsterUser == "" || trader.AsterSigner == "" || trader.AsterPrivateKey == "" {
-				return fmt.Errorf("trader[%d]: 使用Aster时必须配置aster_user, aster_signer和aster_private_key", i)
-			}
+		if len(trader.ExchangeCredentials) == 0 {
+			return fmt.Errorf("trader[%d]: 必须配置exchange_credentials", i)
 		}
 
 		if trader.AIModel == "qwen" && trader.QwenKey == "" {
@@ -183,13 +159,13 @@ func (c *Config) Validate() error {
 		c.Leverage.BTCETHLeverage = 5 // 默认5倍（安全值，适配子账户）
 	}
 	if c.Leverage.BTCETHLeverage > 5 {
-		fmt.Printf("⚠️  警告: BTC/ETH杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）\n", c.Leverage.BTCETHLeverage)
+		configLog.Warnf(context.Background(), "⚠️  警告: BTC/ETH杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）", c.Leverage.BTCETHLeverage)
 	}
 	if c.Leverage.AltcoinLeverage <= 0 {
 		c.Leverage.AltcoinLeverage = 5 // 默认5倍（安全值，适配子账户）
 	}
 	if c.Leverage.AltcoinLeverage > 5 {
-		fmt.Printf("⚠️  警告: 山寨币杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）\n", c.Leverage.AltcoinLeverage)
+		configLog.Warnf(context.Background(), "⚠️  警告: 山寨币杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）", c.Leverage.AltcoinLeverage)
 	}
 
 	return nil
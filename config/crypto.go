@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// credentialsPrefix 加密凭证前缀（便于区分版本和识别未加密的旧数据）
+const credentialsPrefix = "enc:v1:"
+
+// legacyCredentialsKeyConfig 旧版本保存在 system_config 中的自动生成密钥
+const legacyCredentialsKeyConfig = "credentials_encryption_key"
+
+// initCredentialsKey 初始化交易所凭证加密密钥
+// 优先使用 CREDENTIALS_ENCRYPTION_KEY 环境变量，其次为数据库之外的密钥文件（不存在时自动生成）。
+// 密钥不能与密文保存在同一个数据库中，否则拿到数据库备份即可解密全部凭证
+func (d *Database) initCredentialsKey(keyFile string) error {
+	ctx := context.Background()
+	secret := os.Getenv("CREDENTIALS_ENCRYPTION_KEY")
+	if secret == "" {
+		if keyFile == "" {
+			return fmt.Errorf("未设置 CREDENTIALS_ENCRYPTION_KEY 环境变量，也未配置密钥文件 CREDENTIALS_ENCRYPTION_KEY_FILE")
+		}
+		stored, err := d.loadCredentialsKeyFile(keyFile)
+		if err != nil {
+			return err
+		}
+		configLog.Warnf(ctx, "⚠️  未设置 CREDENTIALS_ENCRYPTION_KEY 环境变量，使用密钥文件 %s，请妥善备份该文件", keyFile)
+		secret = stored
+	}
+
+	// 清理旧版本写入数据库的密钥（已迁移到密钥文件或由环境变量接管）
+	if legacy, _ := d.GetSystemConfig(legacyCredentialsKeyConfig); legacy != "" {
+		if legacy != secret {
+			configLog.Warnf(ctx, "⚠️  数据库中残留旧版凭证加密密钥，但与当前密钥不一致，保留不删除；确认凭证可正常解密后请手动删除 system_config.%s", legacyCredentialsKeyConfig)
+		} else if err := d.deleteSystemConfig(legacyCredentialsKeyConfig); err != nil {
+			configLog.Warnf(ctx, "⚠️  删除数据库中的旧版凭证加密密钥失败: %v", err)
+		} else {
+			configLog.Infof(ctx, "✓ 已从数据库中删除旧版凭证加密密钥")
+		}
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	d.credentialsKey = key[:]
+	return nil
+}
+
+// loadCredentialsKeyFile 读取密钥文件；文件不存在时迁移数据库中的旧版密钥或生成新密钥，并以 0600 权限写入
+func (d *Database) loadCredentialsKeyFile(keyFile string) (string, error) {
+	ctx := context.Background()
+	data, err := os.ReadFile(keyFile)
+	if err == nil {
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return "", fmt.Errorf("凭证加密密钥文件 %s 为空", keyFile)
+		}
+		return secret, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("读取凭证加密密钥文件失败: %w", err)
+	}
+
+	// 旧版本将自动生成的密钥保存在数据库中，迁移到密钥文件以继续解密已保存的凭证
+	secret, _ := d.GetSystemConfig(legacyCredentialsKeyConfig)
+	if secret != "" {
+		configLog.Infof(ctx, "🔄 将数据库中的凭证加密密钥迁移到密钥文件 %s", keyFile)
+	} else {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("生成凭证加密密钥失败: %w", err)
+		}
+		secret = base64.StdEncoding.EncodeToString(buf)
+		configLog.Infof(ctx, "✓ 已生成凭证加密密钥文件 %s", keyFile)
+	}
+
+	if dir := filepath.Dir(keyFile); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", fmt.Errorf("创建密钥文件目录失败: %w", err)
+		}
+	}
+	f, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("创建凭证加密密钥文件失败: %w", err)
+	}
+	if _, err := f.WriteString(secret + "\n"); err != nil {
+		f.Close()
+		return "", fmt.Errorf("写入凭证加密密钥文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("写入凭证加密密钥文件失败: %w", err)
+	}
+	return secret, nil
+}
+
+// encryptCredentials 将交易所凭证序列化为JSON并使用AES-GCM加密
+func (d *Database) encryptCredentials(creds map[string]string) (string, error) {
+	if len(creds) == 0 {
+		return "", nil
+	}
+
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return "", fmt.Errorf("序列化凭证失败: %w", err)
+	}
+
+	gcm, err := d.credentialsCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return credentialsPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptCredentials 解密交易所凭证
+func (d *Database) decryptCredentials(encrypted string) (map[string]string, error) {
+	creds := make(map[string]string)
+	if encrypted == "" {
+		return creds, nil
+	}
+	if !strings.HasPrefix(encrypted, credentialsPrefix) {
+		return nil, fmt.Errorf("凭证格式不正确")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, credentialsPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("解码凭证失败: %w", err)
+	}
+
+	gcm, err := d.credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("凭证数据长度异常")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密凭证失败（加密密钥是否变更？）: %w", err)
+	}
+
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("解析凭证失败: %w", err)
+	}
+	return creds, nil
+}
+
+// credentialsCipher 创建AES-GCM实例
+func (d *Database) credentialsCipher() (cipher.AEAD, error) {
+	if len(d.credentialsKey) == 0 {
+		return nil, fmt.Errorf("凭证加密密钥未初始化")
+	}
+	block, err := aes.NewCipher(d.credentialsKey)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
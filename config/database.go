@@ -1,24 +1,24 @@
 package config
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/base32"
 	"fmt"
-	"log"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-		_ "github.com/lib/pq"  // PostgreSQL 驱动
-	// _ "github.com/mattn/go-sqlite3"  // 不再使用 SQLite
+		_ "github.com/lib/pq"  // PostgreSQL 驱动（SQLite 驱动见 sqlite_driver.go）
 )
 
 // Database 配置数据库
 type Database struct {
 	db *sql.DB
-	isPostgreSQL bool
+	backend string // sqlite / postgres / supabase
+	isPostgreSQL bool // postgres 和 supabase 均为 true
+	credentialsKey []byte // 交易所凭证加密密钥（AES-256）
 }
 
 // 辅助函数：根据数据库类型选择参数占位符
@@ -49,232 +49,98 @@ func (d *Database) convertQuery(sqliteQuery string) string {
 	return result
 }
 
-// NewDatabase 创建配置数据库
+// NewDatabase 创建配置数据库（后端由 DATABASE_BACKEND / DATABASE_URL 决定，未配置时使用 SQLite 文件 dbPath）
 func NewDatabase(dbPath string) (*Database, error) {
-	var db *sql.DB
-	var err error
-
-	// 强制使用 Supabase PostgreSQL
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL 环境变量未设置，必须配置 Supabase 连接字符串")
-	}
+	return OpenDatabase(ResolveDatabaseOptions(dbPath))
+}
 
-	if !strings.Contains(dbURL, "supabase") {
-		return nil, fmt.Errorf("DATABASE_URL 必须是 Supabase 连接字符串")
+// OpenDatabase 按指定后端打开配置数据库，检查结构版本并初始化默认数据
+func OpenDatabase(opts DatabaseOptions) (*Database, error) {
+	database, err := ConnectDatabase(opts)
+	if err != nil {
+		return nil, err
 	}
+	db := database.db
 
-	log.Printf("📋 连接到 Supabase PostgreSQL 数据库")
-
-	// 解析并修改连接字符串以添加必要的参数
-	if strings.HasPrefix(dbURL, "postgresql://") {
-		// 将 postgresql:// 转换为 postgres:// 以便正确解析
-		dbURL = strings.Replace(dbURL, "postgresql://", "postgres://", 1)
+	if err := database.checkSchema(opts.AutoMigrate); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("检查数据库结构失败: %w", err)
 	}
 
-	// 添加连接参数
-	if !strings.Contains(dbURL, "?") {
-		dbURL += "?sslmode=require&connect_timeout=30"
-	} else {
-		dbURL += "&sslmode=require&connect_timeout=30"
+	if err := database.initDefaultData(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化默认数据失败: %w", err)
 	}
 
-	// 隐藏敏感信息的连接字符串日志
-	safeURL := dbURL
-	if strings.Contains(safeURL, "@") {
-		parts := strings.Split(safeURL, "@")
-		if len(parts) >= 2 {
-			// 隐藏密码部分
-			hostPart := strings.Join(parts[1:], "@")
-			safeURL = strings.Split(parts[0], ":")[0] + ":***@***" + hostPart
-		}
+	if err := database.initCredentialsKey(opts.CredentialsKeyFile); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化凭证加密密钥失败: %w", err)
 	}
-	log.Printf("🔗 使用连接字符串: %s", safeURL)
 
-	db, err = sql.Open("postgres", dbURL)
-	if err != nil {
-		return nil, fmt.Errorf("连接 Supabase 失败: %w", err)
-	}
-
-	// 测试连接
-	if err := db.Ping(); err != nil {
-		// 提供更详细的错误诊断
-		if strings.Contains(err.Error(), "network is unreachable") {
-			return nil, fmt.Errorf("Supabase 连接测试失败: 网络不可达。请检查:\n1. DATABASE_URL 是否正确配置\n2. Supabase 项目是否正常运行\n3. 网络连接是否正常\n详细错误: %w", err)
-		} else if strings.Contains(err.Error(), "connection refused") {
-			return nil, fmt.Errorf("Supabase 连接测试失败: 连接被拒绝。请检查:\n1. Supabase 项目状态\n2. 连接字符串中的端口和主机\n3. 防火墙设置\n详细错误: %w", err)
-		} else if strings.Contains(err.Error(), "password authentication failed") {
-			return nil, fmt.Errorf("Supabase 连接测试失败: 密码认证失败。请检查 DATABASE_URL 中的密码是否正确\n详细错误: %w", err)
-		} else {
-			return nil, fmt.Errorf("Supabase 连接测试失败: %w", err)
-		}
+	// 将旧版明文凭证迁移为加密存储
+	if err := database.migrateExchangeCredentials(); err != nil {
+		configLog.Warnf(context.Background(), "⚠️  迁移交易所凭证失败: %v", err)
 	}
 
-	log.Printf("✅ Supabase 数据库连接成功")
-
-	database := &Database{
-		db:          db,
-		isPostgreSQL: true, // 强制使用 PostgreSQL
-	}
+	return database, nil
+}
 
-	// Supabase 数据库不需要创建表（通过 SQL 迁移脚本创建）
-	if dbURL != "" && strings.Contains(dbURL, "supabase") {
-		log.Printf("✅ Supabase 数据库连接成功，跳过表创建")
-	} else {
-		if err := database.createTables(); err != nil {
-			return nil, fmt.Errorf("创建表失败: %w", err)
-		}
+// ConnectDatabase 只建立数据库连接，不检查结构版本（供 nofx migrate 命令使用）
+func ConnectDatabase(opts DatabaseOptions) (*Database, error) {
+	db, err := openBackend(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := database.initDefaultData(); err != nil {
-		return nil, fmt.Errorf("初始化默认数据失败: %w", err)
-	}
+	return &Database{
+		db:           db,
+		backend:      opts.Backend,
+		isPostgreSQL: opts.Backend != BackendSQLite,
+	}, nil
+}
 
-	return database, nil
+// Backend 当前使用的数据库后端（sqlite / postgres / supabase）
+func (d *Database) Backend() string {
+	return d.backend
 }
 
-// createTables 创建数据库表 (仅用于 SQLite)
-func (d *Database) createTables() error {
-	// 检查是否使用 PostgreSQL
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL != "" && strings.Contains(dbURL, "supabase") {
-		// PostgreSQL 使用迁移脚本，不需要在这里创建表
-		return nil
-	}
-	queries := []string{
-		// AI模型配置表
-		`CREATE TABLE IF NOT EXISTS ai_models (
-			id TEXT PRIMARY KEY,
-			user_id TEXT NOT NULL DEFAULT 'default',
-			name TEXT NOT NULL,
-			provider TEXT NOT NULL,
-			enabled BOOLEAN DEFAULT 0,
-			api_key TEXT DEFAULT '',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-
-		// 交易所配置表
-		`CREATE TABLE IF NOT EXISTS exchanges (
-			id TEXT PRIMARY KEY,
-			user_id TEXT NOT NULL DEFAULT 'default',
-			name TEXT NOT NULL,
-			type TEXT NOT NULL, -- 'cex' or 'dex'
-			enabled BOOLEAN DEFAULT 0,
-			api_key TEXT DEFAULT '',
-			secret_key TEXT DEFAULT '',
-			testnet BOOLEAN DEFAULT 0,
-			-- Hyperliquid 特定字段
-			hyperliquid_wallet_addr TEXT DEFAULT '',
-			-- Aster 特定字段
-			aster_user TEXT DEFAULT '',
-			aster_signer TEXT DEFAULT '',
-			aster_private_key TEXT DEFAULT '',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-
-		// 交易员配置表
-		`CREATE TABLE IF NOT EXISTS traders (
-			id TEXT PRIMARY KEY,
-			user_id TEXT NOT NULL DEFAULT 'default',
-			name TEXT NOT NULL,
-			ai_model_id TEXT NOT NULL,
-			exchange_id TEXT NOT NULL,
-			initial_balance REAL NOT NULL,
-			scan_interval_minutes INTEGER DEFAULT 3,
-			is_running BOOLEAN DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-			FOREIGN KEY (ai_model_id) REFERENCES ai_models(id),
-			FOREIGN KEY (exchange_id) REFERENCES exchanges(id)
-		)`,
-
-		// 用户表
-		`CREATE TABLE IF NOT EXISTS users (
-			id TEXT PRIMARY KEY,
-			email TEXT UNIQUE NOT NULL,
-			password_hash TEXT NOT NULL,
-			otp_secret TEXT,
-			otp_verified BOOLEAN DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// 系统配置表
-		`CREATE TABLE IF NOT EXISTS system_config (
-			key TEXT PRIMARY KEY,
-			value TEXT NOT NULL,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// 触发器：自动更新 updated_at
-		`CREATE TRIGGER IF NOT EXISTS update_users_updated_at
-			AFTER UPDATE ON users
-			BEGIN
-				UPDATE users SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
-			END`,
-
-		`CREATE TRIGGER IF NOT EXISTS update_ai_models_updated_at
-			AFTER UPDATE ON ai_models
-			BEGIN
-				UPDATE ai_models SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
-			END`,
-
-		`CREATE TRIGGER IF NOT EXISTS update_exchanges_updated_at
-			AFTER UPDATE ON exchanges
-			BEGIN
-				UPDATE exchanges SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
-			END`,
-
-		`CREATE TRIGGER IF NOT EXISTS update_traders_updated_at
-			AFTER UPDATE ON traders
-			BEGIN
-				UPDATE traders SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
-			END`,
-
-		`CREATE TRIGGER IF NOT EXISTS update_system_config_updated_at
-			AFTER UPDATE ON system_config
-			BEGIN
-				UPDATE system_config SET updated_at = CURRENT_TIMESTAMP WHERE key = NEW.key;
-			END`,
-	}
-
-	for _, query := range queries {
-		if _, err := d.db.Exec(query); err != nil {
-			return fmt.Errorf("执行SQL失败 [%s]: %w", query, err)
+// upgradeLegacySchema 补齐引入迁移版本管理之前创建的旧库缺少的字段，之后由初始迁移接管
+func (d *Database) upgradeLegacySchema() error {
+	// 旧版 SQLite 数据库的 exchanges 表使用 type 字段
+	if !d.isPostgreSQL {
+		if err := d.migrateExchangesTable(); err != nil {
+			return fmt.Errorf("迁移exchanges表失败: %w", err)
 		}
 	}
 
-	// 为现有数据库添加新字段（向后兼容）
-	alterQueries := []string{
-		`ALTER TABLE exchanges ADD COLUMN hyperliquid_wallet_addr TEXT DEFAULT ''`,
-		`ALTER TABLE exchanges ADD COLUMN aster_user TEXT DEFAULT ''`,
-		`ALTER TABLE exchanges ADD COLUMN aster_signer TEXT DEFAULT ''`,
-		`ALTER TABLE exchanges ADD COLUMN aster_private_key TEXT DEFAULT ''`,
-		`ALTER TABLE traders ADD COLUMN custom_prompt TEXT DEFAULT ''`,
-		`ALTER TABLE traders ADD COLUMN override_base_prompt BOOLEAN DEFAULT 0`,
-		`ALTER TABLE traders ADD COLUMN is_cross_margin BOOLEAN DEFAULT 1`, // 默认为全仓模式
-	}
+	for _, column := range legacyColumns {
+		table, definition := column[0], column[1]
+		name := strings.Fields(definition)[0]
 
-	for _, query := range alterQueries {
-		// 忽略已存在字段的错误
-		d.db.Exec(query)
-	}
+		// 旧库中不存在的表由初始迁移创建，已存在的字段不再添加
+		exists, err := d.tableExists(table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		exists, err = d.columnExists(table, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
 
-	// 检查是否需要迁移exchanges表的主键结构
-	err := d.migrateExchangesTable()
-	if err != nil {
-		log.Printf("⚠️ 迁移exchanges表失败: %v", err)
+		if _, err := d.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, definition)); err != nil {
+			return fmt.Errorf("为%s表添加%s字段失败: %w", table, name, err)
+		}
 	}
-
 	return nil
 }
 
-// initDefaultData 初始化默认数据 (仅 PostgreSQL)
+// initDefaultData 初始化默认数据
 func (d *Database) initDefaultData() error {
 	// 不再初始化默认的AI模型和交易所配置
 	// 用户需要自己创建模型和交易所配置
@@ -310,85 +176,44 @@ func (d *Database) initDefaultData() error {
 	return nil
 }
 
-// migrateExchangesTable 迁移exchanges表支持多用户
+// migrateExchangesTable 将旧版 SQLite exchanges 表的 type 字段重命名为 exchange_type
 func (d *Database) migrateExchangesTable() error {
-	// 检查是否已经迁移过
-	var count int
+	ctx := context.Background()
+	var hasTable, hasType, hasExchangeType int
 	err := d.db.QueryRow(`
-		SELECT COUNT(*) FROM sqlite_master 
-		WHERE type='table' AND name='exchanges_new'
-	`).Scan(&count)
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type='table' AND name='exchanges'
+	`).Scan(&hasTable)
 	if err != nil {
 		return err
 	}
-	
-	// 如果已经迁移过，直接返回
-	if count > 0 {
+
+	// 新数据库由初始迁移直接创建新版表结构
+	if hasTable == 0 {
 		return nil
 	}
-	
-	log.Printf("🔄 开始迁移exchanges表...")
-	
-	// 创建新的exchanges表，使用复合主键
-	_, err = d.db.Exec(`
-		CREATE TABLE exchanges_new (
-			id TEXT NOT NULL,
-			user_id TEXT NOT NULL DEFAULT 'default',
-			name TEXT NOT NULL,
-			type TEXT NOT NULL,
-			enabled BOOLEAN DEFAULT 0,
-			api_key TEXT DEFAULT '',
-			secret_key TEXT DEFAULT '',
-			testnet BOOLEAN DEFAULT 0,
-			hyperliquid_wallet_addr TEXT DEFAULT '',
-			aster_user TEXT DEFAULT '',
-			aster_signer TEXT DEFAULT '',
-			aster_private_key TEXT DEFAULT '',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			PRIMARY KEY (id, user_id),
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("创建新exchanges表失败: %w", err)
-	}
-	
-	// 复制数据到新表
-	_, err = d.db.Exec(`
-		INSERT INTO exchanges_new 
-		SELECT * FROM exchanges
-	`)
-	if err != nil {
-		return fmt.Errorf("复制数据失败: %w", err)
-	}
-	
-	// 删除旧表
-	_, err = d.db.Exec(`DROP TABLE exchanges`)
+
+	err = d.db.QueryRow(`
+		SELECT COUNT(CASE WHEN name = 'type' THEN 1 END),
+		       COUNT(CASE WHEN name = 'exchange_type' THEN 1 END)
+		FROM pragma_table_info('exchanges')
+	`).Scan(&hasType, &hasExchangeType)
 	if err != nil {
-		return fmt.Errorf("删除旧表失败: %w", err)
+		return err
 	}
-	
-	// 重命名新表
-	_, err = d.db.Exec(`ALTER TABLE exchanges_new RENAME TO exchanges`)
-	if err != nil {
-		return fmt.Errorf("重命名表失败: %w", err)
+
+	// 已经迁移过
+	if hasExchangeType > 0 || hasType == 0 {
+		return nil
 	}
-	
-	// 重新创建触发器
-	_, err = d.db.Exec(`
-		CREATE TRIGGER IF NOT EXISTS update_exchanges_updated_at
-			AFTER UPDATE ON exchanges
-			BEGIN
-				UPDATE exchanges SET updated_at = CURRENT_TIMESTAMP 
-				WHERE id = NEW.id AND user_id = NEW.user_id;
-			END
-	`)
-	if err != nil {
-		return fmt.Errorf("创建触发器失败: %w", err)
+
+	configLog.Infof(ctx, "🔄 开始迁移exchanges表...")
+
+	if _, err := d.db.Exec(`ALTER TABLE exchanges RENAME COLUMN type TO exchange_type`); err != nil {
+		return fmt.Errorf("重命名type字段失败: %w", err)
 	}
-	
-	log.Printf("✅ exchanges表迁移完成")
+
+	configLog.Infof(ctx, "✅ exchanges表迁移完成")
 	return nil
 }
 
@@ -424,18 +249,12 @@ type ExchangeConfig struct {
 	Name      string    `json:"name"`
 	Type      string    `json:"type"`
 	Enabled   bool      `json:"enabled"`
-	APIKey    string    `json:"apiKey"`
-	SecretKey string    `json:"secretKey"`
 	Testnet   bool      `json:"testnet"`
-	// Hyperliquid 特定字段
-	HyperliquidWalletAddr string `json:"hyperliquidWalletAddr"`
-	// Aster 特定字段
-	AsterUser       string `json:"asterUser"`
-	AsterSigner     string `json:"asterSigner"`
-	AsterPrivateKey string `json:"asterPrivateKey"`
-	Description     string    `json:"description"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	// Credentials 交易所凭证（键名由 trader 包中的交易所注册表定义，数据库中加密存储）
+	Credentials map[string]string `json:"credentials"`
+	Description string            `json:"description"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
 }
 
 // TraderRecord 交易员配置（数据库实体）
@@ -453,6 +272,16 @@ type TraderRecord struct {
 	CustomPrompt       string    `json:"custom_prompt"`       // 自定义交易策略prompt
 	OverrideBasePrompt bool      `json:"override_base_prompt"` // 是否覆盖基础prompt
 	IsCrossMargin      bool      `json:"is_cross_margin"`      // 是否为全仓模式
+	IndicatorSpec      string    `json:"indicator_spec"`       // 指标配置JSON（market.IndicatorSpec，为空使用默认）
+	MarginPolicy       string    `json:"margin_policy"`        // 保证金分配策略JSON（trader.MarginPolicy，为空使用默认）
+	ExposureLimits     string    `json:"exposure_limits"`      // 组合敞口限制JSON（decision.ExposureLimits，为空使用默认）
+	RiskRewardRules    string    `json:"risk_reward_rules"`    // 止损止盈校验阈值JSON（decision.RiskRewardRules，为空使用默认）
+	RiskPolicy         string    `json:"risk_policy"`          // 风控策略JSON（decision.RiskPolicy，为空使用默认）
+	PromptTemplate     string    `json:"prompt_template"`      // 提示词模板引用（name 或 name@version，为空使用默认模板）
+	CoinUniverse       string    `json:"coin_universe"`        // 币种范围JSON（pool.Universe，为空使用默认）
+	Schedule           string    `json:"schedule"`             // 周期调度JSON（trader.Schedule，为空按扫描间隔执行）
+	HealthStatus       string    `json:"health_status"`        // 健康状态（healthy/degraded/errored，由监督器维护）
+	LastError          string    `json:"last_error"`           // 最近一次错误
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
 }
@@ -587,7 +416,7 @@ func (d *Database) CreateAIModelWithDescription(userID, name, provider string, e
 
 	query := d.convertQuery(`
 		INSERT INTO ai_models (id, user_id, name, provider, enabled, api_key, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, user_id, name, provider, enabled, api_key, description, created_at, updated_at
 	`)
 
@@ -628,7 +457,7 @@ func (d *Database) UpdateAIModelWithDescription(userID, id string, name string,
 
 	// 更新模型
 	query = d.convertQuery(`
-		UPDATE ai_models SET name = ?, enabled = ?, api_key = ?, description = ?, updated_at = NOW()
+		UPDATE ai_models SET name = ?, enabled = ?, api_key = ?, description = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND user_id = ?
 	`)
 	_, err = d.db.Exec(query, name, enabled, apiKey, description, id, userID)
@@ -657,16 +486,91 @@ func (d *Database) DeleteAIModel(userID, id string) error {
 	return nil
 }
 
-// GetExchanges 获取用户的交易所配置
-func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
-	query := d.convertQuery(`
-		SELECT id, user_id, name, exchange_type, enabled, api_key, secret_key, testnet,
+// exchangeColumns 交易所查询列（旧版平铺凭证列仅用于兼容未迁移的数据）
+const exchangeColumns = `id, user_id, name, exchange_type, enabled, testnet,
+		       COALESCE(credentials, '') as credentials,
+		       COALESCE(api_key, '') as api_key,
+		       COALESCE(secret_key, '') as secret_key,
 		       COALESCE(hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
 		       COALESCE(aster_user, '') as aster_user,
 		       COALESCE(aster_signer, '') as aster_signer,
 		       COALESCE(aster_private_key, '') as aster_private_key,
+		       COALESCE(passphrase, '') as passphrase,
 		       COALESCE(description, '') as description,
-		       created_at, updated_at
+		       created_at, updated_at`
+
+// PromptTemplateRecord 提示词模板（数据库实体，每个版本一条记录）
+type PromptTemplateRecord struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	Name           string    `json:"name"`
+	Version        int       `json:"version"`
+	Language       string    `json:"language"`
+	SystemTemplate string    `json:"system_template"`
+	UserTemplate   string    `json:"user_template"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ExperimentRecord A/B实验（数据库实体）
+type ExperimentRecord struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Arms        string     `json:"arms"` // 分组JSON（manager.ExperimentArm 数组，第一个为对照组）
+	StartTime   time.Time  `json:"start_time"`
+	EndTime     *time.Time `json:"end_time"` // nil 表示进行中
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanExchange 扫描交易所记录并解密凭证
+func (d *Database) scanExchange(row rowScanner) (*ExchangeConfig, error) {
+	var exchange ExchangeConfig
+	var encrypted string
+	var legacy [7]string
+	err := row.Scan(
+		&exchange.ID, &exchange.UserID, &exchange.Name, &exchange.Type,
+		&exchange.Enabled, &exchange.Testnet, &encrypted,
+		&legacy[0], &legacy[1], &legacy[2], &legacy[3], &legacy[4], &legacy[5], &legacy[6],
+		&exchange.Description, &exchange.CreatedAt, &exchange.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypted != "" {
+		exchange.Credentials, err = d.decryptCredentials(encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("交易所 %s: %w", exchange.ID, err)
+		}
+	} else {
+		exchange.Credentials = legacyCredentials(legacy)
+	}
+
+	return &exchange, nil
+}
+
+// legacyCredentials 将旧版平铺凭证列转换为凭证map（键名为旧版字段名，由交易所注册表映射）
+func legacyCredentials(legacy [7]string) map[string]string {
+	keys := [7]string{"apiKey", "secretKey", "hyperliquidWalletAddr", "asterUser", "asterSigner", "asterPrivateKey", "passphrase"}
+	creds := make(map[string]string)
+	for i, key := range keys {
+		if legacy[i] != "" {
+			creds[key] = legacy[i]
+		}
+	}
+	return creds
+}
+
+// GetExchanges 获取用户的交易所配置
+func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
+	query := d.convertQuery(`
+		SELECT ` + exchangeColumns + `
 		FROM exchanges WHERE user_id = ? ORDER BY id
 	`)
 	rows, err := d.db.Query(query, userID)
@@ -678,65 +582,65 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 	// 初始化为空切片而不是nil，确保JSON序列化为[]而不是null
 	exchanges := make([]*ExchangeConfig, 0)
 	for rows.Next() {
-		var exchange ExchangeConfig
-		err := rows.Scan(
-			&exchange.ID, &exchange.UserID, &exchange.Name, &exchange.Type,
-			&exchange.Enabled, &exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
-			&exchange.HyperliquidWalletAddr, &exchange.AsterUser,
-			&exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.Description,
-			&exchange.CreatedAt, &exchange.UpdatedAt,
-		)
+		exchange, err := d.scanExchange(rows)
 		if err != nil {
 			return nil, err
 		}
-		exchanges = append(exchanges, &exchange)
+		exchanges = append(exchanges, exchange)
 	}
 
 	return exchanges, nil
 }
 
+// GetExchange 获取单个交易所配置
+func (d *Database) GetExchange(userID, id string) (*ExchangeConfig, error) {
+	query := d.convertQuery(`
+		SELECT ` + exchangeColumns + `
+		FROM exchanges WHERE id = ? AND user_id = ?
+	`)
+	exchange, err := d.scanExchange(d.db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("交易所不存在或不属于当前用户")
+	}
+	return exchange, err
+}
 
 // CreateExchange 创建用户自定义交易所配置
-func (d *Database) CreateExchange(userID, name, exchangeType string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) (*ExchangeConfig, error) {
-	return d.CreateExchangeWithDescription(userID, name, exchangeType, enabled, apiKey, secretKey, testnet, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, "")
+func (d *Database) CreateExchange(userID, name, exchangeType string, enabled, testnet bool, credentials map[string]string) (*ExchangeConfig, error) {
+	return d.CreateExchangeWithDescription(userID, name, exchangeType, enabled, testnet, credentials, "")
 }
 
 // CreateExchangeWithDescription 创建用户自定义交易所配置（带描述）
-func (d *Database) CreateExchangeWithDescription(userID, name, exchangeType string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, description string) (*ExchangeConfig, error) {
+// credentials 以加密JSON存储，不同交易所的凭证字段无需修改表结构
+func (d *Database) CreateExchangeWithDescription(userID, name, exchangeType string, enabled, testnet bool, credentials map[string]string, description string) (*ExchangeConfig, error) {
 	// 生成唯一的交易所ID
 	exchangeID := fmt.Sprintf("%s_%d", userID, time.Now().UnixNano())
 
-	query := d.convertQuery(`
-		INSERT INTO exchanges (id, user_id, name, exchange_type, enabled, api_key, secret_key, testnet,
-		                      hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
-		RETURNING id, user_id, name, exchange_type, enabled, api_key, secret_key, testnet,
-		           hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key, description, created_at, updated_at
-	`)
+	encrypted, err := d.encryptCredentials(credentials)
+	if err != nil {
+		return nil, err
+	}
 
-	var exchange ExchangeConfig
-	err := d.db.QueryRow(query, exchangeID, userID, name, exchangeType, enabled, apiKey, secretKey, testnet,
-		hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, description).Scan(
-		&exchange.ID, &exchange.UserID, &exchange.Name, &exchange.Type, &exchange.Enabled,
-		&exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
-		&exchange.HyperliquidWalletAddr, &exchange.AsterUser, &exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.Description,
-		&exchange.CreatedAt, &exchange.UpdatedAt,
-	)
+	query := d.convertQuery(`
+		INSERT INTO exchanges (id, user_id, name, exchange_type, enabled, testnet, credentials, description, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING ` + exchangeColumns)
 
+	exchange, err := d.scanExchange(d.db.QueryRow(query, exchangeID, userID, name, exchangeType, enabled, testnet, encrypted, description))
 	if err != nil {
 		return nil, fmt.Errorf("创建交易所失败: %w", err)
 	}
 
-	return &exchange, nil
+	return exchange, nil
 }
 
 // UpdateExchange 更新用户交易所配置
-func (d *Database) UpdateExchange(userID, id string, name string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error {
-	return d.UpdateExchangeWithDescription(userID, id, name, enabled, apiKey, secretKey, testnet, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, "")
+func (d *Database) UpdateExchange(userID, id string, name string, enabled, testnet bool, credentials map[string]string) error {
+	return d.UpdateExchangeWithDescription(userID, id, name, enabled, testnet, credentials, "")
 }
 
 // UpdateExchangeWithDescription 更新用户交易所配置（带描述）
-func (d *Database) UpdateExchangeWithDescription(userID, id string, name string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, description string) error {
+func (d *Database) UpdateExchangeWithDescription(userID, id string, name string, enabled, testnet bool, credentials map[string]string, description string) error {
 	// 检查交易所是否属于当前用户
 	var exists bool
 	query := d.convertQuery(`
@@ -751,17 +655,60 @@ func (d *Database) UpdateExchangeWithDescription(userID, id string, name string,
 		return fmt.Errorf("交易所不存在或不属于当前用户")
 	}
 
-	// 更新交易所
+	encrypted, err := d.encryptCredentials(credentials)
+	if err != nil {
+		return err
+	}
+
+	// 更新交易所（同时清空旧版明文凭证列）
 	query = d.convertQuery(`
-		UPDATE exchanges SET name = ?, enabled = ?, api_key = ?, secret_key = ?, testnet = ?,
-		                     hyperliquid_wallet_addr = ?, aster_user = ?, aster_signer = ?, aster_private_key = ?, description = ?, updated_at = NOW()
+		UPDATE exchanges SET name = ?, enabled = ?, testnet = ?, credentials = ?, description = ?,
+		                     api_key = '', secret_key = '', hyperliquid_wallet_addr = '',
+		                     aster_user = '', aster_signer = '', aster_private_key = '', passphrase = '',
+		                     updated_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND user_id = ?
 	`)
-	_, err = d.db.Exec(query, name, enabled, apiKey, secretKey, testnet,
-		hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, description, id, userID)
+	_, err = d.db.Exec(query, name, enabled, testnet, encrypted, description, id, userID)
 	return err
 }
 
+// migrateExchangeCredentials 将旧版明文凭证列迁移为加密JSON
+func (d *Database) migrateExchangeCredentials() error {
+	rows, err := d.db.Query(`
+		SELECT ` + exchangeColumns + `
+		FROM exchanges WHERE COALESCE(credentials, '') = ''
+	`)
+	if err != nil {
+		return err
+	}
+
+	var pending []*ExchangeConfig
+	for rows.Next() {
+		exchange, err := d.scanExchange(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if len(exchange.Credentials) > 0 {
+			pending = append(pending, exchange)
+		}
+	}
+	rows.Close()
+
+	for _, exchange := range pending {
+		err := d.UpdateExchangeWithDescription(exchange.UserID, exchange.ID, exchange.Name, exchange.Enabled,
+			exchange.Testnet, exchange.Credentials, exchange.Description)
+		if err != nil {
+			return fmt.Errorf("迁移交易所 %s 凭证失败: %w", exchange.ID, err)
+		}
+	}
+
+	if len(pending) > 0 {
+		configLog.Infof(context.Background(), "🔐 已将 %d 个交易所的凭证迁移为加密存储", len(pending))
+	}
+	return nil
+}
+
 // DeleteExchange 删除用户交易所
 func (d *Database) DeleteExchange(userID, id string) error {
 	query := d.convertQuery(`
@@ -789,10 +736,10 @@ func (d *Database) DeleteExchange(userID, id string) error {
 func (d *Database) CreateTrader(trader *TraderRecord) error {
 	// 验证 AI 模型是否属于当前用户且已启用
 	var modelCount int
-	err := d.db.QueryRow(`
+	err := d.db.QueryRow(d.convertQuery(`
 		SELECT COUNT(*) FROM ai_models
-		WHERE id = $1 AND user_id = $2 AND enabled = TRUE
-	`, trader.AIModelID, trader.UserID).Scan(&modelCount)
+		WHERE id = ? AND user_id = ? AND enabled = TRUE
+	`), trader.AIModelID, trader.UserID).Scan(&modelCount)
 	if err != nil {
 		return fmt.Errorf("验证 AI 模型失败: %w", err)
 	}
@@ -802,10 +749,10 @@ func (d *Database) CreateTrader(trader *TraderRecord) error {
 
 	// 验证交易所是否属于当前用户且已启用
 	var exchangeCount int
-	err = d.db.QueryRow(`
+	err = d.db.QueryRow(d.convertQuery(`
 		SELECT COUNT(*) FROM exchanges
-		WHERE id = $1 AND user_id = $2 AND enabled = TRUE
-	`, trader.ExchangeID, trader.UserID).Scan(&exchangeCount)
+		WHERE id = ? AND user_id = ? AND enabled = TRUE
+	`), trader.ExchangeID, trader.UserID).Scan(&exchangeCount)
 	if err != nil {
 		return fmt.Errorf("验证交易所失败: %w", err)
 	}
@@ -815,9 +762,9 @@ func (d *Database) CreateTrader(trader *TraderRecord) error {
 
 	// 检查用户是否已达到最大交易员数量限制
 	var traderCount int
-	err = d.db.QueryRow(`
-		SELECT COUNT(*) FROM traders WHERE user_id = $1
-	`, trader.UserID).Scan(&traderCount)
+	err = d.db.QueryRow(d.convertQuery(`
+		SELECT COUNT(*) FROM traders WHERE user_id = ?
+	`), trader.UserID).Scan(&traderCount)
 	if err != nil {
 		return fmt.Errorf("检查交易员数量失败: %w", err)
 	}
@@ -841,16 +788,17 @@ func (d *Database) CreateTrader(trader *TraderRecord) error {
 	}
 
 	// 创建交易员，包含所有配置字段
-	query := `
+	query := d.convertQuery(`
 		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, description, enabled,
 		                   initial_balance, scan_interval_minutes, is_running, custom_prompt,
-		                   override_base_prompt, is_cross_margin, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-	`
+		                   override_base_prompt, is_cross_margin, indicator_spec, margin_policy, exposure_limits,
+		                   risk_reward_rules, risk_policy, prompt_template, coin_universe, schedule, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
 	_, err = d.db.Exec(query, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID,
 		trader.Description, trader.Enabled, trader.InitialBalance, trader.ScanIntervalMinutes,
 		trader.IsRunning, trader.CustomPrompt, trader.OverrideBasePrompt, trader.IsCrossMargin,
-		trader.CreatedAt, trader.UpdatedAt)
+		trader.IndicatorSpec, trader.MarginPolicy, trader.ExposureLimits, trader.RiskRewardRules, trader.RiskPolicy, trader.PromptTemplate, trader.CoinUniverse, trader.Schedule, trader.CreatedAt, trader.UpdatedAt)
 	return err
 }
 
@@ -865,6 +813,16 @@ func (d *Database) GetAllTraders() ([]*TraderRecord, error) {
 		       COALESCE(custom_prompt, '') as custom_prompt,
 		       COALESCE(override_base_prompt, FALSE) as override_base_prompt,
 		       COALESCE(is_cross_margin, TRUE) as is_cross_margin,
+		       COALESCE(indicator_spec, '') as indicator_spec,
+		       COALESCE(margin_policy, '') as margin_policy,
+		       COALESCE(exposure_limits, '') as exposure_limits,
+		       COALESCE(risk_reward_rules, '') as risk_reward_rules,
+		       COALESCE(risk_policy, '') as risk_policy,
+		       COALESCE(prompt_template, '') as prompt_template,
+		       COALESCE(coin_universe, '') as coin_universe,
+		       COALESCE(schedule, '') as schedule,
+		       COALESCE(health_status, 'healthy') as health_status,
+		       COALESCE(last_error, '') as last_error,
 		       created_at, updated_at
 		FROM traders
 		ORDER BY created_at DESC
@@ -892,6 +850,16 @@ func (d *Database) GetAllTraders() ([]*TraderRecord, error) {
 			&trader.CustomPrompt,
 			&trader.OverrideBasePrompt,
 			&trader.IsCrossMargin,
+			&trader.IndicatorSpec,
+			&trader.MarginPolicy,
+			&trader.ExposureLimits,
+			&trader.RiskRewardRules,
+			&trader.RiskPolicy,
+			&trader.PromptTemplate,
+			&trader.CoinUniverse,
+			&trader.Schedule,
+			&trader.HealthStatus,
+			&trader.LastError,
 			&trader.CreatedAt,
 			&trader.UpdatedAt,
 		)
@@ -910,7 +878,7 @@ func (d *Database) GetAllTraders() ([]*TraderRecord, error) {
 
 // GetTraders 获取用户的交易员
 func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
-	query := `
+	query := d.convertQuery(`
 		SELECT id, user_id, name, ai_model_id, exchange_id,
 		       COALESCE(description, '') as description, enabled,
 		       COALESCE(initial_balance, 1000.0) as initial_balance,
@@ -919,11 +887,21 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 		       COALESCE(custom_prompt, '') as custom_prompt,
 		       COALESCE(override_base_prompt, FALSE) as override_base_prompt,
 		       COALESCE(is_cross_margin, TRUE) as is_cross_margin,
+		       COALESCE(indicator_spec, '') as indicator_spec,
+		       COALESCE(margin_policy, '') as margin_policy,
+		       COALESCE(exposure_limits, '') as exposure_limits,
+		       COALESCE(risk_reward_rules, '') as risk_reward_rules,
+		       COALESCE(risk_policy, '') as risk_policy,
+		       COALESCE(prompt_template, '') as prompt_template,
+		       COALESCE(coin_universe, '') as coin_universe,
+		       COALESCE(schedule, '') as schedule,
+		       COALESCE(health_status, 'healthy') as health_status,
+		       COALESCE(last_error, '') as last_error,
 		       created_at, updated_at
 		FROM traders
-		WHERE user_id = $1
+		WHERE user_id = ?
 		ORDER BY created_at DESC
-	`
+	`)
 	rows, err := d.db.Query(query, userID)
 	if err != nil {
 		return nil, err
@@ -936,7 +914,7 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 		err := rows.Scan(&trader.ID, &trader.UserID, &trader.Name, &trader.AIModelID, &trader.ExchangeID,
 			&trader.Description, &trader.Enabled, &trader.InitialBalance, &trader.ScanIntervalMinutes,
 			&trader.IsRunning, &trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.IsCrossMargin,
-			&trader.CreatedAt, &trader.UpdatedAt)
+			&trader.IndicatorSpec, &trader.MarginPolicy, &trader.ExposureLimits, &trader.RiskRewardRules, &trader.RiskPolicy, &trader.PromptTemplate, &trader.CoinUniverse, &trader.Schedule, &trader.HealthStatus, &trader.LastError, &trader.CreatedAt, &trader.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -953,11 +931,24 @@ func (d *Database) UpdateTraderStatus(userID, id string, isRunning bool) error {
 	return err
 }
 
+// UpdateTraderHealth 更新交易员健康状态（由监督器调用）
+func (d *Database) UpdateTraderHealth(id, healthStatus, lastError string) error {
+	query := d.convertQuery(`UPDATE traders SET health_status = ?, last_error = ? WHERE id = ?`)
+	_, err := d.db.Exec(query, healthStatus, lastError, id)
+	return err
+}
+
 // UpdateTraderCustomPrompt 更新交易员自定义Prompt
 func (d *Database) UpdateTraderCustomPrompt(userID, id string, customPrompt string, overrideBase bool) error {
 	query := d.convertQuery(`UPDATE traders SET custom_prompt = ?, override_base_prompt = ? WHERE id = ? AND user_id = ?`)
-	_, err := d.db.Exec(query, customPrompt, overrideBase, id, userID)
-	return err
+	result, err := d.db.Exec(query, customPrompt, overrideBase, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTraderNotFound
+	}
+	return nil
 }
 
 // DeleteTrader 删除交易员
@@ -971,41 +962,163 @@ func (d *Database) DeleteTrader(userID, id string) error {
 func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIModelConfig, *ExchangeConfig, error) {
 	var trader TraderRecord
 	var aiModel AIModelConfig
-	var exchange ExchangeConfig
 
-	query := `
+	query := d.convertQuery(`
 		SELECT
 			t.id, t.user_id, t.name, t.ai_model_id, t.exchange_id, t.description, t.enabled, t.created_at, t.updated_at,
-			a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key, COALESCE(a.description, '') as ai_description, a.created_at, a.updated_at,
-			e.id, e.user_id, e.name, e.exchange_type, e.enabled, e.api_key, e.secret_key, e.testnet,
-			COALESCE(e.hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
-			COALESCE(e.aster_user, '') as aster_user,
-			COALESCE(e.aster_signer, '') as aster_signer,
-			COALESCE(e.aster_private_key, '') as aster_private_key,
-			COALESCE(e.description, '') as exchange_description,
-			e.created_at, e.updated_at
+			a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key, COALESCE(a.description, '') as ai_description, a.created_at, a.updated_at
 		FROM traders t
 		JOIN ai_models a ON t.ai_model_id = a.id AND t.user_id = a.user_id
-		JOIN exchanges e ON t.exchange_id = e.id AND t.user_id = e.user_id
-		WHERE t.id = $1 AND t.user_id = $2
-	`
+		WHERE t.id = ? AND t.user_id = ?
+	`)
 
 	err := d.db.QueryRow(query, traderID, userID).Scan(
 		&trader.ID, &trader.UserID, &trader.Name, &trader.AIModelID, &trader.ExchangeID,
 		&trader.Description, &trader.Enabled, &trader.CreatedAt, &trader.UpdatedAt,
 		&aiModel.ID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey, &aiModel.Description,
 		&aiModel.CreatedAt, &aiModel.UpdatedAt,
-		&exchange.ID, &exchange.UserID, &exchange.Name, &exchange.Type, &exchange.Enabled,
-		&exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
-		&exchange.HyperliquidWalletAddr, &exchange.AsterUser, &exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.Description,
-		&exchange.CreatedAt, &exchange.UpdatedAt,
 	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
+	exchange, err := d.GetExchange(userID, trader.ExchangeID)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	return &trader, &aiModel, &exchange, nil
+	return &trader, &aiModel, exchange, nil
+}
+
+// CreatePromptTemplate 保存提示词模板的新版本（版本号为同名模板最大版本+1）
+func (d *Database) CreatePromptTemplate(tpl *PromptTemplateRecord) error {
+	var maxVersion int
+	query := d.convertQuery(`SELECT COALESCE(MAX(version), 0) FROM prompt_templates WHERE user_id = ? AND name = ?`)
+	if err := d.db.QueryRow(query, tpl.UserID, tpl.Name).Scan(&maxVersion); err != nil {
+		return fmt.Errorf("查询模板版本失败: %w", err)
+	}
+	tpl.Version = maxVersion + 1
+	tpl.ID = fmt.Sprintf("%s_%s_v%d", tpl.UserID, tpl.Name, tpl.Version)
+	tpl.CreatedAt = time.Now()
+
+	query = d.convertQuery(`
+		INSERT INTO prompt_templates (id, user_id, name, version, language, system_template, user_template, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if _, err := d.db.Exec(query, tpl.ID, tpl.UserID, tpl.Name, tpl.Version, tpl.Language, tpl.SystemTemplate, tpl.UserTemplate, tpl.CreatedAt); err != nil {
+		return fmt.Errorf("保存提示词模板失败: %w", err)
+	}
+	return nil
+}
+
+// GetPromptTemplates 获取用户的所有提示词模板（按名称、版本排序）
+func (d *Database) GetPromptTemplates(userID string) ([]*PromptTemplateRecord, error) {
+	query := d.convertQuery(`
+		SELECT id, user_id, name, version, COALESCE(language, 'zh'), system_template, user_template, created_at
+		FROM prompt_templates WHERE user_id = ? ORDER BY name, version
+	`)
+	rows, err := d.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*PromptTemplateRecord
+	for rows.Next() {
+		var tpl PromptTemplateRecord
+		if err := rows.Scan(&tpl.ID, &tpl.UserID, &tpl.Name, &tpl.Version, &tpl.Language,
+			&tpl.SystemTemplate, &tpl.UserTemplate, &tpl.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, &tpl)
+	}
+	return templates, rows.Err()
+}
+
+// GetPromptTemplate 获取用户的提示词模板（version 为0时返回最新版本）
+func (d *Database) GetPromptTemplate(userID, name string, version int) (*PromptTemplateRecord, error) {
+	query := d.convertQuery(`
+		SELECT id, user_id, name, version, COALESCE(language, 'zh'), system_template, user_template, created_at
+		FROM prompt_templates WHERE user_id = ? AND name = ? AND (? = 0 OR version = ?)
+		ORDER BY version DESC LIMIT 1
+	`)
+	var tpl PromptTemplateRecord
+	err := d.db.QueryRow(query, userID, name, version, version).Scan(&tpl.ID, &tpl.UserID, &tpl.Name, &tpl.Version, &tpl.Language,
+		&tpl.SystemTemplate, &tpl.UserTemplate, &tpl.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// CreateExperiment 创建A/B实验
+func (d *Database) CreateExperiment(exp *ExperimentRecord) error {
+	query := d.convertQuery(`
+		INSERT INTO experiments (id, user_id, name, description, arms, start_time, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	_, err := d.db.Exec(query, exp.ID, exp.UserID, exp.Name, exp.Description, exp.Arms, exp.StartTime, exp.CreatedAt)
+	return err
+}
+
+// scanExperiment 扫描一行实验记录
+func scanExperiment(row rowScanner) (*ExperimentRecord, error) {
+	var exp ExperimentRecord
+	var endTime sql.NullTime
+	if err := row.Scan(&exp.ID, &exp.UserID, &exp.Name, &exp.Description, &exp.Arms,
+		&exp.StartTime, &endTime, &exp.CreatedAt); err != nil {
+		return nil, err
+	}
+	if endTime.Valid {
+		exp.EndTime = &endTime.Time
+	}
+	return &exp, nil
+}
+
+// GetExperiments 获取用户的所有A/B实验（最新的在前）
+func (d *Database) GetExperiments(userID string) ([]*ExperimentRecord, error) {
+	query := d.convertQuery(`
+		SELECT id, user_id, name, COALESCE(description, ''), arms, start_time, end_time, created_at
+		FROM experiments WHERE user_id = ? ORDER BY created_at DESC
+	`)
+	rows, err := d.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var experiments []*ExperimentRecord
+	for rows.Next() {
+		exp, err := scanExperiment(rows)
+		if err != nil {
+			return nil, err
+		}
+		experiments = append(experiments, exp)
+	}
+	return experiments, rows.Err()
+}
+
+// GetExperiment 获取单个A/B实验
+func (d *Database) GetExperiment(userID, id string) (*ExperimentRecord, error) {
+	query := d.convertQuery(`
+		SELECT id, user_id, name, COALESCE(description, ''), arms, start_time, end_time, created_at
+		FROM experiments WHERE id = ? AND user_id = ?
+	`)
+	return scanExperiment(d.db.QueryRow(query, id, userID))
+}
+
+// StopExperiment 结束A/B实验（固定统计窗口的结束时间）
+func (d *Database) StopExperiment(userID, id string, endTime time.Time) error {
+	query := d.convertQuery(`UPDATE experiments SET end_time = ? WHERE id = ? AND user_id = ? AND end_time IS NULL`)
+	_, err := d.db.Exec(query, endTime, id, userID)
+	return err
+}
+
+// DeleteExperiment 删除A/B实验（不影响交易员和决策日志）
+func (d *Database) DeleteExperiment(userID, id string) error {
+	query := d.convertQuery(`DELETE FROM experiments WHERE id = ? AND user_id = ?`)
+	_, err := d.db.Exec(query, id, userID)
+	return err
 }
 
 // GetSystemConfig 获取系统配置
@@ -1026,6 +1139,12 @@ func (d *Database) SetSystemConfig(key, value string) error {
 	return err
 }
 
+// deleteSystemConfig 删除系统配置
+func (d *Database) deleteSystemConfig(key string) error {
+	_, err := d.db.Exec(d.convertQuery(`DELETE FROM system_config WHERE key = ?`), key)
+	return err
+}
+
 // Close 关闭数据库连接
 func (d *Database) Close() error {
 	return d.db.Close()
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDatabase 在临时目录中打开内嵌 SQLite 数据库（执行全部迁移）
+func openTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	t.Setenv("CREDENTIALS_ENCRYPTION_KEY", "test-credentials-key")
+	db, err := OpenDatabase(DatabaseOptions{
+		Backend:     BackendSQLite,
+		DSN:         filepath.Join(t.TempDir(), "config.db"),
+		AutoMigrate: true,
+	})
+	if err != nil {
+		t.Fatalf("打开SQLite数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestOpenSQLiteAppliesAllMigrations(t *testing.T) {
+	db := openTestDatabase(t)
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("查询迁移状态失败: %v", err)
+	}
+	if len(statuses) == 0 {
+		t.Fatal("没有内嵌的迁移脚本")
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			t.Errorf("迁移 %04d_%s 未执行", s.Version, s.Name)
+		}
+	}
+}
+
+func TestCreateAndListTraders(t *testing.T) {
+	db := openTestDatabase(t)
+
+	for _, id := range []string{"u1", "u2"} {
+		if err := db.CreateUser(&User{ID: id, Email: id + "@example.com", PasswordHash: "x"}); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+	model, err := db.CreateAIModel("u1", "DeepSeek", "deepseek", true, "sk-test")
+	if err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	exchange, err := db.CreateExchange("u1", "Binance", "binance", true, false, map[string]string{"api_key": "k", "secret_key": "s"})
+	if err != nil {
+		t.Fatalf("创建交易所失败: %v", err)
+	}
+
+	record := &TraderRecord{
+		ID:                  "u1_trader",
+		UserID:              "u1",
+		Name:                "trader",
+		AIModelID:           model.ID,
+		ExchangeID:          exchange.ID,
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 5,
+		IsCrossMargin:       true,
+		Schedule:            `{"mode":"candle","candle_minutes":15}`,
+	}
+	if err := db.CreateTrader(record); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	traders, err := db.GetTraders("u1")
+	if err != nil {
+		t.Fatalf("查询交易员失败: %v", err)
+	}
+	if len(traders) != 1 {
+		t.Fatalf("交易员数量 = %d, 期望 1", len(traders))
+	}
+	got := traders[0]
+	if got.ScanIntervalMinutes != 5 || got.Schedule != record.Schedule || got.HealthStatus != "healthy" {
+		t.Errorf("读取的交易员配置不一致: %+v", got)
+	}
+
+	others, err := db.GetTraders("u2")
+	if err != nil {
+		t.Fatalf("查询交易员失败: %v", err)
+	}
+	if len(others) != 0 {
+		t.Errorf("其他用户不应看到该交易员，实际 %d 个", len(others))
+	}
+}
+
+func TestUpdateTraderSettingChecksOwner(t *testing.T) {
+	db := openTestDatabase(t)
+
+	for _, id := range []string{"u1", "u2"} {
+		if err := db.CreateUser(&User{ID: id, Email: id + "@example.com", PasswordHash: "x"}); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+	model, err := db.CreateAIModel("u1", "DeepSeek", "deepseek", true, "sk-test")
+	if err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	exchange, err := db.CreateExchange("u1", "Binance", "binance", true, false, map[string]string{"api_key": "k", "secret_key": "s"})
+	if err != nil {
+		t.Fatalf("创建交易所失败: %v", err)
+	}
+	record := &TraderRecord{ID: "u1_trader", UserID: "u1", Name: "trader", AIModelID: model.ID, ExchangeID: exchange.ID, InitialBalance: 1000, ScanIntervalMinutes: 5}
+	if err := db.CreateTrader(record); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	schedule := `{"mode":"candle","candle_minutes":15}`
+	if err := db.UpdateTraderSetting("u2", record.ID, SettingSchedule, schedule); !errors.Is(err, ErrTraderNotFound) {
+		t.Errorf("其他用户更新配置应返回 ErrTraderNotFound, got %v", err)
+	}
+	if err := db.UpdateTraderSetting("u1", "missing", SettingSchedule, schedule); !errors.Is(err, ErrTraderNotFound) {
+		t.Errorf("更新不存在的交易员应返回 ErrTraderNotFound, got %v", err)
+	}
+	if err := db.UpdateTraderCustomPrompt("u2", record.ID, "prompt", true); !errors.Is(err, ErrTraderNotFound) {
+		t.Errorf("其他用户更新自定义prompt应返回 ErrTraderNotFound, got %v", err)
+	}
+	if err := db.UpdateTraderSetting("u1", record.ID, TraderSetting("name"), "x"); err == nil {
+		t.Error("未知配置列应返回错误")
+	}
+
+	if err := db.UpdateTraderSetting("u1", record.ID, SettingSchedule, schedule); err != nil {
+		t.Fatalf("更新周期调度失败: %v", err)
+	}
+	got, err := db.GetTrader("u1", record.ID)
+	if err != nil {
+		t.Fatalf("查询交易员失败: %v", err)
+	}
+	if got.Setting(SettingSchedule) != schedule || got.CustomPrompt != "" {
+		t.Errorf("读取的交易员配置不一致: %+v", got)
+	}
+	if _, err := db.GetTrader("u2", record.ID); !errors.Is(err, ErrTraderNotFound) {
+		t.Errorf("其他用户查询交易员应返回 ErrTraderNotFound, got %v", err)
+	}
+}
+
+func TestCredentialsKeyStoredOutsideDatabase(t *testing.T) {
+	t.Setenv("CREDENTIALS_ENCRYPTION_KEY", "")
+	dir := t.TempDir()
+	opts := DatabaseOptions{
+		Backend:            BackendSQLite,
+		DSN:                filepath.Join(dir, "config.db"),
+		AutoMigrate:        true,
+		CredentialsKeyFile: filepath.Join(dir, "secrets", "credentials.key"),
+	}
+
+	db, err := OpenDatabase(opts)
+	if err != nil {
+		t.Fatalf("打开SQLite数据库失败: %v", err)
+	}
+	info, err := os.Stat(opts.CredentialsKeyFile)
+	if err != nil {
+		t.Fatalf("未生成密钥文件: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("密钥文件权限 = %o, want 600", perm)
+	}
+	if stored, _ := db.GetSystemConfig(legacyCredentialsKeyConfig); stored != "" {
+		t.Error("密钥不应保存在数据库中")
+	}
+	encrypted, err := db.encryptCredentials(map[string]string{"api_key": "k"})
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	db.Close()
+
+	// 重新打开后使用同一密钥文件解密
+	db, err = OpenDatabase(opts)
+	if err != nil {
+		t.Fatalf("重新打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	creds, err := db.decryptCredentials(encrypted)
+	if err != nil || creds["api_key"] != "k" {
+		t.Fatalf("解密失败: %v %v", creds, err)
+	}
+}
+
+func TestLegacyCredentialsKeyMigratedToFile(t *testing.T) {
+	t.Setenv("CREDENTIALS_ENCRYPTION_KEY", "")
+	dir := t.TempDir()
+	opts := DatabaseOptions{
+		Backend:            BackendSQLite,
+		DSN:                filepath.Join(dir, "config.db"),
+		AutoMigrate:        true,
+		CredentialsKeyFile: filepath.Join(dir, "credentials.key"),
+	}
+
+	// 模拟旧版本：密钥保存在数据库中
+	db, err := OpenDatabase(opts)
+	if err != nil {
+		t.Fatalf("打开SQLite数据库失败: %v", err)
+	}
+	if err := db.SetSystemConfig(legacyCredentialsKeyConfig, "legacy-key"); err != nil {
+		t.Fatalf("写入旧版密钥失败: %v", err)
+	}
+	db.Close()
+	os.Remove(opts.CredentialsKeyFile)
+
+	db, err = OpenDatabase(opts)
+	if err != nil {
+		t.Fatalf("重新打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	data, err := os.ReadFile(opts.CredentialsKeyFile)
+	if err != nil || strings.TrimSpace(string(data)) != "legacy-key" {
+		t.Fatalf("旧版密钥未迁移到密钥文件: %q %v", data, err)
+	}
+	if stored, _ := db.GetSystemConfig(legacyCredentialsKeyConfig); stored != "" {
+		t.Error("迁移后应从数据库中删除旧版密钥")
+	}
+}
+
+func TestLegacyDatabaseUpgraded(t *testing.T) {
+	t.Setenv("CREDENTIALS_ENCRYPTION_KEY", "test-credentials-key")
+	dsn := filepath.Join(t.TempDir(), "config.db")
+
+	// 模拟引入迁移版本管理之前的旧库：exchanges 使用 type 字段，traders 只有部分新字段
+	driver, err := sqliteDriverName()
+	if err != nil {
+		t.Fatalf("获取SQLite驱动失败: %v", err)
+	}
+	raw, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("打开SQLite数据库失败: %v", err)
+	}
+	legacy := []string{
+		`CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT UNIQUE NOT NULL, password_hash TEXT NOT NULL, otp_secret TEXT NOT NULL DEFAULT '',
+			otp_verified BOOLEAN DEFAULT FALSE, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE ai_models (id TEXT PRIMARY KEY, user_id TEXT NOT NULL, name TEXT NOT NULL, provider TEXT NOT NULL, enabled BOOLEAN DEFAULT FALSE,
+			api_key TEXT DEFAULT '', created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE exchanges (id TEXT PRIMARY KEY, user_id TEXT NOT NULL, name TEXT NOT NULL, type TEXT NOT NULL, enabled BOOLEAN DEFAULT FALSE,
+			api_key TEXT DEFAULT '', secret_key TEXT DEFAULT '', testnet BOOLEAN DEFAULT FALSE, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE traders (id TEXT PRIMARY KEY, user_id TEXT NOT NULL, name TEXT NOT NULL, ai_model_id TEXT NOT NULL, exchange_id TEXT NOT NULL,
+			description TEXT DEFAULT '', initial_balance REAL DEFAULT 1000, scan_interval_minutes INTEGER DEFAULT 3, is_running BOOLEAN DEFAULT FALSE,
+			custom_prompt TEXT DEFAULT '', created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id) VALUES ('t1', 'u1', 'legacy', 'm1', 'e1')`,
+	}
+	for _, stmt := range legacy {
+		if _, err := raw.Exec(stmt); err != nil {
+			t.Fatalf("创建旧库失败: %v", err)
+		}
+	}
+	raw.Close()
+
+	db, err := OpenDatabase(DatabaseOptions{Backend: BackendSQLite, DSN: dsn, AutoMigrate: true})
+	if err != nil {
+		t.Fatalf("接管旧库失败: %v", err)
+	}
+	defer db.Close()
+
+	for _, column := range append(legacyColumns, [2]string{"exchanges", "exchange_type"}, [2]string{"traders", "schedule"}) {
+		name := strings.Fields(column[1])[0]
+		if exists, err := db.columnExists(column[0], name); err != nil || !exists {
+			t.Errorf("%s.%s 未补齐: %v", column[0], name, err)
+		}
+	}
+	if exists, _ := db.columnExists("exchanges", "type"); exists {
+		t.Error("exchanges.type 应重命名为 exchange_type")
+	}
+	if version, err := db.SchemaVersion(); err != nil || version < 3 {
+		t.Errorf("接管后结构版本 = %d, %v", version, err)
+	}
+	var name string
+	if err := db.db.QueryRow(`SELECT name FROM traders WHERE id = 't1'`).Scan(&name); err != nil || name != "legacy" {
+		t.Errorf("旧数据丢失: %q %v", name, err)
+	}
+}
+
+func TestOwnedTablesRequireUserID(t *testing.T) {
+	db := openTestDatabase(t)
+
+	// 提示词模板和实验必须显式指定已存在的用户
+	inserts := []string{
+		`INSERT INTO prompt_templates (id, name, version, system_template, user_template) VALUES ('p1', 'mine', 1, 's', 'u')`,
+		`INSERT INTO experiments (id, name, arms, start_time) VALUES ('x1', 'ab', '[]', CURRENT_TIMESTAMP)`,
+		`INSERT INTO prompt_templates (id, user_id, name, version, system_template, user_template) VALUES ('p2', 'nobody', 'mine', 1, 's', 'u')`,
+	}
+	for _, stmt := range inserts {
+		if _, err := db.db.Exec(stmt); err == nil {
+			t.Errorf("应拒绝: %s", stmt)
+		}
+	}
+}
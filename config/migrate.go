@@ -0,0 +1,298 @@
+package config
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFS 内嵌的迁移脚本，按方言分目录：migrations/<sqlite|postgres>/<版本号>_<名称>.sql
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFS embed.FS
+
+// 迁移脚本方言
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres" // 普通 PostgreSQL 和 Supabase 共用
+)
+
+// Migration 一个向前迁移
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus 迁移执行状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	Unknown   bool       `json:"unknown,omitempty"` // 数据库中已执行但程序中不存在的迁移
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// schemaMigrationsTable 记录已执行迁移的表（两种方言通用）
+const schemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+
+// LoadMigrations 读取指定方言的内嵌迁移脚本，按版本号升序返回
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移目录 %s 失败: %w", dir, err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(fileName, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, ".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("迁移文件名格式错误: %s（应为 <版本号>_<名称>.sql）", fileName)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移文件版本号无效: %s", fileName)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("迁移版本号重复: %s 与 %s", other, fileName)
+		}
+		seen[version] = fileName
+
+		content, err := migrationFS.ReadFile(path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件 %s 失败: %w", fileName, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// dialect 当前数据库对应的迁移方言
+func (d *Database) dialect() string {
+	if d.isPostgreSQL {
+		return DialectPostgres
+	}
+	return DialectSQLite
+}
+
+// tableExists 检查表是否存在
+func (d *Database) tableExists(table string) (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if d.isPostgreSQL {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+
+	var count int
+	if err := d.db.QueryRow(d.convertQuery(query), table).Scan(&count); err != nil {
+		return false, fmt.Errorf("检查表 %s 是否存在失败: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// columnExists 检查表中是否存在字段
+func (d *Database) columnExists(table, column string) (bool, error) {
+	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	if d.isPostgreSQL {
+		query = `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+	}
+
+	var count int
+	if err := d.db.QueryRow(d.convertQuery(query), table, column).Scan(&count); err != nil {
+		return false, fmt.Errorf("检查%s表的%s字段是否存在失败: %w", table, column, err)
+	}
+	return count > 0, nil
+}
+
+// appliedMigrations 读取已执行的迁移（版本号 -> 执行时间）
+func (d *Database) appliedMigrations() (map[int]time.Time, error) {
+	if _, err := d.db.Exec(schemaMigrationsTable); err != nil {
+		return nil, fmt.Errorf("创建 schema_migrations 表失败: %w", err)
+	}
+
+	rows, err := d.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("读取已执行迁移失败: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("读取已执行迁移失败: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatus 返回所有迁移的执行状态（包括数据库中存在但程序不认识的版本）
+func (d *Database) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(d.dialect())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]bool)
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		known[m.Version] = true
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, appliedAt := range applied {
+		if !known[version] {
+			appliedAt := appliedAt
+			statuses = append(statuses, MigrationStatus{Version: version, Applied: true, Unknown: true, AppliedAt: &appliedAt})
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// SchemaVersion 当前数据库结构版本（已执行的最大迁移版本号，0 表示尚未迁移）
+func (d *Database) SchemaVersion() (int, error) {
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// MigrateUp 按版本顺序执行所有未执行的迁移，每个迁移在独立事务中执行，返回本次执行的迁移
+func (d *Database) MigrateUp() ([]Migration, error) {
+	ctx := context.Background()
+	migrations, err := LoadMigrations(d.dialect())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// 引入版本管理之前创建的旧库：先补齐旧版字段，再由幂等的初始迁移接管
+	if len(applied) == 0 {
+		legacy, err := d.tableExists("traders")
+		if err != nil {
+			return nil, err
+		}
+		if legacy {
+			configLog.Infof(ctx, "🔄 检测到未记录迁移版本的旧数据库，开始接管...")
+			if err := d.upgradeLegacySchema(); err != nil {
+				return nil, fmt.Errorf("接管旧数据库失败: %w", err)
+			}
+		}
+	}
+
+	var executed []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		configLog.Infof(ctx, "🔄 执行数据库迁移 %04d_%s", m.Version, m.Name)
+		if err := d.applyMigration(m); err != nil {
+			return executed, err
+		}
+		executed = append(executed, m)
+	}
+	return executed, nil
+}
+
+// applyMigration 在事务中执行迁移脚本并记录版本
+func (d *Database) applyMigration(m Migration) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始迁移 %04d_%s 事务失败: %w", m.Version, m.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return fmt.Errorf("执行迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec(d.convertQuery(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), m.Version, m.Name); err != nil {
+		return fmt.Errorf("记录迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// checkSchema 启动时检查数据库结构版本
+// 数据库中存在程序不认识的迁移版本（数据库由更新的程序升级过）时拒绝启动；
+// 存在未执行的迁移时，autoMigrate 为 true 则自动执行，否则拒绝启动
+func (d *Database) checkSchema(autoMigrate bool) error {
+	ctx := context.Background()
+	statuses, err := d.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	latest := 0
+	for _, status := range statuses {
+		if status.Unknown {
+			return fmt.Errorf("数据库结构版本 %d 不被当前程序识别（数据库可能已被更新版本的程序迁移），请升级程序后再启动", status.Version)
+		}
+		if !status.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+		latest = status.Version
+	}
+
+	if len(pending) == 0 {
+		configLog.Infof(ctx, "✓ 数据库结构版本: %d", latest)
+		return nil
+	}
+	if !autoMigrate {
+		return fmt.Errorf("数据库有 %d 个未执行的迁移 %v，请先运行 nofx migrate up", len(pending), pending)
+	}
+
+	executed, err := d.MigrateUp()
+	if err != nil {
+		return err
+	}
+	configLog.Infof(ctx, "✅ 已执行 %d 个数据库迁移，当前结构版本: %d", len(executed), latest)
+	return nil
+}
@@ -0,0 +1,156 @@
+-- 初始表结构（PostgreSQL / Supabase）
+-- 包含原根目录 database_*.sql、database_migration_v2~v12.sql、supabase_migration.sql、fix_user_table.sql 的全部表结构
+
+-- 用户表
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    email TEXT UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL,
+    otp_secret TEXT NOT NULL DEFAULT '',
+    otp_verified BOOLEAN DEFAULT FALSE,
+    admin BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- AI模型配置表（用户可创建多个同类型模型）
+CREATE TABLE IF NOT EXISTS ai_models (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    enabled BOOLEAN DEFAULT FALSE,
+    api_key TEXT DEFAULT '',
+    description TEXT DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 交易所配置表（用户可创建多个同类型交易所）
+CREATE TABLE IF NOT EXISTS exchanges (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    exchange_type TEXT NOT NULL,
+    enabled BOOLEAN DEFAULT FALSE,
+    api_key TEXT DEFAULT '',
+    secret_key TEXT DEFAULT '',
+    testnet BOOLEAN DEFAULT FALSE,
+    hyperliquid_wallet_addr TEXT DEFAULT '',
+    aster_user TEXT DEFAULT '',
+    aster_signer TEXT DEFAULT '',
+    aster_private_key TEXT DEFAULT '',
+    passphrase TEXT DEFAULT '',
+    credentials TEXT DEFAULT '',
+    description TEXT DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 交易员配置表
+CREATE TABLE IF NOT EXISTS traders (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    ai_model_id TEXT NOT NULL,
+    exchange_id TEXT NOT NULL,
+    description TEXT DEFAULT '',
+    enabled BOOLEAN DEFAULT FALSE,
+    initial_balance DECIMAL(20,8) DEFAULT 1000.00000000,
+    scan_interval_minutes INTEGER DEFAULT 3,
+    is_running BOOLEAN DEFAULT FALSE,
+    custom_prompt TEXT DEFAULT '',
+    override_base_prompt BOOLEAN DEFAULT FALSE,
+    is_cross_margin BOOLEAN DEFAULT TRUE,
+    indicator_spec TEXT DEFAULT '',
+    margin_policy TEXT DEFAULT '',
+    exposure_limits TEXT DEFAULT '',
+    risk_reward_rules TEXT DEFAULT '',
+    risk_policy TEXT DEFAULT '',
+    prompt_template TEXT DEFAULT '',
+    coin_universe TEXT DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (ai_model_id) REFERENCES ai_models(id) ON DELETE CASCADE,
+    FOREIGN KEY (exchange_id) REFERENCES exchanges(id) ON DELETE CASCADE
+);
+
+-- 提示词模板表（同一用户同名模板按版本递增保存，不覆盖旧版本）
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    language TEXT DEFAULT 'zh',
+    system_template TEXT NOT NULL,
+    user_template TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, name, version),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- A/B实验表（同一交易所、同一AI模型下的多个交易员对比）
+CREATE TABLE IF NOT EXISTS experiments (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT DEFAULT '',
+    arms TEXT NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 系统配置表
+CREATE TABLE IF NOT EXISTS system_config (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 索引
+CREATE INDEX IF NOT EXISTS idx_ai_models_user_id ON ai_models(user_id);
+CREATE INDEX IF NOT EXISTS idx_ai_models_provider ON ai_models(provider);
+CREATE INDEX IF NOT EXISTS idx_ai_models_enabled ON ai_models(enabled);
+CREATE INDEX IF NOT EXISTS idx_exchanges_user_id ON exchanges(user_id);
+CREATE INDEX IF NOT EXISTS idx_exchanges_type ON exchanges(exchange_type);
+CREATE INDEX IF NOT EXISTS idx_exchanges_enabled ON exchanges(enabled);
+CREATE INDEX IF NOT EXISTS idx_traders_user_id ON traders(user_id);
+CREATE INDEX IF NOT EXISTS idx_traders_ai_model_id ON traders(ai_model_id);
+CREATE INDEX IF NOT EXISTS idx_traders_exchange_id ON traders(exchange_id);
+CREATE INDEX IF NOT EXISTS idx_traders_enabled ON traders(enabled);
+CREATE INDEX IF NOT EXISTS idx_prompt_templates_user_id ON prompt_templates(user_id);
+CREATE INDEX IF NOT EXISTS idx_experiments_user_id ON experiments(user_id);
+
+-- 自动更新 updated_at 的函数和触发器
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_ai_models_updated_at ON ai_models;
+CREATE TRIGGER update_ai_models_updated_at BEFORE UPDATE ON ai_models
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_exchanges_updated_at ON exchanges;
+CREATE TRIGGER update_exchanges_updated_at BEFORE UPDATE ON exchanges
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_traders_updated_at ON traders;
+CREATE TRIGGER update_traders_updated_at BEFORE UPDATE ON traders
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_system_config_updated_at ON system_config;
+CREATE TRIGGER update_system_config_updated_at BEFORE UPDATE ON system_config
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+-- 交易员健康状态（由监督器在连续失败或重启耗尽时标记 degraded/errored）
+ALTER TABLE traders ADD COLUMN IF NOT EXISTS health_status TEXT DEFAULT 'healthy';
+ALTER TABLE traders ADD COLUMN IF NOT EXISTS last_error TEXT DEFAULT '';
@@ -0,0 +1,2 @@
+-- 交易员周期调度配置（trader.Schedule JSON，为空表示按扫描间隔固定执行）
+ALTER TABLE traders ADD COLUMN schedule TEXT DEFAULT '';
@@ -0,0 +1,26 @@
+package config
+
+// legacyColumns 引入迁移版本管理之前的旧版数据库可能缺少的字段（表名、字段定义），接管旧库时补齐
+var legacyColumns = [][2]string{
+	{"users", "admin BOOLEAN DEFAULT FALSE"},
+	{"ai_models", "description TEXT DEFAULT ''"},
+	{"exchanges", "hyperliquid_wallet_addr TEXT DEFAULT ''"},
+	{"exchanges", "aster_user TEXT DEFAULT ''"},
+	{"exchanges", "aster_signer TEXT DEFAULT ''"},
+	{"exchanges", "aster_private_key TEXT DEFAULT ''"},
+	{"exchanges", "passphrase TEXT DEFAULT ''"},
+	{"exchanges", "credentials TEXT DEFAULT ''"},
+	{"exchanges", "description TEXT DEFAULT ''"},
+	{"traders", "description TEXT DEFAULT ''"},
+	{"traders", "enabled BOOLEAN DEFAULT FALSE"},
+	{"traders", "custom_prompt TEXT DEFAULT ''"},
+	{"traders", "override_base_prompt BOOLEAN DEFAULT FALSE"},
+	{"traders", "is_cross_margin BOOLEAN DEFAULT TRUE"}, // 默认为全仓模式
+	{"traders", "indicator_spec TEXT DEFAULT ''"},
+	{"traders", "margin_policy TEXT DEFAULT ''"},
+	{"traders", "exposure_limits TEXT DEFAULT ''"},
+	{"traders", "risk_reward_rules TEXT DEFAULT ''"},
+	{"traders", "risk_policy TEXT DEFAULT ''"},
+	{"traders", "prompt_template TEXT DEFAULT ''"},
+	{"traders", "coin_universe TEXT DEFAULT ''"},
+}
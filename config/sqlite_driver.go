@@ -0,0 +1,7 @@
+//go:build !nosqlite
+
+package config
+
+// 内嵌 SQLite 驱动（纯 Go 实现，无需 CGO），默认编译进程序以便不配置 DATABASE_URL 也能在本地和测试中运行
+// 只使用 PostgreSQL/Supabase 的部署可使用 go build -tags nosqlite 去掉此驱动以减小体积
+import _ "modernc.org/sqlite"
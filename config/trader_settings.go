@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// TraderSetting 交易员的单列配置（除提示词模板外均为带 Validate 方法的配置对象序列化后的JSON，为空表示使用默认值）
+type TraderSetting string
+
+const (
+	SettingIndicatorSpec   TraderSetting = "indicator_spec"    // market.IndicatorSpec
+	SettingMarginPolicy    TraderSetting = "margin_policy"     // trader.MarginPolicy
+	SettingExposureLimits  TraderSetting = "exposure_limits"   // decision.ExposureLimits
+	SettingRiskRewardRules TraderSetting = "risk_reward_rules" // decision.RiskRewardRules
+	SettingRiskPolicy      TraderSetting = "risk_policy"       // decision.RiskPolicy
+	SettingPromptTemplate  TraderSetting = "prompt_template"   // 提示词模板引用（name 或 name@version）
+	SettingCoinUniverse    TraderSetting = "coin_universe"     // pool.Universe
+	SettingSchedule        TraderSetting = "schedule"          // trader.Schedule
+)
+
+// traderSettingLabels 配置名称（同时作为可更新列的白名单）
+var traderSettingLabels = map[TraderSetting]string{
+	SettingIndicatorSpec:   "指标配置",
+	SettingMarginPolicy:    "保证金策略",
+	SettingExposureLimits:  "组合敞口限制",
+	SettingRiskRewardRules: "止损止盈校验阈值",
+	SettingRiskPolicy:      "风控策略",
+	SettingPromptTemplate:  "提示词模板",
+	SettingCoinUniverse:    "币种范围",
+	SettingSchedule:        "周期调度",
+}
+
+// ErrTraderNotFound 交易员不存在或不属于当前用户
+var ErrTraderNotFound = errors.New("交易员不存在")
+
+// Label 配置名称（用于日志和错误信息）
+func (s TraderSetting) Label() string {
+	if label, ok := traderSettingLabels[s]; ok {
+		return label
+	}
+	return string(s)
+}
+
+// Setting 读取交易员记录中的配置原始值
+func (t *TraderRecord) Setting(s TraderSetting) string {
+	switch s {
+	case SettingIndicatorSpec:
+		return t.IndicatorSpec
+	case SettingMarginPolicy:
+		return t.MarginPolicy
+	case SettingExposureLimits:
+		return t.ExposureLimits
+	case SettingRiskRewardRules:
+		return t.RiskRewardRules
+	case SettingRiskPolicy:
+		return t.RiskPolicy
+	case SettingPromptTemplate:
+		return t.PromptTemplate
+	case SettingCoinUniverse:
+		return t.CoinUniverse
+	case SettingSchedule:
+		return t.Schedule
+	default:
+		return ""
+	}
+}
+
+// UpdateTraderSetting 更新交易员的单列配置，交易员不存在或不属于该用户时返回 ErrTraderNotFound
+func (d *Database) UpdateTraderSetting(userID, id string, setting TraderSetting, value string) error {
+	if _, ok := traderSettingLabels[setting]; !ok {
+		return fmt.Errorf("未知的交易员配置: %s", setting)
+	}
+
+	// 列名来自白名单，不会拼接用户输入
+	query := d.convertQuery(`UPDATE traders SET ` + string(setting) + ` = ? WHERE id = ? AND user_id = ?`)
+	result, err := d.db.Exec(query, value, id, userID)
+	if err != nil {
+		return fmt.Errorf("更新%s失败: %w", setting.Label(), err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTraderNotFound
+	}
+	return nil
+}
+
+// GetTrader 获取用户的单个交易员，不存在或不属于该用户时返回 ErrTraderNotFound
+func (d *Database) GetTrader(userID, id string) (*TraderRecord, error) {
+	traders, err := d.GetTraders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易员列表失败: %w", err)
+	}
+	for _, t := range traders {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, ErrTraderNotFound
+}
+
+// SettingValue 可保存为交易员配置的对象（指向配置结构体的指针）
+type SettingValue[T any] interface {
+	*T
+	Validate() error
+}
+
+// EncodeTraderSetting 校验配置并序列化为JSON（nil返回空字符串，表示使用默认值）
+func EncodeTraderSetting[T any, PT SettingValue[T]](setting TraderSetting, value PT) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	if err := value.Validate(); err != nil {
+		return "", fmt.Errorf("%s无效: %w", setting.Label(), err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("序列化%s失败: %w", setting.Label(), err)
+	}
+	return string(data), nil
+}
+
+// DecodeTraderSetting 解析并校验交易员记录中的配置（未配置时返回nil，表示使用默认值）
+func DecodeTraderSetting[T any, PT SettingValue[T]](record *TraderRecord, setting TraderSetting) (PT, error) {
+	raw := record.Setting(setting)
+	if raw == "" {
+		return nil, nil
+	}
+	value := PT(new(T))
+	if err := json.Unmarshal([]byte(raw), value); err != nil {
+		return nil, fmt.Errorf("解析%s失败: %w", setting.Label(), err)
+	}
+	if err := value.Validate(); err != nil {
+		return nil, fmt.Errorf("%s无效: %w", setting.Label(), err)
+	}
+	return value, nil
+}
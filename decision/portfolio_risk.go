@@ -0,0 +1,417 @@
+package decision
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"nofx/market"
+	"sort"
+	"strings"
+)
+
+// ExposureLimits 组合敞口限制（百分比均相对账户净值，0表示不限制该项）
+type ExposureLimits struct {
+	MaxNetExposurePct   float64 `json:"max_net_exposure_pct"`   // 净敞口（多头名义-空头名义）上限，默认1000
+	MaxGrossExposurePct float64 `json:"max_gross_exposure_pct"` // 总敞口（多头名义+空头名义）上限，默认1500
+	MaxBetaExposurePct  float64 `json:"max_beta_exposure_pct"`  // 按BTC Beta加权的净敞口上限，默认1000
+	MaxSameDirection    int     `json:"max_same_direction"`     // 同方向持仓数量上限，默认3
+	MaxCorrelation      float64 `json:"max_correlation"`        // 与同方向持仓的收益相关性阈值，超过时缩减仓位，默认0.85
+	CorrelatedSizePct   float64 `json:"correlated_size_pct"`    // 高相关时仓位缩减到的比例，默认50
+	CorrelationInterval string  `json:"correlation_interval"`   // 计算收益率的K线周期，默认1h
+	CorrelationLookback int     `json:"correlation_lookback"`   // 收益率样本数量，默认72
+}
+
+// minExposureSizeUSD 敞口缩减后低于此仓位价值时直接拒绝
+const minExposureSizeUSD = 10
+
+// DefaultExposureLimits 默认组合敞口限制
+func DefaultExposureLimits() ExposureLimits {
+	return ExposureLimits{
+		MaxNetExposurePct:   1000,
+		MaxGrossExposurePct: 1500,
+		MaxBetaExposurePct:  1000,
+		MaxSameDirection:    3,
+		MaxCorrelation:      0.85,
+		CorrelatedSizePct:   50,
+		CorrelationInterval: "1h",
+		CorrelationLookback: 72,
+	}
+}
+
+// Validate 校验并补全默认值
+func (l *ExposureLimits) Validate() error {
+	defaults := DefaultExposureLimits()
+	if l.CorrelatedSizePct == 0 {
+		l.CorrelatedSizePct = defaults.CorrelatedSizePct
+	}
+	if l.CorrelationInterval == "" {
+		l.CorrelationInterval = defaults.CorrelationInterval
+	}
+	if l.CorrelationLookback == 0 {
+		l.CorrelationLookback = defaults.CorrelationLookback
+	}
+
+	if l.MaxNetExposurePct < 0 || l.MaxGrossExposurePct < 0 || l.MaxBetaExposurePct < 0 {
+		return fmt.Errorf("敞口上限不能为负数")
+	}
+	if l.MaxSameDirection < 0 {
+		return fmt.Errorf("同方向持仓数量上限不能为负数: %d", l.MaxSameDirection)
+	}
+	if l.MaxCorrelation < 0 || l.MaxCorrelation > 1 {
+		return fmt.Errorf("相关性阈值必须在0-1之间: %.2f", l.MaxCorrelation)
+	}
+	if l.CorrelatedSizePct <= 0 || l.CorrelatedSizePct > 100 {
+		return fmt.Errorf("高相关仓位比例必须在0-100%%之间: %.2f", l.CorrelatedSizePct)
+	}
+	if l.CorrelationLookback < 10 || l.CorrelationLookback > 500 {
+		return fmt.Errorf("收益率样本数量必须在10-500之间: %d", l.CorrelationLookback)
+	}
+	if !market.IsSupportedInterval(l.CorrelationInterval) {
+		return fmt.Errorf("不支持的K线周期: %s", l.CorrelationInterval)
+	}
+	return nil
+}
+
+// exposure 单个持仓的敞口
+type exposure struct {
+	symbol   string
+	side     string // "long" or "short"
+	notional float64
+	beta     float64
+}
+
+// signed 带方向的名义价值（多头为正，空头为负）
+func (e exposure) signed() float64 {
+	if e.side == "short" {
+		return -e.notional
+	}
+	return e.notional
+}
+
+// ExposureCheck 单个开仓决策的敞口检查结果
+type ExposureCheck struct {
+	RequestedSizeUSD float64
+	SizeUSD          float64 // 检查后允许的仓位价值（拒绝时为0）
+	Resized          bool
+	Rejected         bool
+	Reason           string
+}
+
+// PortfolioRisk 组合风险（净敞口、BTC Beta、收益相关性），在一个交易周期内使用
+// 持仓敞口随本周期的开平仓决策更新，保证后续决策看到的是执行后的组合
+type PortfolioRisk struct {
+	limits    ExposureLimits
+	equity    float64
+	provider  market.Provider
+	fetchCtx  context.Context // 获取K线使用的context（交易周期的生命周期）
+	exposures []exposure
+	returns   map[string][]float64 // symbol -> 对数收益率（缓存，nil表示获取失败）
+}
+
+// NewPortfolioRisk 根据交易上下文构建组合风险
+func NewPortfolioRisk(runCtx context.Context, ctx *Context) *PortfolioRisk {
+	limits := DefaultExposureLimits()
+	if ctx.ExposureLimits != nil {
+		limits = *ctx.ExposureLimits
+	}
+	provider := ctx.MarketProvider
+	if provider == nil {
+		provider = market.DefaultProvider()
+	}
+
+	r := &PortfolioRisk{
+		limits:   limits,
+		equity:   ctx.Account.TotalEquity,
+		provider: provider,
+		fetchCtx: runCtx,
+		returns:  make(map[string][]float64),
+	}
+	for _, pos := range ctx.Positions {
+		r.exposures = append(r.exposures, exposure{
+			symbol:   pos.Symbol,
+			side:     pos.Side,
+			notional: pos.Quantity * pos.MarkPrice,
+			beta:     r.Beta(pos.Symbol),
+		})
+	}
+	return r
+}
+
+// symbolReturns 获取币种的对数收益率序列（带缓存）
+func (r *PortfolioRisk) symbolReturns(symbol string) []float64 {
+	if rets, ok := r.returns[symbol]; ok {
+		return rets
+	}
+
+	klines, err := r.provider.GetKlines(r.fetchCtx, symbol, r.limits.CorrelationInterval, r.limits.CorrelationLookback+1)
+	if err != nil {
+		decisionLog.Warnf(r.fetchCtx, "⚠️  获取%s K线失败，无法计算相关性: %v", symbol, err)
+		r.returns[symbol] = nil
+		return nil
+	}
+
+	var rets []float64
+	for i := 1; i < len(klines); i++ {
+		if klines[i-1].Close > 0 && klines[i].Close > 0 {
+			rets = append(rets, math.Log(klines[i].Close/klines[i-1].Close))
+		}
+	}
+	r.returns[symbol] = rets
+	return rets
+}
+
+// alignedReturns 取两个币种最近相同数量的收益率
+func (r *PortfolioRisk) alignedReturns(a, b string) ([]float64, []float64) {
+	ra, rb := r.symbolReturns(a), r.symbolReturns(b)
+	n := len(ra)
+	if len(rb) < n {
+		n = len(rb)
+	}
+	if n < 10 {
+		return nil, nil
+	}
+	return ra[len(ra)-n:], rb[len(rb)-n:]
+}
+
+// Correlation 两个币种的收益率相关系数（数据不足时 ok=false）
+func (r *PortfolioRisk) Correlation(a, b string) (float64, bool) {
+	if a == b {
+		return 1, true
+	}
+	xa, xb := r.alignedReturns(a, b)
+	if xa == nil {
+		return 0, false
+	}
+	cov, varA, varB := covariance(xa, xb)
+	if varA == 0 || varB == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varA*varB), true
+}
+
+// Beta 币种相对BTC的Beta（数据不足时按1估算）
+func (r *PortfolioRisk) Beta(symbol string) float64 {
+	if symbol == "BTCUSDT" {
+		return 1
+	}
+	xs, xb := r.alignedReturns(symbol, "BTCUSDT")
+	if xs == nil {
+		return 1
+	}
+	cov, _, varBTC := covariance(xs, xb)
+	if varBTC == 0 {
+		return 1
+	}
+	return cov / varBTC
+}
+
+// covariance 协方差及各自方差
+func covariance(a, b []float64) (cov, varA, varB float64) {
+	n := float64(len(a))
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= n
+	meanB /= n
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	return cov / n, varA / n, varB / n
+}
+
+// totals 当前多头名义、空头名义、Beta加权净敞口
+func (r *PortfolioRisk) totals() (long, short, betaNet float64) {
+	for _, e := range r.exposures {
+		if e.side == "short" {
+			short += e.notional
+		} else {
+			long += e.notional
+		}
+		betaNet += e.signed() * e.beta
+	}
+	return long, short, betaNet
+}
+
+// Check 检查开仓决策是否突破组合敞口限制，必要时缩减仓位（接受时立即计入敞口）
+func (r *PortfolioRisk) Check(d *Decision) ExposureCheck {
+	check := ExposureCheck{RequestedSizeUSD: d.PositionSizeUSD, SizeUSD: d.PositionSizeUSD}
+	side := "long"
+	dir := 1.0
+	if d.Action == "open_short" {
+		side = "short"
+		dir = -1
+	}
+	if r.equity <= 0 {
+		check.Rejected = true
+		check.SizeUSD = 0
+		check.Reason = "账户净值无效，无法计算组合敞口"
+		return check
+	}
+
+	// 1. 同方向持仓数量
+	sameDirection := 0
+	for _, e := range r.exposures {
+		if e.side == side {
+			sameDirection++
+		}
+	}
+	if r.limits.MaxSameDirection > 0 && sameDirection >= r.limits.MaxSameDirection {
+		check.Rejected = true
+		check.SizeUSD = 0
+		check.Reason = fmt.Sprintf("已有%d个%s仓，达到同方向持仓上限%d", sameDirection, side, r.limits.MaxSameDirection)
+		return check
+	}
+
+	var reasons []string
+	size := d.PositionSizeUSD
+
+	// 2. 与同方向持仓高度相关时缩减仓位（相当于加仓同一风险因子）
+	if r.limits.MaxCorrelation > 0 {
+		for _, e := range r.exposures {
+			if e.side != side {
+				continue
+			}
+			if corr, ok := r.Correlation(d.Symbol, e.symbol); ok && corr > r.limits.MaxCorrelation {
+				size *= r.limits.CorrelatedSizePct / 100
+				reasons = append(reasons, fmt.Sprintf("与%s %s仓相关性%.2f>%.2f，仓位缩减至%.0f%%",
+					e.symbol, side, corr, r.limits.MaxCorrelation, r.limits.CorrelatedSizePct))
+				break
+			}
+		}
+	}
+
+	// 3. 净敞口、总敞口、Beta加权净敞口
+	long, short, betaNet := r.totals()
+	net := long - short
+	beta := r.Beta(d.Symbol)
+
+	if r.limits.MaxNetExposurePct > 0 {
+		room := r.equity*r.limits.MaxNetExposurePct/100 - dir*net
+		if size > room {
+			size = room
+			reasons = append(reasons, fmt.Sprintf("净敞口上限%.0f%%（当前%+.0f U）", r.limits.MaxNetExposurePct, net))
+		}
+	}
+	if r.limits.MaxGrossExposurePct > 0 {
+		room := r.equity*r.limits.MaxGrossExposurePct/100 - (long + short)
+		if size > room {
+			size = room
+			reasons = append(reasons, fmt.Sprintf("总敞口上限%.0f%%（当前%.0f U）", r.limits.MaxGrossExposurePct, long+short))
+		}
+	}
+	if r.limits.MaxBetaExposurePct > 0 && beta > 0 {
+		room := (r.equity*r.limits.MaxBetaExposurePct/100 - dir*betaNet) / beta
+		if size > room {
+			size = room
+			reasons = append(reasons, fmt.Sprintf("BTC Beta加权净敞口上限%.0f%%（当前%+.0f U，%s Beta %.2f）",
+				r.limits.MaxBetaExposurePct, betaNet, d.Symbol, beta))
+		}
+	}
+
+	check.Reason = strings.Join(reasons, "；")
+	if size < minExposureSizeUSD {
+		check.Rejected = true
+		check.SizeUSD = 0
+		if check.Reason == "" {
+			check.Reason = "仓位价值过小"
+		}
+		check.Reason = fmt.Sprintf("%s，剩余可开仓位 %.2f U 低于 %d U", check.Reason, math.Max(size, 0), minExposureSizeUSD)
+		return check
+	}
+	if size < d.PositionSizeUSD {
+		check.Resized = true
+		check.SizeUSD = size
+	}
+
+	r.exposures = append(r.exposures, exposure{symbol: d.Symbol, side: side, notional: size, beta: beta})
+	return check
+}
+
+// Rollback 开仓失败时撤销 Check 计入的敞口
+func (r *PortfolioRisk) Rollback(symbol, side string) {
+	for i := len(r.exposures) - 1; i >= 0; i-- {
+		if r.exposures[i].symbol == symbol && r.exposures[i].side == side {
+			r.exposures = append(r.exposures[:i], r.exposures[i+1:]...)
+			return
+		}
+	}
+}
+
+// Release 平仓后移除该持仓的敞口
+func (r *PortfolioRisk) Release(symbol, side string) {
+	kept := r.exposures[:0]
+	for _, e := range r.exposures {
+		if e.symbol != symbol || e.side != side {
+			kept = append(kept, e)
+		}
+	}
+	r.exposures = kept
+}
+
+// Summary 组合风险摘要（用于User Prompt）
+func (r *PortfolioRisk) Summary() string {
+	if r.equity <= 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	long, short, betaNet := r.totals()
+	pct := func(v float64) float64 { return v / r.equity * 100 }
+
+	sb.WriteString("## 📐 组合风险\n")
+	sb.WriteString(fmt.Sprintf("多头名义%.0f U | 空头名义%.0f U | 净敞口%+.0f U (%+.0f%%%s) | 总敞口%.0f U (%.0f%%%s) | BTC Beta加权净敞口%+.0f U (%+.0f%%%s)\n",
+		long, short,
+		long-short, pct(long-short), limitSuffix(r.limits.MaxNetExposurePct),
+		long+short, pct(long+short), limitSuffix(r.limits.MaxGrossExposurePct),
+		betaNet, pct(betaNet), limitSuffix(r.limits.MaxBetaExposurePct)))
+
+	if len(r.exposures) > 0 {
+		for _, e := range r.exposures {
+			corr, ok := r.Correlation(e.symbol, "BTCUSDT")
+			corrStr := "N/A"
+			if ok {
+				corrStr = fmt.Sprintf("%.2f", corr)
+			}
+			sb.WriteString(fmt.Sprintf("- %s %s 名义%.0f U | Beta %.2f | 与BTC相关性 %s\n",
+				e.symbol, strings.ToUpper(e.side), e.notional, e.beta, corrStr))
+		}
+
+		// 同方向高相关持仓对
+		var pairs []string
+		for i := 0; i < len(r.exposures); i++ {
+			for j := i + 1; j < len(r.exposures); j++ {
+				a, b := r.exposures[i], r.exposures[j]
+				if a.side != b.side || a.symbol == b.symbol {
+					continue
+				}
+				if corr, ok := r.Correlation(a.symbol, b.symbol); ok && corr > r.limits.MaxCorrelation {
+					pairs = append(pairs, fmt.Sprintf("%s/%s %.2f", a.symbol, b.symbol, corr))
+				}
+			}
+		}
+		if len(pairs) > 0 {
+			sort.Strings(pairs)
+			sb.WriteString(fmt.Sprintf("⚠️ 同方向高相关持仓: %s\n", strings.Join(pairs, ", ")))
+		}
+	}
+
+	if r.limits.MaxSameDirection > 0 {
+		sb.WriteString(fmt.Sprintf("同方向持仓上限%d个；与同方向持仓相关性>%.2f的新开仓会缩减至%.0f%%，超出敞口上限的开仓会被缩减或拒绝\n",
+			r.limits.MaxSameDirection, r.limits.MaxCorrelation, r.limits.CorrelatedSizePct))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// limitSuffix 敞口上限显示（0表示不限制）
+func limitSuffix(limitPct float64) string {
+	if limitPct <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" / 上限%.0f%%", limitPct)
+}
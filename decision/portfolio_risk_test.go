@@ -0,0 +1,335 @@
+package decision
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"nofx/market"
+)
+
+// factorProvider 按两个正交收益率因子合成K线：收益率 = a×因子A + b×因子B
+// 因子A: +1 -1 +1 -1 ...，因子B: +1 +1 -1 -1 ...（每4个样本均值为0且互不相关）
+type factorProvider map[string][2]float64
+
+func (p factorProvider) Name() string { return "factor" }
+
+func (p factorProvider) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]market.Kline, error) {
+	w, ok := p[symbol]
+	if !ok {
+		return nil, fmt.Errorf("no klines for %s", symbol)
+	}
+	klines := make([]market.Kline, limit)
+	price := 100.0
+	for i := range klines {
+		if i > 0 {
+			a := []float64{1, -1}[(i-1)%2]
+			b := []float64{1, 1, -1, -1}[(i-1)%4]
+			price *= math.Exp(0.01 * (w[0]*a + w[1]*b))
+		}
+		klines[i] = market.Kline{Close: price}
+	}
+	return klines, nil
+}
+
+func (p factorProvider) GetOpenInterest(ctx context.Context, symbol string) (*market.OIData, error) {
+	return &market.OIData{}, nil
+}
+
+func (p factorProvider) GetFundingRate(ctx context.Context, symbol string) (float64, error) {
+	return 0, nil
+}
+
+// testFactors 各币种的因子暴露
+var testFactors = factorProvider{
+	"BTCUSDT":  {1, 0},  // 基准
+	"ETHUSDT":  {2, 0},  // 与BTC完全相关，Beta 2
+	"SOLUSDT":  {-1, 0}, // 与BTC完全负相关，Beta -1
+	"DOGEUSDT": {0, 1},  // 与BTC不相关，Beta 0
+	"XRPUSDT":  {1, 1},  // 与BTC相关性0.71，Beta 1
+}
+
+// noExposureLimits 只保留相关性计算参数、不限制任何敞口
+func noExposureLimits() ExposureLimits {
+	l := DefaultExposureLimits()
+	l.MaxNetExposurePct = 0
+	l.MaxGrossExposurePct = 0
+	l.MaxBetaExposurePct = 0
+	l.MaxSameDirection = 0
+	l.MaxCorrelation = 0
+	return l
+}
+
+// testPosition 名义价值为 notional 的持仓
+func testPosition(symbol, side string, notional float64) PositionInfo {
+	return PositionInfo{Symbol: symbol, Side: side, Quantity: notional, MarkPrice: 1}
+}
+
+func newTestPortfolioRisk(equity float64, limits ExposureLimits, positions ...PositionInfo) *PortfolioRisk {
+	return NewPortfolioRisk(context.Background(), &Context{
+		Account:        AccountInfo{TotalEquity: equity},
+		Positions:      positions,
+		ExposureLimits: &limits,
+		MarketProvider: testFactors,
+	})
+}
+
+func TestPortfolioRiskBetaAndCorrelation(t *testing.T) {
+	r := newTestPortfolioRisk(1000, noExposureLimits())
+	betas := map[string]float64{"BTCUSDT": 1, "ETHUSDT": 2, "SOLUSDT": -1, "DOGEUSDT": 0, "XRPUSDT": 1, "PEPEUSDT": 1}
+	for symbol, want := range betas {
+		if got := r.Beta(symbol); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Beta(%s) = %.4f, want %.4f", symbol, got, want)
+		}
+	}
+	corrs := []struct {
+		a, b   string
+		want   float64
+		wantOK bool
+	}{
+		{"ETHUSDT", "BTCUSDT", 1, true},
+		{"SOLUSDT", "BTCUSDT", -1, true},
+		{"DOGEUSDT", "BTCUSDT", 0, true},
+		{"XRPUSDT", "BTCUSDT", 1 / math.Sqrt2, true},
+		{"PEPEUSDT", "PEPEUSDT", 1, true},
+		{"PEPEUSDT", "BTCUSDT", 0, false}, // 获取K线失败
+	}
+	for _, c := range corrs {
+		got, ok := r.Correlation(c.a, c.b)
+		if ok != c.wantOK || math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("Correlation(%s, %s) = %.4f, %v, want %.4f, %v", c.a, c.b, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func TestPortfolioRiskCheck(t *testing.T) {
+	with := func(update func(l *ExposureLimits)) ExposureLimits {
+		l := noExposureLimits()
+		update(&l)
+		return l
+	}
+
+	cases := []struct {
+		name       string
+		equity     float64
+		limits     ExposureLimits
+		positions  []PositionInfo
+		decision   Decision
+		wantSize   float64
+		wantReject bool
+		wantReason string
+	}{
+		{
+			name:       "账户净值无效",
+			equity:     0,
+			limits:     noExposureLimits(),
+			decision:   Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 100},
+			wantReject: true,
+			wantReason: "账户净值无效",
+		},
+		{
+			name:     "不限制",
+			equity:   1000,
+			limits:   noExposureLimits(),
+			decision: Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 50000},
+			wantSize: 50000,
+		},
+		{
+			name:       "达到同方向持仓上限",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxSameDirection = 2 }),
+			positions:  []PositionInfo{testPosition("BTCUSDT", "long", 100), testPosition("DOGEUSDT", "long", 100)},
+			decision:   Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 100},
+			wantReject: true,
+			wantReason: "已有2个long仓，达到同方向持仓上限2",
+		},
+		{
+			name:      "同方向上限不限制反方向",
+			equity:    1000,
+			limits:    with(func(l *ExposureLimits) { l.MaxSameDirection = 2 }),
+			positions: []PositionInfo{testPosition("BTCUSDT", "long", 100), testPosition("DOGEUSDT", "long", 100)},
+			decision:  Decision{Symbol: "XRPUSDT", Action: "open_short", PositionSizeUSD: 100},
+			wantSize:  100,
+		},
+		{
+			name:       "与同方向持仓高相关时缩减",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxCorrelation = 0.85 }),
+			positions:  []PositionInfo{testPosition("BTCUSDT", "long", 100)},
+			decision:   Decision{Symbol: "ETHUSDT", Action: "open_long", PositionSizeUSD: 200},
+			wantSize:   100,
+			wantReason: "与BTCUSDT long仓相关性1.00>0.85，仓位缩减至50%",
+		},
+		{
+			name:      "相关性低于阈值不缩减",
+			equity:    1000,
+			limits:    with(func(l *ExposureLimits) { l.MaxCorrelation = 0.85 }),
+			positions: []PositionInfo{testPosition("BTCUSDT", "long", 100)},
+			decision:  Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 200},
+			wantSize:  200,
+		},
+		{
+			name:      "高相关但方向相反不缩减",
+			equity:    1000,
+			limits:    with(func(l *ExposureLimits) { l.MaxCorrelation = 0.85 }),
+			positions: []PositionInfo{testPosition("BTCUSDT", "long", 100)},
+			decision:  Decision{Symbol: "ETHUSDT", Action: "open_short", PositionSizeUSD: 200},
+			wantSize:  200,
+		},
+		{
+			name:       "净敞口上限",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxNetExposurePct = 100 }),
+			positions:  []PositionInfo{testPosition("DOGEUSDT", "long", 800)},
+			decision:   Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 500},
+			wantSize:   200,
+			wantReason: "净敞口上限100%（当前+800 U）",
+		},
+		{
+			name:      "反向开仓降低净敞口",
+			equity:    1000,
+			limits:    with(func(l *ExposureLimits) { l.MaxNetExposurePct = 100 }),
+			positions: []PositionInfo{testPosition("DOGEUSDT", "long", 800)},
+			decision:  Decision{Symbol: "XRPUSDT", Action: "open_short", PositionSizeUSD: 1500},
+			wantSize:  1500,
+		},
+		{
+			name:       "总敞口上限",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxGrossExposurePct = 150 }),
+			positions:  []PositionInfo{testPosition("DOGEUSDT", "long", 800), testPosition("SOLUSDT", "short", 600)},
+			decision:   Decision{Symbol: "BTCUSDT", Action: "open_short", PositionSizeUSD: 500},
+			wantSize:   100,
+			wantReason: "总敞口上限150%（当前1400 U）",
+		},
+		{
+			name:       "Beta加权净敞口上限按新币种Beta折算",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxBetaExposurePct = 100 }),
+			positions:  []PositionInfo{testPosition("BTCUSDT", "long", 400)},
+			decision:   Decision{Symbol: "ETHUSDT", Action: "open_long", PositionSizeUSD: 500},
+			wantSize:   300,
+			wantReason: "BTC Beta加权净敞口上限100%（当前+400 U，ETHUSDT Beta 2.00）",
+		},
+		{
+			name:      "负Beta币种不受Beta上限限制",
+			equity:    1000,
+			limits:    with(func(l *ExposureLimits) { l.MaxBetaExposurePct = 100 }),
+			positions: []PositionInfo{testPosition("BTCUSDT", "long", 900)},
+			decision:  Decision{Symbol: "SOLUSDT", Action: "open_long", PositionSizeUSD: 500},
+			wantSize:  500,
+		},
+		{
+			name:       "无K线时Beta按1估算",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxBetaExposurePct = 100 }),
+			decision:   Decision{Symbol: "PEPEUSDT", Action: "open_short", PositionSizeUSD: 2000},
+			wantSize:   1000,
+			wantReason: "PEPEUSDT Beta 1.00",
+		},
+		{
+			name:   "多项限制取最严",
+			equity: 1000,
+			limits: with(func(l *ExposureLimits) {
+				l.MaxNetExposurePct = 100
+				l.MaxGrossExposurePct = 120
+			}),
+			positions:  []PositionInfo{testPosition("DOGEUSDT", "long", 800), testPosition("SOLUSDT", "short", 200)},
+			decision:   Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 1000},
+			wantSize:   200,
+			wantReason: "净敞口上限100%（当前+600 U）；总敞口上限120%（当前1000 U）",
+		},
+		{
+			name:       "剩余额度过小时拒绝",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxNetExposurePct = 100 }),
+			positions:  []PositionInfo{testPosition("DOGEUSDT", "long", 995)},
+			decision:   Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 100},
+			wantReject: true,
+			wantReason: "净敞口上限100%（当前+995 U），剩余可开仓位 5.00 U 低于 10 U",
+		},
+		{
+			name:       "超出上限时剩余额度按0显示",
+			equity:     1000,
+			limits:     with(func(l *ExposureLimits) { l.MaxNetExposurePct = 100 }),
+			positions:  []PositionInfo{testPosition("DOGEUSDT", "long", 1200)},
+			decision:   Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 100},
+			wantReject: true,
+			wantReason: "剩余可开仓位 0.00 U",
+		},
+		{
+			name:       "原始仓位过小时拒绝",
+			equity:     1000,
+			limits:     noExposureLimits(),
+			decision:   Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 5},
+			wantReject: true,
+			wantReason: "仓位价值过小，剩余可开仓位 5.00 U 低于 10 U",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := newTestPortfolioRisk(c.equity, c.limits, c.positions...)
+			before := len(r.exposures)
+			check := r.Check(&c.decision)
+
+			if check.Rejected != c.wantReject {
+				t.Fatalf("Rejected = %v, want %v (%+v)", check.Rejected, c.wantReject, check)
+			}
+			if !strings.Contains(check.Reason, c.wantReason) {
+				t.Errorf("Reason = %q, want 包含 %q", check.Reason, c.wantReason)
+			}
+			if c.wantReason == "" && check.Reason != "" {
+				t.Errorf("不应有原因: %q", check.Reason)
+			}
+			if check.RequestedSizeUSD != c.decision.PositionSizeUSD {
+				t.Errorf("RequestedSizeUSD = %.2f", check.RequestedSizeUSD)
+			}
+			if c.wantReject {
+				if check.SizeUSD != 0 || len(r.exposures) != before {
+					t.Errorf("拒绝后不应计入敞口: size=%.2f exposures=%d", check.SizeUSD, len(r.exposures))
+				}
+				return
+			}
+			if math.Abs(check.SizeUSD-c.wantSize) > 1e-6 {
+				t.Errorf("SizeUSD = %.4f, want %.4f", check.SizeUSD, c.wantSize)
+			}
+			if check.Resized != (c.wantSize < c.decision.PositionSizeUSD) {
+				t.Errorf("Resized = %v", check.Resized)
+			}
+			if len(r.exposures) != before+1 {
+				t.Errorf("通过后应计入敞口: exposures=%d", len(r.exposures))
+			}
+		})
+	}
+}
+
+func TestPortfolioRiskTracksCycleDecisions(t *testing.T) {
+	limits := noExposureLimits()
+	limits.MaxNetExposurePct = 100
+	limits.MaxSameDirection = 2
+	r := newTestPortfolioRisk(1000, limits, testPosition("DOGEUSDT", "long", 600))
+
+	// 本周期已通过的开仓计入敞口，后续决策看到执行后的组合
+	if check := r.Check(&Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 300}); check.Rejected || check.Resized {
+		t.Fatalf("第一次开仓 = %+v", check)
+	}
+	if check := r.Check(&Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 300}); !check.Rejected {
+		t.Fatalf("同方向已有2个仓位，应拒绝: %+v", check)
+	}
+
+	// 开仓失败回滚后额度恢复
+	r.Rollback("BTCUSDT", "long")
+	if check := r.Check(&Decision{Symbol: "XRPUSDT", Action: "open_long", PositionSizeUSD: 600}); check.Rejected || check.SizeUSD != 400 {
+		t.Fatalf("回滚后开仓 = %+v, want 缩减到400", check)
+	}
+
+	// 平仓后释放敞口
+	r.Release("DOGEUSDT", "long")
+	r.Release("XRPUSDT", "long")
+	if check := r.Check(&Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 1000}); check.Rejected || check.Resized {
+		t.Fatalf("平仓后开仓 = %+v", check)
+	}
+}
@@ -0,0 +1,347 @@
+package decision
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"nofx/market"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplateFS embed.FS
+
+// DefaultPromptTemplateName 默认提示词模板名称
+const DefaultPromptTemplateName = "default"
+
+// PromptTemplate 提示词模板（system + user 两部分，使用 text/template 语法）
+type PromptTemplate struct {
+	Name     string `json:"name"`
+	Version  int    `json:"version"`
+	Language string `json:"language"` // zh / en
+	System   string `json:"system_template"`
+	User     string `json:"user_template"`
+	Builtin  bool   `json:"builtin"` // 内置模板（随程序发布，不可修改）
+}
+
+// builtinPromptTemplates 内置模板（名称 -> 模板）
+var builtinPromptTemplates = map[string]*PromptTemplate{
+	DefaultPromptTemplateName: mustLoadBuiltinTemplate(DefaultPromptTemplateName, "zh"),
+	"default_en":              mustLoadBuiltinTemplate("default_en", "en"),
+}
+
+// mustLoadBuiltinTemplate 从嵌入文件加载内置模板
+func mustLoadBuiltinTemplate(name, language string) *PromptTemplate {
+	system, err := builtinTemplateFS.ReadFile("templates/system_" + language + ".tmpl")
+	if err != nil {
+		panic(fmt.Sprintf("加载内置模板 %s 失败: %v", name, err))
+	}
+	user, err := builtinTemplateFS.ReadFile("templates/user_" + language + ".tmpl")
+	if err != nil {
+		panic(fmt.Sprintf("加载内置模板 %s 失败: %v", name, err))
+	}
+	return &PromptTemplate{
+		Name:     name,
+		Version:  1,
+		Language: language,
+		System:   string(system),
+		User:     string(user),
+		Builtin:  true,
+	}
+}
+
+// BuiltinPromptTemplates 所有内置模板（按名称排序）
+func BuiltinPromptTemplates() []*PromptTemplate {
+	templates := make([]*PromptTemplate, 0, len(builtinPromptTemplates))
+	for _, tpl := range builtinPromptTemplates {
+		templates = append(templates, tpl)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// GetBuiltinPromptTemplate 获取内置模板
+func GetBuiltinPromptTemplate(name string) (*PromptTemplate, bool) {
+	tpl, ok := builtinPromptTemplates[name]
+	return tpl, ok
+}
+
+// DefaultPromptTemplate 默认模板（中文）
+func DefaultPromptTemplate() *PromptTemplate {
+	return builtinPromptTemplates[DefaultPromptTemplateName]
+}
+
+// Ref 模板引用（name@version），记录在决策日志中
+func (t *PromptTemplate) Ref() string {
+	return fmt.Sprintf("%s@%d", t.Name, t.Version)
+}
+
+// ParsePromptTemplateRef 解析模板引用，"name" 表示最新版本（version=0），"name@3" 表示指定版本
+func ParsePromptTemplateRef(ref string) (string, int, error) {
+	ref = strings.TrimSpace(ref)
+	name, versionStr, hasVersion := strings.Cut(ref, "@")
+	if name == "" {
+		return "", 0, fmt.Errorf("模板名称不能为空")
+	}
+	if !hasVersion {
+		return name, 0, nil
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version < 1 {
+		return "", 0, fmt.Errorf("无效的模板版本: %s", ref)
+	}
+	return name, version, nil
+}
+
+// promptTemplateFuncs 模板可用的函数
+var promptTemplateFuncs = template.FuncMap{
+	"add":   func(a, b int) int { return a + b },
+	"mul":   func(a, b float64) float64 { return a * b },
+	"upper": strings.ToUpper,
+	"join":  strings.Join,
+	"pct": func(part, total float64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return part / total * 100
+	},
+	"formatMarket": market.Format,
+}
+
+// parse 解析模板（引用不存在的字段时报错）
+func (t *PromptTemplate) parse() (*template.Template, *template.Template, error) {
+	system, err := template.New(t.Name + ".system").Funcs(promptTemplateFuncs).Option("missingkey=error").Parse(t.System)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析system模板失败: %w", err)
+	}
+	user, err := template.New(t.Name + ".user").Funcs(promptTemplateFuncs).Option("missingkey=error").Parse(t.User)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析user模板失败: %w", err)
+	}
+	return system, user, nil
+}
+
+// Render 使用模板变量渲染 system 和 user prompt
+func (t *PromptTemplate) Render(data *PromptData) (string, string, error) {
+	systemTpl, userTpl, err := t.parse()
+	if err != nil {
+		return "", "", err
+	}
+	var system, user bytes.Buffer
+	if err := systemTpl.Execute(&system, data); err != nil {
+		return "", "", fmt.Errorf("渲染system模板失败: %w", err)
+	}
+	if err := userTpl.Execute(&user, data); err != nil {
+		return "", "", fmt.Errorf("渲染user模板失败: %w", err)
+	}
+	return system.String(), user.String(), nil
+}
+
+// Validate 校验模板（名称、语法，并用示例数据试渲染，保存前调用）
+func (t *PromptTemplate) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("模板名称不能为空")
+	}
+	if strings.ContainsAny(t.Name, "@ ") {
+		return fmt.Errorf("模板名称不能包含 @ 或空格: %s", t.Name)
+	}
+	if t.Language == "" {
+		t.Language = "zh"
+	}
+	if strings.TrimSpace(t.System) == "" || strings.TrimSpace(t.User) == "" {
+		return fmt.Errorf("system模板和user模板都不能为空")
+	}
+	if _, _, err := t.Render(samplePromptData()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PromptData 模板变量
+type PromptData struct {
+	// system prompt 变量
+	AccountEquity     float64
+	Policy            RiskPolicy
+	ExampleTier       SymbolTier // 输出示例使用的档位（BTCUSDT所属档位）
+	MaxMarginUsagePct float64
+	MinRiskReward     float64
+	CustomPrompt      string // 个性化交易策略（覆盖模式下不会渲染模板）
+
+	// user prompt 变量
+	CurrentTime     string
+	CallCount       int
+	RuntimeMinutes  int
+	Account         AccountInfo
+	BTC             *market.Data // 为空表示没有BTC行情
+	CoolingDown     []CoolingSymbol
+	PortfolioRisk   string // 组合风险摘要（敞口、Beta、相关性）
+	Positions       []PromptPosition
+	Candidates      []PromptCandidate // 只包含有行情数据的候选币种
+	MarketDataCount int
+	HasSharpeRatio  bool
+	SharpeRatio     float64
+}
+
+// CoolingSymbol 平仓冷却中的币种
+type CoolingSymbol struct {
+	Symbol           string
+	RemainingMinutes int
+}
+
+// PromptPosition 持仓（附带持仓时长和行情）
+type PromptPosition struct {
+	PositionInfo
+	HasHoldingTime          bool
+	HoldingMinutes          int64
+	HoldingHours            int64
+	HoldingRemainderMinutes int64
+	Market                  *market.Data // 为空表示没有行情数据
+}
+
+// PromptCandidate 候选币种（附带来源标记和行情）
+type PromptCandidate struct {
+	Symbol     string
+	Sources    []string
+	DualSignal bool // AI500 + OI_Top 双重信号
+	OITopOnly  bool // 仅来自 OI_Top
+	Market     *market.Data
+}
+
+// NewPromptData 根据交易上下文生成模板变量（需先获取市场数据）
+func NewPromptData(ctx *Context, customPrompt string) *PromptData {
+	policy := ctx.riskPolicy()
+	exampleTier, ok := policy.TierFor("BTCUSDT")
+	if !ok {
+		exampleTier = policy.Tiers[0]
+	}
+	maxMarginUsagePct := ctx.MaxMarginUsagePct
+	if maxMarginUsagePct <= 0 {
+		maxMarginUsagePct = 90
+	}
+
+	data := &PromptData{
+		AccountEquity:     ctx.Account.TotalEquity,
+		Policy:            policy,
+		ExampleTier:       exampleTier,
+		MaxMarginUsagePct: maxMarginUsagePct,
+		MinRiskReward:     ctx.riskRewardRules().MinRiskReward,
+		CustomPrompt:      customPrompt,
+		CurrentTime:       ctx.CurrentTime,
+		CallCount:         ctx.CallCount,
+		RuntimeMinutes:    ctx.RuntimeMinutes,
+		Account:           ctx.Account,
+		BTC:               ctx.MarketDataMap["BTCUSDT"],
+		MarketDataCount:   len(ctx.MarketDataMap),
+	}
+
+	// 冷却中的币种
+	if policy.CooldownMinutes > 0 {
+		for symbol, closedAt := range ctx.RecentCloses {
+			if remaining := policy.Cooldown() - time.Since(closedAt); remaining > 0 {
+				data.CoolingDown = append(data.CoolingDown, CoolingSymbol{Symbol: symbol, RemainingMinutes: int(remaining.Minutes()) + 1})
+			}
+		}
+		sort.Slice(data.CoolingDown, func(i, j int) bool { return data.CoolingDown[i].Symbol < data.CoolingDown[j].Symbol })
+	}
+
+	if ctx.PortfolioRisk != nil {
+		data.PortfolioRisk = ctx.PortfolioRisk.Summary()
+	}
+
+	for _, pos := range ctx.Positions {
+		p := PromptPosition{PositionInfo: pos, Market: ctx.MarketDataMap[pos.Symbol]}
+		if pos.UpdateTime > 0 {
+			p.HasHoldingTime = true
+			p.HoldingMinutes = (time.Now().UnixMilli() - pos.UpdateTime) / (1000 * 60)
+			p.HoldingHours = p.HoldingMinutes / 60
+			p.HoldingRemainderMinutes = p.HoldingMinutes % 60
+		}
+		data.Positions = append(data.Positions, p)
+	}
+
+	for _, coin := range ctx.CandidateCoins {
+		marketData, ok := ctx.MarketDataMap[coin.Symbol]
+		if !ok {
+			continue
+		}
+		data.Candidates = append(data.Candidates, PromptCandidate{
+			Symbol:     coin.Symbol,
+			Sources:    coin.Sources,
+			DualSignal: len(coin.Sources) > 1,
+			OITopOnly:  len(coin.Sources) == 1 && coin.Sources[0] == "oi_top",
+			Market:     marketData,
+		})
+	}
+
+	// 夏普比率（直接从interface{}中提取SharpeRatio）
+	if ctx.Performance != nil {
+		var perfData struct {
+			SharpeRatio float64 `json:"sharpe_ratio"`
+		}
+		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
+			if err := json.Unmarshal(jsonData, &perfData); err == nil {
+				data.HasSharpeRatio = true
+				data.SharpeRatio = perfData.SharpeRatio
+			}
+		}
+	}
+
+	return data
+}
+
+// samplePromptData 校验模板使用的示例数据
+func samplePromptData() *PromptData {
+	btc := &market.Data{Symbol: "BTCUSDT", CurrentPrice: 95000}
+	ctx := &Context{
+		CurrentTime:     "2025-01-01 00:00:00",
+		RuntimeMinutes:  30,
+		CallCount:       10,
+		Account:         AccountInfo{TotalEquity: 1000, AvailableBalance: 800, MarginUsed: 200, MarginUsedPct: 20, PositionCount: 1},
+		Positions:       []PositionInfo{{Symbol: "BTCUSDT", Side: "long", EntryPrice: 94000, MarkPrice: 95000, Quantity: 0.01, Leverage: 5, MarginUsed: 190, UpdateTime: time.Now().Add(-90 * time.Minute).UnixMilli()}},
+		CandidateCoins:  []CandidateCoin{{Symbol: "BTCUSDT", Sources: []string{"ai500", "oi_top"}}},
+		MarketDataMap:   map[string]*market.Data{"BTCUSDT": btc},
+		RecentCloses:    map[string]time.Time{"ETHUSDT": time.Now()},
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		Performance:     map[string]float64{"sharpe_ratio": 0.5},
+	}
+	return NewPromptData(ctx, "示例策略")
+}
+
+// promptTemplate 当前生效的提示词模板（未配置时使用默认模板）
+func (ctx *Context) promptTemplate() *PromptTemplate {
+	if ctx.PromptTemplate != nil {
+		return ctx.PromptTemplate
+	}
+	return DefaultPromptTemplate()
+}
+
+// renderPrompts 使用当前模板渲染 system 和 user prompt（需先获取市场数据）
+// 覆盖基础prompt且有自定义prompt时，system prompt 只使用自定义prompt
+func renderPrompts(ctx *Context, customPrompt string, overrideBase bool) (string, string, error) {
+	tpl := ctx.promptTemplate()
+	systemPrompt, userPrompt, err := tpl.Render(NewPromptData(ctx, customPrompt))
+	if err != nil {
+		return "", "", fmt.Errorf("模板 %s: %w", tpl.Ref(), err)
+	}
+	if overrideBase && customPrompt != "" {
+		systemPrompt = customPrompt
+	}
+	return systemPrompt, userPrompt, nil
+}
+
+// BuildPrompts 获取市场数据并渲染提示词（不调用AI，用于预览模板效果）
+func BuildPrompts(runCtx context.Context, ctx *Context, customPrompt string, overrideBase bool) (string, string, error) {
+	if err := fetchMarketDataForContext(runCtx, ctx); err != nil {
+		return "", "", fmt.Errorf("获取市场数据失败: %w", err)
+	}
+	ctx.PortfolioRisk = NewPortfolioRisk(runCtx, ctx)
+	return renderPrompts(ctx, customPrompt, overrideBase)
+}
@@ -0,0 +1,189 @@
+package decision
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/market"
+)
+
+// promptTestContext 渲染模板用的交易上下文（full=false 时没有BTC行情、持仓和候选币种）
+func promptTestContext(full bool) *Context {
+	ctx := &Context{
+		CurrentTime:     "2025-01-01 08:00:00",
+		CallCount:       42,
+		RuntimeMinutes:  210,
+		Account:         AccountInfo{TotalEquity: 1000, AvailableBalance: 600, MarginUsed: 400, MarginUsedPct: 40, PositionCount: 2},
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 3,
+		RiskPolicy: &RiskPolicy{
+			Tiers: []SymbolTier{
+				{Name: "major", Symbols: []string{"BTCUSDT", "ETHUSDT"}, MaxLeverage: 5, MinPositionEquity: 2, MaxPositionEquity: 4},
+				{Name: "alt", MaxLeverage: 3, MinPositionEquity: 0.5, MaxPositionEquity: 1},
+			},
+			MaxPositions:    2,
+			MinConfidence:   80,
+			CooldownMinutes: 30,
+		},
+		MarketDataMap: map[string]*market.Data{},
+	}
+	if !full {
+		return ctx
+	}
+
+	btc := &market.Data{Symbol: "BTCUSDT", CurrentPrice: 95000, PriceChange1h: 0.5, PriceChange4h: -1.2, CurrentRSI7: 55,
+		LongerTermContext: &market.LongerTermData{ATR14: 800}}
+	sol := &market.Data{Symbol: "SOLUSDT", CurrentPrice: 180}
+	ctx.MarketDataMap = map[string]*market.Data{"BTCUSDT": btc, "SOLUSDT": sol}
+	ctx.Positions = []PositionInfo{
+		{Symbol: "BTCUSDT", Side: "long", EntryPrice: 94000, MarkPrice: 95000, Quantity: 0.02, Leverage: 5, UnrealizedPnLPct: 5.3, MarginUsed: 380,
+			UpdateTime: time.Now().Add(-95 * time.Minute).UnixMilli()},
+		{Symbol: "DOGEUSDT", Side: "short", EntryPrice: 0.4, MarkPrice: 0.39, Quantity: 100, Leverage: 3, MarginUsed: 13},
+	}
+	ctx.CandidateCoins = []CandidateCoin{
+		{Symbol: "BTCUSDT", Sources: []string{"ai500", "oi_top"}},
+		{Symbol: "SOLUSDT", Sources: []string{"oi_top"}},
+		{Symbol: "PEPEUSDT", Sources: []string{"ai500"}}, // 没有行情数据，不出现在候选列表中
+	}
+	ctx.RecentCloses = map[string]time.Time{"ETHUSDT": time.Now().Add(-10 * time.Minute), "XRPUSDT": time.Now().Add(-time.Hour)}
+	ctx.Performance = map[string]float64{"sharpe_ratio": 1.25}
+	ctx.MarketProvider = testFactors
+	ctx.PortfolioRisk = NewPortfolioRisk(context.Background(), ctx)
+	return ctx
+}
+
+func TestNewPromptData(t *testing.T) {
+	data := NewPromptData(promptTestContext(true), "只做趋势")
+
+	if data.ExampleTier.Name != "major" || data.Policy.MaxPositions != 2 || data.MaxMarginUsagePct != 90 || data.MinRiskReward != 3 {
+		t.Errorf("策略变量 = tier %s, policy %+v, margin %.0f, rr %.1f", data.ExampleTier.Name, data.Policy, data.MaxMarginUsagePct, data.MinRiskReward)
+	}
+	if data.BTC == nil || data.MarketDataCount != 2 || data.CustomPrompt != "只做趋势" {
+		t.Errorf("行情变量 = %+v", data)
+	}
+	if len(data.CoolingDown) != 1 || data.CoolingDown[0].Symbol != "ETHUSDT" || data.CoolingDown[0].RemainingMinutes != 20 {
+		t.Errorf("冷却中的币种 = %+v", data.CoolingDown)
+	}
+	if len(data.Positions) != 2 || !data.Positions[0].HasHoldingTime || data.Positions[0].HoldingHours != 1 || data.Positions[0].HoldingRemainderMinutes != 35 ||
+		data.Positions[0].Market == nil || data.Positions[1].HasHoldingTime || data.Positions[1].Market != nil {
+		t.Errorf("持仓变量 = %+v", data.Positions)
+	}
+	if len(data.Candidates) != 2 || !data.Candidates[0].DualSignal || !data.Candidates[1].OITopOnly {
+		t.Errorf("候选币种 = %+v", data.Candidates)
+	}
+	if !data.HasSharpeRatio || data.SharpeRatio != 1.25 || data.PortfolioRisk == "" {
+		t.Errorf("绩效/组合风险变量: sharpe=%v %.2f risk=%q", data.HasSharpeRatio, data.SharpeRatio, data.PortfolioRisk)
+	}
+}
+
+func TestBuiltinPromptTemplatesRender(t *testing.T) {
+	want := map[string][]string{
+		"zh": {"**最多持仓**: 2个币种", "综合信心度 ≥ 80 才开仓", "**时间**: 2025-01-01 08:00:00 | **周期**: #42", "ETHUSDT(剩余20分钟)",
+			"1. BTCUSDT LONG", "持仓时长1小时35分钟", "2. DOGEUSDT SHORT", "(AI500+OI_Top双重信号)", "(OI_Top持仓增长)", "夏普比率: 1.25", "只做趋势"},
+		"en": {"**Max positions**: 2 symbols", "confidence ≥ 80", "**Time**: 2025-01-01 08:00:00 | **Cycle**: #42", "ETHUSDT(20 min left)",
+			"1. BTCUSDT LONG", "held 1h35m", "2. DOGEUSDT SHORT", "(AI500 + OI_Top dual signal)", "(OI_Top open interest growth)", "Sharpe Ratio: 1.25", "只做趋势"},
+	}
+	wantEmpty := map[string]string{"zh": "**当前持仓**: 无", "en": "**Current positions**: none"}
+
+	templates := BuiltinPromptTemplates()
+	if len(templates) != len(want) {
+		t.Fatalf("内置模板数量 = %d, want %d", len(templates), len(want))
+	}
+	for _, tpl := range templates {
+		t.Run(tpl.Name, func(t *testing.T) {
+			validated := *tpl
+			if err := validated.Validate(); err != nil {
+				t.Fatalf("内置模板校验失败: %v", err)
+			}
+
+			system, user, err := tpl.Render(NewPromptData(promptTestContext(true), "只做趋势"))
+			if err != nil {
+				t.Fatalf("渲染失败: %v", err)
+			}
+			out := system + user
+			for _, s := range want[tpl.Language] {
+				if !strings.Contains(out, s) {
+					t.Errorf("渲染结果缺少 %q", s)
+				}
+			}
+			if !strings.Contains(user, "## 📐 组合风险") {
+				t.Error("user prompt 缺少组合风险摘要")
+			}
+			for _, bad := range []string{"<no value>", "%!", "PEPEUSDT", "XRPUSDT"} {
+				if strings.Contains(out, bad) {
+					t.Errorf("渲染结果不应包含 %q", bad)
+				}
+			}
+
+			// 没有BTC行情、持仓和候选币种时也能渲染
+			system, user, err = tpl.Render(NewPromptData(promptTestContext(false), ""))
+			if err != nil {
+				t.Fatalf("空数据渲染失败: %v", err)
+			}
+			if !strings.Contains(user, wantEmpty[tpl.Language]) || strings.Contains(user, "**BTC**") || strings.Contains(system+user, "<no value>") {
+				t.Errorf("空数据渲染结果不正确:\n%s", user)
+			}
+		})
+	}
+}
+
+func TestPromptTemplateValidateRejects(t *testing.T) {
+	base := DefaultPromptTemplate()
+	cases := []struct {
+		name    string
+		tpl     PromptTemplate
+		wantErr string
+	}{
+		{"名称为空", PromptTemplate{Name: " ", System: base.System, User: base.User}, "模板名称不能为空"},
+		{"名称包含@", PromptTemplate{Name: "mine@2", System: base.System, User: base.User}, "不能包含 @ 或空格"},
+		{"模板为空", PromptTemplate{Name: "mine", System: base.System, User: "  "}, "都不能为空"},
+		{"语法错误", PromptTemplate{Name: "mine", System: "{{if .CallCount}}未结束", User: base.User}, "解析system模板失败"},
+		{"未知函数", PromptTemplate{Name: "mine", System: base.System, User: "{{shout .CurrentTime}}"}, "解析user模板失败"},
+		{"未知顶层字段", PromptTemplate{Name: "mine", System: base.System + "{{.MaxLeverage}}", User: base.User}, "渲染system模板失败"},
+		{"未知嵌套字段", PromptTemplate{Name: "mine", System: base.System, User: "{{.Account.Balance}}"}, "渲染user模板失败"},
+		{"range中的未知字段", PromptTemplate{Name: "mine", System: base.System, User: "{{range .Positions}}{{.Size}}{{end}}"}, "渲染user模板失败"},
+		{"with中的未知字段", PromptTemplate{Name: "mine", System: base.System, User: "{{with .BTC}}{{.Price}}{{end}}"}, "渲染user模板失败"},
+		{"策略中的未知字段", PromptTemplate{Name: "mine", System: "{{.Policy.MaxLeverage}}", User: base.User}, "渲染system模板失败"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.tpl.Validate()
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("错误 = %v, want 包含 %q", err, c.wantErr)
+			}
+		})
+	}
+
+	// 合法的自定义模板通过校验并默认为中文
+	custom := PromptTemplate{Name: " mine ", System: "净值{{.AccountEquity}}", User: "{{range .Candidates}}{{.Symbol}}{{end}}"}
+	if err := custom.Validate(); err != nil {
+		t.Fatalf("自定义模板校验失败: %v", err)
+	}
+	if custom.Name != "mine" || custom.Language != "zh" {
+		t.Errorf("校验后 = name %q language %q", custom.Name, custom.Language)
+	}
+}
+
+func TestParsePromptTemplateRef(t *testing.T) {
+	cases := []struct {
+		ref         string
+		wantName    string
+		wantVersion int
+		wantErr     bool
+	}{
+		{"default", "default", 0, false},
+		{" mine@3 ", "mine", 3, false},
+		{"mine@0", "", 0, true},
+		{"mine@v2", "", 0, true},
+		{"@2", "", 0, true},
+		{"", "", 0, true},
+	}
+	for _, c := range cases {
+		name, version, err := ParsePromptTemplateRef(c.ref)
+		if (err != nil) != c.wantErr || name != c.wantName || version != c.wantVersion {
+			t.Errorf("ParsePromptTemplateRef(%q) = %q, %d, %v", c.ref, name, version, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SymbolTier 币种档位（杠杆和单币仓位上限按档位配置）
+type SymbolTier struct {
+	Name              string   `json:"name"`                // 档位名称，如 major, altcoin
+	Symbols           []string `json:"symbols,omitempty"`   // 属于该档位的币种，为空表示默认档位（匹配其余所有币种）
+	MaxLeverage       int      `json:"max_leverage"`        // 最大杠杆倍数
+	MinPositionEquity float64  `json:"min_position_equity"` // 建议最小仓位价值（账户净值倍数，仅用于提示词）
+	MaxPositionEquity float64  `json:"max_position_equity"` // 单币仓位价值上限（账户净值倍数）
+}
+
+// RiskPolicy 交易员风控策略（同一份配置用于代码校验和系统提示词）
+type RiskPolicy struct {
+	Tiers              []SymbolTier `json:"tiers"`
+	MaxPositions       int          `json:"max_positions"`         // 最多同时持仓币种数（同一币种多空双向持仓算一个）
+	MinConfidence      int          `json:"min_confidence"`        // 开仓最低信心度
+	MinOpenInterestUSD float64      `json:"min_open_interest_usd"` // 候选币种最低持仓价值（USD），现有持仓不受限制
+	CooldownMinutes    int          `json:"cooldown_minutes"`      // 平仓后同币种再次开仓的冷却时间
+}
+
+// DefaultRiskPolicy 默认风控策略（BTC/ETH 和山寨币两档，杠杆来自系统配置）
+func DefaultRiskPolicy(btcEthLeverage, altcoinLeverage int) RiskPolicy {
+	return RiskPolicy{
+		Tiers: []SymbolTier{
+			{Name: "BTC/ETH", Symbols: []string{"BTCUSDT", "ETHUSDT"}, MaxLeverage: btcEthLeverage, MinPositionEquity: 5, MaxPositionEquity: 10},
+			{Name: "山寨", MaxLeverage: altcoinLeverage, MinPositionEquity: 0.8, MaxPositionEquity: 1.5},
+		},
+		MaxPositions:       3,
+		MinConfidence:      75,
+		MinOpenInterestUSD: 15_000_000,
+		CooldownMinutes:    15,
+	}
+}
+
+// Validate 校验风控策略
+func (p *RiskPolicy) Validate() error {
+	if len(p.Tiers) == 0 {
+		return fmt.Errorf("至少需要一个币种档位")
+	}
+	names := make(map[string]bool)
+	seen := make(map[string]string)
+	defaultTiers := 0
+	for i := range p.Tiers {
+		tier := &p.Tiers[i]
+		if tier.Name == "" {
+			return fmt.Errorf("第%d个档位缺少名称", i+1)
+		}
+		if names[tier.Name] {
+			return fmt.Errorf("档位名称重复: %s", tier.Name)
+		}
+		names[tier.Name] = true
+		if tier.MaxLeverage < 1 || tier.MaxLeverage > 125 {
+			return fmt.Errorf("档位 %s 的杠杆必须在1-125之间: %d", tier.Name, tier.MaxLeverage)
+		}
+		if tier.MaxPositionEquity <= 0 {
+			return fmt.Errorf("档位 %s 的仓位上限必须大于0", tier.Name)
+		}
+		if tier.MinPositionEquity < 0 || tier.MinPositionEquity > tier.MaxPositionEquity {
+			return fmt.Errorf("档位 %s 的建议最小仓位必须在0到仓位上限之间", tier.Name)
+		}
+		if len(tier.Symbols) == 0 {
+			defaultTiers++
+		}
+		for j, symbol := range tier.Symbols {
+			symbol = strings.ToUpper(strings.TrimSpace(symbol))
+			if other, ok := seen[symbol]; ok {
+				return fmt.Errorf("%s 同时属于档位 %s 和 %s", symbol, other, tier.Name)
+			}
+			seen[symbol] = tier.Name
+			tier.Symbols[j] = symbol
+		}
+	}
+	if defaultTiers > 1 {
+		return fmt.Errorf("最多只能有一个默认档位（symbols 为空）")
+	}
+	if p.MaxPositions < 1 {
+		return fmt.Errorf("最多持仓数必须大于0: %d", p.MaxPositions)
+	}
+	if p.MinConfidence < 0 || p.MinConfidence > 100 {
+		return fmt.Errorf("最低信心度必须在0-100之间: %d", p.MinConfidence)
+	}
+	if p.MinOpenInterestUSD < 0 || p.CooldownMinutes < 0 {
+		return fmt.Errorf("最低持仓价值和冷却时间不能为负数")
+	}
+	return nil
+}
+
+// TierFor 查找币种所属档位（未列出的币种使用默认档位，没有默认档位时返回false）
+func (p *RiskPolicy) TierFor(symbol string) (SymbolTier, bool) {
+	var fallback *SymbolTier
+	for i := range p.Tiers {
+		tier := &p.Tiers[i]
+		if len(tier.Symbols) == 0 {
+			fallback = tier
+			continue
+		}
+		for _, s := range tier.Symbols {
+			if s == symbol {
+				return *tier, true
+			}
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return SymbolTier{}, false
+}
+
+// Cooldown 平仓冷却时间
+func (p *RiskPolicy) Cooldown() time.Duration {
+	return time.Duration(p.CooldownMinutes) * time.Minute
+}
+
+// CheckOpen 执行前检查开仓决策（信心度、持仓数量、冷却时间）
+// held 为执行本决策前各币种的持仓方向数，lastClose 为该币种最近一次平仓时间（零值表示没有）
+// 已持有的币种再开反向仓不占用新的持仓名额
+func (p *RiskPolicy) CheckOpen(d *Decision, held map[string]int, lastClose time.Time) error {
+	if d.Confidence < p.MinConfidence {
+		return fmt.Errorf("信心度%d低于开仓要求%d", d.Confidence, p.MinConfidence)
+	}
+	if symbols := heldSymbolCount(held); held[d.Symbol] <= 0 && symbols >= p.MaxPositions {
+		return fmt.Errorf("已持有%d个币种，达到最多持仓数%d", symbols, p.MaxPositions)
+	}
+	if !lastClose.IsZero() && p.CooldownMinutes > 0 {
+		if remaining := p.Cooldown() - time.Since(lastClose); remaining > 0 {
+			return fmt.Errorf("%s 平仓后冷却中，还需%d分钟", d.Symbol, int(remaining.Minutes())+1)
+		}
+	}
+	return nil
+}
+
+// heldSymbolCount 持仓币种数
+func heldSymbolCount(held map[string]int) int {
+	count := 0
+	for _, sides := range held {
+		if sides > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// CheckOpen 按当前生效的风控策略检查开仓决策
+func (ctx *Context) CheckOpen(d *Decision, held map[string]int) error {
+	policy := ctx.riskPolicy()
+	return policy.CheckOpen(d, held, ctx.RecentCloses[d.Symbol])
+}
+
+// riskPolicy 当前生效的风控策略（未配置时按系统杠杆配置生成默认策略）
+func (ctx *Context) riskPolicy() RiskPolicy {
+	if ctx.RiskPolicy != nil {
+		return *ctx.RiskPolicy
+	}
+	return DefaultRiskPolicy(ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+}
@@ -0,0 +1,158 @@
+package decision
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRiskPolicyValidate(t *testing.T) {
+	valid := func() RiskPolicy { return DefaultRiskPolicy(5, 3) }
+	cases := []struct {
+		name    string
+		update  func(p *RiskPolicy)
+		wantErr string // 为空表示通过
+	}{
+		{"默认策略", func(p *RiskPolicy) {}, ""},
+		{"没有档位", func(p *RiskPolicy) { p.Tiers = nil }, "至少需要一个币种档位"},
+		{"档位缺少名称", func(p *RiskPolicy) { p.Tiers[0].Name = "" }, "第1个档位缺少名称"},
+		{"档位名称重复", func(p *RiskPolicy) { p.Tiers[1].Name = p.Tiers[0].Name }, "档位名称重复"},
+		{"杠杆过低", func(p *RiskPolicy) { p.Tiers[0].MaxLeverage = 0 }, "杠杆必须在1-125之间"},
+		{"杠杆过高", func(p *RiskPolicy) { p.Tiers[1].MaxLeverage = 126 }, "杠杆必须在1-125之间"},
+		{"仓位上限为0", func(p *RiskPolicy) { p.Tiers[0].MaxPositionEquity = 0 }, "仓位上限必须大于0"},
+		{"建议最小仓位超过上限", func(p *RiskPolicy) { p.Tiers[1].MinPositionEquity = 2 }, "建议最小仓位"},
+		{"币种属于多个档位", func(p *RiskPolicy) {
+			p.Tiers = append(p.Tiers, SymbolTier{Name: "meme", Symbols: []string{" ethusdt"}, MaxLeverage: 2, MaxPositionEquity: 1})
+		}, "ETHUSDT 同时属于档位 BTC/ETH 和 meme"},
+		{"多个默认档位", func(p *RiskPolicy) {
+			p.Tiers = append(p.Tiers, SymbolTier{Name: "other", MaxLeverage: 2, MaxPositionEquity: 1})
+		}, "最多只能有一个默认档位"},
+		{"最多持仓数为0", func(p *RiskPolicy) { p.MaxPositions = 0 }, "最多持仓数必须大于0"},
+		{"信心度超出范围", func(p *RiskPolicy) { p.MinConfidence = 101 }, "最低信心度必须在0-100之间"},
+		{"冷却时间为负", func(p *RiskPolicy) { p.CooldownMinutes = -1 }, "不能为负数"},
+		{"最低持仓价值为负", func(p *RiskPolicy) { p.MinOpenInterestUSD = -1 }, "不能为负数"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := valid()
+			c.update(&p)
+			err := p.Validate()
+			if c.wantErr == "" {
+				if err != nil {
+					t.Errorf("应通过校验: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("错误 = %v, want 包含 %q", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestRiskPolicyTierFor(t *testing.T) {
+	p := RiskPolicy{
+		Tiers: []SymbolTier{
+			{Name: "alt", MaxLeverage: 3, MaxPositionEquity: 1.5}, // 默认档位可以排在前面
+			{Name: "major", Symbols: []string{"btcusdt", " ETHUSDT "}, MaxLeverage: 10, MaxPositionEquity: 10},
+			{Name: "meme", Symbols: []string{"DOGEUSDT"}, MaxLeverage: 2, MaxPositionEquity: 0.5},
+		},
+		MaxPositions: 3,
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate失败: %v", err)
+	}
+
+	cases := []struct {
+		symbol string
+		want   string
+	}{
+		{"BTCUSDT", "major"}, // Validate 统一为大写并去除空格
+		{"ETHUSDT", "major"},
+		{"DOGEUSDT", "meme"},
+		{"SOLUSDT", "alt"},
+	}
+	for _, c := range cases {
+		tier, ok := p.TierFor(c.symbol)
+		if !ok || tier.Name != c.want {
+			t.Errorf("TierFor(%s) = %s, %v, want %s", c.symbol, tier.Name, ok, c.want)
+		}
+	}
+
+	// 没有默认档位时，未列出的币种不允许交易
+	p.Tiers = p.Tiers[1:]
+	if tier, ok := p.TierFor("SOLUSDT"); ok {
+		t.Errorf("没有默认档位时 TierFor(SOLUSDT) = %s, want false", tier.Name)
+	}
+}
+
+func TestRiskPolicyCheckOpen(t *testing.T) {
+	p := RiskPolicy{MaxPositions: 2, MinConfidence: 70, CooldownMinutes: 15}
+	now := time.Now()
+
+	cases := []struct {
+		name      string
+		decision  Decision
+		held      map[string]int
+		lastClose time.Time
+		wantErr   string // 为空表示通过
+	}{
+		{"通过", Decision{Symbol: "SOLUSDT", Confidence: 70}, map[string]int{"BTCUSDT": 1}, time.Time{}, ""},
+		{"信心度不足", Decision{Symbol: "SOLUSDT", Confidence: 69}, nil, time.Time{}, "信心度69低于开仓要求70"},
+		{"达到最多持仓币种数", Decision{Symbol: "SOLUSDT", Confidence: 80}, map[string]int{"BTCUSDT": 1, "ETHUSDT": 1}, time.Time{}, "已持有2个币种，达到最多持仓数2"},
+		{"双向持仓只算一个币种", Decision{Symbol: "SOLUSDT", Confidence: 80}, map[string]int{"BTCUSDT": 2}, time.Time{}, ""},
+		{"已持有的币种开反向仓不占新名额", Decision{Symbol: "ETHUSDT", Confidence: 80}, map[string]int{"BTCUSDT": 1, "ETHUSDT": 1}, time.Time{}, ""},
+		{"已平仓的币种不计入", Decision{Symbol: "SOLUSDT", Confidence: 80}, map[string]int{"BTCUSDT": 1, "ETHUSDT": 0}, time.Time{}, ""},
+		{"冷却中", Decision{Symbol: "SOLUSDT", Confidence: 80}, nil, now.Add(-5 * time.Minute), "SOLUSDT 平仓后冷却中，还需10分钟"},
+		{"冷却结束", Decision{Symbol: "SOLUSDT", Confidence: 80}, nil, now.Add(-16 * time.Minute), ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := p.CheckOpen(&c.decision, c.held, c.lastClose)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Errorf("应通过检查: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("错误 = %v, want 包含 %q", err, c.wantErr)
+			}
+		})
+	}
+
+	// 冷却时间为0时不检查最近平仓
+	noCooldown := p
+	noCooldown.CooldownMinutes = 0
+	if err := noCooldown.CheckOpen(&Decision{Symbol: "SOLUSDT", Confidence: 80}, nil, now); err != nil {
+		t.Errorf("未配置冷却时间时应通过: %v", err)
+	}
+}
+
+func TestContextCheckOpen(t *testing.T) {
+	// 未配置风控策略时使用默认策略（最多3个币种、信心度75、冷却15分钟）
+	ctx := &Context{
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 3,
+		RecentCloses:    map[string]time.Time{"SOLUSDT": time.Now().Add(-time.Minute)},
+	}
+	held := map[string]int{"BTCUSDT": 1, "ETHUSDT": 2}
+	if err := ctx.CheckOpen(&Decision{Symbol: "DOGEUSDT", Confidence: 75}, held); err != nil {
+		t.Errorf("默认策略应通过: %v", err)
+	}
+	if err := ctx.CheckOpen(&Decision{Symbol: "DOGEUSDT", Confidence: 74}, held); err == nil {
+		t.Error("默认策略信心度不足应拒绝")
+	}
+	if err := ctx.CheckOpen(&Decision{Symbol: "SOLUSDT", Confidence: 80}, held); err == nil || !strings.Contains(err.Error(), "冷却中") {
+		t.Errorf("最近平仓的币种应在冷却中, got %v", err)
+	}
+
+	// 配置的风控策略优先
+	ctx.RiskPolicy = &RiskPolicy{MaxPositions: 2, MinConfidence: 50}
+	if err := ctx.CheckOpen(&Decision{Symbol: "DOGEUSDT", Confidence: 60}, held); err == nil || !strings.Contains(err.Error(), "达到最多持仓数2") {
+		t.Errorf("应按配置的最多持仓数拒绝, got %v", err)
+	}
+	if err := ctx.CheckOpen(&Decision{Symbol: "SOLUSDT", Confidence: 60}, nil); err != nil {
+		t.Errorf("未配置冷却时间时应通过: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/market"
+)
+
+// RiskRewardRules 开仓止损止盈校验阈值（按实时价格和ATR计算）
+type RiskRewardRules struct {
+	MinRiskReward           float64 `json:"min_risk_reward"`            // 最低风险回报比，默认3.0
+	MinStopATR              float64 `json:"min_stop_atr"`               // 止损距离下限（ATR倍数），防止被噪音扫损，默认0.5，0表示不限制
+	MaxStopATR              float64 `json:"max_stop_atr"`               // 止损距离上限（ATR倍数），默认6，0表示不限制
+	MinLiquidationBufferPct float64 `json:"min_liquidation_buffer_pct"` // 止损价与预估强平价的最小距离（占入场价%），默认1
+}
+
+// maintenanceMarginRate 估算强平价使用的维持保证金率
+const maintenanceMarginRate = 0.005
+
+// DefaultRiskRewardRules 默认校验阈值
+func DefaultRiskRewardRules() RiskRewardRules {
+	return RiskRewardRules{
+		MinRiskReward:           3.0,
+		MinStopATR:              0.5,
+		MaxStopATR:              6,
+		MinLiquidationBufferPct: 1,
+	}
+}
+
+// UnmarshalJSON 未出现的字段使用 DefaultRiskRewardRules 中的值（显式设置为0的止损ATR上下限表示不限制）
+func (r *RiskRewardRules) UnmarshalJSON(data []byte) error {
+	type plain RiskRewardRules
+	rules := plain(DefaultRiskRewardRules())
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	*r = RiskRewardRules(rules)
+	return nil
+}
+
+// Validate 校验并补全默认值（风险回报比和强平缓冲为0时使用默认值，止损ATR上下限为0表示不限制）
+func (r *RiskRewardRules) Validate() error {
+	defaults := DefaultRiskRewardRules()
+	if r.MinRiskReward == 0 {
+		r.MinRiskReward = defaults.MinRiskReward
+	}
+	if r.MinLiquidationBufferPct == 0 {
+		r.MinLiquidationBufferPct = defaults.MinLiquidationBufferPct
+	}
+	if r.MinRiskReward < 0 || r.MinStopATR < 0 || r.MaxStopATR < 0 || r.MinLiquidationBufferPct < 0 {
+		return fmt.Errorf("校验阈值不能为负数")
+	}
+	if r.MaxStopATR > 0 && r.MinStopATR > r.MaxStopATR {
+		return fmt.Errorf("止损距离下限(%.2f ATR)不能大于上限(%.2f ATR)", r.MinStopATR, r.MaxStopATR)
+	}
+	if r.MinLiquidationBufferPct >= 100 {
+		return fmt.Errorf("强平缓冲必须小于100%%: %.2f", r.MinLiquidationBufferPct)
+	}
+	return nil
+}
+
+// estimateLiquidationPrice 按杠杆估算逐仓强平价（未计入手续费和资金费）
+func estimateLiquidationPrice(isLong bool, entryPrice float64, leverage int) float64 {
+	if isLong {
+		return entryPrice * (1 - 1/float64(leverage) + maintenanceMarginRate)
+	}
+	return entryPrice * (1 + 1/float64(leverage) - maintenanceMarginRate)
+}
+
+// validateRiskReward 按实时价格校验止损止盈位置、风险回报比、ATR止损距离和强平缓冲
+func validateRiskReward(d *Decision, data *market.Data, rules RiskRewardRules) error {
+	if data == nil || data.CurrentPrice <= 0 {
+		return fmt.Errorf("缺少%s的实时价格，无法校验止损止盈", d.Symbol)
+	}
+	price := data.CurrentPrice
+	isLong := d.Action == "open_long"
+
+	// 1. 止损止盈必须位于当前价格两侧
+	var risk, reward float64
+	if isLong {
+		if d.StopLoss >= price || d.TakeProfit <= price {
+			return fmt.Errorf("做多时必须 止损(%.4f) < 当前价(%.4f) < 止盈(%.4f)", d.StopLoss, price, d.TakeProfit)
+		}
+		risk = price - d.StopLoss
+		reward = d.TakeProfit - price
+	} else {
+		if d.StopLoss <= price || d.TakeProfit >= price {
+			return fmt.Errorf("做空时必须 止盈(%.4f) < 当前价(%.4f) < 止损(%.4f)", d.TakeProfit, price, d.StopLoss)
+		}
+		risk = d.StopLoss - price
+		reward = price - d.TakeProfit
+	}
+	riskPercent := risk / price * 100
+	rewardPercent := reward / price * 100
+
+	// 2. 风险回报比
+	riskRewardRatio := reward / risk
+	if riskRewardRatio < rules.MinRiskReward {
+		return fmt.Errorf("风险回报比过低(%.2f:1)，必须≥%.1f:1 [当前价:%.4f 风险:%.2f%% 收益:%.2f%%] [止损:%.4f 止盈:%.4f]",
+			riskRewardRatio, rules.MinRiskReward, price, riskPercent, rewardPercent, d.StopLoss, d.TakeProfit)
+	}
+
+	// 3. 止损距离（ATR倍数）
+	if atr, interval := data.ATR(); atr > 0 {
+		stopATR := risk / atr
+		if rules.MinStopATR > 0 && stopATR < rules.MinStopATR {
+			return fmt.Errorf("止损距离过近(%.2f倍%s ATR)，必须≥%.2f倍 [当前价:%.4f 止损:%.4f ATR:%.4f]",
+				stopATR, interval, rules.MinStopATR, price, d.StopLoss, atr)
+		}
+		if rules.MaxStopATR > 0 && stopATR > rules.MaxStopATR {
+			return fmt.Errorf("止损距离过远(%.2f倍%s ATR)，必须≤%.2f倍 [当前价:%.4f 止损:%.4f ATR:%.4f]",
+				stopATR, interval, rules.MaxStopATR, price, d.StopLoss, atr)
+		}
+	}
+
+	// 4. 止损必须先于强平触发，并保留缓冲
+	liqPrice := estimateLiquidationPrice(isLong, price, d.Leverage)
+	bufferPct := (d.StopLoss - liqPrice) / price * 100
+	if !isLong {
+		bufferPct = (liqPrice - d.StopLoss) / price * 100
+	}
+	if bufferPct <= 0 {
+		return fmt.Errorf("止损价(%.4f)在预估强平价(%.4f)之外，%dx杠杆下止损无法生效", d.StopLoss, liqPrice, d.Leverage)
+	}
+	if bufferPct < rules.MinLiquidationBufferPct {
+		return fmt.Errorf("止损价(%.4f)距预估强平价(%.4f)仅%.2f%%，必须≥%.2f%%，请降低杠杆或收紧止损",
+			d.StopLoss, liqPrice, bufferPct, rules.MinLiquidationBufferPct)
+	}
+
+	return nil
+}
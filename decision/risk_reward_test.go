@@ -0,0 +1,145 @@
+package decision
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"nofx/market"
+)
+
+// testMarketData 当前价100、4小时ATR为2的行情
+func testMarketData() *market.Data {
+	return &market.Data{
+		Symbol:            "BTCUSDT",
+		CurrentPrice:      100,
+		LongerTermContext: &market.LongerTermData{ATR14: 2},
+	}
+}
+
+func TestValidateRiskReward(t *testing.T) {
+	noATRBounds := DefaultRiskRewardRules()
+	noATRBounds.MinStopATR = 0
+	noATRBounds.MaxStopATR = 0
+
+	cases := []struct {
+		name    string
+		d       Decision
+		data    *market.Data
+		rules   RiskRewardRules
+		wantErr string // 为空表示通过
+	}{
+		{"做多通过", Decision{Action: "open_long", StopLoss: 98, TakeProfit: 106, Leverage: 5}, testMarketData(), DefaultRiskRewardRules(), ""},
+		{"做空通过", Decision{Action: "open_short", StopLoss: 102, TakeProfit: 94, Leverage: 5}, testMarketData(), DefaultRiskRewardRules(), ""},
+		{"缺少实时价格", Decision{Action: "open_long", StopLoss: 98, TakeProfit: 106, Leverage: 5}, nil, DefaultRiskRewardRules(), "缺少"},
+		{"做多止损在当前价之上", Decision{Action: "open_long", StopLoss: 101, TakeProfit: 106, Leverage: 5}, testMarketData(), DefaultRiskRewardRules(), "做多时必须"},
+		{"做空止盈在当前价之上", Decision{Action: "open_short", StopLoss: 102, TakeProfit: 101, Leverage: 5}, testMarketData(), DefaultRiskRewardRules(), "做空时必须"},
+		{"风险回报比低于下限", Decision{Action: "open_long", StopLoss: 98, TakeProfit: 105, Leverage: 5}, testMarketData(), DefaultRiskRewardRules(), "风险回报比过低(2.50:1)"},
+		{"止损距离过近", Decision{Action: "open_long", StopLoss: 99.5, TakeProfit: 102, Leverage: 5}, testMarketData(), DefaultRiskRewardRules(), "止损距离过近(0.25倍4h ATR)"},
+		{"止损距离过远", Decision{Action: "open_long", StopLoss: 86, TakeProfit: 142, Leverage: 5}, testMarketData(), DefaultRiskRewardRules(), "止损距离过远(7.00倍4h ATR)"},
+		{"关闭ATR下限", Decision{Action: "open_long", StopLoss: 99.5, TakeProfit: 102, Leverage: 5}, testMarketData(), noATRBounds, ""},
+		{"关闭ATR上限", Decision{Action: "open_long", StopLoss: 86, TakeProfit: 142, Leverage: 5}, testMarketData(), noATRBounds, ""},
+		{"没有ATR时不校验止损距离", Decision{Action: "open_long", StopLoss: 99.5, TakeProfit: 102, Leverage: 5}, &market.Data{CurrentPrice: 100}, DefaultRiskRewardRules(), ""},
+		// 20倍杠杆预估强平价 = 100 × (1 - 1/20 + 0.005) = 95.5
+		{"止损在强平价之外", Decision{Action: "open_long", StopLoss: 94, TakeProfit: 118, Leverage: 20}, testMarketData(), DefaultRiskRewardRules(), "在预估强平价(95.5000)之外"},
+		{"强平缓冲不足", Decision{Action: "open_long", StopLoss: 96, TakeProfit: 112, Leverage: 20}, testMarketData(), DefaultRiskRewardRules(), "仅0.50%"},
+		{"做空强平缓冲不足", Decision{Action: "open_short", StopLoss: 104, TakeProfit: 88, Leverage: 20}, testMarketData(), DefaultRiskRewardRules(), "仅0.50%"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.d.Symbol = "BTCUSDT"
+			err := validateRiskReward(&c.d, c.data, c.rules)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Errorf("应通过校验: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("错误 = %v, want 包含 %q", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestRiskRewardRulesJSON(t *testing.T) {
+	// 未出现的字段使用默认值
+	var partial RiskRewardRules
+	if err := json.Unmarshal([]byte(`{"min_risk_reward":2}`), &partial); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := DefaultRiskRewardRules()
+	want.MinRiskReward = 2
+	if partial != want {
+		t.Errorf("部分配置 = %+v, want %+v", partial, want)
+	}
+
+	// 显式设置为0的止损ATR上下限表示不限制，校验后保持为0
+	var disabled RiskRewardRules
+	if err := json.Unmarshal([]byte(`{"min_stop_atr":0,"max_stop_atr":0}`), &disabled); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if err := disabled.Validate(); err != nil {
+		t.Fatalf("Validate失败: %v", err)
+	}
+	if disabled.MinStopATR != 0 || disabled.MaxStopATR != 0 || disabled.MinRiskReward != 3 {
+		t.Errorf("关闭ATR限制后 = %+v", disabled)
+	}
+
+	for _, invalid := range []RiskRewardRules{
+		{MinStopATR: -1},
+		{MinStopATR: 3, MaxStopATR: 2},
+		{MinLiquidationBufferPct: 100},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%+v 应校验失败", invalid)
+		}
+	}
+}
+
+func TestValidateDecisions(t *testing.T) {
+	ctx := &Context{
+		Account:         AccountInfo{TotalEquity: 1000},
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 3,
+		MarketDataMap:   map[string]*market.Data{"BTCUSDT": testMarketData()},
+	}
+	decisions := []Decision{
+		{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1000, StopLoss: 98, TakeProfit: 106},
+		{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1000, StopLoss: 98, TakeProfit: 105},  // 风险回报比不足
+		{Symbol: "BTCUSDT", Action: "open_long", Leverage: 10, PositionSizeUSD: 1000, StopLoss: 98, TakeProfit: 106}, // 超过档位杠杆
+		{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 20000, StopLoss: 98, TakeProfit: 106}, // 超过档位仓位上限
+		{Symbol: "ETHUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 1000, StopLoss: 102, TakeProfit: 94}, // 缺少行情
+		{Symbol: "BTCUSDT", Action: "hold"},
+		{Symbol: "BTCUSDT", Action: "buy"},
+	}
+
+	valid, rejected := validateDecisions(decisions, ctx)
+	if len(valid) != 2 || valid[0].Action != "open_long" || valid[1].Action != "hold" {
+		t.Errorf("通过的决策 = %+v", valid)
+	}
+	wantReasons := []string{
+		"决策 #2 验证失败: 风险回报比过低",
+		"决策 #3 验证失败: 杠杆必须在1-5之间",
+		"决策 #4 验证失败: BTC/ETH档位单币种仓位价值不能超过10000 USDT",
+		"决策 #5 验证失败: 缺少ETHUSDT的实时价格",
+		"决策 #7 验证失败: 无效的action",
+	}
+	if len(rejected) != len(wantReasons) {
+		t.Fatalf("未通过的决策 = %+v", rejected)
+	}
+	for i, want := range wantReasons {
+		if !strings.HasPrefix(rejected[i].Reason, want) {
+			t.Errorf("未通过原因 #%d = %q, want 前缀 %q", i, rejected[i].Reason, want)
+		}
+	}
+
+	// 配置中关闭ATR下限后，过近的止损不再被拒绝
+	rules := DefaultRiskRewardRules()
+	rules.MinStopATR = 0
+	ctx.RiskRewardRules = &rules
+	tight := []Decision{{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1000, StopLoss: 99.5, TakeProfit: 102}}
+	if valid, rejected := validateDecisions(tight, ctx); len(valid) != 1 {
+		t.Errorf("关闭ATR下限后应通过: %+v", rejected)
+	}
+}
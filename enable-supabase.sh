@@ -33,7 +33,7 @@ echo "🎯 现在可以配置 DATABASE_URL 环境变量来使用 Supabase"
 echo ""
 echo "📋 下一步:"
 echo "1. 设置 DATABASE_URL 环境变量"
-echo "2. 在 Supabase 中执行 supabase_migration.sql"
+echo "2. 执行 go run . migrate up 创建表结构（启动时也会自动执行）"
 echo "3. 启动应用测试"
 echo ""
 echo "💡 示例环境变量:"
@@ -1,15 +1,21 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
+	"nofx/logging"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
+var recordLog = logging.For("logger")
+
 // DecisionRecord 决策记录
 type DecisionRecord struct {
 	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
@@ -20,10 +26,22 @@ type DecisionRecord struct {
 	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
 	Positions      []PositionSnapshot `json:"positions"`       // 持仓快照
 	CandidateCoins []string           `json:"candidate_coins"` // 候选币种列表
+	MarketSource   string             `json:"market_source"`   // 行情数据来源
+	PromptTemplate string             `json:"prompt_template"` // 使用的提示词模板（name@version）
+	AIUsage        AIUsage            `json:"ai_usage"`        // AI调用的token用量和估算费用
 	Decisions      []DecisionAction   `json:"decisions"`       // 执行的决策
 	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
 	Success        bool               `json:"success"`         // 是否成功
 	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	TraceID        string             `json:"trace_id"`        // 本周期的 OpenTelemetry trace ID（未启用追踪时为空）
+	Trigger        string             `json:"trigger"`         // 周期触发方式（schedule 或 event: 原因）
+}
+
+// AIUsage AI调用用量
+type AIUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"` // 按参考价格估算，自定义API为0
 }
 
 // AccountSnapshot 账户状态快照
@@ -58,6 +76,17 @@ type DecisionAction struct {
 	Timestamp time.Time `json:"timestamp"` // 执行时间
 	Success   bool      `json:"success"`   // 是否成功
 	Error     string    `json:"error"`     // 错误信息
+
+	// 保证金分配结果（开仓时）
+	RequestedSizeUSD float64 `json:"requested_size_usd,omitempty"` // AI给出的仓位价值
+	SizeUSD          float64 `json:"size_usd,omitempty"`           // 分配后实际使用的仓位价值
+	MarginUSD        float64 `json:"margin_usd,omitempty"`         // 分配的保证金
+	MarginAdjust     string  `json:"margin_adjust,omitempty"`      // "resized"=已缩减/调整, "rejected"=已拒绝
+	MarginReason     string  `json:"margin_reason,omitempty"`      // 调整或拒绝原因
+
+	// 组合敞口检查结果（开仓时）
+	ExposureAdjust string `json:"exposure_adjust,omitempty"` // "resized"=已缩减, "rejected"=已拒绝
+	ExposureReason string `json:"exposure_reason,omitempty"` // 缩减或拒绝原因
 }
 
 // DecisionLogger 决策日志记录器
@@ -74,7 +103,7 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 
 	// 确保日志目录存在
 	if err := os.MkdirAll(logDir, 0755); err != nil {
-		fmt.Printf("⚠ 创建日志目录失败: %v\n", err)
+		recordLog.Warnf(context.Background(), "⚠ 创建日志目录失败: %v", err)
 	}
 
 	return &DecisionLogger{
@@ -83,8 +112,8 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 	}
 }
 
-// LogDecision 记录决策
-func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
+// LogDecision 记录决策（ctx 携带 trader_id 等日志字段）
+func (l *DecisionLogger) LogDecision(ctx context.Context, record *DecisionRecord) error {
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
 	record.Timestamp = time.Now()
@@ -107,7 +136,7 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 		return fmt.Errorf("写入决策记录失败: %w", err)
 	}
 
-	fmt.Printf("📝 决策记录已保存: %s\n", filename)
+	recordLog.Debugf(ctx, "📝 决策记录已保存: %s", filename)
 	return nil
 }
 
@@ -178,8 +207,48 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 	return records, nil
 }
 
+// GetRecordsInRange 获取时间范围内的所有记录（按时间正序）
+func (l *DecisionLogger) GetRecordsInRange(start, end time.Time) ([]*DecisionRecord, error) {
+	files, err := ioutil.ReadDir(l.logDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	var records []*DecisionRecord
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, "decision_") || len(name) < len("decision_20060102_150405") {
+			continue
+		}
+
+		// 先按文件名中的时间过滤，避免读取窗口外的文件（文件名精确到秒，边界放宽1秒）
+		fileTime, err := time.ParseInLocation("20060102_150405", name[len("decision_"):len("decision_20060102_150405")], time.Local)
+		if err != nil || fileTime.Before(start.Add(-time.Second)) || fileTime.After(end) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(l.logDir, name))
+		if err != nil {
+			continue
+		}
+
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		if record.Timestamp.Before(start) || record.Timestamp.After(end) {
+			continue
+		}
+
+		records = append(records, &record)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	return records, nil
+}
+
 // CleanOldRecords 清理N天前的旧记录
-func (l *DecisionLogger) CleanOldRecords(days int) error {
+func (l *DecisionLogger) CleanOldRecords(ctx context.Context, days int) error {
 	cutoffTime := time.Now().AddDate(0, 0, -days)
 
 	files, err := ioutil.ReadDir(l.logDir)
@@ -196,7 +265,7 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 		if file.ModTime().Before(cutoffTime) {
 			filepath := filepath.Join(l.logDir, file.Name())
 			if err := os.Remove(filepath); err != nil {
-				fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", file.Name(), err)
+				recordLog.Warnf(ctx, "⚠ 删除旧记录失败 %s: %v", file.Name(), err)
 				continue
 			}
 			removedCount++
@@ -204,7 +273,7 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 	}
 
 	if removedCount > 0 {
-		fmt.Printf("🗑️ 已清理 %d 条旧记录（%d天前）\n", removedCount, days)
+		recordLog.Infof(ctx, "🗑️ 已清理 %d 条旧记录（%d天前）", removedCount, days)
 	}
 
 	return nil
@@ -609,3 +678,70 @@ func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64
 	sharpeRatio := meanReturn / stdDev
 	return sharpeRatio
 }
+
+// PositionHistory 从决策日志重建的持仓状态（用于重启后恢复平仓冷却和持仓时长）
+type PositionHistory struct {
+	RecentCloses map[string]time.Time // 各币种最近一次平仓时间
+	OpenSince    map[string]time.Time // 最近一条记录中的持仓（symbol_side）及其首次出现时间
+}
+
+// RebuildPositionHistory 根据最近N条决策记录重建平仓时间和持仓首次出现时间
+// 平仓时间来自成功的平仓决策，以及相邻两条记录之间消失的持仓（止盈止损等在交易所触发的平仓）
+func (l *DecisionLogger) RebuildPositionHistory(lookbackCycles int) (*PositionHistory, error) {
+	records, err := l.GetLatestRecords(lookbackCycles)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史记录失败: %w", err)
+	}
+
+	history := &PositionHistory{
+		RecentCloses: make(map[string]time.Time),
+		OpenSince:    make(map[string]time.Time),
+	}
+	markClosed := func(symbol string, at time.Time) {
+		if at.After(history.RecentCloses[symbol]) {
+			history.RecentCloses[symbol] = at
+		}
+	}
+
+	open := make(map[string]time.Time) // 当前仍持有的仓位
+	for _, record := range records {
+		// 持仓快照在周期开始时记录（构建交易上下文失败的周期没有快照，跳过比较）
+		if record.AccountState.TotalBalance > 0 {
+			current := make(map[string]time.Time, len(record.Positions))
+			for _, pos := range record.Positions {
+				key := pos.Symbol + "_" + pos.Side
+				if since, ok := open[key]; ok {
+					current[key] = since
+				} else {
+					current[key] = record.Timestamp
+				}
+			}
+			for key := range open {
+				if _, ok := current[key]; !ok {
+					markClosed(key[:strings.LastIndex(key, "_")], record.Timestamp)
+				}
+			}
+			open = current
+		}
+
+		// 本周期成功平仓的持仓
+		for _, action := range record.Decisions {
+			if !action.Success {
+				continue
+			}
+			switch action.Action {
+			case "close_long":
+				markClosed(action.Symbol, action.Timestamp)
+				delete(open, action.Symbol+"_long")
+			case "close_short":
+				markClosed(action.Symbol, action.Timestamp)
+				delete(open, action.Symbol+"_short")
+			}
+		}
+	}
+
+	for key, since := range open {
+		history.OpenSince[key] = since
+	}
+	return history, nil
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeRecord 按决策日志的文件名格式写入记录
+func writeRecord(t *testing.T, dir string, record *DecisionRecord) {
+	t.Helper()
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("序列化决策记录失败: %v", err)
+	}
+	name := "decision_" + record.Timestamp.Format("20060102_150405") + ".json"
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatalf("写入决策记录失败: %v", err)
+	}
+}
+
+func TestRebuildPositionHistory(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLogger(dir)
+	base := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	account := AccountSnapshot{TotalBalance: 1000}
+	btc := PositionSnapshot{Symbol: "BTCUSDT", Side: "long"}
+	eth := PositionSnapshot{Symbol: "ETHUSDT", Side: "short"}
+	sol := PositionSnapshot{Symbol: "SOLUSDT", Side: "long"}
+
+	writeRecord(t, dir, &DecisionRecord{Timestamp: base, AccountState: account, Positions: []PositionSnapshot{btc, eth}})
+	// 构建上下文失败的周期没有持仓快照，不应视为平仓
+	writeRecord(t, dir, &DecisionRecord{Timestamp: base.Add(3 * time.Minute)})
+	// ETH 在交易所被止损（持仓消失），SOL 由AI平仓
+	writeRecord(t, dir, &DecisionRecord{
+		Timestamp:    base.Add(6 * time.Minute),
+		AccountState: account,
+		Positions:    []PositionSnapshot{btc, sol},
+		Decisions: []DecisionAction{
+			{Action: "close_long", Symbol: "SOLUSDT", Success: true, Timestamp: base.Add(5 * time.Minute)},
+			{Action: "close_long", Symbol: "BNBUSDT", Success: false, Timestamp: base.Add(5 * time.Minute)},
+		},
+	})
+
+	history, err := l.RebuildPositionHistory(10)
+	if err != nil {
+		t.Fatalf("RebuildPositionHistory失败: %v", err)
+	}
+
+	if got := history.RecentCloses["ETHUSDT"]; !got.Equal(base.Add(6 * time.Minute)) {
+		t.Errorf("ETH 平仓时间 = %v, want %v", got, base.Add(6*time.Minute))
+	}
+	if got := history.RecentCloses["SOLUSDT"]; !got.Equal(base.Add(5 * time.Minute)) {
+		t.Errorf("SOL 平仓时间 = %v, want %v", got, base.Add(5*time.Minute))
+	}
+	if _, ok := history.RecentCloses["BTCUSDT"]; ok {
+		t.Error("BTC 未平仓，不应记录平仓时间")
+	}
+	if _, ok := history.RecentCloses["BNBUSDT"]; ok {
+		t.Error("失败的平仓不应记录平仓时间")
+	}
+
+	if got := history.OpenSince["BTCUSDT_long"]; !got.Equal(base) {
+		t.Errorf("BTC 首次出现时间 = %v, want %v", got, base)
+	}
+	// SOL 在最后一个周期被平仓，不应作为持仓恢复
+	if len(history.OpenSince) != 1 {
+		t.Errorf("持仓数量 = %d, want 1: %v", len(history.OpenSince), history.OpenSince)
+	}
+}
@@ -0,0 +1,232 @@
+// Package logging 结构化分级日志（基于 log/slog）
+//
+// 配置（环境变量）：
+//   - LOG_LEVEL   全局日志级别 debug/info/warn/error（默认 info）
+//   - LOG_FORMAT  输出格式 json/text（默认 json）
+//   - LOG_LEVELS  按包覆盖级别，如 "trader=debug,market=warn"
+//
+// 标准库 log.Printf 的输出会以 info 级别进入同一个处理器；
+// 通过 WithAttrs 放入 context 的字段（trader_id、cycle 等）会附加到该 context 下的每条日志；
+// 敏感字段和疑似密钥的长十六进制串在输出前脱敏。
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config 日志配置
+type Config struct {
+	Level         slog.Level
+	Format        string                // json / text
+	PackageLevels map[string]slog.Level // 按包覆盖级别
+	Output        io.Writer
+}
+
+// ConfigFromEnv 从环境变量读取日志配置
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Level:         slog.LevelInfo,
+		Format:        "json",
+		PackageLevels: make(map[string]slog.Level),
+		Output:        os.Stdout,
+	}
+	if v := strings.TrimSpace(os.Getenv("LOG_LEVEL")); v != "" {
+		if level, ok := parseLevel(v); ok {
+			cfg.Level = level
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT"))); v == "text" || v == "json" {
+		cfg.Format = v
+	}
+	for _, item := range strings.Split(os.Getenv("LOG_LEVELS"), ",") {
+		pkg, levelStr, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		if level, ok := parseLevel(levelStr); ok {
+			cfg.PackageLevels[strings.TrimSpace(pkg)] = level
+		}
+	}
+	return cfg
+}
+
+// parseLevel 解析日志级别
+func parseLevel(s string) (slog.Level, bool) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, false
+	}
+	return level, true
+}
+
+// state 当前生效的日志配置
+type state struct {
+	root          slog.Handler
+	level         slog.Level
+	packageLevels map[string]slog.Level
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	Setup(Config{Level: slog.LevelInfo, Format: "text", Output: os.Stderr})
+}
+
+// Setup 应用日志配置，并接管标准库 log 和 slog 的默认输出
+func Setup(cfg Config) {
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	opts := &slog.HandlerOptions{
+		Level:       slog.LevelDebug, // 级别由 Enabled 按包判断
+		ReplaceAttr: redactAttr,
+	}
+	var root slog.Handler
+	if cfg.Format == "text" {
+		root = slog.NewTextHandler(cfg.Output, opts)
+	} else {
+		root = slog.NewJSONHandler(cfg.Output, opts)
+	}
+
+	current.Store(&state{root: root, level: cfg.Level, packageLevels: cfg.PackageLevels})
+	// 标准库 log 的输出随之以 info 级别写入该处理器
+	slog.SetDefault(slog.New(&handler{}))
+}
+
+// ctxAttrsKey context 中附加字段的键
+type ctxAttrsKey struct{}
+
+// WithAttrs 返回附加了日志字段的 context（如 trader_id、cycle），该 context 下的日志都会带上这些字段
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	existing, _ := ctx.Value(ctxAttrsKey{}).([]slog.Attr)
+	attrs := make([]slog.Attr, 0, len(existing)+len(args)/2)
+	attrs = append(attrs, existing...)
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, ctxAttrsKey{}, attrs)
+}
+
+// handler 按包过滤级别，附加 context 字段后交给当前根处理器
+// 根处理器在每次输出时读取，因此包级变量中的 Logger 在 Setup 之后同样生效
+type handler struct {
+	pkg  string
+	ops  []func(slog.Handler) slog.Handler // WithAttrs/WithGroup
+	keys []string                          // WithAttrs 已附加的字段（context 中的同名字段不再重复输出）
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	st := current.Load()
+	minLevel := st.level
+	if h.pkg != "" {
+		if pkgLevel, ok := st.packageLevels[h.pkg]; ok {
+			minLevel = pkgLevel
+		}
+	}
+	return level >= minLevel
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	var target slog.Handler = current.Load().root
+	if h.pkg != "" {
+		target = target.WithAttrs([]slog.Attr{slog.String("pkg", h.pkg)})
+	}
+	for _, op := range h.ops {
+		target = op(target)
+	}
+	if ctx != nil {
+		if attrs, ok := ctx.Value(ctxAttrsKey{}).([]slog.Attr); ok {
+			for _, a := range attrs {
+				if !h.hasKey(a.Key) {
+					r.AddAttrs(a)
+				}
+			}
+		}
+	}
+	r.Message = redactString(r.Message)
+	return target.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := h.with(func(target slog.Handler) slog.Handler { return target.WithAttrs(attrs) })
+	for _, a := range attrs {
+		next.keys = append(next.keys, a.Key)
+	}
+	return next
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(target slog.Handler) slog.Handler { return target.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	keys := make([]string, len(h.keys))
+	copy(keys, h.keys)
+	return &handler{pkg: h.pkg, ops: append(ops, op), keys: keys}
+}
+
+func (h *handler) hasKey(key string) bool {
+	for _, k := range h.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Logger 带 printf 风格便捷方法的 slog.Logger
+type Logger struct {
+	*slog.Logger
+}
+
+// For 返回指定包的 Logger（级别可由 LOG_LEVELS 单独配置）
+func For(pkg string) *Logger {
+	return &Logger{slog.New(&handler{pkg: pkg})}
+}
+
+// With 返回附加了固定字段的 Logger
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{l.Logger.With(args...)}
+}
+
+// Debugf 按格式输出 debug 日志
+func (l *Logger) Debugf(ctx context.Context, format string, args ...any) {
+	l.logf(ctx, slog.LevelDebug, format, args...)
+}
+
+// Infof 按格式输出 info 日志
+func (l *Logger) Infof(ctx context.Context, format string, args ...any) {
+	l.logf(ctx, slog.LevelInfo, format, args...)
+}
+
+// Warnf 按格式输出 warn 日志
+func (l *Logger) Warnf(ctx context.Context, format string, args ...any) {
+	l.logf(ctx, slog.LevelWarn, format, args...)
+}
+
+// Errorf 按格式输出 error 日志
+func (l *Logger) Errorf(ctx context.Context, format string, args ...any) {
+	l.logf(ctx, slog.LevelError, format, args...)
+}
+
+func (l *Logger) logf(ctx context.Context, level slog.Level, format string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	l.Log(ctx, level, fmt.Sprintf(format, args...))
+}
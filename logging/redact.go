@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted 脱敏后的占位值
+const redacted = "[REDACTED]"
+
+// sensitiveKeys 字段名包含这些词时整个值被脱敏
+var sensitiveKeys = []string{
+	"secret", "token", "password", "passwd", "passphrase",
+	"private_key", "privatekey", "api_key", "apikey", "signature", "authorization",
+}
+
+var (
+	// longHexPattern 私钥、HMAC签名等长十六进制串（钱包地址只有40位，不受影响）
+	longHexPattern = regexp.MustCompile(`\b(0x)?[0-9a-fA-F]{64,}\b`)
+	// bearerPattern Authorization 头中的令牌
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-]+`)
+)
+
+// isSensitiveKey 字段名是否敏感
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString 脱敏字符串中的疑似密钥
+func redactString(s string) string {
+	s = longHexPattern.ReplaceAllString(s, redacted)
+	return bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redactAttr 敏感字段整体脱敏，其余字符串值脱敏疑似密钥
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, redactString(a.Value.String()))
+	}
+	return a
+}
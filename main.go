@@ -1,19 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"nofx/api"
 	"nofx/auth"
 	"nofx/config"
+	"nofx/logging"
 	"nofx/manager"
 	"nofx/pool"
+	"nofx/tracing"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -108,6 +112,22 @@ func syncConfigToDatabase(database *config.Database) error {
 }
 
 func main() {
+	// 结构化日志（LOG_LEVEL / LOG_FORMAT / LOG_LEVELS）
+	logging.Setup(logging.ConfigFromEnv())
+
+	// 链路追踪（设置 OTEL_EXPORTER_OTLP_ENDPOINT 后通过 OTLP 导出，否则为 no-op）
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		log.Printf("⚠️  初始化链路追踪失败: %v", err)
+	} else if tracing.Enabled() {
+		log.Printf("✓ 链路追踪已启用 (OTLP)")
+	}
+
+	// 数据库迁移子命令: nofx migrate <up|status> [数据库文件路径]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║    🤖 AI多模型交易系统 - 支持 DeepSeek & Qwen            ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
@@ -190,6 +210,7 @@ func main() {
 
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
+	traderManager.SetHealthStore(database)
 
 	// 从数据库加载所有交易员到内存
 	err = traderManager.LoadTradersFromDatabase(database)
@@ -281,7 +302,15 @@ func main() {
 	fmt.Println()
 	fmt.Println()
 	log.Println("📛 收到退出信号，正在停止所有trader...")
-	traderManager.StopAll()
+	// 等待进行中的决策周期（含下单）完成，最多30秒
+	traderManager.StopAll(30 * time.Second)
+
+	// 导出剩余的 trace span
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Printf("⚠️  关闭链路追踪失败: %v", err)
+	}
+	cancelTracing()
 
 	fmt.Println()
 	fmt.Println("👋 感谢使用AI交易系统！")
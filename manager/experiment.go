@@ -0,0 +1,299 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"nofx/config"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// bootstrapIterations bootstrap 重采样次数
+const bootstrapIterations = 2000
+
+// minExperimentSamples 每个分组建议的最少周期收益样本数，少于此数时报告中给出警告
+const minExperimentSamples = 20
+
+// ExperimentArm 实验分组（一个交易员即一个分组）
+type ExperimentArm struct {
+	TraderID string `json:"trader_id"`
+	Label    string `json:"label"` // 分组名称，如 control、prompt_v2
+}
+
+// ParseExperimentArms 解析实验分组JSON
+func ParseExperimentArms(armsJSON string) ([]ExperimentArm, error) {
+	var arms []ExperimentArm
+	if err := json.Unmarshal([]byte(armsJSON), &arms); err != nil {
+		return nil, fmt.Errorf("解析实验分组失败: %w", err)
+	}
+	return arms, nil
+}
+
+// ArmMetrics 分组在实验窗口内的表现
+type ArmMetrics struct {
+	TraderID         string   `json:"trader_id"`
+	Label            string   `json:"label"`
+	PromptTemplates  []string `json:"prompt_templates"` // 窗口内使用过的提示词模板
+	Cycles           int      `json:"cycles"`           // 决策周期数
+	StartEquity      float64  `json:"start_equity"`
+	EndEquity        float64  `json:"end_equity"`
+	ReturnPct        float64  `json:"return_pct"`      // 窗口内收益率
+	MeanReturnPct    float64  `json:"mean_return_pct"` // 平均周期收益率
+	SharpeRatio      float64  `json:"sharpe_ratio"`    // 周期级别夏普比率
+	MaxDrawdownPct   float64  `json:"max_drawdown_pct"`
+	Opens            int      `json:"opens"`          // 开仓次数
+	ClosedTrades     int      `json:"closed_trades"`  // 窗口内完成开平仓的交易数
+	WinRate          float64  `json:"win_rate"`       // 胜率（%）
+	TradesPerDay     float64  `json:"trades_per_day"` // 每天开仓次数
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	AICostUSD        float64  `json:"ai_cost_usd"`
+
+	returns []float64 // 周期收益率（用于显著性检验）
+}
+
+// ArmComparison 实验组与对照组的比较（bootstrap 重采样周期收益率）
+type ArmComparison struct {
+	Label             string  `json:"label"`
+	MeanReturnDiffPct float64 `json:"mean_return_diff_pct"` // 平均周期收益率差（实验组-对照组）
+	CILowPct          float64 `json:"ci_low_pct"`           // 95%置信区间下限
+	CIHighPct         float64 `json:"ci_high_pct"`          // 95%置信区间上限
+	SharpeDiff        float64 `json:"sharpe_diff"`          // 夏普比率差
+	SharpeCILow       float64 `json:"sharpe_ci_low"`
+	SharpeCIHigh      float64 `json:"sharpe_ci_high"`
+	PValue            float64 `json:"p_value"`     // 双侧p值（平均收益率差为0的原假设）
+	Significant       bool    `json:"significant"` // p < 0.05
+}
+
+// ExperimentReport 实验报告
+type ExperimentReport struct {
+	ExperimentID string          `json:"experiment_id"`
+	Name         string          `json:"name"`
+	WindowStart  time.Time       `json:"window_start"`
+	WindowEnd    time.Time       `json:"window_end"`
+	Running      bool            `json:"running"`
+	Control      string          `json:"control"` // 对照组名称
+	Arms         []*ArmMetrics   `json:"arms"`
+	Comparisons  []ArmComparison `json:"comparisons"`
+	Warnings     []string        `json:"warnings"`
+}
+
+// GetExperimentReport 统计实验窗口内各分组的表现并与对照组比较
+func (tm *TraderManager) GetExperimentReport(exp *config.ExperimentRecord) (*ExperimentReport, error) {
+	arms, err := ParseExperimentArms(exp.Arms)
+	if err != nil {
+		return nil, err
+	}
+	if len(arms) < 2 {
+		return nil, fmt.Errorf("实验至少需要2个分组")
+	}
+
+	report := &ExperimentReport{
+		ExperimentID: exp.ID,
+		Name:         exp.Name,
+		WindowStart:  exp.StartTime,
+		WindowEnd:    time.Now(),
+		Running:      exp.EndTime == nil,
+		Control:      arms[0].Label,
+		Comparisons:  []ArmComparison{},
+		Warnings:     []string{},
+	}
+	if exp.EndTime != nil {
+		report.WindowEnd = *exp.EndTime
+	}
+
+	for _, arm := range arms {
+		// 交易员未加载时直接读取其决策日志目录
+		decisionLogger := logger.NewDecisionLogger(fmt.Sprintf("decision_logs/%s", arm.TraderID))
+		if at, err := tm.GetTrader(arm.TraderID); err == nil {
+			decisionLogger = at.GetDecisionLogger()
+		}
+
+		records, err := decisionLogger.GetRecordsInRange(report.WindowStart, report.WindowEnd)
+		if err != nil {
+			return nil, fmt.Errorf("读取分组 %s 的决策日志失败: %w", arm.Label, err)
+		}
+		metrics := computeArmMetrics(arm, records, report.WindowEnd.Sub(report.WindowStart))
+		if len(metrics.returns) < minExperimentSamples {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("分组 %s 只有%d个周期收益样本（<%d），结果仅供参考", arm.Label, len(metrics.returns), minExperimentSamples))
+		}
+		if len(metrics.PromptTemplates) > 1 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("分组 %s 在实验期间更换过提示词模板: %v", arm.Label, metrics.PromptTemplates))
+		}
+		report.Arms = append(report.Arms, metrics)
+	}
+
+	// 固定随机种子，同一窗口的报告可重现
+	rng := rand.New(rand.NewSource(report.WindowStart.UnixNano()))
+	control := report.Arms[0]
+	for _, arm := range report.Arms[1:] {
+		if len(control.returns) < 2 || len(arm.returns) < 2 {
+			continue
+		}
+		report.Comparisons = append(report.Comparisons, bootstrapCompare(rng, control, arm))
+	}
+
+	return report, nil
+}
+
+// computeArmMetrics 根据决策日志计算分组指标
+func computeArmMetrics(arm ExperimentArm, records []*logger.DecisionRecord, window time.Duration) *ArmMetrics {
+	metrics := &ArmMetrics{TraderID: arm.TraderID, Label: arm.Label, PromptTemplates: []string{}}
+	metrics.Cycles = len(records)
+
+	templates := make(map[string]bool)
+	var equities []float64
+	openPrices := make(map[string]logger.DecisionAction) // symbol_side -> 开仓记录
+	wins := 0
+	for _, record := range records {
+		if record.PromptTemplate != "" && !templates[record.PromptTemplate] {
+			templates[record.PromptTemplate] = true
+			metrics.PromptTemplates = append(metrics.PromptTemplates, record.PromptTemplate)
+		}
+		metrics.PromptTokens += record.AIUsage.PromptTokens
+		metrics.CompletionTokens += record.AIUsage.CompletionTokens
+		metrics.AICostUSD += record.AIUsage.CostUSD
+
+		if record.AccountState.TotalBalance > 0 {
+			equities = append(equities, record.AccountState.TotalBalance)
+		}
+
+		for _, action := range record.Decisions {
+			if !action.Success {
+				continue
+			}
+			switch action.Action {
+			case "open_long", "open_short":
+				metrics.Opens++
+				openPrices[action.Symbol+"_"+action.Action[5:]] = action
+			case "close_long", "close_short":
+				key := action.Symbol + "_" + action.Action[6:]
+				open, ok := openPrices[key]
+				if !ok {
+					continue // 开仓在实验窗口之前，不计入
+				}
+				delete(openPrices, key)
+				metrics.ClosedTrades++
+				pnl := open.Quantity * (action.Price - open.Price)
+				if action.Action == "close_short" {
+					pnl = -pnl
+				}
+				if pnl > 0 {
+					wins++
+				}
+			}
+		}
+	}
+
+	if metrics.ClosedTrades > 0 {
+		metrics.WinRate = float64(wins) / float64(metrics.ClosedTrades) * 100
+	}
+	if days := window.Hours() / 24; days > 0 {
+		metrics.TradesPerDay = float64(metrics.Opens) / days
+	}
+
+	if len(equities) > 0 {
+		metrics.StartEquity = equities[0]
+		metrics.EndEquity = equities[len(equities)-1]
+		metrics.ReturnPct = (metrics.EndEquity - metrics.StartEquity) / metrics.StartEquity * 100
+
+		peak := equities[0]
+		for i, equity := range equities {
+			if equity > peak {
+				peak = equity
+			}
+			if drawdown := (peak - equity) / peak * 100; drawdown > metrics.MaxDrawdownPct {
+				metrics.MaxDrawdownPct = drawdown
+			}
+			if i > 0 {
+				metrics.returns = append(metrics.returns, (equity-equities[i-1])/equities[i-1])
+			}
+		}
+	}
+	metrics.MeanReturnPct = mean(metrics.returns) * 100
+	metrics.SharpeRatio = sharpe(metrics.returns)
+
+	return metrics
+}
+
+// bootstrapCompare 分别对两个分组的周期收益率有放回重采样，估计差值的置信区间和p值
+func bootstrapCompare(rng *rand.Rand, control, arm *ArmMetrics) ArmComparison {
+	cmp := ArmComparison{
+		Label:             arm.Label,
+		MeanReturnDiffPct: (mean(arm.returns) - mean(control.returns)) * 100,
+		SharpeDiff:        sharpe(arm.returns) - sharpe(control.returns),
+	}
+
+	meanDiffs := make([]float64, bootstrapIterations)
+	sharpeDiffs := make([]float64, bootstrapIterations)
+	controlSample := make([]float64, len(control.returns))
+	armSample := make([]float64, len(arm.returns))
+	nonPositive, nonNegative := 0, 0
+	for i := 0; i < bootstrapIterations; i++ {
+		resample(rng, control.returns, controlSample)
+		resample(rng, arm.returns, armSample)
+		meanDiffs[i] = mean(armSample) - mean(controlSample)
+		sharpeDiffs[i] = sharpe(armSample) - sharpe(controlSample)
+		if meanDiffs[i] <= 0 {
+			nonPositive++
+		}
+		if meanDiffs[i] >= 0 {
+			nonNegative++
+		}
+	}
+
+	cmp.CILowPct, cmp.CIHighPct = percentileInterval(meanDiffs)
+	cmp.CILowPct *= 100
+	cmp.CIHighPct *= 100
+	cmp.SharpeCILow, cmp.SharpeCIHigh = percentileInterval(sharpeDiffs)
+	cmp.PValue = math.Min(1, 2*float64(min(nonPositive, nonNegative))/float64(bootstrapIterations))
+	cmp.Significant = cmp.PValue < 0.05
+	return cmp
+}
+
+// resample 有放回重采样
+func resample(rng *rand.Rand, src, dst []float64) {
+	for i := range dst {
+		dst[i] = src[rng.Intn(len(src))]
+	}
+}
+
+// percentileInterval 95%百分位置信区间
+func percentileInterval(values []float64) (float64, float64) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	last := float64(len(sorted) - 1)
+	return sorted[int(0.025*last)], sorted[int(0.975*last)]
+}
+
+// mean 平均值
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// sharpe 周期级别夏普比率（无风险利率为0，零波动时返回0）
+func sharpe(returns []float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+	m := mean(returns)
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - m) * (r - m)
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)))
+	if stdDev == 0 {
+		return 0
+	}
+	return m / stdDev
+}
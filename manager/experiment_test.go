@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"nofx/logger"
+)
+
+// experimentRecord 合成的决策周期记录
+func experimentRecord(equity float64, template string, usage logger.AIUsage, actions ...logger.DecisionAction) *logger.DecisionRecord {
+	return &logger.DecisionRecord{
+		AccountState:   logger.AccountSnapshot{TotalBalance: equity},
+		PromptTemplate: template,
+		AIUsage:        usage,
+		Decisions:      actions,
+	}
+}
+
+// filled 成功执行的决策
+func filled(action, symbol string, quantity, price float64) logger.DecisionAction {
+	return logger.DecisionAction{Action: action, Symbol: symbol, Quantity: quantity, Price: price, Success: true}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestComputeArmMetrics(t *testing.T) {
+	usage := logger.AIUsage{PromptTokens: 1000, CompletionTokens: 200, CostUSD: 0.01}
+	records := []*logger.DecisionRecord{
+		experimentRecord(1000, "default@1", usage,
+			filled("open_long", "BTCUSDT", 1, 100),
+			filled("open_short", "ETHUSDT", 2, 50),
+			logger.DecisionAction{Action: "open_long", Symbol: "SOLUSDT", Quantity: 10, Price: 20}, // 执行失败，不计入
+		),
+		experimentRecord(1100, "default@1", usage,
+			filled("close_long", "BTCUSDT", 1, 110),  // 盈利 +10
+			filled("close_short", "ETHUSDT", 2, 55),  // 亏损 -10
+			filled("close_long", "DOGEUSDT", 100, 1), // 开仓在窗口之前，不计入
+		),
+		experimentRecord(990, "default@2", usage, filled("open_long", "BTCUSDT", 1, 120)),
+		experimentRecord(1089, "", usage, filled("close_long", "BTCUSDT", 1, 115)), // 亏损 -5
+		experimentRecord(0, "default@2", logger.AIUsage{}),                         // 获取账户失败的周期
+	}
+
+	m := computeArmMetrics(ExperimentArm{TraderID: "t1", Label: "prompt_v2"}, records, 48*time.Hour)
+
+	if m.TraderID != "t1" || m.Label != "prompt_v2" || m.Cycles != 5 {
+		t.Errorf("基本信息 = %+v", m)
+	}
+	if !reflect.DeepEqual(m.PromptTemplates, []string{"default@1", "default@2"}) {
+		t.Errorf("PromptTemplates = %v", m.PromptTemplates)
+	}
+	if m.PromptTokens != 4000 || m.CompletionTokens != 800 || !approxEqual(m.AICostUSD, 0.04) {
+		t.Errorf("AI用量 = %d/%d/%.4f", m.PromptTokens, m.CompletionTokens, m.AICostUSD)
+	}
+	if m.Opens != 3 || m.ClosedTrades != 3 || !approxEqual(m.WinRate, 100.0/3) || !approxEqual(m.TradesPerDay, 1.5) {
+		t.Errorf("交易统计 = opens %d closed %d win %.2f perDay %.2f", m.Opens, m.ClosedTrades, m.WinRate, m.TradesPerDay)
+	}
+	if m.StartEquity != 1000 || m.EndEquity != 1089 || !approxEqual(m.ReturnPct, 8.9) || !approxEqual(m.MaxDrawdownPct, 10) {
+		t.Errorf("净值统计 = start %.0f end %.0f return %.4f dd %.4f", m.StartEquity, m.EndEquity, m.ReturnPct, m.MaxDrawdownPct)
+	}
+	// 周期收益率 +10%, -10%, +10%
+	if len(m.returns) != 3 || !approxEqual(m.MeanReturnPct, 10.0/3) || !approxEqual(m.SharpeRatio, 1/(2*math.Sqrt2)) {
+		t.Errorf("收益率 = %v mean %.4f sharpe %.4f", m.returns, m.MeanReturnPct, m.SharpeRatio)
+	}
+
+	empty := computeArmMetrics(ExperimentArm{Label: "control"}, nil, 0)
+	if empty.Cycles != 0 || empty.PromptTemplates == nil || len(empty.returns) != 0 || empty.SharpeRatio != 0 || empty.TradesPerDay != 0 {
+		t.Errorf("空记录 = %+v", empty)
+	}
+}
+
+// repeatReturns 将 pattern 重复 n 次作为周期收益率
+func repeatReturns(n int, pattern ...float64) []float64 {
+	var returns []float64
+	for i := 0; i < n; i++ {
+		returns = append(returns, pattern...)
+	}
+	return returns
+}
+
+func TestBootstrapCompare(t *testing.T) {
+	control := &ArmMetrics{Label: "control", returns: repeatReturns(15, 0.01, -0.01)}
+
+	t.Run("显著优于对照组", func(t *testing.T) {
+		arm := &ArmMetrics{Label: "prompt_v2", returns: repeatReturns(15, 0.03, 0.01)}
+		cmp := bootstrapCompare(rand.New(rand.NewSource(1)), control, arm)
+		if cmp.Label != "prompt_v2" || !approxEqual(cmp.MeanReturnDiffPct, 2) || !approxEqual(cmp.SharpeDiff, 2) {
+			t.Errorf("点估计 = %+v", cmp)
+		}
+		if cmp.CILowPct <= 0 || cmp.CIHighPct <= cmp.CILowPct || cmp.CILowPct > 2 || cmp.CIHighPct < 2 {
+			t.Errorf("置信区间 [%.4f, %.4f] 应在0以上并包含2", cmp.CILowPct, cmp.CIHighPct)
+		}
+		if cmp.PValue != 0 || !cmp.Significant {
+			t.Errorf("p值 = %.4f significant=%v", cmp.PValue, cmp.Significant)
+		}
+	})
+
+	t.Run("与对照组相同", func(t *testing.T) {
+		arm := &ArmMetrics{Label: "same", returns: append([]float64(nil), control.returns...)}
+		cmp := bootstrapCompare(rand.New(rand.NewSource(1)), control, arm)
+		if cmp.MeanReturnDiffPct != 0 || cmp.SharpeDiff != 0 {
+			t.Errorf("点估计 = %+v", cmp)
+		}
+		if cmp.CILowPct >= 0 || cmp.CIHighPct <= 0 || cmp.SharpeCILow >= 0 || cmp.SharpeCIHigh <= 0 {
+			t.Errorf("置信区间应包含0: %+v", cmp)
+		}
+		if cmp.PValue < 0.5 || cmp.Significant {
+			t.Errorf("p值 = %.4f significant=%v", cmp.PValue, cmp.Significant)
+		}
+	})
+
+	t.Run("固定种子可重现", func(t *testing.T) {
+		arm := &ArmMetrics{Label: "noisy", returns: []float64{0.02, -0.015, 0.004, 0.01, -0.02, 0.03, -0.005, 0.012}}
+		first := bootstrapCompare(rand.New(rand.NewSource(42)), control, arm)
+		second := bootstrapCompare(rand.New(rand.NewSource(42)), control, arm)
+		if first != second {
+			t.Errorf("相同种子结果不同:\n%+v\n%+v", first, second)
+		}
+		other := bootstrapCompare(rand.New(rand.NewSource(7)), control, arm)
+		if other.MeanReturnDiffPct != first.MeanReturnDiffPct || other.SharpeDiff != first.SharpeDiff {
+			t.Errorf("点估计不应依赖随机种子: %+v vs %+v", other, first)
+		}
+	})
+}
+
+func TestExperimentStats(t *testing.T) {
+	values := make([]float64, 101)
+	for i := range values {
+		values[100-i] = float64(i) // 乱序输入
+	}
+	if low, high := percentileInterval(values); low != 2 || high != 97 {
+		t.Errorf("percentileInterval = %.0f, %.0f, want 2, 97", low, high)
+	}
+	if values[0] != 100 {
+		t.Error("percentileInterval 不应修改输入")
+	}
+
+	cases := []struct {
+		returns    []float64
+		wantMean   float64
+		wantSharpe float64
+	}{
+		{nil, 0, 0},
+		{[]float64{0.05}, 0.05, 0},             // 样本不足
+		{[]float64{0.01, 0.01, 0.01}, 0.01, 0}, // 零波动
+		{[]float64{0.03, 0.01}, 0.02, 2},
+		{[]float64{-0.03, -0.01}, -0.02, -2},
+	}
+	for _, c := range cases {
+		if got := mean(c.returns); !approxEqual(got, c.wantMean) {
+			t.Errorf("mean(%v) = %.4f, want %.4f", c.returns, got, c.wantMean)
+		}
+		if got := sharpe(c.returns); !approxEqual(got, c.wantSharpe) {
+			t.Errorf("sharpe(%v) = %.4f, want %.4f", c.returns, got, c.wantSharpe)
+		}
+	}
+}
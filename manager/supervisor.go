@@ -0,0 +1,253 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"nofx/logging"
+	"nofx/trader"
+	"sync"
+	"time"
+)
+
+// managerLog 交易员管理和监督器日志
+var managerLog = logging.For("manager")
+
+// SupervisorPolicy 交易员监督策略
+type SupervisorPolicy struct {
+	InitialBackoff time.Duration // 首次重启前的等待时间
+	MaxBackoff     time.Duration // 重启等待时间上限（每次翻倍）；稳定运行超过该时长后重新计数
+	MaxRestarts    int           // 连续重启次数上限，超过后标记 errored 并停止重启
+	DegradedAfter  int           // 连续失败多少个周期后标记 degraded
+	ErroredAfter   int           // 连续失败多少个周期后标记 errored（交易员继续运行）
+}
+
+// DefaultSupervisorPolicy 默认监督策略
+func DefaultSupervisorPolicy() SupervisorPolicy {
+	return SupervisorPolicy{
+		InitialBackoff: 5 * time.Second,
+		MaxBackoff:     5 * time.Minute,
+		MaxRestarts:    5,
+		DegradedAfter:  3,
+		ErroredAfter:   10,
+	}
+}
+
+// HealthStore 交易员健康状态持久化（*config.Database 实现）
+type HealthStore interface {
+	UpdateTraderHealth(id, healthStatus, lastError string) error
+}
+
+// SupervisedTrader 受监督的交易员（*trader.AutoTrader 实现）
+type SupervisedTrader interface {
+	GetID() string
+	GetName() string
+	IsRunning() bool
+	Run(ctx context.Context) error
+	Stop()
+	UpdateHealth(update func(h *trader.TraderHealth)) trader.TraderHealth
+	SetCycleHook(hook func(h trader.TraderHealth))
+}
+
+// clock 监督器计时使用的时钟（测试中替换为假时钟）
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Supervisor 在独立 goroutine 中运行交易员：主循环异常退出（panic）时按指数退避重启，
+// 并根据连续失败次数维护健康状态
+type Supervisor struct {
+	policy SupervisorPolicy
+	store  HealthStore
+	clock  clock
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // trader ID -> 取消监督
+}
+
+// NewSupervisor 创建监督器（store 为 nil 时不持久化健康状态）
+func NewSupervisor(policy SupervisorPolicy, store HealthStore) *Supervisor {
+	return &Supervisor{
+		policy:  policy,
+		store:   store,
+		clock:   realClock{},
+		running: make(map[string]context.CancelFunc),
+	}
+}
+
+// SetStore 设置健康状态持久化
+func (s *Supervisor) SetStore(store HealthStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// Start 在监督下启动交易员
+func (s *Supervisor) Start(at SupervisedTrader) error {
+	s.mu.Lock()
+	if _, exists := s.running[at.GetID()]; exists || at.IsRunning() {
+		s.mu.Unlock()
+		return trader.ErrAlreadyRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.running[at.GetID()] = cancel
+	s.mu.Unlock()
+
+	// 手动启动视为重新开始，清除之前的失败记录（数据库中可能残留上次运行的状态）
+	h := at.UpdateHealth(func(h *trader.TraderHealth) {
+		h.Status = trader.HealthHealthy
+		h.ConsecutiveFailures = 0
+		h.Restarts = 0
+	})
+	s.persist(at, h)
+	at.SetCycleHook(func(h trader.TraderHealth) {
+		s.onCycle(at, h)
+	})
+
+	go s.supervise(ctx, at)
+	return nil
+}
+
+// Stop 停止监督并停止交易员（包括正在等待重启的交易员），返回是否有需要停止的交易员
+func (s *Supervisor) Stop(at SupervisedTrader) bool {
+	s.mu.Lock()
+	cancel, exists := s.running[at.GetID()]
+	delete(s.running, at.GetID())
+	s.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+	running := at.IsRunning()
+	at.Stop()
+	return exists || running
+}
+
+// IsSupervised 交易员是否处于监督下（运行中或等待重启）
+func (s *Supervisor) IsSupervised(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.running[id]
+	return exists
+}
+
+// supervise 运行交易员主循环，异常退出时按指数退避重启
+func (s *Supervisor) supervise(ctx context.Context, at SupervisedTrader) {
+	defer s.release(ctx, at.GetID())
+	logCtx := logging.WithAttrs(ctx, "trader_id", at.GetID())
+
+	attempt := 0
+	for {
+		startedAt := s.clock.Now()
+		err := at.Run(ctx)
+		if err == nil || errors.Is(err, trader.ErrAlreadyRunning) || ctx.Err() != nil {
+			return
+		}
+
+		// 稳定运行足够长时间后重新计算退避
+		now := s.clock.Now()
+		if now.Sub(startedAt) > s.policy.MaxBackoff {
+			attempt = 0
+		}
+		attempt++
+		at.UpdateHealth(func(h *trader.TraderHealth) {
+			h.Restarts++
+			h.LastError = err.Error()
+			h.LastErrorAt = &now
+		})
+
+		if attempt > s.policy.MaxRestarts {
+			managerLog.Errorf(logCtx, "❌ [%s] 连续重启 %d 次仍异常退出，停止重启: %v", at.GetName(), s.policy.MaxRestarts, err)
+			s.setStatus(at, trader.HealthErrored)
+			return
+		}
+		s.setStatus(at, trader.HealthDegraded)
+
+		backoff := s.backoff(attempt)
+		managerLog.Warnf(logCtx, "🔄 [%s] 主循环异常退出，%v 后进行第 %d 次重启: %v", at.GetName(), backoff, attempt, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(backoff):
+		}
+	}
+}
+
+// release 监督结束后移除记录（已被重新启动的同ID交易员不受影响）
+func (s *Supervisor) release(ctx context.Context, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, exists := s.running[id]; exists && ctx.Err() == nil {
+		cancel()
+		delete(s.running, id)
+	}
+}
+
+// backoff 第 attempt 次重启前的等待时间
+func (s *Supervisor) backoff(attempt int) time.Duration {
+	backoff := s.policy.InitialBackoff
+	for i := 1; i < attempt && backoff < s.policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.policy.MaxBackoff {
+		backoff = s.policy.MaxBackoff
+	}
+	return backoff
+}
+
+// onCycle 每个决策周期结束后根据连续失败次数更新健康状态
+func (s *Supervisor) onCycle(at SupervisedTrader, h trader.TraderHealth) {
+	switch {
+	case h.ConsecutiveFailures == 0:
+		s.setStatus(at, trader.HealthHealthy)
+	case h.ConsecutiveFailures >= s.policy.ErroredAfter:
+		s.setStatus(at, trader.HealthErrored)
+	case h.ConsecutiveFailures >= s.policy.DegradedAfter:
+		s.setStatus(at, trader.HealthDegraded)
+	}
+}
+
+// setStatus 更新健康状态，状态变化时写入日志和数据库
+func (s *Supervisor) setStatus(at SupervisedTrader, status trader.HealthStatus) {
+	var previous trader.HealthStatus
+	h := at.UpdateHealth(func(h *trader.TraderHealth) {
+		previous = h.Status
+		h.Status = status
+	})
+	if previous == status {
+		return
+	}
+
+	logCtx := logging.WithAttrs(context.Background(), "trader_id", at.GetID())
+	switch status {
+	case trader.HealthHealthy:
+		managerLog.Infof(logCtx, "✅ [%s] 健康状态: %s → %s", at.GetName(), previous, status)
+	default:
+		managerLog.Warnf(logCtx, "⚠️  [%s] 健康状态: %s → %s（连续失败 %d 次，重启 %d 次，最近错误: %s）",
+			at.GetName(), previous, status, h.ConsecutiveFailures, h.Restarts, h.LastError)
+	}
+
+	s.persist(at, h)
+}
+
+// persist 将健康状态写入数据库
+func (s *Supervisor) persist(at SupervisedTrader, h trader.TraderHealth) {
+	s.mu.Lock()
+	store := s.store
+	s.mu.Unlock()
+	if store == nil {
+		return
+	}
+	lastError := h.LastError
+	if h.Status == trader.HealthHealthy {
+		lastError = ""
+	}
+	if err := store.UpdateTraderHealth(at.GetID(), string(h.Status), lastError); err != nil {
+		managerLog.Warnf(logging.WithAttrs(context.Background(), "trader_id", at.GetID()), "⚠️  [%s] 保存健康状态失败: %v", at.GetName(), err)
+	}
+}
@@ -0,0 +1,292 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"nofx/trader"
+)
+
+// fakeClock 假时钟：After 立即返回并将时间推进等待时长，记录每次等待
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// runResult 假交易员一次主循环的结果
+type runResult struct {
+	ranFor time.Duration // 退出前运行的时长
+	err    error
+}
+
+// fakeRunner 按预设结果依次返回的假交易员，结果用完后阻塞到 ctx 取消
+type fakeRunner struct {
+	clock *fakeClock
+	runs  []runResult
+
+	mu     sync.Mutex
+	calls  int
+	health trader.TraderHealth
+	hook   func(h trader.TraderHealth)
+}
+
+func (r *fakeRunner) GetID() string   { return "fake" }
+func (r *fakeRunner) GetName() string { return "Fake" }
+func (r *fakeRunner) IsRunning() bool { return false }
+func (r *fakeRunner) Stop()           {}
+
+func (r *fakeRunner) Run(ctx context.Context) error {
+	r.mu.Lock()
+	i := r.calls
+	r.calls++
+	r.mu.Unlock()
+	if i >= len(r.runs) {
+		<-ctx.Done()
+		return nil
+	}
+	r.clock.advance(r.runs[i].ranFor)
+	return r.runs[i].err
+}
+
+func (r *fakeRunner) UpdateHealth(update func(h *trader.TraderHealth)) trader.TraderHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	update(&r.health)
+	return r.health
+}
+
+func (r *fakeRunner) SetCycleHook(hook func(h trader.TraderHealth)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hook = hook
+}
+
+func (r *fakeRunner) Health() trader.TraderHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.health
+}
+
+// healthRecord 一次健康状态持久化
+type healthRecord struct {
+	status, lastError string
+}
+
+// fakeHealthStore 记录持久化的健康状态
+type fakeHealthStore struct {
+	mu      sync.Mutex
+	records []healthRecord
+}
+
+func (s *fakeHealthStore) UpdateTraderHealth(id, healthStatus, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, healthRecord{healthStatus, lastError})
+	return nil
+}
+
+func (s *fakeHealthStore) last() healthRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.records) == 0 {
+		return healthRecord{}
+	}
+	return s.records[len(s.records)-1]
+}
+
+func testSupervisorPolicy() SupervisorPolicy {
+	return SupervisorPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     4 * time.Second,
+		MaxRestarts:    3,
+		DegradedAfter:  3,
+		ErroredAfter:   5,
+	}
+}
+
+func newTestSupervisor(store HealthStore) (*Supervisor, *fakeClock) {
+	clk := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewSupervisor(testSupervisorPolicy(), store)
+	s.clock = clk
+	return s, clk
+}
+
+func TestSupervisorRestarts(t *testing.T) {
+	errPanic := errors.New("panic: kline parser bug")
+	crash := runResult{err: errPanic}
+	cases := []struct {
+		name         string
+		runs         []runResult
+		wantWaits    []time.Duration
+		wantRestarts int
+		wantStatus   trader.HealthStatus
+	}{
+		{
+			name:         "重启后正常退出",
+			runs:         []runResult{crash, crash, {}},
+			wantWaits:    []time.Duration{time.Second, 2 * time.Second},
+			wantRestarts: 2,
+			wantStatus:   trader.HealthDegraded,
+		},
+		{
+			name:         "超过重启上限后停止重启",
+			runs:         []runResult{crash, crash, crash, crash, crash},
+			wantWaits:    []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			wantRestarts: 4,
+			wantStatus:   trader.HealthErrored,
+		},
+		{
+			name:         "稳定运行超过退避上限后重新计数",
+			runs:         []runResult{crash, crash, {ranFor: 5 * time.Second, err: errPanic}, crash, {}},
+			wantWaits:    []time.Duration{time.Second, 2 * time.Second, time.Second, 2 * time.Second},
+			wantRestarts: 4,
+			wantStatus:   trader.HealthDegraded,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := &fakeHealthStore{}
+			s, clk := newTestSupervisor(store)
+			runner := &fakeRunner{clock: clk, runs: c.runs}
+
+			s.supervise(context.Background(), runner)
+
+			if !reflect.DeepEqual(clk.waits, c.wantWaits) {
+				t.Errorf("退避等待 = %v, want %v", clk.waits, c.wantWaits)
+			}
+			h := runner.Health()
+			if h.Restarts != c.wantRestarts || h.Status != c.wantStatus {
+				t.Errorf("健康状态 = %+v, want restarts=%d status=%s", h, c.wantRestarts, c.wantStatus)
+			}
+			if h.LastError != errPanic.Error() || h.LastErrorAt == nil {
+				t.Errorf("未记录最近错误: %+v", h)
+			}
+			if got := store.last(); got.status != string(c.wantStatus) || got.lastError != errPanic.Error() {
+				t.Errorf("持久化状态 = %+v, want %s", got, c.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	s, _ := newTestSupervisor(nil)
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 4 * time.Second}, // 不超过 MaxBackoff
+		{10, 4 * time.Second},
+	}
+	for _, c := range cases {
+		if got := s.backoff(c.attempt); got != c.want {
+			t.Errorf("backoff(%d) = %v, want %v", c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestSupervisorHealthThresholds(t *testing.T) {
+	store := &fakeHealthStore{}
+	s, clk := newTestSupervisor(store)
+	runner := &fakeRunner{clock: clk}
+
+	// 每个周期的连续失败次数 → 期望的健康状态
+	steps := []struct {
+		failures int
+		want     trader.HealthStatus
+	}{
+		{0, trader.HealthHealthy},
+		{2, trader.HealthHealthy},
+		{3, trader.HealthDegraded},
+		{4, trader.HealthDegraded},
+		{5, trader.HealthErrored},
+		{6, trader.HealthErrored},
+		{0, trader.HealthHealthy},
+	}
+	for _, step := range steps {
+		h := runner.UpdateHealth(func(h *trader.TraderHealth) {
+			h.ConsecutiveFailures = step.failures
+			if step.failures > 0 {
+				h.LastError = "获取账户余额失败"
+			}
+		})
+		s.onCycle(runner, h)
+		if got := runner.Health().Status; got != step.want {
+			t.Errorf("连续失败 %d 次: 状态 = %s, want %s", step.failures, got, step.want)
+		}
+	}
+
+	// 只在状态变化时持久化（初始状态为空，首次 healthy 也会写入），恢复健康时清除错误
+	want := []healthRecord{
+		{string(trader.HealthHealthy), ""},
+		{string(trader.HealthDegraded), "获取账户余额失败"},
+		{string(trader.HealthErrored), "获取账户余额失败"},
+		{string(trader.HealthHealthy), ""},
+	}
+	if !reflect.DeepEqual(store.records, want) {
+		t.Errorf("持久化记录 = %+v, want %+v", store.records, want)
+	}
+}
+
+func TestSupervisorStartStop(t *testing.T) {
+	store := &fakeHealthStore{}
+	s, clk := newTestSupervisor(store)
+	runner := &fakeRunner{clock: clk, health: trader.TraderHealth{Status: trader.HealthErrored, Restarts: 3, ConsecutiveFailures: 7}}
+
+	if err := s.Start(runner); err != nil {
+		t.Fatalf("Start失败: %v", err)
+	}
+	// 手动启动清除上次运行残留的失败记录
+	if h := runner.Health(); h.Status != trader.HealthHealthy || h.Restarts != 0 || h.ConsecutiveFailures != 0 {
+		t.Errorf("启动后健康状态未重置: %+v", h)
+	}
+	if got := store.last(); got.status != string(trader.HealthHealthy) {
+		t.Errorf("启动后持久化状态 = %+v", got)
+	}
+	if !s.IsSupervised("fake") {
+		t.Error("启动后应处于监督下")
+	}
+	if err := s.Start(runner); !errors.Is(err, trader.ErrAlreadyRunning) {
+		t.Errorf("重复启动应返回 ErrAlreadyRunning, got %v", err)
+	}
+
+	if !s.Stop(runner) {
+		t.Error("Stop 应返回 true")
+	}
+	if s.IsSupervised("fake") {
+		t.Error("停止后不应处于监督下")
+	}
+	if s.Stop(runner) {
+		t.Error("重复 Stop 应返回 false")
+	}
+}
@@ -1,9 +1,14 @@
 package manager
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/logging"
+	"nofx/market"
+	"nofx/metrics"
+	"nofx/pool"
 	"nofx/trader"
 	"strconv"
 	"strings"
@@ -13,19 +18,48 @@ import (
 
 // TraderManager 管理多个trader实例
 type TraderManager struct {
-	traders map[string]*trader.AutoTrader // key: trader ID
-	mu      sync.RWMutex
+	traders    map[string]*trader.AutoTrader // key: trader ID
+	mu         sync.RWMutex
+	supervisor *Supervisor // 运行交易员并在异常退出时自动重启
 }
 
 // NewTraderManager 创建trader管理器
 func NewTraderManager() *TraderManager {
 	return &TraderManager{
-		traders: make(map[string]*trader.AutoTrader),
+		traders:    make(map[string]*trader.AutoTrader),
+		supervisor: NewSupervisor(DefaultSupervisorPolicy(), nil),
 	}
 }
 
+// SetHealthStore 设置交易员健康状态的持久化存储
+func (tm *TraderManager) SetHealthStore(store HealthStore) {
+	tm.supervisor.SetStore(store)
+}
+
+// StartTrader 在监督下启动指定交易员
+func (tm *TraderManager) StartTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	return tm.supervisor.Start(t)
+}
+
+// StopTrader 停止指定交易员（包括正在等待重启的交易员）
+func (tm *TraderManager) StopTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if !tm.supervisor.Stop(t) {
+		return fmt.Errorf("交易员已停止")
+	}
+	return nil
+}
+
 // LoadTradersFromDatabase 从数据库加载所有交易员到内存
 func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) error {
+	ctx := context.Background()
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
@@ -38,12 +72,12 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 
 	if multiUserMode {
 		// 多用户模式：加载所有用户的交易员
-		log.Printf("🌐 多用户模式已启用，加载所有用户的交易员...")
+		managerLog.Infof(ctx, "🌐 多用户模式已启用，加载所有用户的交易员...")
 		traders, err = database.GetAllTraders()
 		if err != nil {
 			return fmt.Errorf("获取所有交易员列表失败: %w", err)
 		}
-		log.Printf("📋 加载数据库中的交易员配置: %d 个 (所有用户)", len(traders))
+		managerLog.Infof(ctx, "📋 加载数据库中的交易员配置: %d 个 (所有用户)", len(traders))
 	} else {
 		// 单用户模式：根据admin_mode确定用户ID
 		adminModeStr, _ := database.GetSystemConfig("admin_mode")
@@ -57,51 +91,18 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 		if err != nil {
 			return fmt.Errorf("获取交易员列表失败: %w", err)
 		}
-		log.Printf("📋 加载数据库中的交易员配置: %d 个 (用户: %s)", len(traders), userID)
+		managerLog.Infof(ctx, "📋 加载数据库中的交易员配置: %d 个 (用户: %s)", len(traders), userID)
 	}
 
 	// 获取系统配置
-	coinPoolURL, _ := database.GetSystemConfig("coin_pool_api_url")
-	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
-	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
-	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
-	// 获取杠杆配置
-	btcEthLeverageStr, _ := database.GetSystemConfig("btc_eth_leverage")
-	altcoinLeverageStr, _ := database.GetSystemConfig("altcoin_leverage")
-
-	// 解析配置
-	maxDailyLoss := 10.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDailyLossStr, 64); err == nil {
-		maxDailyLoss = val
-	}
-
-	maxDrawdown := 20.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDrawdownStr, 64); err == nil {
-		maxDrawdown = val
-	}
-
-	stopTradingMinutes := 60 // 默认值
-	if val, err := strconv.Atoi(stopTradingMinutesStr); err == nil {
-		stopTradingMinutes = val
-	}
-
-	// 解析杠杆配置（默认5倍，适配币安子账户）
-	btcEthLeverage := 5
-	if val, err := strconv.Atoi(btcEthLeverageStr); err == nil && val > 0 {
-		btcEthLeverage = val
-	}
-
-	altcoinLeverage := 5
-	if val, err := strconv.Atoi(altcoinLeverageStr); err == nil && val > 0 {
-		altcoinLeverage = val
-	}
+	sysCfg := loadSystemTraderConfig(database)
 
 	// 为每个交易员获取AI模型和交易所配置
 	for _, traderCfg := range traders {
 		// 使用交易员的用户ID获取AI模型配置
 		aiModels, err := database.GetAIModels(traderCfg.UserID)
 		if err != nil {
-			log.Printf("⚠️  获取AI模型配置失败: %v", err)
+			managerLog.Warnf(ctx, "⚠️  获取AI模型配置失败: %v", err)
 			continue
 		}
 
@@ -114,19 +115,19 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 		}
 
 		if aiModelCfg == nil {
-			log.Printf("⚠️  交易员 %s 的AI模型 %s 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
+			managerLog.Warnf(ctx, "⚠️  交易员 %s 的AI模型 %s 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
 		if !aiModelCfg.Enabled {
-			log.Printf("⚠️  交易员 %s 的AI模型 %s 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
+			managerLog.Warnf(ctx, "⚠️  交易员 %s 的AI模型 %s 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
 		// 获取交易所配置
 		exchanges, err := database.GetExchanges(traderCfg.UserID)
 		if err != nil {
-			log.Printf("⚠️  获取交易所配置失败: %v", err)
+			managerLog.Warnf(ctx, "⚠️  获取交易所配置失败: %v", err)
 			continue
 		}
 
@@ -139,106 +140,88 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 		}
 
 		if exchangeCfg == nil {
-			log.Printf("⚠️  交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			managerLog.Warnf(ctx, "⚠️  交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
 		if !exchangeCfg.Enabled {
-			log.Printf("⚠️  交易员 %s 的交易所 %s 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			managerLog.Warnf(ctx, "⚠️  交易员 %s 的交易所 %s 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
 		// 添加到TraderManager
-		err = tm.addTraderFromDB(traderCfg, aiModelCfg, exchangeCfg, coinPoolURL, maxDailyLoss, maxDrawdown, stopTradingMinutes, btcEthLeverage, altcoinLeverage)
+		err = tm.addTraderFromDB(traderCfg, aiModelCfg, exchangeCfg, sysCfg)
 		if err != nil {
-			log.Printf("❌ 添加交易员 %s 失败: %v", traderCfg.Name, err)
+			managerLog.Errorf(ctx, "❌ 添加交易员 %s 失败: %v", traderCfg.Name, err)
 			continue
 		}
+		tm.applyPromptTemplate(database, traderCfg)
 	}
 
-	log.Printf("✓ 成功加载 %d 个交易员到内存", len(tm.traders))
+	managerLog.Infof(ctx, "✓ 成功加载 %d 个交易员到内存", len(tm.traders))
 	return nil
 }
 
-// addTraderFromConfig 内部方法：从配置添加交易员（不加锁，因为调用方已加锁）
-func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModelCfg *config.AIModelConfig, exchangeCfg *config.ExchangeConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, btcEthLeverage, altcoinLeverage int) error {
-	if _, exists := tm.traders[traderCfg.ID]; exists {
-		return fmt.Errorf("trader ID '%s' 已存在", traderCfg.ID)
-	}
+// systemTraderConfig 所有交易员共用的系统配置
+type systemTraderConfig struct {
+	coinPoolURL        string
+	maxDailyLoss       float64
+	maxDrawdown        float64
+	stopTradingMinutes int
+	btcEthLeverage     int
+	altcoinLeverage    int
+}
 
-	// 构建AutoTraderConfig - 使用 trader 配置中的字段
-	traderConfig := trader.AutoTraderConfig{
-		ID:                    traderCfg.ID,
-		Name:                  traderCfg.Name,
-		AIModel:               aiModelCfg.Provider, // 使用provider作为模型标识
-		Exchange:              exchangeCfg.Type,    // 使用exchange type而不是ID
-		BinanceAPIKey:         "",
-		BinanceSecretKey:      "",
-		HyperliquidPrivateKey: "",
-		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        coinPoolURL,
-		UseQwen:               aiModelCfg.Provider == "qwen",
-		DeepSeekKey:           "",
-		QwenKey:               "",
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		InitialBalance:        traderCfg.InitialBalance,
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:         traderCfg.IsCrossMargin,
-		BTCETHLeverage:        btcEthLeverage,
-		AltcoinLeverage:       altcoinLeverage,
+// loadSystemTraderConfig 从系统配置表读取交易员共用配置（缺失或无效时使用默认值）
+func loadSystemTraderConfig(database *config.Database) systemTraderConfig {
+	coinPoolURL, _ := database.GetSystemConfig("coin_pool_api_url")
+	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
+	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
+	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
+	// 获取杠杆配置
+	btcEthLeverageStr, _ := database.GetSystemConfig("btc_eth_leverage")
+	altcoinLeverageStr, _ := database.GetSystemConfig("altcoin_leverage")
+
+	// 解析配置
+	maxDailyLoss := 10.0 // 默认值
+	if val, err := strconv.ParseFloat(maxDailyLossStr, 64); err == nil {
+		maxDailyLoss = val
 	}
 
-	// 根据交易所类型设置API密钥
-	if exchangeCfg.ID == "binance" {
-		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
-		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
-	} else if exchangeCfg.ID == "hyperliquid" {
-		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
-		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
-	} else if exchangeCfg.ID == "aster" {
-		traderConfig.AsterUser = exchangeCfg.AsterUser
-		traderConfig.AsterSigner = exchangeCfg.AsterSigner
-		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
+	maxDrawdown := 20.0 // 默认值
+	if val, err := strconv.ParseFloat(maxDrawdownStr, 64); err == nil {
+		maxDrawdown = val
 	}
 
-	// 根据AI模型设置API密钥
-	if aiModelCfg.Provider == "qwen" {
-		traderConfig.QwenKey = aiModelCfg.APIKey
-	} else if aiModelCfg.Provider == "deepseek" {
-		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	stopTradingMinutes := 60 // 默认值
+	if val, err := strconv.Atoi(stopTradingMinutesStr); err == nil {
+		stopTradingMinutes = val
 	}
 
-	// 创建trader实例
-	at, err := trader.NewAutoTrader(traderConfig)
-	if err != nil {
-		return fmt.Errorf("创建trader失败: %w", err)
+	// 解析杠杆配置（默认5倍，适配币安子账户）
+	btcEthLeverage := 5
+	if val, err := strconv.Atoi(btcEthLeverageStr); err == nil && val > 0 {
+		btcEthLeverage = val
 	}
 
-	// 设置自定义prompt（如果有）
-	if traderCfg.CustomPrompt != "" {
-		at.SetCustomPrompt(traderCfg.CustomPrompt)
-		at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
-		if traderCfg.OverrideBasePrompt {
-			log.Printf("✓ 已设置自定义交易策略prompt (覆盖基础prompt)")
-		} else {
-			log.Printf("✓ 已设置自定义交易策略prompt (补充基础prompt)")
-		}
+	altcoinLeverage := 5
+	if val, err := strconv.Atoi(altcoinLeverageStr); err == nil && val > 0 {
+		altcoinLeverage = val
 	}
 
-	tm.traders[traderCfg.ID] = at
-	log.Printf("✓ Trader '%s' (%s + %s) 已加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
-	return nil
+	return systemTraderConfig{
+		coinPoolURL:        coinPoolURL,
+		maxDailyLoss:       maxDailyLoss,
+		maxDrawdown:        maxDrawdown,
+		stopTradingMinutes: stopTradingMinutes,
+		btcEthLeverage:     btcEthLeverage,
+		altcoinLeverage:    altcoinLeverage,
+	}
 }
 
-// AddTrader 从数据库配置添加trader (移除旧版兼容性)
-
-// AddTraderFromDB 从数据库配置添加trader
-func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModelCfg *config.AIModelConfig, exchangeCfg *config.ExchangeConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int) error {
-	tm.mu.Lock()
-	defer tm.mu.Unlock()
-
+// addTraderFromDB 内部方法：从数据库配置构建并添加交易员（不加锁，因为调用方已加锁）
+func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModelCfg *config.AIModelConfig, exchangeCfg *config.ExchangeConfig, sysCfg systemTraderConfig) error {
+	ctx := logging.WithAttrs(context.Background(), "trader_id", traderCfg.ID)
 	if _, exists := tm.traders[traderCfg.ID]; exists {
 		return fmt.Errorf("trader ID '%s' 已存在", traderCfg.ID)
 	}
@@ -249,38 +232,29 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		Name:                  traderCfg.Name,
 		AIModel:               aiModelCfg.Provider, // 使用provider作为模型标识
 		Exchange:              exchangeCfg.Type,    // 使用exchange type而不是ID
-		BinanceAPIKey:         "",
-		BinanceSecretKey:      "",
-		HyperliquidPrivateKey: "",
-		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        coinPoolURL,
+		ExchangeCredentials:   exchangeCfg.Credentials, // 凭证由交易所注册表校验
+		ExchangeTestnet:       exchangeCfg.Testnet,
+		CoinPoolAPIURL:        sysCfg.coinPoolURL,
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
 		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
 		InitialBalance:        traderCfg.InitialBalance,
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		MaxDailyLoss:          sysCfg.maxDailyLoss,
+		MaxDrawdown:           sysCfg.maxDrawdown,
+		StopTradingTime:       time.Duration(sysCfg.stopTradingMinutes) * time.Minute,
 		IsCrossMargin:         traderCfg.IsCrossMargin,
-		// 注意：此函数未接收杠杆配置参数，使用默认值5倍
-		// 如果需要自定义杠杆，请使用 addTraderFromDB 或 loadSingleTrader
-		BTCETHLeverage:  5,
-		AltcoinLeverage: 5,
+		IndicatorSpec:         parseTraderSetting[market.IndicatorSpec](traderCfg, config.SettingIndicatorSpec),
+		MarginPolicy:          parseTraderSetting[trader.MarginPolicy](traderCfg, config.SettingMarginPolicy),
+		ExposureLimits:        parseTraderSetting[decision.ExposureLimits](traderCfg, config.SettingExposureLimits),
+		RiskRewardRules:       parseTraderSetting[decision.RiskRewardRules](traderCfg, config.SettingRiskRewardRules),
+		RiskPolicy:            parseTraderSetting[decision.RiskPolicy](traderCfg, config.SettingRiskPolicy),
+		CoinUniverse:          parseTraderSetting[pool.Universe](traderCfg, config.SettingCoinUniverse),
+		Schedule:              parseTraderSetting[trader.Schedule](traderCfg, config.SettingSchedule),
+		BTCETHLeverage:        sysCfg.btcEthLeverage,
+		AltcoinLeverage:       sysCfg.altcoinLeverage,
 	}
 
-	// 根据交易所类型设置API密钥
-	if exchangeCfg.ID == "binance" {
-		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
-		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
-	} else if exchangeCfg.ID == "hyperliquid" {
-		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
-		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
-	} else if exchangeCfg.ID == "aster" {
-		traderConfig.AsterUser = exchangeCfg.AsterUser
-		traderConfig.AsterSigner = exchangeCfg.AsterSigner
-		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
-	}
 
 	// 根据AI模型设置API密钥
 	if aiModelCfg.Provider == "qwen" {
@@ -295,11 +269,19 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
 
-	// 新版本中自定义prompt功能暂时移除，后续可通过API扩展
-	log.Printf("✓ 使用默认交易策略prompt")
+	// 设置自定义prompt（如果有）
+	if traderCfg.CustomPrompt != "" {
+		at.SetCustomPrompt(traderCfg.CustomPrompt)
+		at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
+		if traderCfg.OverrideBasePrompt {
+			managerLog.Infof(ctx, "✓ 已设置自定义交易策略prompt (覆盖基础prompt)")
+		} else {
+			managerLog.Infof(ctx, "✓ 已设置自定义交易策略prompt (补充基础prompt)")
+		}
+	}
 
 	tm.traders[traderCfg.ID] = at
-	log.Printf("✓ Trader '%s' (%s + %s) 已添加", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
+	managerLog.Infof(ctx, "✓ Trader '%s' (%s + %s) 已加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
 
@@ -317,18 +299,18 @@ func (tm *TraderManager) GetTrader(id string) (*trader.AutoTrader, error) {
 
 // RemoveTrader 从内存中移除指定ID的trader
 func (tm *TraderManager) RemoveTrader(id string) {
+	ctx := context.Background()
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
 	if t, exists := tm.traders[id]; exists {
 		// 如果交易员正在运行，先停止它
-		status := t.GetStatus()
-		if isRunning, ok := status["is_running"].(bool); ok && isRunning {
-			t.Stop()
-			log.Printf("⏹  已停止运行中的交易员: %s", id)
+		if tm.supervisor.Stop(t) {
+			managerLog.Infof(ctx, "⏹  已停止运行中的交易员: %s", id)
 		}
 		delete(tm.traders, id)
-		log.Printf("✓ 交易员 %s 已从内存中移除", id)
+		metrics.DeleteTrader(id)
+		managerLog.Infof(ctx, "✓ 交易员 %s 已从内存中移除", id)
 	}
 }
 
@@ -358,22 +340,22 @@ func (tm *TraderManager) GetTraderIDs() []string {
 
 // StartAll 启动所有trader
 func (tm *TraderManager) StartAll() {
+	ctx := context.Background()
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
-	log.Println("🚀 启动所有Trader...")
-	for id, t := range tm.traders {
-		go func(traderID string, at *trader.AutoTrader) {
-			log.Printf("▶️  启动 %s...", at.GetName())
-			if err := at.Run(); err != nil {
-				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
-			}
-		}(id, t)
+	managerLog.Infof(ctx, "🚀 启动所有Trader...")
+	for _, t := range tm.traders {
+		managerLog.Infof(ctx, "▶️  启动 %s...", t.GetName())
+		if err := tm.supervisor.Start(t); err != nil {
+			managerLog.Errorf(ctx, "❌ %s 启动失败: %v", t.GetName(), err)
+		}
 	}
 }
 
 // RestoreRunningTraders 根据数据库中的运行状态恢复交易员的运行状态
 func (tm *TraderManager) RestoreRunningTraders(database *config.Database) error {
+	ctx := context.Background()
 	// 检查是否启用多用户模式
 	multiUserModeStr, _ := database.GetSystemConfig("multi_user_mode")
 	multiUserMode := multiUserModeStr == "true"
@@ -409,11 +391,11 @@ func (tm *TraderManager) RestoreRunningTraders(database *config.Database) error
 	}
 
 	if runningCount == 0 {
-		log.Printf("📋 没有需要恢复运行状态的交易员")
+		managerLog.Infof(ctx, "📋 没有需要恢复运行状态的交易员")
 		return nil
 	}
 
-	log.Printf("🔄 开始恢复 %d 个交易员的运行状态...", runningCount)
+	managerLog.Infof(ctx, "🔄 开始恢复 %d 个交易员的运行状态...", runningCount)
 
 	// 恢复运行状态
 	tm.mu.RLock()
@@ -426,42 +408,63 @@ func (tm *TraderManager) RestoreRunningTraders(database *config.Database) error
 		// 检查交易员是否在内存中
 		t, exists := tm.traders[traderCfg.ID]
 		if !exists {
-			log.Printf("⚠️  交易员 %s (%s) 不在内存中，跳过恢复", traderCfg.Name, traderCfg.ID)
+			managerLog.Warnf(ctx, "⚠️  交易员 %s (%s) 不在内存中，跳过恢复", traderCfg.Name, traderCfg.ID)
 			continue
 		}
 
 		// 检查交易员是否已经在运行
-		status := t.GetStatus()
-		if isRunning, ok := status["is_running"].(bool); ok && isRunning {
-			log.Printf("✓ 交易员 %s 已在运行中，跳过", traderCfg.Name)
+		if t.IsRunning() {
+			managerLog.Infof(ctx, "✓ 交易员 %s 已在运行中，跳过", traderCfg.Name)
 			continue
 		}
 
 		// 启动交易员
-		go func(traderID string, traderName string, at *trader.AutoTrader) {
-			log.Printf("▶️  恢复交易员运行状态: %s (%s)", traderName, traderID)
-			if err := at.Run(); err != nil {
-				log.Printf("❌ 交易员 %s 运行错误: %v", traderName, err)
-			}
-		}(traderCfg.ID, traderCfg.Name, t)
+		managerLog.Infof(ctx, "▶️  恢复交易员运行状态: %s (%s)", traderCfg.Name, traderCfg.ID)
+		if err := tm.supervisor.Start(t); err != nil {
+			managerLog.Errorf(ctx, "❌ 交易员 %s 启动失败: %v", traderCfg.Name, err)
+			continue
+		}
 
 		restoredCount++
 	}
 	tm.mu.RUnlock()
 
-	log.Printf("✓ 成功恢复 %d 个交易员的运行状态", restoredCount)
+	managerLog.Infof(ctx, "✓ 成功恢复 %d 个交易员的运行状态", restoredCount)
 	return nil
 }
 
-// StopAll 停止所有trader
-func (tm *TraderManager) StopAll() {
+// StopAll 停止所有trader，并在 timeout 内等待正在执行的决策周期结束
+func (tm *TraderManager) StopAll(timeout time.Duration) {
+	ctx := context.Background()
 	tm.mu.RLock()
-	defer tm.mu.RUnlock()
-
-	log.Println("⏹  停止所有Trader...")
+	traders := make([]*trader.AutoTrader, 0, len(tm.traders))
 	for _, t := range tm.traders {
-		t.Stop()
+		traders = append(traders, t)
 	}
+	tm.mu.RUnlock()
+
+	managerLog.Infof(ctx, "⏹  停止所有Trader...")
+	for _, t := range traders {
+		tm.supervisor.Stop(t)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for _, t := range traders {
+		select {
+		case <-t.Done():
+		case <-deadline.C:
+			var pending []string
+			for _, t := range traders {
+				if t.IsRunning() {
+					pending = append(pending, t.GetName())
+				}
+			}
+			managerLog.Warnf(ctx, "⚠️  等待Trader停止超时(%v)，仍在运行: %v", timeout, pending)
+			return
+		}
+	}
+	managerLog.Infof(ctx, "✓ 所有Trader已停止")
 }
 
 // GetComparisonData 获取对比数据
@@ -473,7 +476,7 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 	traders := make([]map[string]interface{}, 0, len(tm.traders))
 
 	for _, t := range tm.traders {
-		account, err := t.GetAccountInfo()
+		account, err := t.GetAccountInfo(context.Background())
 		if err != nil {
 			continue
 		}
@@ -502,6 +505,7 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 
 // GetPublicCompetitionData 获取公开竞赛数据（所有用户的所有交易员）
 func (tm *TraderManager) GetPublicCompetitionData(database *config.Database) (map[string]interface{}, error) {
+	ctx := context.Background()
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
@@ -524,13 +528,13 @@ func (tm *TraderManager) GetPublicCompetitionData(database *config.Database) (ma
 	for traderID, t := range tm.traders {
 		// 验证交易员是否仍在数据库中（防止返回已删除的交易员）
 		if validTraderIDs != nil && !validTraderIDs[traderID] {
-			log.Printf("⚠️ 交易员 %s 不在数据库中，跳过（可能已被删除）", traderID)
+			managerLog.Warnf(ctx, "⚠️ 交易员 %s 不在数据库中，跳过（可能已被删除）", traderID)
 			continue
 		}
 
-		account, err := t.GetAccountInfo()
+		account, err := t.GetAccountInfo(ctx)
 		if err != nil {
-			log.Printf("⚠️ 获取交易员 %s 账户信息失败: %v", traderID, err)
+			managerLog.Warnf(ctx, "⚠️ 获取交易员 %s 账户信息失败: %v", traderID, err)
 			continue
 		}
 
@@ -600,6 +604,7 @@ func (tm *TraderManager) GetCompetitionData(userID string) (map[string]interface
 
 // GetCompetitionDataWithDatabase 获取竞赛数据（带数据库访问权限，用于获取交易所信息）
 func (tm *TraderManager) GetCompetitionDataWithDatabase(userID string, database *config.Database) (map[string]interface{}, error) {
+	ctx := context.Background()
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
@@ -628,13 +633,13 @@ func (tm *TraderManager) GetCompetitionDataWithDatabase(userID string, database
 
 		// 验证交易员是否仍在数据库中（防止返回已删除的交易员）
 		if validTraderIDs != nil && !validTraderIDs[traderID] {
-			log.Printf("⚠️ 交易员 %s 不在数据库中，跳过（可能已被删除）", traderID)
+			managerLog.Warnf(ctx, "⚠️ 交易员 %s 不在数据库中，跳过（可能已被删除）", traderID)
 			continue
 		}
 
-		account, err := t.GetAccountInfo()
+		account, err := t.GetAccountInfo(ctx)
 		if err != nil {
-			log.Printf("⚠️ 获取交易员 %s 账户信息失败: %v", traderID, err)
+			managerLog.Warnf(ctx, "⚠️ 获取交易员 %s 账户信息失败: %v", traderID, err)
 			continue
 		}
 
@@ -718,6 +723,7 @@ func containsUserPrefix(traderID string) bool {
 
 // LoadUserTraders 为特定用户加载交易员到内存
 func (tm *TraderManager) LoadUserTraders(database *config.Database, userID string) error {
+	ctx := context.Background()
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
@@ -727,56 +733,23 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 		return fmt.Errorf("获取用户 %s 的交易员列表失败: %w", userID, err)
 	}
 
-	log.Printf("📋 为用户 %s 加载交易员配置: %d 个", userID, len(traders))
+	managerLog.Infof(ctx, "📋 为用户 %s 加载交易员配置: %d 个", userID, len(traders))
 
 	// 获取系统配置
-	coinPoolURL, _ := database.GetSystemConfig("coin_pool_api_url")
-	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
-	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
-	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
-	// 获取杠杆配置
-	btcEthLeverageStr, _ := database.GetSystemConfig("btc_eth_leverage")
-	altcoinLeverageStr, _ := database.GetSystemConfig("altcoin_leverage")
-
-	// 解析配置
-	maxDailyLoss := 10.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDailyLossStr, 64); err == nil {
-		maxDailyLoss = val
-	}
-
-	maxDrawdown := 20.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDrawdownStr, 64); err == nil {
-		maxDrawdown = val
-	}
-
-	stopTradingMinutes := 60 // 默认值
-	if val, err := strconv.Atoi(stopTradingMinutesStr); err == nil {
-		stopTradingMinutes = val
-	}
-
-	// 解析杠杆配置（默认5倍，适配币安子账户）
-	btcEthLeverage := 5
-	if val, err := strconv.Atoi(btcEthLeverageStr); err == nil && val > 0 {
-		btcEthLeverage = val
-	}
-
-	altcoinLeverage := 5
-	if val, err := strconv.Atoi(altcoinLeverageStr); err == nil && val > 0 {
-		altcoinLeverage = val
-	}
+	sysCfg := loadSystemTraderConfig(database)
 
 	// 为每个交易员获取AI模型和交易所配置
 	for _, traderCfg := range traders {
 		// 检查是否已经加载过这个交易员
 		if _, exists := tm.traders[traderCfg.ID]; exists {
-			log.Printf("⚠️ 交易员 %s 已经加载，跳过", traderCfg.Name)
+			managerLog.Warnf(ctx, "⚠️ 交易员 %s 已经加载，跳过", traderCfg.Name)
 			continue
 		}
 
 		// 获取AI模型配置（使用该用户的配置）
 		aiModels, err := database.GetAIModels(userID)
 		if err != nil {
-			log.Printf("⚠️ 获取用户 %s 的AI模型配置失败: %v", userID, err)
+			managerLog.Warnf(ctx, "⚠️ 获取用户 %s 的AI模型配置失败: %v", userID, err)
 			continue
 		}
 
@@ -789,19 +762,19 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 		}
 
 		if aiModelCfg == nil {
-			log.Printf("⚠️ 交易员 %s 的AI模型 %s 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
+			managerLog.Warnf(ctx, "⚠️ 交易员 %s 的AI模型 %s 不存在，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
 		if !aiModelCfg.Enabled {
-			log.Printf("⚠️ 交易员 %s 的AI模型 %s 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
+			managerLog.Warnf(ctx, "⚠️ 交易员 %s 的AI模型 %s 未启用，跳过", traderCfg.Name, traderCfg.AIModelID)
 			continue
 		}
 
 		// 获取交易所配置（使用该用户的配置）
 		exchanges, err := database.GetExchanges(userID)
 		if err != nil {
-			log.Printf("⚠️ 获取用户 %s 的交易所配置失败: %v", userID, err)
+			managerLog.Warnf(ctx, "⚠️ 获取用户 %s 的交易所配置失败: %v", userID, err)
 			continue
 		}
 
@@ -814,80 +787,76 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 		}
 
 		if exchangeCfg == nil {
-			log.Printf("⚠️ 交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			managerLog.Warnf(ctx, "⚠️ 交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
 		if !exchangeCfg.Enabled {
-			log.Printf("⚠️ 交易员 %s 的交易所 %s 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			managerLog.Warnf(ctx, "⚠️ 交易员 %s 的交易所 %s 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
 			continue
 		}
 
-		// 使用现有的方法加载交易员
-		err = tm.loadSingleTrader(traderCfg, aiModelCfg, exchangeCfg, coinPoolURL, maxDailyLoss, maxDrawdown, stopTradingMinutes, btcEthLeverage, altcoinLeverage)
+		// 添加到TraderManager
+		err = tm.addTraderFromDB(traderCfg, aiModelCfg, exchangeCfg, sysCfg)
 		if err != nil {
-			log.Printf("⚠️ 加载交易员 %s 失败: %v", traderCfg.Name, err)
+			managerLog.Warnf(ctx, "⚠️ 加载交易员 %s 失败: %v", traderCfg.Name, err)
+			continue
 		}
+		tm.applyPromptTemplate(database, traderCfg)
 	}
 
 	return nil
 }
 
-// loadSingleTrader 加载单个交易员（从现有代码提取的公共逻辑）
-func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiModelCfg *config.AIModelConfig, exchangeCfg *config.ExchangeConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, btcEthLeverage, altcoinLeverage int) error {
-	// 构建AutoTraderConfig - 使用 trader 配置中的字段
-	traderConfig := trader.AutoTraderConfig{
-		ID:                    traderCfg.ID,
-		Name:                  traderCfg.Name,
-		AIModel:               aiModelCfg.Provider, // 使用provider作为模型标识
-		Exchange:              exchangeCfg.Type,    // 使用exchange type而不是ID
-		BinanceAPIKey:         "",
-		BinanceSecretKey:      "",
-		HyperliquidPrivateKey: "",
-		HyperliquidTestnet:    exchangeCfg.Testnet, // 从交易所配置读取testnet设置
-		HyperliquidWalletAddr: "",
-		InitialBalance:        traderCfg.InitialBalance,
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		CoinPoolAPIURL:        coinPoolURL,
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:         traderCfg.IsCrossMargin,
-		BTCETHLeverage:        btcEthLeverage,
-		AltcoinLeverage:       altcoinLeverage,
-	}
-
-	// 根据交易所类型设置API密钥
-	if exchangeCfg.Type == "binance" {
-		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
-		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
-	} else if exchangeCfg.Type == "hyperliquid" {
-		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
-		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
-		// HyperliquidTestnet 已经在上面设置了
-	} else if exchangeCfg.Type == "aster" {
-		traderConfig.AsterUser = exchangeCfg.AsterUser
-		traderConfig.AsterSigner = exchangeCfg.AsterSigner
-		traderConfig.AsterPrivateKey = exchangeCfg.AsterPrivateKey
+// applyPromptTemplate 为已加载的交易员设置提示词模板（为空或加载失败时使用默认模板）
+func (tm *TraderManager) applyPromptTemplate(database *config.Database, traderCfg *config.TraderRecord) {
+	ctx := logging.WithAttrs(context.Background(), "trader_id", traderCfg.ID)
+	at, exists := tm.traders[traderCfg.ID]
+	if !exists || traderCfg.PromptTemplate == "" {
+		return
 	}
 
-	// 根据AI模型设置API密钥
-	if aiModelCfg.Provider == "qwen" {
-		traderConfig.QwenKey = aiModelCfg.APIKey
-	} else if aiModelCfg.Provider == "deepseek" {
-		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	tpl, err := ResolvePromptTemplate(database, traderCfg.UserID, traderCfg.PromptTemplate)
+	if err != nil {
+		managerLog.Warnf(ctx, "⚠️ 交易员 %s 的提示词模板加载失败，使用默认模板: %v", traderCfg.Name, err)
+		return
 	}
+	at.SetPromptTemplate(tpl)
+	managerLog.Infof(ctx, "✓ 交易员 %s 使用提示词模板 %s", traderCfg.Name, tpl.Ref())
+}
 
-	// 创建trader实例
-	at, err := trader.NewAutoTrader(traderConfig)
+// ResolvePromptTemplate 按引用（name 或 name@version）查找提示词模板，优先匹配内置模板
+func ResolvePromptTemplate(database *config.Database, userID, ref string) (*decision.PromptTemplate, error) {
+	name, version, err := decision.ParsePromptTemplateRef(ref)
 	if err != nil {
-		return fmt.Errorf("创建trader失败: %w", err)
+		return nil, err
+	}
+	if tpl, ok := decision.GetBuiltinPromptTemplate(name); ok {
+		if version > tpl.Version {
+			return nil, fmt.Errorf("内置模板 %s 不存在版本 %d", name, version)
+		}
+		return tpl, nil
 	}
 
-	// 新版本中自定义prompt功能暂时移除，后续可通过API扩展
-	log.Printf("✓ 使用默认交易策略prompt")
+	record, err := database.GetPromptTemplate(userID, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("提示词模板 %s 不存在: %w", ref, err)
+	}
+	return &decision.PromptTemplate{
+		Name:     record.Name,
+		Version:  record.Version,
+		Language: record.Language,
+		System:   record.SystemTemplate,
+		User:     record.UserTemplate,
+	}, nil
+}
 
-	tm.traders[traderCfg.ID] = at
-	log.Printf("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
-	return nil
+// parseTraderSetting 解析交易员的单项配置（为空或无效时返回nil，使用默认配置）
+func parseTraderSetting[T any, PT config.SettingValue[T]](traderCfg *config.TraderRecord, setting config.TraderSetting) PT {
+	value, err := config.DecodeTraderSetting[T, PT](traderCfg, setting)
+	if err != nil {
+		managerLog.Warnf(logging.WithAttrs(context.Background(), "trader_id", traderCfg.ID), "⚠️ 交易员 %s 的%s无效，使用默认配置: %v", traderCfg.Name, setting.Label(), err)
+		return nil
+	}
+	return value
 }
@@ -0,0 +1,310 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// BinanceProvider Binance USDT合约行情数据源
+// Aster 的行情接口与 Binance fapi 兼容，复用同一实现
+type BinanceProvider struct {
+	name    string
+	baseURL string
+	wsURL   string // 强平推送地址
+}
+
+// NewBinanceProvider 创建Binance行情数据源
+func NewBinanceProvider() *BinanceProvider {
+	return &BinanceProvider{
+		name:    SourceBinance,
+		baseURL: "https://fapi.binance.com",
+		wsURL:   "wss://fstream.binance.com/ws/!forceOrder@arr",
+	}
+}
+
+// NewAsterProvider 创建Aster行情数据源
+func NewAsterProvider() *BinanceProvider {
+	return &BinanceProvider{
+		name:    SourceAster,
+		baseURL: "https://fapi.asterdex.com",
+		wsURL:   "wss://fstream.asterdex.com/ws/!forceOrder@arr",
+	}
+}
+
+// Name 数据源名称
+func (p *BinanceProvider) Name() string {
+	return p.name
+}
+
+// cacheKey 行情缓存键（Binance 与 Aster 共用实现，按接口地址区分）
+func (p *BinanceProvider) cacheKey() string {
+	return p.name + "@" + p.baseURL
+}
+
+// get 发送GET请求并返回响应体
+func (p *BinanceProvider) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s API错误 (status %d): %s", p.name, resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// GetKlines 获取K线数据
+func (p *BinanceProvider) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	body, err := p.get(ctx, fmt.Sprintf("/fapi/v1/klines?symbol=%s&interval=%s&limit=%d", symbol, interval, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var rawData [][]interface{}
+	if err := json.Unmarshal(body, &rawData); err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, len(rawData))
+	for _, item := range rawData {
+		if len(item) < 7 {
+			continue
+		}
+		openTime, _ := item[0].(float64)
+		open, _ := parseFloat(item[1])
+		high, _ := parseFloat(item[2])
+		low, _ := parseFloat(item[3])
+		close, _ := parseFloat(item[4])
+		volume, _ := parseFloat(item[5])
+		closeTime, _ := item[6].(float64)
+
+		klines = append(klines, Kline{
+			OpenTime:  int64(openTime),
+			Open:      open,
+			High:      high,
+			Low:       low,
+			Close:     close,
+			Volume:    volume,
+			CloseTime: int64(closeTime),
+		})
+	}
+
+	return klines, nil
+}
+
+// GetOpenInterest 获取OI数据（平均值和序列来自最近2.5小时的5分钟OI历史）
+func (p *BinanceProvider) GetOpenInterest(ctx context.Context, symbol string) (*OIData, error) {
+	body, err := p.get(ctx, fmt.Sprintf("/fapi/v1/openInterest?symbol=%s", symbol))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		OpenInterest string `json:"openInterest"`
+		Symbol       string `json:"symbol"`
+		Time         int64  `json:"time"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	oi, _ := strconv.ParseFloat(result.OpenInterest, 64)
+	data := &OIData{Latest: oi, Average: oi}
+
+	// OI历史（失败时只返回最新值）
+	if v, err := featureCache.getOrLoad(p.name+":"+symbol+":oi_hist", featureCacheTTL, func() (interface{}, error) {
+		return p.getOpenInterestHistory(ctx, symbol, 30)
+	}); err == nil {
+		history := v.([]float64)
+		if len(history) > 0 {
+			sum := 0.0
+			for _, h := range history {
+				sum += h
+			}
+			data.Average = sum / float64(len(history))
+			data.History = history
+		}
+	}
+
+	return data, nil
+}
+
+// getOpenInterestHistory 获取5分钟间隔的OI历史（旧→新）
+func (p *BinanceProvider) getOpenInterestHistory(ctx context.Context, symbol string, limit int) ([]float64, error) {
+	body, err := p.get(ctx, fmt.Sprintf("/futures/data/openInterestHist?symbol=%s&period=5m&limit=%d", symbol, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		SumOpenInterest string `json:"sumOpenInterest"`
+		Timestamp       int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, err
+	}
+
+	history := make([]float64, 0, len(rows))
+	for _, row := range rows {
+		v, _ := strconv.ParseFloat(row.SumOpenInterest, 64)
+		history = append(history, v)
+	}
+	return history, nil
+}
+
+// GetFundingRate 获取资金费率
+func (p *BinanceProvider) GetFundingRate(ctx context.Context, symbol string) (float64, error) {
+	body, err := p.get(ctx, fmt.Sprintf("/fapi/v1/premiumIndex?symbol=%s", symbol))
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Symbol          string `json:"symbol"`
+		MarkPrice       string `json:"markPrice"`
+		IndexPrice      string `json:"indexPrice"`
+		LastFundingRate string `json:"lastFundingRate"`
+		NextFundingTime int64  `json:"nextFundingTime"`
+		InterestRate    string `json:"interestRate"`
+		Time            int64  `json:"time"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
+	return rate, nil
+}
+
+// GetDepth 获取盘口深度
+func (p *BinanceProvider) GetDepth(ctx context.Context, symbol string, levels int) (*DepthData, error) {
+	body, err := p.get(ctx, fmt.Sprintf("/fapi/v1/depth?symbol=%s&limit=%d", symbol, levels))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	depth := computeDepth(parseBookLevels(result.Bids), parseBookLevels(result.Asks), levels)
+	if depth == nil {
+		return nil, fmt.Errorf("%s 盘口为空", symbol)
+	}
+	return depth, nil
+}
+
+// parseBookLevels 解析 [["价格","数量"], ...] 格式的盘口
+func parseBookLevels(raw [][]string) [][2]float64 {
+	levels := make([][2]float64, 0, len(raw))
+	for _, lv := range raw {
+		if len(lv) < 2 {
+			continue
+		}
+		price, _ := strconv.ParseFloat(lv[0], 64)
+		qty, _ := strconv.ParseFloat(lv[1], 64)
+		levels = append(levels, [2]float64{price, qty})
+	}
+	return levels
+}
+
+// GetTakerVolume 获取最近1小时的主动买卖量（5分钟 × 12）
+func (p *BinanceProvider) GetTakerVolume(ctx context.Context, symbol string) (*TakerVolumeData, error) {
+	body, err := p.get(ctx, fmt.Sprintf("/futures/data/takerlongshortRatio?symbol=%s&period=5m&limit=12", symbol))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		BuyVol  string `json:"buyVol"`
+		SellVol string `json:"sellVol"`
+	}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s 无主动买卖量数据", symbol)
+	}
+
+	data := &TakerVolumeData{Window: "1h"}
+	for _, row := range rows {
+		buy, _ := strconv.ParseFloat(row.BuyVol, 64)
+		sell, _ := strconv.ParseFloat(row.SellVol, 64)
+		data.BuyVolume += buy
+		data.SellVolume += sell
+	}
+	if data.SellVolume > 0 {
+		data.BuySellRatio = data.BuyVolume / data.SellVolume
+	}
+	return data, nil
+}
+
+// GetLongShortRatios 获取全市场和大户多空账户比
+func (p *BinanceProvider) GetLongShortRatios(ctx context.Context, symbol string) (*LongShortRatio, *LongShortRatio, error) {
+	global, err := p.getLongShortRatio(ctx, "/futures/data/globalLongShortAccountRatio", symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	top, err := p.getLongShortRatio(ctx, "/futures/data/topLongShortAccountRatio", symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	return global, top, nil
+}
+
+func (p *BinanceProvider) getLongShortRatio(ctx context.Context, path, symbol string) (*LongShortRatio, error) {
+	body, err := p.get(ctx, fmt.Sprintf("%s?symbol=%s&period=5m&limit=1", path, symbol))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		LongShortRatio string `json:"longShortRatio"`
+		LongAccount    string `json:"longAccount"`
+		ShortAccount   string `json:"shortAccount"`
+	}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s 无多空比数据", symbol)
+	}
+
+	row := rows[len(rows)-1]
+	ratio := &LongShortRatio{}
+	ratio.Ratio, _ = strconv.ParseFloat(row.LongShortRatio, 64)
+	ratio.LongAccount, _ = strconv.ParseFloat(row.LongAccount, 64)
+	ratio.ShortAccount, _ = strconv.ParseFloat(row.ShortAccount, 64)
+	return ratio, nil
+}
+
+// GetLiquidations 获取最近的强平统计（来自实时推送）
+func (p *BinanceProvider) GetLiquidations(symbol string, window time.Duration) (*LiquidationData, error) {
+	if p.wsURL == "" {
+		return nil, ErrFeatureNotSupported
+	}
+	return getLiquidationStream(p.wsURL).summary(symbol, window), nil
+}
@@ -0,0 +1,251 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// BybitProvider Bybit USDT永续行情数据源（v5 公共接口）
+type BybitProvider struct {
+	baseURL string
+}
+
+// NewBybitProvider 创建Bybit行情数据源
+func NewBybitProvider(testnet bool) *BybitProvider {
+	baseURL := "https://api.bybit.com"
+	if testnet {
+		baseURL = "https://api-testnet.bybit.com"
+	}
+	return &BybitProvider{baseURL: baseURL}
+}
+
+// Name 数据源名称
+func (p *BybitProvider) Name() string {
+	return SourceBybit
+}
+
+// cacheKey 行情缓存键（主网与测试网分开缓存）
+func (p *BybitProvider) cacheKey() string {
+	return SourceBybit + "@" + p.baseURL
+}
+
+// get 调用公共接口并返回 result 字段
+func (p *BybitProvider) get(ctx context.Context, path string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("bybit API错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析Bybit响应失败: %w", err)
+	}
+	if result.RetCode != 0 {
+		return nil, fmt.Errorf("bybit API错误 (retCode %d): %s", result.RetCode, result.RetMsg)
+	}
+	return result.Result, nil
+}
+
+// toBybitInterval 将 3m/4h/1d 格式的K线周期转换为 Bybit 格式（分钟数或 D/W）
+func toBybitInterval(interval string) (string, time.Duration, error) {
+	step, err := intervalDuration(interval)
+	if err != nil {
+		return "", 0, err
+	}
+	switch {
+	case step == 7*24*time.Hour:
+		return "W", step, nil
+	case step == 24*time.Hour:
+		return "D", step, nil
+	case step < 24*time.Hour:
+		return strconv.Itoa(int(step / time.Minute)), step, nil
+	default:
+		return "", 0, fmt.Errorf("bybit 不支持的K线周期: %s", interval)
+	}
+}
+
+// GetKlines 获取K线数据（接口按时间倒序返回，这里转为升序）
+func (p *BybitProvider) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	bybitInterval, step, err := toBybitInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.get(ctx, fmt.Sprintf("/v5/market/kline?category=linear&symbol=%s&interval=%s&limit=%d", symbol, bybitInterval, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		List [][]string `json:"list"` // [startTime, open, high, low, close, volume, turnover]
+	}
+	if err := json.Unmarshal(result, &data); err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, len(data.List))
+	for i := len(data.List) - 1; i >= 0; i-- {
+		item := data.List[i]
+		if len(item) < 6 {
+			continue
+		}
+		openTime, _ := strconv.ParseInt(item[0], 10, 64)
+		open, _ := strconv.ParseFloat(item[1], 64)
+		high, _ := strconv.ParseFloat(item[2], 64)
+		low, _ := strconv.ParseFloat(item[3], 64)
+		close, _ := strconv.ParseFloat(item[4], 64)
+		volume, _ := strconv.ParseFloat(item[5], 64)
+
+		klines = append(klines, Kline{
+			OpenTime:  openTime,
+			Open:      open,
+			High:      high,
+			Low:       low,
+			Close:     close,
+			Volume:    volume,
+			CloseTime: openTime + step.Milliseconds() - 1,
+		})
+	}
+	return klines, nil
+}
+
+// GetOpenInterest 获取OI数据（平均值和序列来自最近2.5小时的5分钟OI历史）
+func (p *BybitProvider) GetOpenInterest(ctx context.Context, symbol string) (*OIData, error) {
+	result, err := p.get(ctx, fmt.Sprintf("/v5/market/open-interest?category=linear&symbol=%s&intervalTime=5min&limit=30", symbol))
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		List []struct {
+			OpenInterest string `json:"openInterest"`
+			Timestamp    string `json:"timestamp"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(result, &data); err != nil {
+		return nil, err
+	}
+	if len(data.List) == 0 {
+		return nil, fmt.Errorf("%s 无持仓量数据", symbol)
+	}
+
+	// 按时间倒序返回，第一条为最新值
+	history := make([]float64, 0, len(data.List))
+	sum := 0.0
+	for i := len(data.List) - 1; i >= 0; i-- {
+		v, _ := strconv.ParseFloat(data.List[i].OpenInterest, 64)
+		history = append(history, v)
+		sum += v
+	}
+	return &OIData{
+		Latest:  history[len(history)-1],
+		Average: sum / float64(len(history)),
+		History: history,
+	}, nil
+}
+
+// GetFundingRate 获取当前资金费率
+func (p *BybitProvider) GetFundingRate(ctx context.Context, symbol string) (float64, error) {
+	result, err := p.get(ctx, fmt.Sprintf("/v5/market/tickers?category=linear&symbol=%s", symbol))
+	if err != nil {
+		return 0, err
+	}
+
+	var data struct {
+		List []struct {
+			FundingRate string `json:"fundingRate"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(result, &data); err != nil {
+		return 0, err
+	}
+	if len(data.List) == 0 {
+		return 0, fmt.Errorf("%s 无资金费率数据", symbol)
+	}
+
+	rate, _ := strconv.ParseFloat(data.List[0].FundingRate, 64)
+	return rate, nil
+}
+
+// GetDepth 获取盘口深度
+func (p *BybitProvider) GetDepth(ctx context.Context, symbol string, levels int) (*DepthData, error) {
+	result, err := p.get(ctx, fmt.Sprintf("/v5/market/orderbook?category=linear&symbol=%s&limit=%d", symbol, levels))
+	if err != nil {
+		return nil, err
+	}
+
+	var book struct {
+		Bids [][]string `json:"b"`
+		Asks [][]string `json:"a"`
+	}
+	if err := json.Unmarshal(result, &book); err != nil {
+		return nil, err
+	}
+
+	depth := computeDepth(parseBookLevels(book.Bids), parseBookLevels(book.Asks), levels)
+	if depth == nil {
+		return nil, fmt.Errorf("%s 盘口为空", symbol)
+	}
+	return depth, nil
+}
+
+// GetTakerVolume Bybit 未提供主动买卖量统计接口
+func (p *BybitProvider) GetTakerVolume(ctx context.Context, symbol string) (*TakerVolumeData, error) {
+	return nil, ErrFeatureNotSupported
+}
+
+// GetLongShortRatios 获取全市场多空账户比（Bybit 不提供大户多空比）
+func (p *BybitProvider) GetLongShortRatios(ctx context.Context, symbol string) (*LongShortRatio, *LongShortRatio, error) {
+	result, err := p.get(ctx, fmt.Sprintf("/v5/market/account-ratio?category=linear&symbol=%s&period=5min&limit=1", symbol))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var data struct {
+		List []struct {
+			BuyRatio  string `json:"buyRatio"`
+			SellRatio string `json:"sellRatio"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(result, &data); err != nil {
+		return nil, nil, err
+	}
+	if len(data.List) == 0 {
+		return nil, nil, fmt.Errorf("%s 无多空比数据", symbol)
+	}
+
+	ratio := &LongShortRatio{}
+	ratio.LongAccount, _ = strconv.ParseFloat(data.List[0].BuyRatio, 64)
+	ratio.ShortAccount, _ = strconv.ParseFloat(data.List[0].SellRatio, 64)
+	if ratio.ShortAccount > 0 {
+		ratio.Ratio = ratio.LongAccount / ratio.ShortAccount
+	}
+	return ratio, nil, nil
+}
+
+// GetLiquidations Bybit 暂不支持
+func (p *BybitProvider) GetLiquidations(symbol string, window time.Duration) (*LiquidationData, error) {
+	return nil, ErrFeatureNotSupported
+}
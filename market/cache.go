@@ -0,0 +1,60 @@
+package market
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache 简单的带过期时间的缓存（多个交易员共享同一数据源的请求结果）
+type ttlCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// featureCache 微观结构数据共享缓存
+var featureCache = &ttlCache{entries: make(map[string]cacheEntry)}
+
+// getOrLoad 读取缓存，过期或不存在时调用 load 并缓存结果（出错不缓存）
+func (c *ttlCache) getOrLoad(key string, ttl time.Duration, load func() (interface{}, error)) (interface{}, error) {
+	if value, ok := c.get(key); ok {
+		return value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, value, ttl)
+	return value, nil
+}
+
+// get 读取未过期的缓存
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && time.Now().Before(e.expires) {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// set 写入缓存
+func (c *ttlCache) set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.entries[key] = cacheEntry{value: value, expires: now.Add(ttl)}
+	// 顺便清理过期条目，避免币种轮换后缓存无限增长
+	if len(c.entries) > 2048 {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+}
@@ -1,18 +1,23 @@
 package market
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"math"
-	"net/http"
+	"nofx/metrics"
+	"nofx/tracing"
 	"strconv"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Data 市场数据结构
 type Data struct {
 	Symbol            string
+	Source            string // 行情数据来源（如 binance, hyperliquid, aster）
 	CurrentPrice      float64
 	PriceChange1h     float64 // 1小时价格变化百分比
 	PriceChange4h     float64 // 4小时价格变化百分比
@@ -23,12 +28,20 @@ type Data struct {
 	FundingRate       float64
 	IntradaySeries    *IntradayData
 	LongerTermContext *LongerTermData
+
+	// 盘口深度、主动买卖量、多空比、强平等微观结构数据（数据源支持时提供）
+	Microstructure *MicrostructureData
+
+	// 按交易员指标配置计算的多时间框架数据（见 GetWithSpec）
+	Timeframes []*TimeframeData
+	Formatter  string // 指定的输出格式（为空时使用默认格式）
 }
 
 // OIData Open Interest数据
 type OIData struct {
 	Latest  float64
 	Average float64
+	History []float64 // OI历史序列（旧→新，数据源支持时提供）
 }
 
 // IntradayData 日内数据(3分钟间隔)
@@ -63,22 +76,52 @@ type Kline struct {
 	CloseTime int64
 }
 
-// Get 获取指定代币的市场数据
-func Get(symbol string) (*Data, error) {
+// startGetSpan 为一次币种行情获取开始 span
+func startGetSpan(ctx context.Context, provider Provider, symbol string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "market.get",
+		attribute.String("symbol", symbol),
+		attribute.String("provider", provider.Name()),
+	)
+}
+
+// recordFetchFailure 记录行情数据获取失败（kind: klines/open_interest/funding_rate/depth 等）
+func recordFetchFailure(provider Provider, kind string) {
+	metrics.MarketFetchFailures.WithLabelValues(provider.Name(), kind).Inc()
+}
+
+// Get 获取指定代币的市场数据（使用默认数据源 Binance）
+func Get(ctx context.Context, symbol string) (*Data, error) {
+	return GetWithProvider(ctx, DefaultProvider(), symbol)
+}
+
+// GetWithProvider 从指定数据源获取代币的市场数据
+func GetWithProvider(ctx context.Context, provider Provider, symbol string) (_ *Data, err error) {
+	if provider == nil {
+		provider = DefaultProvider()
+	}
+
 	// 标准化symbol
 	symbol = Normalize(symbol)
 
+	ctx, span := startGetSpan(ctx, provider, symbol)
+	defer func() { tracing.End(span, err) }()
+
 	// 获取3分钟K线数据 (最近10个)
-	klines3m, err := getKlines(symbol, "3m", 40) // 多获取一些用于计算
+	klines3m, err := provider.GetKlines(ctx, symbol, "3m", 40) // 多获取一些用于计算
 	if err != nil {
+		recordFetchFailure(provider, "klines")
 		return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
 	}
 
 	// 获取4小时K线数据 (最近10个)
-	klines4h, err := getKlines(symbol, "4h", 60) // 多获取用于计算指标
+	klines4h, err := provider.GetKlines(ctx, symbol, "4h", 60) // 多获取用于计算指标
 	if err != nil {
+		recordFetchFailure(provider, "klines")
 		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
 	}
+	if len(klines3m) == 0 || len(klines4h) == 0 {
+		return nil, fmt.Errorf("%s 在 %s 无K线数据", symbol, provider.Name())
+	}
 
 	// 计算当前指标 (基于3分钟最新数据)
 	currentPrice := klines3m[len(klines3m)-1].Close
@@ -106,14 +149,18 @@ func Get(symbol string) (*Data, error) {
 	}
 
 	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
+	oiData, err := provider.GetOpenInterest(ctx, symbol)
 	if err != nil {
 		// OI失败不影响整体,使用默认值
+		recordFetchFailure(provider, "open_interest")
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
 	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	fundingRate, err := provider.GetFundingRate(ctx, symbol)
+	if err != nil {
+		recordFetchFailure(provider, "funding_rate")
+	}
 
 	// 计算日内系列数据
 	intradayData := calculateIntradaySeries(klines3m)
@@ -121,8 +168,9 @@ func Get(symbol string) (*Data, error) {
 	// 计算长期数据
 	longerTermData := calculateLongerTermData(klines4h)
 
-	return &Data{
+	data := &Data{
 		Symbol:            symbol,
+		Source:            provider.Name(),
 		CurrentPrice:      currentPrice,
 		PriceChange1h:     priceChange1h,
 		PriceChange4h:     priceChange4h,
@@ -133,52 +181,10 @@ func Get(symbol string) (*Data, error) {
 		FundingRate:       fundingRate,
 		IntradaySeries:    intradayData,
 		LongerTermContext: longerTermData,
-	}, nil
-}
-
-// getKlines 从Binance获取K线数据
-func getKlines(symbol, interval string, limit int) ([]Kline, error) {
-	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&limit=%d",
-		symbol, interval, limit)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var rawData [][]interface{}
-	if err := json.Unmarshal(body, &rawData); err != nil {
-		return nil, err
-	}
-
-	klines := make([]Kline, len(rawData))
-	for i, item := range rawData {
-		openTime := int64(item[0].(float64))
-		open, _ := parseFloat(item[1])
-		high, _ := parseFloat(item[2])
-		low, _ := parseFloat(item[3])
-		close, _ := parseFloat(item[4])
-		volume, _ := parseFloat(item[5])
-		closeTime := int64(item[6].(float64))
-
-		klines[i] = Kline{
-			OpenTime:  openTime,
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     close,
-			Volume:    volume,
-			CloseTime: closeTime,
-		}
 	}
+	attachMicrostructure(ctx, provider, data)
 
-	return klines, nil
+	return data, nil
 }
 
 // calculateEMA 计算EMA
@@ -261,6 +267,32 @@ func calculateRSI(klines []Kline, period int) float64 {
 	return rsi
 }
 
+// ATR 用于止损距离校验的ATR及其周期（优先4小时ATR14，自定义指标配置时取最长周期的ATR，无数据返回0）
+func (d *Data) ATR() (float64, string) {
+	if d.LongerTermContext != nil && d.LongerTermContext.ATR14 > 0 {
+		return d.LongerTermContext.ATR14, "4h"
+	}
+
+	var atr float64
+	var interval string
+	var longest time.Duration
+	for _, tf := range d.Timeframes {
+		step, err := intervalDuration(tf.Interval)
+		if err != nil || step <= longest {
+			continue
+		}
+		for _, ind := range tf.Indicators {
+			if strings.HasPrefix(ind.Name, "ATR") {
+				if v := ind.Latest(); v > 0 {
+					atr, interval, longest = v, tf.Interval, step
+				}
+				break
+			}
+		}
+	}
+	return atr, interval
+}
+
 // calculateATR 计算ATR
 func calculateATR(klines []Kline, period int) float64 {
 	if len(klines) <= period {
@@ -386,74 +418,13 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 	return data
 }
 
-// getOpenInterestData 获取OI数据
-func getOpenInterestData(symbol string) (*OIData, error) {
-	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/openInterest?symbol=%s", symbol)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var result struct {
-		OpenInterest string `json:"openInterest"`
-		Symbol       string `json:"symbol"`
-		Time         int64  `json:"time"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
-	oi, _ := strconv.ParseFloat(result.OpenInterest, 64)
-
-	return &OIData{
-		Latest:  oi,
-		Average: oi * 0.999, // 近似平均值
-	}, nil
-}
-
-// getFundingRate 获取资金费率
-func getFundingRate(symbol string) (float64, error) {
-	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
-
-	var result struct {
-		Symbol          string `json:"symbol"`
-		MarkPrice       string `json:"markPrice"`
-		IndexPrice      string `json:"indexPrice"`
-		LastFundingRate string `json:"lastFundingRate"`
-		NextFundingTime int64  `json:"nextFundingTime"`
-		InterestRate    string `json:"interestRate"`
-		Time            int64  `json:"time"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, err
-	}
-
-	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
-	return rate, nil
+// Format 格式化输出市场数据（使用数据中指定的输出格式）
+func Format(data *Data) string {
+	return FormatWith(data.Formatter, data)
 }
 
-// Format 格式化输出市场数据
-func Format(data *Data) string {
+// formatDefault 默认文本格式
+func formatDefault(data *Data) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("current_price = %.2f, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
@@ -462,13 +433,24 @@ func Format(data *Data) string {
 	sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
 		data.Symbol))
 
+	if data.Source != "" {
+		sb.WriteString(fmt.Sprintf("Data source: %s\n\n", data.Source))
+	}
+
 	if data.OpenInterest != nil {
 		sb.WriteString(fmt.Sprintf("Open Interest: Latest: %.2f Average: %.2f\n\n",
 			data.OpenInterest.Latest, data.OpenInterest.Average))
+		if len(data.OpenInterest.History) > 0 {
+			sb.WriteString(fmt.Sprintf("Open Interest history (oldest → latest): %s\n\n", formatFloatSlice(data.OpenInterest.History)))
+		}
 	}
 
 	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
 
+	if data.Microstructure != nil {
+		sb.WriteString(formatMicrostructure(data.Microstructure))
+	}
+
 	if data.IntradaySeries != nil {
 		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
 
@@ -514,6 +496,14 @@ func Format(data *Data) string {
 		}
 	}
 
+	for _, tf := range data.Timeframes {
+		sb.WriteString(fmt.Sprintf("Series (%s timeframe, oldest → latest):\n\n", tf.Interval))
+		sb.WriteString(fmt.Sprintf("Close prices: %s\n\n", formatFloatSlice(tf.Prices)))
+		for _, ind := range tf.Indicators {
+			sb.WriteString(fmt.Sprintf("%s: %s\n\n", ind.Name, formatFloatSlice(ind.Values)))
+		}
+	}
+
 	return sb.String()
 }
 
@@ -0,0 +1,183 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrFeatureNotSupported 数据源不支持该数据
+var ErrFeatureNotSupported = errors.New("数据源不支持该数据")
+
+// FeatureProvider 可选的市场微观结构数据接口（数据源实现后自动附加到 Data）
+type FeatureProvider interface {
+	// GetDepth 获取盘口前N档深度
+	GetDepth(ctx context.Context, symbol string, levels int) (*DepthData, error)
+	// GetTakerVolume 获取最近的主动买卖成交量
+	GetTakerVolume(ctx context.Context, symbol string) (*TakerVolumeData, error)
+	// GetLongShortRatios 获取全市场和大户的多空账户比
+	GetLongShortRatios(ctx context.Context, symbol string) (global *LongShortRatio, topTrader *LongShortRatio, err error)
+	// GetLiquidations 获取最近一段时间的强平数据
+	GetLiquidations(symbol string, window time.Duration) (*LiquidationData, error)
+}
+
+// MicrostructureData 市场微观结构数据
+type MicrostructureData struct {
+	Depth              *DepthData       `json:"depth,omitempty"`
+	TakerVolume        *TakerVolumeData `json:"taker_volume,omitempty"`
+	GlobalLongShort    *LongShortRatio  `json:"global_long_short,omitempty"`
+	TopTraderLongShort *LongShortRatio  `json:"top_trader_long_short,omitempty"`
+	Liquidations       *LiquidationData `json:"liquidations,omitempty"`
+}
+
+// DepthData 盘口深度
+type DepthData struct {
+	Levels    int     `json:"levels"`     // 统计档位数
+	BidVolume float64 `json:"bid_volume"` // 买盘前N档数量（币）
+	AskVolume float64 `json:"ask_volume"` // 卖盘前N档数量（币）
+	Imbalance float64 `json:"imbalance"`  // 买卖失衡度 (bid-ask)/(bid+ask)，范围 -1~1，正数买盘更厚
+	BestBid   float64 `json:"best_bid"`
+	BestAsk   float64 `json:"best_ask"`
+	SpreadBps float64 `json:"spread_bps"` // 买卖价差（基点）
+}
+
+// TakerVolumeData 主动买卖成交量
+type TakerVolumeData struct {
+	Window       string  `json:"window"`         // 统计区间，如 "1h"
+	BuyVolume    float64 `json:"buy_volume"`     // 主动买入量（币）
+	SellVolume   float64 `json:"sell_volume"`    // 主动卖出量（币）
+	BuySellRatio float64 `json:"buy_sell_ratio"` // 主动买卖比
+}
+
+// LongShortRatio 多空账户比
+type LongShortRatio struct {
+	Ratio        float64 `json:"ratio"`         // 多空比
+	LongAccount  float64 `json:"long_account"`  // 多头账户占比
+	ShortAccount float64 `json:"short_account"` // 空头账户占比
+}
+
+// LiquidationData 强平统计
+type LiquidationData struct {
+	Window        string  `json:"window"`                   // 统计区间，如 "1h"
+	LongUSD       float64 `json:"long_usd"`                 // 多头被强平金额（USD）
+	ShortUSD      float64 `json:"short_usd"`                // 空头被强平金额（USD）
+	Count         int     `json:"count"`                    // 强平笔数
+	CollectingFor string  `json:"collecting_for,omitempty"` // 采集时长不足统计区间时的实际采集时长
+}
+
+const (
+	depthLevels       = 20
+	depthCacheTTL     = 5 * time.Second
+	featureCacheTTL   = time.Minute
+	liquidationWindow = time.Hour
+)
+
+// attachMicrostructure 获取微观结构数据并附加到 Data（单项失败不影响整体）
+func attachMicrostructure(ctx context.Context, provider Provider, data *Data) {
+	fp, ok := provider.(FeatureProvider)
+	if !ok {
+		return
+	}
+
+	prefix := provider.Name() + ":" + data.Symbol
+	ms := &MicrostructureData{}
+
+	if v, err := featureCache.getOrLoad(prefix+":depth", depthCacheTTL, func() (interface{}, error) {
+		return fp.GetDepth(ctx, data.Symbol, depthLevels)
+	}); err == nil {
+		ms.Depth = v.(*DepthData)
+	} else {
+		recordFeatureFailure(provider, "depth", err)
+	}
+
+	if v, err := featureCache.getOrLoad(prefix+":taker", featureCacheTTL, func() (interface{}, error) {
+		return fp.GetTakerVolume(ctx, data.Symbol)
+	}); err == nil {
+		ms.TakerVolume = v.(*TakerVolumeData)
+	} else {
+		recordFeatureFailure(provider, "taker_volume", err)
+	}
+
+	if v, err := featureCache.getOrLoad(prefix+":ratio", featureCacheTTL, func() (interface{}, error) {
+		global, top, err := fp.GetLongShortRatios(ctx, data.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		return [2]*LongShortRatio{global, top}, nil
+	}); err == nil {
+		ratios := v.([2]*LongShortRatio)
+		ms.GlobalLongShort, ms.TopTraderLongShort = ratios[0], ratios[1]
+	} else {
+		recordFeatureFailure(provider, "long_short_ratio", err)
+	}
+
+	// 强平数据来自本地订阅的实时流，无需缓存
+	if liq, err := fp.GetLiquidations(data.Symbol, liquidationWindow); err == nil {
+		ms.Liquidations = liq
+	}
+
+	if ms.Depth != nil || ms.TakerVolume != nil || ms.GlobalLongShort != nil || ms.TopTraderLongShort != nil || ms.Liquidations != nil {
+		data.Microstructure = ms
+	}
+}
+
+// recordFeatureFailure 记录微观结构数据获取失败（数据源不支持的数据不计入）
+func recordFeatureFailure(provider Provider, kind string, err error) {
+	if !errors.Is(err, ErrFeatureNotSupported) {
+		recordFetchFailure(provider, kind)
+	}
+}
+
+// computeDepth 根据买卖盘计算深度特征（bids/asks 为 [价格, 数量]，按优先级排序）
+func computeDepth(bids, asks [][2]float64, levels int) *DepthData {
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil
+	}
+	depth := &DepthData{Levels: levels, BestBid: bids[0][0], BestAsk: asks[0][0]}
+	for i := 0; i < levels && i < len(bids); i++ {
+		depth.BidVolume += bids[i][1]
+	}
+	for i := 0; i < levels && i < len(asks); i++ {
+		depth.AskVolume += asks[i][1]
+	}
+	if total := depth.BidVolume + depth.AskVolume; total > 0 {
+		depth.Imbalance = (depth.BidVolume - depth.AskVolume) / total
+	}
+	if mid := (depth.BestBid + depth.BestAsk) / 2; mid > 0 {
+		depth.SpreadBps = (depth.BestAsk - depth.BestBid) / mid * 10000
+	}
+	return depth
+}
+
+// formatMicrostructure 输出微观结构数据文本
+func formatMicrostructure(ms *MicrostructureData) string {
+	var sb strings.Builder
+	sb.WriteString("Market microstructure:\n\n")
+	if d := ms.Depth; d != nil {
+		sb.WriteString(fmt.Sprintf("Order book (top %d levels): bid qty %.3f vs ask qty %.3f, imbalance %+.3f, spread %.2f bps\n\n",
+			d.Levels, d.BidVolume, d.AskVolume, d.Imbalance, d.SpreadBps))
+	}
+	if t := ms.TakerVolume; t != nil {
+		sb.WriteString(fmt.Sprintf("Taker volume (last %s): buy %.3f vs sell %.3f, buy/sell ratio %.3f\n\n",
+			t.Window, t.BuyVolume, t.SellVolume, t.BuySellRatio))
+	}
+	if r := ms.GlobalLongShort; r != nil {
+		sb.WriteString(fmt.Sprintf("Global long/short account ratio: %.3f (long %.1f%% / short %.1f%%)\n\n",
+			r.Ratio, r.LongAccount*100, r.ShortAccount*100))
+	}
+	if r := ms.TopTraderLongShort; r != nil {
+		sb.WriteString(fmt.Sprintf("Top trader long/short account ratio: %.3f (long %.1f%% / short %.1f%%)\n\n",
+			r.Ratio, r.LongAccount*100, r.ShortAccount*100))
+	}
+	if l := ms.Liquidations; l != nil {
+		window := l.Window
+		if l.CollectingFor != "" {
+			window = l.CollectingFor
+		}
+		sb.WriteString(fmt.Sprintf("Liquidations (last %s): longs %.0f USD, shorts %.0f USD, %d orders\n\n",
+			window, l.LongUSD, l.ShortUSD, l.Count))
+	}
+	return sb.String()
+}
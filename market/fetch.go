@@ -0,0 +1,221 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"nofx/metrics"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// FetchOptions 批量获取行情数据的配置
+type FetchOptions struct {
+	Concurrency int           // 同时获取的币种数
+	Timeout     time.Duration // 单个币种（含K线/OI/资金费率等全部请求）的超时
+	CacheTTL    time.Duration // 结果在交易员之间共享的时长（0 表示不缓存）
+}
+
+// 批量获取默认值（可通过环境变量 MARKET_FETCH_CONCURRENCY / MARKET_FETCH_TIMEOUT / MARKET_CACHE_TTL 覆盖）
+const (
+	defaultFetchConcurrency = 8
+	defaultFetchTimeout     = 20 * time.Second
+	defaultDataCacheTTL     = 30 * time.Second
+)
+
+// DefaultFetchOptions 默认批量获取配置
+func DefaultFetchOptions() FetchOptions {
+	opts := FetchOptions{
+		Concurrency: defaultFetchConcurrency,
+		Timeout:     defaultFetchTimeout,
+		CacheTTL:    defaultDataCacheTTL,
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("MARKET_FETCH_CONCURRENCY"))); err == nil && v > 0 {
+		opts.Concurrency = v
+	}
+	if v, err := time.ParseDuration(strings.TrimSpace(os.Getenv("MARKET_FETCH_TIMEOUT"))); err == nil && v > 0 {
+		opts.Timeout = v
+	}
+	if v, err := time.ParseDuration(strings.TrimSpace(os.Getenv("MARKET_CACHE_TTL"))); err == nil && v >= 0 {
+		opts.CacheTTL = v
+	}
+	return opts
+}
+
+// dataCache 按 数据源+币种+指标配置 缓存的行情数据（多个交易员同一周期窗口内共享）
+var (
+	dataCache  = &ttlCache{entries: make(map[string]cacheEntry)}
+	dataFlight singleflight.Group
+)
+
+// panicError 工作 goroutine 中恢复的 panic，在调用方 goroutine 中重新抛出，
+// 由交易员主循环转为错误退出并交给监督器重启（否则会直接终止整个进程）
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverPanic 将 recover() 的结果包装为 panicError 并记录堆栈（没有 panic 时返回 nil）
+func recoverPanic(r interface{}) *panicError {
+	if r == nil {
+		return nil
+	}
+	if pe, ok := r.(*panicError); ok {
+		return pe
+	}
+	pe := &panicError{value: r, stack: debug.Stack()}
+	marketLog.Errorf(context.Background(), "❌ 获取行情数据panic: %v\n%s", pe.value, pe.stack)
+	return pe
+}
+
+// GetMany 以有限并发获取多个币种的行情数据
+// 返回成功获取的数据和失败币种的错误；单个币种失败不影响其他币种，获取过程中的 panic 在调用方重新抛出
+func GetMany(ctx context.Context, provider Provider, symbols []string, spec *IndicatorSpec, opts FetchOptions) (map[string]*Data, map[string]error) {
+	if provider == nil {
+		provider = DefaultProvider()
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = make(map[string]*Data, len(symbols))
+		errs     = make(map[string]error)
+		sem      = make(chan struct{}, opts.Concurrency)
+		panicked *panicError
+	)
+	for _, symbol := range symbols {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs[symbol] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(symbol string) {
+			defer func() {
+				if pe := recoverPanic(recover()); pe != nil {
+					mu.Lock()
+					if panicked == nil {
+						panicked = pe
+					}
+					mu.Unlock()
+				}
+				<-sem
+				wg.Done()
+			}()
+			data, err := GetShared(ctx, provider, symbol, spec, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[symbol] = err
+				return
+			}
+			results[symbol] = data
+		}(symbol)
+	}
+	wg.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+	return results, errs
+}
+
+// GetShared 获取单个币种的行情数据：优先使用缓存，多个交易员同时请求同一币种时只发起一次请求
+// 请求不随 ctx 取消而中断（其他交易员可能在等待同一结果），但受 opts.Timeout 限制；ctx 取消时调用方立即返回
+func GetShared(ctx context.Context, provider Provider, symbol string, spec *IndicatorSpec, opts FetchOptions) (*Data, error) {
+	if provider == nil {
+		provider = DefaultProvider()
+	}
+	symbol = Normalize(symbol)
+	key := providerKey(provider) + "|" + symbol + "|" + specKey(spec)
+
+	if opts.CacheTTL > 0 {
+		if v, ok := dataCache.get(key); ok {
+			metrics.MarketDataRequests.WithLabelValues(provider.Name(), "cache_hit").Inc()
+			return v.(*Data), nil
+		}
+	}
+
+	loaded := false
+	ch := dataFlight.DoChan(key, func() (v interface{}, err error) {
+		// singleflight 会在新 goroutine 中重新抛出 panic，这里转为错误交给调用方处理
+		defer func() {
+			if pe := recoverPanic(recover()); pe != nil {
+				err = pe
+			}
+		}()
+		loaded = true
+		fetchCtx := context.WithoutCancel(ctx)
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithTimeout(fetchCtx, opts.Timeout)
+			defer cancel()
+		}
+		data, err := GetWithSpec(fetchCtx, provider, symbol, spec)
+		if err != nil {
+			return nil, err
+		}
+		if opts.CacheTTL > 0 {
+			dataCache.set(key, data, opts.CacheTTL)
+		}
+		return data, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		result := "fetched"
+		if r.Shared && !loaded {
+			result = "shared"
+		}
+		metrics.MarketDataRequests.WithLabelValues(provider.Name(), result).Inc()
+		if pe, ok := r.Err.(*panicError); ok {
+			panic(pe)
+		}
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.(*Data), nil
+	}
+}
+
+// providerKey 区分数据源实例的缓存键（同名数据源可能指向不同环境，如 Hyperliquid 测试网）
+func providerKey(provider Provider) string {
+	if k, ok := provider.(interface{ cacheKey() string }); ok {
+		return k.cacheKey()
+	}
+	// 未知实现不与其他实例共享缓存
+	return fmt.Sprintf("%s@%p", provider.Name(), provider)
+}
+
+// specKey 指标配置的缓存键
+func specKey(spec *IndicatorSpec) string {
+	if spec == nil || len(spec.Timeframes) == 0 {
+		return "default"
+	}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Sprintf("%p", spec)
+	}
+	h := fnv.New64a()
+	h.Write(raw)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,40 @@
+package market
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// panicProvider 获取K线时 panic 的数据源
+type panicProvider struct{}
+
+func (panicProvider) Name() string { return "panic" }
+
+func (panicProvider) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	panic("kline parser bug")
+}
+
+func (panicProvider) GetOpenInterest(ctx context.Context, symbol string) (*OIData, error) {
+	return &OIData{}, nil
+}
+
+func (panicProvider) GetFundingRate(ctx context.Context, symbol string) (float64, error) {
+	return 0, nil
+}
+
+func TestGetManyRepanicsInCaller(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("数据源 panic 时 GetMany 应在调用方重新抛出")
+		}
+		pe, ok := r.(*panicError)
+		if !ok || pe.value != "kline parser bug" {
+			t.Errorf("panic 值 = %#v", r)
+		}
+	}()
+
+	opts := FetchOptions{Concurrency: 2, Timeout: time.Second}
+	GetMany(context.Background(), panicProvider{}, []string{"BTCUSDT", "ETHUSDT", "SOLUSDT"}, nil, opts)
+}
@@ -0,0 +1,136 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Formatter 市场数据格式化函数（输出给AI的文本）
+type Formatter func(data *Data) string
+
+var (
+	formatters   = make(map[string]Formatter)
+	formattersMu sync.RWMutex
+)
+
+func init() {
+	RegisterFormatter("default", formatDefault)
+	RegisterFormatter("compact", formatCompact)
+	RegisterFormatter("json", formatJSON)
+}
+
+// RegisterFormatter 注册格式化器（同名会覆盖）
+func RegisterFormatter(name string, f Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[name] = f
+}
+
+// Formatters 获取已注册的格式化器名称
+func Formatters() []string {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hasFormatter(name string) bool {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	_, ok := formatters[name]
+	return ok
+}
+
+// FormatWith 使用指定格式化器输出（未注册时使用默认格式）
+func FormatWith(name string, data *Data) string {
+	formattersMu.RLock()
+	f, ok := formatters[name]
+	formattersMu.RUnlock()
+	if !ok {
+		f = formatDefault
+	}
+	return f(data)
+}
+
+// formatCompact 紧凑格式：每个时间框架一行，只输出最新值
+func formatCompact(data *Data) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("price=%.4f chg1h=%.2f%% chg4h=%.2f%% funding=%.2e",
+		data.CurrentPrice, data.PriceChange1h, data.PriceChange4h, data.FundingRate))
+	if data.OpenInterest != nil {
+		sb.WriteString(fmt.Sprintf(" oi=%.2f", data.OpenInterest.Latest))
+	}
+	if data.Source != "" {
+		sb.WriteString(fmt.Sprintf(" source=%s", data.Source))
+	}
+	sb.WriteString("\n")
+
+	if ms := data.Microstructure; ms != nil {
+		if ms.Depth != nil {
+			sb.WriteString(fmt.Sprintf("book_imbalance=%+.3f spread_bps=%.2f ", ms.Depth.Imbalance, ms.Depth.SpreadBps))
+		}
+		if ms.TakerVolume != nil {
+			sb.WriteString(fmt.Sprintf("taker_buy_sell_%s=%.3f ", ms.TakerVolume.Window, ms.TakerVolume.BuySellRatio))
+		}
+		if ms.GlobalLongShort != nil {
+			sb.WriteString(fmt.Sprintf("global_ls=%.3f ", ms.GlobalLongShort.Ratio))
+		}
+		if ms.TopTraderLongShort != nil {
+			sb.WriteString(fmt.Sprintf("top_ls=%.3f ", ms.TopTraderLongShort.Ratio))
+		}
+		if ms.Liquidations != nil {
+			sb.WriteString(fmt.Sprintf("liq_%s_long=%.0f liq_%s_short=%.0f", ms.Liquidations.Window, ms.Liquidations.LongUSD,
+				ms.Liquidations.Window, ms.Liquidations.ShortUSD))
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, tf := range data.Timeframes {
+		sb.WriteString(fmt.Sprintf("[%s] chg=%.2f%%", tf.Interval, tf.PriceChange))
+		for _, ind := range tf.Indicators {
+			if v := ind.Latest(); !math.IsNaN(v) {
+				sb.WriteString(fmt.Sprintf(" %s=%.4g", ind.Name, v))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// formatJSON JSON格式（便于模型按结构读取）
+func formatJSON(data *Data) string {
+	payload := map[string]interface{}{
+		"symbol":          data.Symbol,
+		"source":          data.Source,
+		"price":           data.CurrentPrice,
+		"price_change_1h": data.PriceChange1h,
+		"price_change_4h": data.PriceChange4h,
+		"funding_rate":    data.FundingRate,
+		"timeframes":      data.Timeframes,
+	}
+	if data.OpenInterest != nil {
+		payload["open_interest"] = data.OpenInterest.Latest
+		payload["open_interest_history"] = data.OpenInterest.History
+	}
+	if data.Microstructure != nil {
+		payload["microstructure"] = data.Microstructure
+	}
+	if len(data.Timeframes) == 0 {
+		payload["intraday_3m"] = data.IntradaySeries
+		payload["longer_term_4h"] = data.LongerTermContext
+	}
+
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return formatDefault(data)
+	}
+	return string(out) + "\n"
+}
@@ -0,0 +1,325 @@
+package market
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// hyperliquidCtxTTL metaAndAssetCtxs 缓存时间（同一周期内OI和资金费率共用一次请求）
+	hyperliquidCtxTTL = 15 * time.Second
+	// Hyperliquid 不提供OI历史接口，本地每5分钟采样一次，保留最近30个
+	hyperliquidOISampleInterval = 5 * time.Minute
+	hyperliquidOISamples        = 30
+)
+
+// HyperliquidProvider Hyperliquid行情数据源
+type HyperliquidProvider struct {
+	infoURL string
+
+	ctxsFlight singleflight.Group // 缓存过期时合并并发的 metaAndAssetCtxs 请求
+
+	mu        sync.Mutex
+	ctxs      map[string]hyperliquidAssetCtx // coin -> 资产上下文
+	ctxsFetch time.Time
+	oiHistory map[string][]float64 // coin -> 本地采样的OI序列
+	oiSampled time.Time
+}
+
+// hyperliquidAssetCtx metaAndAssetCtxs 返回的资产上下文
+type hyperliquidAssetCtx struct {
+	Funding      string `json:"funding"`
+	OpenInterest string `json:"openInterest"`
+	MarkPx       string `json:"markPx"`
+	OraclePx     string `json:"oraclePx"`
+}
+
+// NewHyperliquidProvider 创建Hyperliquid行情数据源
+func NewHyperliquidProvider(testnet bool) *HyperliquidProvider {
+	infoURL := "https://api.hyperliquid.xyz/info"
+	if testnet {
+		infoURL = "https://api.hyperliquid-testnet.xyz/info"
+	}
+	return &HyperliquidProvider{infoURL: infoURL}
+}
+
+// Name 数据源名称
+func (p *HyperliquidProvider) Name() string {
+	return SourceHyperliquid
+}
+
+// cacheKey 行情缓存键（主网与测试网分开缓存）
+func (p *HyperliquidProvider) cacheKey() string {
+	return SourceHyperliquid + "@" + p.infoURL
+}
+
+// toHyperliquidCoin 将 BTCUSDT 格式转换为 Hyperliquid 币种名（BTC；1000PEPEUSDT -> kPEPE）
+func toHyperliquidCoin(symbol string) string {
+	coin := strings.TrimSuffix(strings.ToUpper(symbol), "USDT")
+	if strings.HasPrefix(coin, "1000") {
+		coin = "k" + strings.TrimPrefix(coin, "1000")
+	}
+	return coin
+}
+
+// post 调用 info 接口
+func (p *HyperliquidProvider) post(ctx context.Context, payload interface{}, out interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.infoURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("hyperliquid API错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// GetKlines 通过 candleSnapshot 获取K线数据
+func (p *HyperliquidProvider) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	step, err := intervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	req := map[string]interface{}{
+		"type": "candleSnapshot",
+		"req": map[string]interface{}{
+			"coin":      toHyperliquidCoin(symbol),
+			"interval":  interval,
+			"startTime": now.Add(-step * time.Duration(limit)).UnixMilli(),
+			"endTime":   now.UnixMilli(),
+		},
+	}
+
+	var candles []struct {
+		OpenTime  int64  `json:"t"`
+		CloseTime int64  `json:"T"`
+		Open      string `json:"o"`
+		High      string `json:"h"`
+		Low       string `json:"l"`
+		Close     string `json:"c"`
+		Volume    string `json:"v"`
+	}
+	if err := p.post(ctx, req, &candles); err != nil {
+		return nil, fmt.Errorf("获取Hyperliquid K线失败: %w", err)
+	}
+
+	klines := make([]Kline, 0, len(candles))
+	for _, c := range candles {
+		open, _ := strconv.ParseFloat(c.Open, 64)
+		high, _ := strconv.ParseFloat(c.High, 64)
+		low, _ := strconv.ParseFloat(c.Low, 64)
+		close, _ := strconv.ParseFloat(c.Close, 64)
+		volume, _ := strconv.ParseFloat(c.Volume, 64)
+		klines = append(klines, Kline{
+			OpenTime:  c.OpenTime,
+			Open:      open,
+			High:      high,
+			Low:       low,
+			Close:     close,
+			Volume:    volume,
+			CloseTime: c.CloseTime,
+		})
+	}
+
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	return klines, nil
+}
+
+// assetCtx 获取币种的资产上下文（metaAndAssetCtxs，带短期缓存；网络请求期间不持有锁）
+func (p *HyperliquidProvider) assetCtx(ctx context.Context, symbol string) (*hyperliquidAssetCtx, error) {
+	p.mu.Lock()
+	ctxs := p.ctxs
+	fresh := ctxs != nil && time.Since(p.ctxsFetch) <= hyperliquidCtxTTL
+	p.mu.Unlock()
+
+	if !fresh {
+		v, err, _ := p.ctxsFlight.Do("metaAndAssetCtxs", func() (interface{}, error) {
+			return p.fetchAssetCtxs(ctx)
+		})
+		if err != nil {
+			return nil, err
+		}
+		ctxs = v.(map[string]hyperliquidAssetCtx)
+	}
+
+	coin := toHyperliquidCoin(symbol)
+	asset, ok := ctxs[coin]
+	if !ok {
+		return nil, fmt.Errorf("Hyperliquid 不支持币种 %s", coin)
+	}
+	return &asset, nil
+}
+
+// fetchAssetCtxs 请求 metaAndAssetCtxs 并更新缓存和OI采样
+func (p *HyperliquidProvider) fetchAssetCtxs(ctx context.Context) (map[string]hyperliquidAssetCtx, error) {
+	var raw []json.RawMessage
+	if err := p.post(ctx, map[string]string{"type": "metaAndAssetCtxs"}, &raw); err != nil {
+		return nil, fmt.Errorf("获取Hyperliquid资产信息失败: %w", err)
+	}
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("Hyperliquid资产信息格式异常")
+	}
+
+	var meta struct {
+		Universe []struct {
+			Name string `json:"name"`
+		} `json:"universe"`
+	}
+	var assets []hyperliquidAssetCtx
+	if err := json.Unmarshal(raw[0], &meta); err != nil {
+		return nil, fmt.Errorf("解析Hyperliquid meta失败: %w", err)
+	}
+	if err := json.Unmarshal(raw[1], &assets); err != nil {
+		return nil, fmt.Errorf("解析Hyperliquid assetCtxs失败: %w", err)
+	}
+
+	ctxs := make(map[string]hyperliquidAssetCtx, len(assets))
+	for i, asset := range meta.Universe {
+		if i < len(assets) {
+			ctxs[asset.Name] = assets[i]
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ctxs = ctxs
+	p.ctxsFetch = time.Now()
+	p.sampleOpenInterest()
+	return ctxs, nil
+}
+
+// sampleOpenInterest 记录OI采样（调用方需持有锁）
+func (p *HyperliquidProvider) sampleOpenInterest() {
+	if time.Since(p.oiSampled) < hyperliquidOISampleInterval {
+		return
+	}
+	if p.oiHistory == nil {
+		p.oiHistory = make(map[string][]float64)
+	}
+	for coin, ctx := range p.ctxs {
+		oi, err := strconv.ParseFloat(ctx.OpenInterest, 64)
+		if err != nil {
+			continue
+		}
+		history := append(p.oiHistory[coin], oi)
+		if len(history) > hyperliquidOISamples {
+			history = history[len(history)-hyperliquidOISamples:]
+		}
+		p.oiHistory[coin] = history
+	}
+	p.oiSampled = time.Now()
+}
+
+// GetOpenInterest 获取OI数据（平均值和序列来自本地采样，启动初期样本较少）
+func (p *HyperliquidProvider) GetOpenInterest(ctx context.Context, symbol string) (*OIData, error) {
+	asset, err := p.assetCtx(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	oi, _ := strconv.ParseFloat(asset.OpenInterest, 64)
+	data := &OIData{Latest: oi, Average: oi}
+
+	p.mu.Lock()
+	history := append([]float64(nil), p.oiHistory[toHyperliquidCoin(symbol)]...)
+	p.mu.Unlock()
+	if len(history) > 0 {
+		sum := 0.0
+		for _, h := range history {
+			sum += h
+		}
+		data.Average = sum / float64(len(history))
+		data.History = history
+	}
+	return data, nil
+}
+
+// GetFundingRate 获取资金费率
+// Hyperliquid 每小时结算资金费，这里换算为8小时费率，与其他数据源口径一致
+func (p *HyperliquidProvider) GetFundingRate(ctx context.Context, symbol string) (float64, error) {
+	asset, err := p.assetCtx(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	rate, _ := strconv.ParseFloat(asset.Funding, 64)
+	return rate * 8, nil
+}
+
+// GetDepth 通过 l2Book 获取盘口深度
+func (p *HyperliquidProvider) GetDepth(ctx context.Context, symbol string, levels int) (*DepthData, error) {
+	var book struct {
+		Levels [][]struct {
+			Px string `json:"px"`
+			Sz string `json:"sz"`
+		} `json:"levels"`
+	}
+	if err := p.post(ctx, map[string]string{"type": "l2Book", "coin": toHyperliquidCoin(symbol)}, &book); err != nil {
+		return nil, fmt.Errorf("获取Hyperliquid盘口失败: %w", err)
+	}
+	if len(book.Levels) < 2 {
+		return nil, fmt.Errorf("Hyperliquid盘口格式异常")
+	}
+
+	sides := make([][][2]float64, 2)
+	for i := 0; i < 2; i++ {
+		for _, lv := range book.Levels[i] {
+			px, _ := strconv.ParseFloat(lv.Px, 64)
+			sz, _ := strconv.ParseFloat(lv.Sz, 64)
+			sides[i] = append(sides[i], [2]float64{px, sz})
+		}
+	}
+
+	depth := computeDepth(sides[0], sides[1], levels)
+	if depth == nil {
+		return nil, fmt.Errorf("%s 盘口为空", symbol)
+	}
+	return depth, nil
+}
+
+// GetTakerVolume Hyperliquid 暂不支持
+func (p *HyperliquidProvider) GetTakerVolume(ctx context.Context, symbol string) (*TakerVolumeData, error) {
+	return nil, ErrFeatureNotSupported
+}
+
+// GetLongShortRatios Hyperliquid 暂不支持
+func (p *HyperliquidProvider) GetLongShortRatios(ctx context.Context, symbol string) (*LongShortRatio, *LongShortRatio, error) {
+	return nil, nil, ErrFeatureNotSupported
+}
+
+// GetLiquidations Hyperliquid 暂不支持
+func (p *HyperliquidProvider) GetLiquidations(symbol string, window time.Duration) (*LiquidationData, error) {
+	return nil, ErrFeatureNotSupported
+}
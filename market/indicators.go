@@ -0,0 +1,395 @@
+package market
+
+import (
+	"math"
+	"time"
+)
+
+// 以下指标函数均返回与K线等长的序列，指标尚未形成的位置为 NaN
+
+// closesOf 提取收盘价序列
+func closesOf(klines []Kline) []float64 {
+	closes := make([]float64, len(klines))
+	for i, k := range klines {
+		closes[i] = k.Close
+	}
+	return closes
+}
+
+// nanSeries 创建全部为 NaN 的序列
+func nanSeries(n int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = math.NaN()
+	}
+	return s
+}
+
+// emaSeries EMA序列（以前period个值的SMA为初始值，跳过开头的NaN）
+func emaSeries(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+
+	start := 0
+	for start < len(values) && math.IsNaN(values[start]) {
+		start++
+	}
+	if len(values)-start < period {
+		return out
+	}
+
+	sum := 0.0
+	for i := start; i < start+period; i++ {
+		sum += values[i]
+	}
+	ema := sum / float64(period)
+	out[start+period-1] = ema
+
+	multiplier := 2.0 / float64(period+1)
+	for i := start + period; i < len(values); i++ {
+		ema = (values[i]-ema)*multiplier + ema
+		out[i] = ema
+	}
+	return out
+}
+
+// smaSeries SMA序列（窗口内包含NaN时结果为NaN）
+func smaSeries(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	for i := period - 1; i < len(values); i++ {
+		sum := 0.0
+		for j := i - period + 1; j <= i; j++ {
+			sum += values[j]
+		}
+		out[i] = sum / float64(period)
+	}
+	return out
+}
+
+// wilderSeries Wilder平滑序列（values[0]通常无意义，从下标1开始累计）
+func wilderSeries(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 || len(values) <= period {
+		return out
+	}
+
+	sum := 0.0
+	for i := 1; i <= period; i++ {
+		sum += values[i]
+	}
+	avg := sum / float64(period)
+	out[period] = avg
+	for i := period + 1; i < len(values); i++ {
+		avg = (avg*float64(period-1) + values[i]) / float64(period)
+		out[i] = avg
+	}
+	return out
+}
+
+// macdSeries MACD序列（MACD线、信号线、柱状图）
+func macdSeries(closes []float64, fast, slow, signal int) ([]float64, []float64, []float64) {
+	emaFast := emaSeries(closes, fast)
+	emaSlow := emaSeries(closes, slow)
+
+	macd := nanSeries(len(closes))
+	for i := range closes {
+		if !math.IsNaN(emaFast[i]) && !math.IsNaN(emaSlow[i]) {
+			macd[i] = emaFast[i] - emaSlow[i]
+		}
+	}
+
+	sig := emaSeries(macd, signal)
+	hist := nanSeries(len(closes))
+	for i := range closes {
+		if !math.IsNaN(macd[i]) && !math.IsNaN(sig[i]) {
+			hist[i] = macd[i] - sig[i]
+		}
+	}
+	return macd, sig, hist
+}
+
+// rsiSeries RSI序列（Wilder平滑）
+func rsiSeries(closes []float64, period int) []float64 {
+	out := nanSeries(len(closes))
+	if period <= 0 || len(closes) <= period {
+		return out
+	}
+
+	gains, losses := 0.0, 0.0
+	for i := 1; i <= period; i++ {
+		change := closes[i] - closes[i-1]
+		if change > 0 {
+			gains += change
+		} else {
+			losses -= change
+		}
+	}
+	avgGain := gains / float64(period)
+	avgLoss := losses / float64(period)
+	out[period] = rsiFromAvg(avgGain, avgLoss)
+
+	for i := period + 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		gain, loss := 0.0, 0.0
+		if change > 0 {
+			gain = change
+		} else {
+			loss = -change
+		}
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = rsiFromAvg(avgGain, avgLoss)
+	}
+	return out
+}
+
+func rsiFromAvg(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100
+	}
+	rs := avgGain / avgLoss
+	return 100 - (100 / (1 + rs))
+}
+
+// trueRanges 真实波幅序列
+func trueRanges(klines []Kline) []float64 {
+	trs := make([]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		high := klines[i].High
+		low := klines[i].Low
+		prevClose := klines[i-1].Close
+		trs[i] = math.Max(high-low, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+	}
+	return trs
+}
+
+// atrSeries ATR序列
+func atrSeries(klines []Kline, period int) []float64 {
+	return wilderSeries(trueRanges(klines), period)
+}
+
+// bollingerSeries 布林带（上轨、中轨、下轨）
+func bollingerSeries(closes []float64, period int, mult float64) ([]float64, []float64, []float64) {
+	mid := smaSeries(closes, period)
+	upper := nanSeries(len(closes))
+	lower := nanSeries(len(closes))
+	for i := period - 1; i < len(closes) && period > 0; i++ {
+		variance := 0.0
+		for j := i - period + 1; j <= i; j++ {
+			d := closes[j] - mid[i]
+			variance += d * d
+		}
+		std := math.Sqrt(variance / float64(period))
+		upper[i] = mid[i] + mult*std
+		lower[i] = mid[i] - mult*std
+	}
+	return upper, mid, lower
+}
+
+// vwapSeries VWAP序列（日内周期按UTC自然日重置，日线及以上在整个窗口内累计）
+func vwapSeries(klines []Kline, interval time.Duration) []float64 {
+	out := nanSeries(len(klines))
+	cumPV, cumVol := 0.0, 0.0
+	lastDay := int64(-1)
+	for i, k := range klines {
+		if interval < 24*time.Hour {
+			day := k.OpenTime / int64(24*time.Hour/time.Millisecond)
+			if day != lastDay {
+				cumPV, cumVol = 0, 0
+				lastDay = day
+			}
+		}
+		typical := (k.High + k.Low + k.Close) / 3
+		cumPV += typical * k.Volume
+		cumVol += k.Volume
+		if cumVol > 0 {
+			out[i] = cumPV / cumVol
+		}
+	}
+	return out
+}
+
+// stochRSISeries 随机RSI（%K、%D，范围0-100）
+func stochRSISeries(closes []float64, rsiPeriod, stochPeriod, kSmooth, dSmooth int) ([]float64, []float64) {
+	rsi := rsiSeries(closes, rsiPeriod)
+	stoch := nanSeries(len(closes))
+	for i := stochPeriod - 1; i < len(closes) && stochPeriod > 0; i++ {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		valid := true
+		for j := i - stochPeriod + 1; j <= i; j++ {
+			if math.IsNaN(rsi[j]) {
+				valid = false
+				break
+			}
+			lo = math.Min(lo, rsi[j])
+			hi = math.Max(hi, rsi[j])
+		}
+		if !valid {
+			continue
+		}
+		if hi == lo {
+			stoch[i] = 50
+		} else {
+			stoch[i] = (rsi[i] - lo) / (hi - lo) * 100
+		}
+	}
+	k := smaSeries(stoch, kSmooth)
+	d := smaSeries(k, dSmooth)
+	return k, d
+}
+
+// adxSeries ADX（ADX、+DI、-DI）
+func adxSeries(klines []Kline, period int) ([]float64, []float64, []float64) {
+	n := len(klines)
+	plusDM := make([]float64, n)
+	minusDM := make([]float64, n)
+	for i := 1; i < n; i++ {
+		up := klines[i].High - klines[i-1].High
+		down := klines[i-1].Low - klines[i].Low
+		if up > down && up > 0 {
+			plusDM[i] = up
+		}
+		if down > up && down > 0 {
+			minusDM[i] = down
+		}
+	}
+
+	atr := wilderSeries(trueRanges(klines), period)
+	plusAvg := wilderSeries(plusDM, period)
+	minusAvg := wilderSeries(minusDM, period)
+
+	plusDI := nanSeries(n)
+	minusDI := nanSeries(n)
+	dx := nanSeries(n)
+	for i := 0; i < n; i++ {
+		if math.IsNaN(atr[i]) || atr[i] == 0 {
+			continue
+		}
+		plusDI[i] = plusAvg[i] / atr[i] * 100
+		minusDI[i] = minusAvg[i] / atr[i] * 100
+		if sum := plusDI[i] + minusDI[i]; sum > 0 {
+			dx[i] = math.Abs(plusDI[i]-minusDI[i]) / sum * 100
+		} else {
+			dx[i] = 0
+		}
+	}
+
+	// ADX = DX 的 Wilder 平滑
+	adx := nanSeries(n)
+	first := period
+	if first+period <= n {
+		sum := 0.0
+		for i := first; i < first+period; i++ {
+			sum += dx[i]
+		}
+		avg := sum / float64(period)
+		adx[first+period-1] = avg
+		for i := first + period; i < n; i++ {
+			avg = (avg*float64(period-1) + dx[i]) / float64(period)
+			adx[i] = avg
+		}
+	}
+	return adx, plusDI, minusDI
+}
+
+// obvSeries 能量潮OBV
+func obvSeries(klines []Kline) []float64 {
+	out := make([]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		switch {
+		case klines[i].Close > klines[i-1].Close:
+			out[i] = out[i-1] + klines[i].Volume
+		case klines[i].Close < klines[i-1].Close:
+			out[i] = out[i-1] - klines[i].Volume
+		default:
+			out[i] = out[i-1]
+		}
+	}
+	return out
+}
+
+// supertrendSeries 超级趋势（趋势线、方向：1=多头，-1=空头）
+func supertrendSeries(klines []Kline, period int, mult float64) ([]float64, []float64) {
+	n := len(klines)
+	line := nanSeries(n)
+	dir := nanSeries(n)
+	atr := atrSeries(klines, period)
+
+	var finalUpper, finalLower float64
+	trend := 1.0
+	started := false
+	for i := 0; i < n; i++ {
+		if math.IsNaN(atr[i]) {
+			continue
+		}
+		hl2 := (klines[i].High + klines[i].Low) / 2
+		basicUpper := hl2 + mult*atr[i]
+		basicLower := hl2 - mult*atr[i]
+
+		if !started {
+			finalUpper, finalLower = basicUpper, basicLower
+			started = true
+		} else {
+			prevClose := klines[i-1].Close
+			if basicUpper < finalUpper || prevClose > finalUpper {
+				finalUpper = basicUpper
+			}
+			if basicLower > finalLower || prevClose < finalLower {
+				finalLower = basicLower
+			}
+		}
+
+		if trend > 0 && klines[i].Close < finalLower {
+			trend = -1
+		} else if trend < 0 && klines[i].Close > finalUpper {
+			trend = 1
+		}
+
+		if trend > 0 {
+			line[i] = finalLower
+		} else {
+			line[i] = finalUpper
+		}
+		dir[i] = trend
+	}
+	return line, dir
+}
+
+// midpointSeries (最高价+最低价)/2 序列
+func midpointSeries(klines []Kline, period int) []float64 {
+	out := nanSeries(len(klines))
+	for i := period - 1; i < len(klines) && period > 0; i++ {
+		hi, lo := math.Inf(-1), math.Inf(1)
+		for j := i - period + 1; j <= i; j++ {
+			hi = math.Max(hi, klines[j].High)
+			lo = math.Min(lo, klines[j].Low)
+		}
+		out[i] = (hi + lo) / 2
+	}
+	return out
+}
+
+// ichimokuSeries 一目均衡表（转换线、基准线、当前K线对应的先行带A、先行带B）
+// 先行带向前平移 kijun 个周期，这里返回的是落在当前K线上的云层
+func ichimokuSeries(klines []Kline, tenkanPeriod, kijunPeriod, senkouBPeriod int) ([]float64, []float64, []float64, []float64) {
+	n := len(klines)
+	tenkan := midpointSeries(klines, tenkanPeriod)
+	kijun := midpointSeries(klines, kijunPeriod)
+	spanBRaw := midpointSeries(klines, senkouBPeriod)
+
+	spanA := nanSeries(n)
+	spanB := nanSeries(n)
+	for i := kijunPeriod; i < n; i++ {
+		src := i - kijunPeriod
+		if !math.IsNaN(tenkan[src]) && !math.IsNaN(kijun[src]) {
+			spanA[i] = (tenkan[src] + kijun[src]) / 2
+		}
+		spanB[i] = spanBRaw[src]
+	}
+	return tenkan, kijun, spanA, spanB
+}
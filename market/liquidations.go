@@ -0,0 +1,162 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// liquidationEvent 单笔强平
+type liquidationEvent struct {
+	time     time.Time
+	longSide bool // true=多头被强平（强平单方向为SELL）
+	usd      float64
+}
+
+// liquidationStream 订阅交易所全市场强平推送（!forceOrder@arr），在内存中保留最近的强平记录
+// 交易所已不提供强平历史REST接口，只能从首次使用时开始采集
+type liquidationStream struct {
+	wsURL     string
+	retention time.Duration
+
+	once    sync.Once
+	mu      sync.Mutex
+	events  map[string][]liquidationEvent // symbol -> 强平记录（按时间升序）
+	started time.Time
+}
+
+var (
+	liquidationStreams   = make(map[string]*liquidationStream)
+	liquidationStreamsMu sync.Mutex
+)
+
+// getLiquidationStream 获取（必要时创建并启动）指定地址的强平订阅，多个交易员共享
+func getLiquidationStream(wsURL string) *liquidationStream {
+	liquidationStreamsMu.Lock()
+	defer liquidationStreamsMu.Unlock()
+
+	s, ok := liquidationStreams[wsURL]
+	if !ok {
+		s = &liquidationStream{
+			wsURL:     wsURL,
+			retention: 2 * liquidationWindow,
+			events:    make(map[string][]liquidationEvent),
+		}
+		liquidationStreams[wsURL] = s
+	}
+	s.once.Do(func() {
+		s.started = time.Now()
+		go s.run()
+	})
+	return s
+}
+
+// run 保持连接，断线后指数退避重连
+func (s *liquidationStream) run() {
+	backoff := time.Second
+	for {
+		err := s.consume()
+		marketLog.Warnf(context.Background(), "⚠️  强平数据流断开 (%s): %v，%v后重连", s.wsURL, err, backoff)
+		time.Sleep(backoff)
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// consume 读取推送直到连接出错
+func (s *liquidationStream) consume() error {
+	conn, _, err := websocket.DefaultDialer.Dial(s.wsURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var payload struct {
+			Order struct {
+				Symbol      string `json:"s"`
+				Side        string `json:"S"`
+				AvgPrice    string `json:"ap"`
+				FilledQty   string `json:"z"`
+				TradeTimeMs int64  `json:"T"`
+			} `json:"o"`
+		}
+		if err := json.Unmarshal(msg, &payload); err != nil {
+			continue
+		}
+
+		price, _ := strconv.ParseFloat(payload.Order.AvgPrice, 64)
+		qty, _ := strconv.ParseFloat(payload.Order.FilledQty, 64)
+		if payload.Order.Symbol == "" || price <= 0 || qty <= 0 {
+			continue
+		}
+		s.add(payload.Order.Symbol, liquidationEvent{
+			time:     time.UnixMilli(payload.Order.TradeTimeMs),
+			longSide: payload.Order.Side == "SELL",
+			usd:      price * qty,
+		})
+	}
+}
+
+// add 记录强平并清理过期数据
+func (s *liquidationStream) add(symbol string, ev liquidationEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := append(s.events[symbol], ev)
+	cutoff := time.Now().Add(-s.retention)
+	i := 0
+	for i < len(events) && events[i].time.Before(cutoff) {
+		i++
+	}
+	s.events[symbol] = events[i:]
+}
+
+// summary 统计最近 window 时间内的强平
+func (s *liquidationStream) summary(symbol string, window time.Duration) *LiquidationData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &LiquidationData{Window: shortDuration(window)}
+	if collected := time.Since(s.started); collected < window {
+		result.CollectingFor = shortDuration(collected.Truncate(time.Second))
+	}
+
+	cutoff := time.Now().Add(-window)
+	for _, ev := range s.events[symbol] {
+		if ev.time.Before(cutoff) {
+			continue
+		}
+		result.Count++
+		if ev.longSide {
+			result.LongUSD += ev.usd
+		} else {
+			result.ShortUSD += ev.usd
+		}
+	}
+	return result
+}
+
+// shortDuration 简化时长显示（1h0m0s -> 1h）
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
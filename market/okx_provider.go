@@ -0,0 +1,382 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// okxMaxCandles /api/v5/market/candles 单次最多返回的K线数量
+const okxMaxCandles = 300
+
+// OKXProvider OKX USDT永续行情数据源（v5 公共接口）
+type OKXProvider struct {
+	baseURL   string
+	simulated bool // 模拟盘（请求头 x-simulated-trading: 1）
+
+	mu     sync.Mutex
+	ctVals map[string]float64 // instId -> 合约面值（盘口数量为张数，需换算为币）
+}
+
+// NewOKXProvider 创建OKX行情数据源
+func NewOKXProvider(simulated bool) *OKXProvider {
+	return &OKXProvider{
+		baseURL:   "https://www.okx.com",
+		simulated: simulated,
+		ctVals:    make(map[string]float64),
+	}
+}
+
+// Name 数据源名称
+func (p *OKXProvider) Name() string {
+	return SourceOKX
+}
+
+// cacheKey 行情缓存键（实盘与模拟盘分开缓存）
+func (p *OKXProvider) cacheKey() string {
+	if p.simulated {
+		return SourceOKX + "@" + p.baseURL + "#simulated"
+	}
+	return SourceOKX + "@" + p.baseURL
+}
+
+// toOKXInstID 将 BTCUSDT 格式转换为 OKX 永续合约ID（BTC-USDT-SWAP）
+func toOKXInstID(symbol string) string {
+	return strings.TrimSuffix(strings.ToUpper(symbol), "USDT") + "-USDT-SWAP"
+}
+
+// get 调用公共接口并返回 data 字段
+func (p *OKXProvider) get(ctx context.Context, path string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.simulated {
+		req.Header.Set("x-simulated-trading", "1")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("okx API错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析OKX响应失败: %w", err)
+	}
+	if result.Code != "0" {
+		return nil, fmt.Errorf("okx API错误 (code %s): %s", result.Code, result.Msg)
+	}
+	return result.Data, nil
+}
+
+// toOKXBar 将 3m/4h/1d 格式的K线周期转换为 OKX 格式（6小时及以上使用UTC对齐，与其他数据源一致）
+func toOKXBar(interval string) (string, error) {
+	step, err := intervalDuration(interval)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case step < time.Hour:
+		return fmt.Sprintf("%dm", int(step/time.Minute)), nil
+	case step < 6*time.Hour:
+		return fmt.Sprintf("%dH", int(step/time.Hour)), nil
+	case step < 24*time.Hour:
+		return fmt.Sprintf("%dHutc", int(step/time.Hour)), nil
+	case step < 7*24*time.Hour:
+		return fmt.Sprintf("%dDutc", int(step/(24*time.Hour))), nil
+	default:
+		return fmt.Sprintf("%dWutc", int(step/(7*24*time.Hour))), nil
+	}
+}
+
+// GetKlines 获取K线数据（接口按时间倒序返回，这里转为升序；成交量使用币数量）
+func (p *OKXProvider) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	bar, err := toOKXBar(interval)
+	if err != nil {
+		return nil, err
+	}
+	step, _ := intervalDuration(interval)
+	if limit > okxMaxCandles {
+		limit = okxMaxCandles
+	}
+
+	data, err := p.get(ctx, fmt.Sprintf("/api/v5/market/candles?instId=%s&bar=%s&limit=%d", toOKXInstID(symbol), bar, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string // [ts, o, h, l, c, vol(张), volCcy(币), volCcyQuote, confirm]
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		item := rows[i]
+		if len(item) < 7 {
+			continue
+		}
+		openTime, _ := strconv.ParseInt(item[0], 10, 64)
+		open, _ := strconv.ParseFloat(item[1], 64)
+		high, _ := strconv.ParseFloat(item[2], 64)
+		low, _ := strconv.ParseFloat(item[3], 64)
+		close, _ := strconv.ParseFloat(item[4], 64)
+		volume, _ := strconv.ParseFloat(item[6], 64)
+
+		klines = append(klines, Kline{
+			OpenTime:  openTime,
+			Open:      open,
+			High:      high,
+			Low:       low,
+			Close:     close,
+			Volume:    volume,
+			CloseTime: openTime + step.Milliseconds() - 1,
+		})
+	}
+	return klines, nil
+}
+
+// GetOpenInterest 获取OI数据（平均值和序列来自最近2.5小时的5分钟OI历史）
+func (p *OKXProvider) GetOpenInterest(ctx context.Context, symbol string) (*OIData, error) {
+	instID := toOKXInstID(symbol)
+	data, err := p.get(ctx, "/api/v5/public/open-interest?instType=SWAP&instId="+instID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		OiCcy string `json:"oiCcy"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s 无持仓量数据", symbol)
+	}
+
+	oi, _ := strconv.ParseFloat(rows[0].OiCcy, 64)
+	result := &OIData{Latest: oi, Average: oi}
+
+	// OI历史（失败时只返回最新值）
+	if v, err := featureCache.getOrLoad(SourceOKX+":"+symbol+":oi_hist", featureCacheTTL, func() (interface{}, error) {
+		return p.getOpenInterestHistory(ctx, instID, 30)
+	}); err == nil {
+		history := v.([]float64)
+		if len(history) > 0 {
+			sum := 0.0
+			for _, h := range history {
+				sum += h
+			}
+			result.Average = sum / float64(len(history))
+			result.History = history
+		}
+	}
+	return result, nil
+}
+
+// getOpenInterestHistory 获取5分钟间隔的OI历史（旧→新，单位：币）
+func (p *OKXProvider) getOpenInterestHistory(ctx context.Context, instID string, limit int) ([]float64, error) {
+	data, err := p.get(ctx, fmt.Sprintf("/api/v5/rubik/stat/contracts/open-interest-history?instId=%s&period=5m&limit=%d", instID, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string // [ts, oi(张), oiCcy(币), oiUsd]，按时间倒序
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	history := make([]float64, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		if len(rows[i]) < 3 {
+			continue
+		}
+		v, _ := strconv.ParseFloat(rows[i][2], 64)
+		history = append(history, v)
+	}
+	return history, nil
+}
+
+// GetFundingRate 获取当前资金费率
+func (p *OKXProvider) GetFundingRate(ctx context.Context, symbol string) (float64, error) {
+	data, err := p.get(ctx, "/api/v5/public/funding-rate?instId="+toOKXInstID(symbol))
+	if err != nil {
+		return 0, err
+	}
+
+	var rows []struct {
+		FundingRate string `json:"fundingRate"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("%s 无资金费率数据", symbol)
+	}
+
+	rate, _ := strconv.ParseFloat(rows[0].FundingRate, 64)
+	return rate, nil
+}
+
+// contractValue 获取合约面值（缓存，网络请求期间不持有锁）
+func (p *OKXProvider) contractValue(ctx context.Context, instID string) (float64, error) {
+	p.mu.Lock()
+	ctVal, ok := p.ctVals[instID]
+	p.mu.Unlock()
+	if ok {
+		return ctVal, nil
+	}
+
+	data, err := p.get(ctx, "/api/v5/public/instruments?instType=SWAP&instId="+instID)
+	if err != nil {
+		return 0, err
+	}
+	var rows []struct {
+		CtVal string `json:"ctVal"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("未找到合约 %s 的信息", instID)
+	}
+	ctVal, _ = strconv.ParseFloat(rows[0].CtVal, 64)
+	if ctVal <= 0 {
+		return 0, fmt.Errorf("%s 合约面值异常: %s", instID, rows[0].CtVal)
+	}
+
+	p.mu.Lock()
+	p.ctVals[instID] = ctVal
+	p.mu.Unlock()
+	return ctVal, nil
+}
+
+// GetDepth 获取盘口深度（张数按合约面值换算为币）
+func (p *OKXProvider) GetDepth(ctx context.Context, symbol string, levels int) (*DepthData, error) {
+	instID := toOKXInstID(symbol)
+	ctVal, err := p.contractValue(ctx, instID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.get(ctx, fmt.Sprintf("/api/v5/market/books?instId=%s&sz=%d", instID, levels))
+	if err != nil {
+		return nil, err
+	}
+
+	var books []struct {
+		Bids [][]string `json:"bids"` // [价格, 张数, 已废弃, 订单数]
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(data, &books); err != nil {
+		return nil, err
+	}
+	if len(books) == 0 {
+		return nil, fmt.Errorf("%s 盘口为空", symbol)
+	}
+
+	bids, asks := parseBookLevels(books[0].Bids), parseBookLevels(books[0].Asks)
+	for _, side := range [][][2]float64{bids, asks} {
+		for i := range side {
+			side[i][1] *= ctVal
+		}
+	}
+	depth := computeDepth(bids, asks, levels)
+	if depth == nil {
+		return nil, fmt.Errorf("%s 盘口为空", symbol)
+	}
+	return depth, nil
+}
+
+// GetTakerVolume 获取最近1小时的主动买卖量（5分钟 × 12，单位：币）
+func (p *OKXProvider) GetTakerVolume(ctx context.Context, symbol string) (*TakerVolumeData, error) {
+	data, err := p.get(ctx, fmt.Sprintf("/api/v5/rubik/stat/taker-volume-contract?instId=%s&period=5m&unit=0&limit=12", toOKXInstID(symbol)))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string // [ts, sellVol, buyVol]
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s 无主动买卖量数据", symbol)
+	}
+
+	result := &TakerVolumeData{Window: "1h"}
+	for _, row := range rows {
+		if len(row) < 3 {
+			continue
+		}
+		sell, _ := strconv.ParseFloat(row[1], 64)
+		buy, _ := strconv.ParseFloat(row[2], 64)
+		result.BuyVolume += buy
+		result.SellVolume += sell
+	}
+	if result.SellVolume > 0 {
+		result.BuySellRatio = result.BuyVolume / result.SellVolume
+	}
+	return result, nil
+}
+
+// GetLongShortRatios 获取全市场和大户多空账户比
+func (p *OKXProvider) GetLongShortRatios(ctx context.Context, symbol string) (*LongShortRatio, *LongShortRatio, error) {
+	instID := toOKXInstID(symbol)
+	global, err := p.getLongShortRatio(ctx, "/api/v5/rubik/stat/contracts/long-short-account-ratio-contract", instID)
+	if err != nil {
+		return nil, nil, err
+	}
+	top, err := p.getLongShortRatio(ctx, "/api/v5/rubik/stat/contracts/long-short-account-ratio-contract-top-trader", instID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return global, top, nil
+}
+
+// getLongShortRatio 获取最新的多空账户比（接口只返回比值，占比由比值换算）
+func (p *OKXProvider) getLongShortRatio(ctx context.Context, path, instID string) (*LongShortRatio, error) {
+	data, err := p.get(ctx, fmt.Sprintf("%s?instId=%s&period=5m&limit=1", path, instID))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string // [ts, longShortAcctRatio]
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, fmt.Errorf("%s 无多空比数据", instID)
+	}
+
+	ratio := &LongShortRatio{}
+	ratio.Ratio, _ = strconv.ParseFloat(rows[0][1], 64)
+	if ratio.Ratio > 0 {
+		ratio.LongAccount = ratio.Ratio / (1 + ratio.Ratio)
+		ratio.ShortAccount = 1 - ratio.LongAccount
+	}
+	return ratio, nil
+}
+
+// GetLiquidations OKX 暂不支持
+func (p *OKXProvider) GetLiquidations(symbol string, window time.Duration) (*LiquidationData, error) {
+	return nil, ErrFeatureNotSupported
+}
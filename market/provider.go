@@ -0,0 +1,94 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"nofx/logging"
+	"nofx/ratelimit"
+	"time"
+)
+
+var marketLog = logging.For("market")
+
+// Provider 行情数据源接口（K线、持仓量、资金费率）
+// 每个交易员使用其交易所对应的数据源，保证提示词、流动性过滤和下单价格基于同一个盘口
+type Provider interface {
+	// Name 数据源名称（如 binance, hyperliquid, aster, bybit, okx）
+	Name() string
+	// GetKlines 获取K线数据（symbol 为标准化后的 XXXUSDT 格式，按时间升序）
+	GetKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error)
+	// GetOpenInterest 获取持仓量（单位：币）
+	GetOpenInterest(ctx context.Context, symbol string) (*OIData, error)
+	// GetFundingRate 获取资金费率（统一为8小时费率）
+	GetFundingRate(ctx context.Context, symbol string) (float64, error)
+}
+
+const (
+	SourceBinance     = "binance"
+	SourceHyperliquid = "hyperliquid"
+	SourceAster       = "aster"
+	SourceBybit       = "bybit"
+	SourceOKX         = "okx"
+)
+
+// httpClient 行情请求共用的HTTP客户端（保持长连接，并发获取时复用到同一主机的连接；与交易器共享按主机的限流额度）
+var httpClient = newHTTPClient()
+
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 32
+	transport.IdleConnTimeout = 90 * time.Second
+	return &http.Client{Timeout: 15 * time.Second, Transport: ratelimit.NewTransport(transport)}
+}
+
+var defaultProvider Provider = NewBinanceProvider()
+
+// DefaultProvider 默认数据源（Binance）
+func DefaultProvider() Provider {
+	return defaultProvider
+}
+
+// NewProvider 根据数据源名称创建行情数据源
+func NewProvider(source string, testnet bool) (Provider, error) {
+	switch source {
+	case "", SourceBinance:
+		return NewBinanceProvider(), nil
+	case SourceHyperliquid:
+		return NewHyperliquidProvider(testnet), nil
+	case SourceAster:
+		return NewAsterProvider(), nil
+	case SourceBybit:
+		return NewBybitProvider(testnet), nil
+	case SourceOKX:
+		return NewOKXProvider(testnet), nil
+	default:
+		return nil, fmt.Errorf("不支持的行情数据源: %s", source)
+	}
+}
+
+// intervalDuration 解析K线周期（如 3m, 4h, 1d）
+func intervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("无效的K线周期: %s", interval)
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(interval[:len(interval)-1], "%d", &n); err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的K线周期: %s", interval)
+	}
+
+	switch interval[len(interval)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("无效的K线周期: %s", interval)
+	}
+}
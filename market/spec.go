@@ -0,0 +1,389 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"nofx/tracing"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndicatorSpec 交易员的指标配置（声明式，按时间框架组织）
+// 为空时使用默认的 3分钟 + 4小时 数据（见 Get）
+type IndicatorSpec struct {
+	Timeframes []TimeframeSpec `json:"timeframes"`
+	Formatter  string          `json:"formatter,omitempty"` // 输出格式: default, compact, json
+}
+
+// TimeframeSpec 单个时间框架的配置
+type TimeframeSpec struct {
+	Interval     string            `json:"interval"`                // 1m, 3m, 5m, 15m, 30m, 1h, 2h, 4h, 6h, 8h, 12h, 1d
+	Limit        int               `json:"limit,omitempty"`         // 获取K线数量（默认100）
+	SeriesLength int               `json:"series_length,omitempty"` // 输出最近多少个值（默认10）
+	Indicators   []IndicatorConfig `json:"indicators"`
+}
+
+// IndicatorConfig 单个指标配置
+type IndicatorConfig struct {
+	Name       string  `json:"name"`                 // 指标名称，见 SupportedIndicators
+	Periods    []int   `json:"periods,omitempty"`    // 周期参数（为空时使用默认值）
+	Multiplier float64 `json:"multiplier,omitempty"` // 倍数参数（布林带、超级趋势）
+}
+
+// IndicatorInfo 指标说明
+type IndicatorInfo struct {
+	Name              string  `json:"name"`
+	Description       string  `json:"description"`
+	DefaultPeriods    []int   `json:"default_periods,omitempty"`
+	DefaultMultiplier float64 `json:"default_multiplier,omitempty"`
+}
+
+// TimeframeData 单个时间框架的计算结果
+type TimeframeData struct {
+	Interval    string            `json:"interval"`
+	Prices      []float64         `json:"prices"`       // 最近的收盘价（旧→新）
+	PriceChange float64           `json:"price_change"` // 最近一根K线涨跌幅（%）
+	Indicators  []IndicatorSeries `json:"indicators"`
+}
+
+// IndicatorSeries 指标输出序列
+type IndicatorSeries struct {
+	Name   string    `json:"name"`   // 如 EMA20, BB20_upper, ADX14
+	Values []float64 `json:"values"` // 最近的指标值（旧→新）
+}
+
+// Latest 最新值（无数据时返回NaN）
+func (s IndicatorSeries) Latest() float64 {
+	if len(s.Values) == 0 {
+		return math.NaN()
+	}
+	return s.Values[len(s.Values)-1]
+}
+
+const (
+	defaultSpecLimit        = 100
+	defaultSpecSeriesLength = 10
+	maxSpecTimeframes       = 6
+	maxSpecLimit            = 1000
+)
+
+// supportedIntervals 支持的K线周期
+var supportedIntervals = []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d"}
+
+// supportedIndicators 支持的指标及默认参数
+var supportedIndicators = []IndicatorInfo{
+	{Name: "ema", Description: "指数移动平均线", DefaultPeriods: []int{20, 50}},
+	{Name: "sma", Description: "简单移动平均线", DefaultPeriods: []int{20}},
+	{Name: "macd", Description: "MACD（快线、慢线、信号线）", DefaultPeriods: []int{12, 26, 9}},
+	{Name: "rsi", Description: "相对强弱指数", DefaultPeriods: []int{7, 14}},
+	{Name: "atr", Description: "平均真实波幅", DefaultPeriods: []int{3, 14}},
+	{Name: "bollinger", Description: "布林带（周期、标准差倍数）", DefaultPeriods: []int{20}, DefaultMultiplier: 2},
+	{Name: "vwap", Description: "成交量加权平均价（日内按UTC日重置）"},
+	{Name: "stoch_rsi", Description: "随机RSI（RSI周期、随机周期、K平滑、D平滑）", DefaultPeriods: []int{14, 14, 3, 3}},
+	{Name: "adx", Description: "平均趋向指数（含+DI/-DI）", DefaultPeriods: []int{14}},
+	{Name: "obv", Description: "能量潮"},
+	{Name: "supertrend", Description: "超级趋势（ATR周期、倍数）", DefaultPeriods: []int{10}, DefaultMultiplier: 3},
+	{Name: "ichimoku", Description: "一目均衡表（转换线、基准线、先行带B周期）", DefaultPeriods: []int{9, 26, 52}},
+	{Name: "volume", Description: "成交量及平均成交量", DefaultPeriods: []int{20}},
+}
+
+// SupportedIntervals 获取支持的K线周期
+func SupportedIntervals() []string {
+	return append([]string(nil), supportedIntervals...)
+}
+
+// SupportedIndicators 获取支持的指标列表
+func SupportedIndicators() []IndicatorInfo {
+	return append([]IndicatorInfo(nil), supportedIndicators...)
+}
+
+func indicatorInfo(name string) (IndicatorInfo, bool) {
+	for _, info := range supportedIndicators {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return IndicatorInfo{}, false
+}
+
+// requiredPeriods 每个指标需要的周期参数个数（0表示不限）
+var requiredPeriods = map[string]int{
+	"macd":       3,
+	"bollinger":  1,
+	"stoch_rsi":  4,
+	"adx":        1,
+	"supertrend": 1,
+	"ichimoku":   3,
+	"volume":     1,
+}
+
+// Validate 校验并补全默认值
+func (s *IndicatorSpec) Validate() error {
+	if len(s.Timeframes) == 0 {
+		return fmt.Errorf("至少需要配置一个时间框架")
+	}
+	if len(s.Timeframes) > maxSpecTimeframes {
+		return fmt.Errorf("时间框架数量不能超过%d个", maxSpecTimeframes)
+	}
+	if s.Formatter == "" {
+		s.Formatter = "default"
+	}
+	if !hasFormatter(s.Formatter) {
+		return fmt.Errorf("不支持的输出格式: %s", s.Formatter)
+	}
+
+	for i := range s.Timeframes {
+		tf := &s.Timeframes[i]
+		if !IsSupportedInterval(tf.Interval) {
+			return fmt.Errorf("不支持的K线周期: %s (可选: %s)", tf.Interval, strings.Join(supportedIntervals, ", "))
+		}
+		if tf.Limit == 0 {
+			tf.Limit = defaultSpecLimit
+		}
+		if tf.Limit < 2 || tf.Limit > maxSpecLimit {
+			return fmt.Errorf("%s K线数量必须在2-%d之间", tf.Interval, maxSpecLimit)
+		}
+		if tf.SeriesLength <= 0 {
+			tf.SeriesLength = defaultSpecSeriesLength
+		}
+		if tf.SeriesLength > tf.Limit {
+			tf.SeriesLength = tf.Limit
+		}
+
+		for j := range tf.Indicators {
+			ind := &tf.Indicators[j]
+			ind.Name = strings.ToLower(strings.TrimSpace(ind.Name))
+			info, ok := indicatorInfo(ind.Name)
+			if !ok {
+				return fmt.Errorf("不支持的指标: %s", ind.Name)
+			}
+			if len(ind.Periods) == 0 {
+				ind.Periods = append([]int(nil), info.DefaultPeriods...)
+			}
+			if n := requiredPeriods[ind.Name]; n > 0 && len(ind.Periods) != n {
+				return fmt.Errorf("指标 %s 需要%d个周期参数", ind.Name, n)
+			}
+			for _, p := range ind.Periods {
+				if p <= 0 || p > tf.Limit {
+					return fmt.Errorf("指标 %s 的周期 %d 无效（需在1-%d之间）", ind.Name, p, tf.Limit)
+				}
+			}
+			if ind.Multiplier == 0 {
+				ind.Multiplier = info.DefaultMultiplier
+			}
+		}
+	}
+	return nil
+}
+
+// IsSupportedInterval 是否为支持的K线周期
+func IsSupportedInterval(interval string) bool {
+	for _, iv := range supportedIntervals {
+		if iv == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWithSpec 按指标配置获取市场数据（每个时间框架只请求一次K线）
+// spec 需先通过 Validate 补全默认值；为空时等同于 GetWithProvider
+func GetWithSpec(ctx context.Context, provider Provider, symbol string, spec *IndicatorSpec) (_ *Data, err error) {
+	if spec == nil || len(spec.Timeframes) == 0 {
+		return GetWithProvider(ctx, provider, symbol)
+	}
+	if provider == nil {
+		provider = DefaultProvider()
+	}
+	symbol = Normalize(symbol)
+
+	ctx, span := startGetSpan(ctx, provider, symbol)
+	defer func() { tracing.End(span, err) }()
+
+	// 同一周期只请求一次（取最大数量）
+	limits := make(map[string]int)
+	for _, tf := range spec.Timeframes {
+		if tf.Limit > limits[tf.Interval] {
+			limits[tf.Interval] = tf.Limit
+		}
+	}
+	klinesByInterval := make(map[string][]Kline, len(limits))
+	for interval, limit := range limits {
+		klines, err := provider.GetKlines(ctx, symbol, interval, limit)
+		if err != nil {
+			recordFetchFailure(provider, "klines")
+			return nil, fmt.Errorf("获取%s K线失败: %v", interval, err)
+		}
+		if len(klines) == 0 {
+			return nil, fmt.Errorf("%s 在 %s 无%s K线数据", symbol, provider.Name(), interval)
+		}
+		klinesByInterval[interval] = klines
+	}
+
+	// 按周期从短到长排序，当前价格和基础指标取最短周期
+	intervals := make([]string, 0, len(klinesByInterval))
+	for interval := range klinesByInterval {
+		intervals = append(intervals, interval)
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		di, _ := intervalDuration(intervals[i])
+		dj, _ := intervalDuration(intervals[j])
+		return di < dj
+	})
+	shortest := klinesByInterval[intervals[0]]
+	currentPrice := shortest[len(shortest)-1].Close
+
+	data := &Data{
+		Symbol:       symbol,
+		Source:       provider.Name(),
+		CurrentPrice: currentPrice,
+		CurrentEMA20: calculateEMA(shortest, 20),
+		CurrentMACD:  calculateMACD(shortest),
+		CurrentRSI7:  calculateRSI(shortest, 7),
+	}
+	data.PriceChange1h = priceChangeOver(klinesByInterval, intervals, time.Hour)
+	data.PriceChange4h = priceChangeOver(klinesByInterval, intervals, 4*time.Hour)
+
+	oiData, err := provider.GetOpenInterest(ctx, symbol)
+	if err != nil {
+		recordFetchFailure(provider, "open_interest")
+		oiData = &OIData{Latest: 0, Average: 0}
+	}
+	data.OpenInterest = oiData
+	if data.FundingRate, err = provider.GetFundingRate(ctx, symbol); err != nil {
+		recordFetchFailure(provider, "funding_rate")
+	}
+
+	for _, tf := range spec.Timeframes {
+		klines := klinesByInterval[tf.Interval]
+		if len(klines) > tf.Limit {
+			klines = klines[len(klines)-tf.Limit:]
+		}
+		data.Timeframes = append(data.Timeframes, computeTimeframe(klines, tf))
+	}
+	data.Formatter = spec.Formatter
+	attachMicrostructure(ctx, provider, data)
+
+	return data, nil
+}
+
+// priceChangeOver 计算一段时间内的价格变化百分比（使用能覆盖该时间段的最短周期）
+func priceChangeOver(klinesByInterval map[string][]Kline, intervals []string, d time.Duration) float64 {
+	for _, interval := range intervals {
+		step, err := intervalDuration(interval)
+		if err != nil || step > d {
+			continue
+		}
+		klines := klinesByInterval[interval]
+		bars := int(d / step)
+		if len(klines) <= bars {
+			continue
+		}
+		current := klines[len(klines)-1].Close
+		past := klines[len(klines)-1-bars].Close
+		if past > 0 {
+			return (current - past) / past * 100
+		}
+	}
+	return 0
+}
+
+// computeTimeframe 计算单个时间框架的全部指标
+func computeTimeframe(klines []Kline, tf TimeframeSpec) *TimeframeData {
+	closes := closesOf(klines)
+	step, _ := intervalDuration(tf.Interval)
+	n := tf.SeriesLength
+
+	result := &TimeframeData{
+		Interval: tf.Interval,
+		Prices:   tail(closes, n),
+	}
+	if len(closes) >= 2 && closes[len(closes)-2] > 0 {
+		result.PriceChange = (closes[len(closes)-1] - closes[len(closes)-2]) / closes[len(closes)-2] * 100
+	}
+
+	add := func(name string, values []float64) {
+		result.Indicators = append(result.Indicators, IndicatorSeries{Name: name, Values: tail(values, n)})
+	}
+
+	for _, ind := range tf.Indicators {
+		p := ind.Periods
+		switch ind.Name {
+		case "ema":
+			for _, period := range p {
+				add(fmt.Sprintf("EMA%d", period), emaSeries(closes, period))
+			}
+		case "sma":
+			for _, period := range p {
+				add(fmt.Sprintf("SMA%d", period), smaSeries(closes, period))
+			}
+		case "macd":
+			macd, signal, hist := macdSeries(closes, p[0], p[1], p[2])
+			add("MACD", macd)
+			add("MACD_signal", signal)
+			add("MACD_hist", hist)
+		case "rsi":
+			for _, period := range p {
+				add(fmt.Sprintf("RSI%d", period), rsiSeries(closes, period))
+			}
+		case "atr":
+			for _, period := range p {
+				add(fmt.Sprintf("ATR%d", period), atrSeries(klines, period))
+			}
+		case "bollinger":
+			upper, mid, lower := bollingerSeries(closes, p[0], ind.Multiplier)
+			add(fmt.Sprintf("BB%d_upper", p[0]), upper)
+			add(fmt.Sprintf("BB%d_mid", p[0]), mid)
+			add(fmt.Sprintf("BB%d_lower", p[0]), lower)
+		case "vwap":
+			add("VWAP", vwapSeries(klines, step))
+		case "stoch_rsi":
+			k, d := stochRSISeries(closes, p[0], p[1], p[2], p[3])
+			add("StochRSI_K", k)
+			add("StochRSI_D", d)
+		case "adx":
+			adx, plusDI, minusDI := adxSeries(klines, p[0])
+			add(fmt.Sprintf("ADX%d", p[0]), adx)
+			add("+DI", plusDI)
+			add("-DI", minusDI)
+		case "obv":
+			add("OBV", obvSeries(klines))
+		case "supertrend":
+			line, dir := supertrendSeries(klines, p[0], ind.Multiplier)
+			add("Supertrend", line)
+			add("Supertrend_dir", dir)
+		case "ichimoku":
+			tenkan, kijun, spanA, spanB := ichimokuSeries(klines, p[0], p[1], p[2])
+			add("Ichimoku_tenkan", tenkan)
+			add("Ichimoku_kijun", kijun)
+			add("Ichimoku_span_a", spanA)
+			add("Ichimoku_span_b", spanB)
+		case "volume":
+			volumes := make([]float64, len(klines))
+			for i, k := range klines {
+				volumes[i] = k.Volume
+			}
+			add("Volume", volumes)
+			add(fmt.Sprintf("Volume_SMA%d", p[0]), smaSeries(volumes, p[0]))
+		}
+	}
+
+	return result
+}
+
+// tail 取序列最后n个有效值（跳过NaN）
+func tail(values []float64, n int) []float64 {
+	start := len(values) - n
+	if start < 0 {
+		start = 0
+	}
+	out := make([]float64, 0, n)
+	for _, v := range values[start:] {
+		if !math.IsNaN(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -2,12 +2,19 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"nofx/logging"
+	"nofx/metrics"
+	"nofx/tracing"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Provider AI提供商类型
@@ -30,6 +37,31 @@ type Client struct {
 	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
 }
 
+// Usage AI调用的token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// mcpLog AI调用日志
+var mcpLog = logging.For("mcp")
+
+// tokenPrices 各提供商默认模型的参考价格（USD / 百万token，输入、输出），自定义API无法估算
+var tokenPrices = map[Provider][2]float64{
+	ProviderDeepSeek: {0.27, 1.10},
+	ProviderQwen:     {0.40, 1.20},
+}
+
+// CostUSD 按提供商参考价格估算费用（未知提供商返回0）
+func (u Usage) CostUSD(provider Provider) float64 {
+	price, ok := tokenPrices[provider]
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*price[0] + float64(u.CompletionTokens)*price[1]) / 1_000_000
+}
+
 func New() *Client {
 	// 默认配置
 	var defaultClient = Client{
@@ -85,47 +117,67 @@ func (cfg *Client) SetClient(Client Client) {
 }
 
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
-func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+// 返回AI回复和本次调用的token用量（调用失败时为零值），ctx 取消时立即中断请求和重试等待
+func (cfg *Client) CallWithMessages(ctx context.Context, systemPrompt, userPrompt string) (_ string, usage Usage, err error) {
 	if cfg.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
+		return "", Usage{}, fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
 	}
 
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "ai.call",
+		attribute.String("provider", string(cfg.Provider)),
+		attribute.String("model", cfg.Model),
+	)
+	defer func() {
+		metrics.ObserveAI(string(cfg.Provider), start, usage.PromptTokens, usage.CompletionTokens, err)
+		span.SetAttributes(
+			attribute.Int("ai.prompt_tokens", usage.PromptTokens),
+			attribute.Int("ai.completion_tokens", usage.CompletionTokens),
+		)
+		tracing.End(span, err)
+	}()
+
 	// 重试配置
 	maxRetries := 3
 	var lastErr error
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		if attempt > 1 {
-			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, maxRetries)
+			mcpLog.Warnf(ctx, "⚠️  AI API调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
+			span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt)))
 		}
 
-		result, err := cfg.callOnce(systemPrompt, userPrompt)
-		if err == nil {
+		result, resultUsage, callErr := cfg.callOnce(ctx, systemPrompt, userPrompt)
+		if callErr == nil {
 			if attempt > 1 {
-				fmt.Printf("✓ AI API重试成功\n")
+				mcpLog.Infof(ctx, "✓ AI API重试成功")
 			}
-			return result, nil
+			return result, resultUsage, nil
 		}
 
-		lastErr = err
-		// 如果不是网络错误，不重试
-		if !isRetryableError(err) {
-			return "", err
+		lastErr = callErr
+		// 已取消或不是网络错误，不重试
+		if ctx.Err() != nil || !isRetryableError(callErr) {
+			return "", Usage{}, callErr
 		}
 
 		// 重试前等待
 		if attempt < maxRetries {
 			waitTime := time.Duration(attempt) * 2 * time.Second
-			fmt.Printf("⏳ 等待%v后重试...\n", waitTime)
-			time.Sleep(waitTime)
+			mcpLog.Infof(ctx, "⏳ 等待%v后重试...", waitTime)
+			select {
+			case <-time.After(waitTime):
+			case <-ctx.Done():
+				return "", Usage{}, ctx.Err()
+			}
 		}
 	}
 
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+	return "", Usage{}, fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
 }
 
 // callOnce 单次调用AI API（内部使用）
-func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
+func (cfg *Client) callOnce(ctx context.Context, systemPrompt, userPrompt string) (string, Usage, error) {
 	// 构建 messages 数组
 	messages := []map[string]string{}
 
@@ -156,7 +208,7 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return "", Usage{}, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	// 创建HTTP请求
@@ -168,9 +220,9 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 		// 默认行为：添加/chat/completions
 		url = fmt.Sprintf("%s/chat/completions", cfg.BaseURL)
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return "", Usage{}, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -191,18 +243,18 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	client := &http.Client{Timeout: cfg.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return "", Usage{}, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return "", Usage{}, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", Usage{}, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	// 解析响应
@@ -212,17 +264,18 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage Usage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return "", Usage{}, fmt.Errorf("解析响应失败: %w", err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+		return "", Usage{}, fmt.Errorf("API返回空响应")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	return result.Choices[0].Message.Content, result.Usage, nil
 }
 
 // isRetryableError 判断错误是否可重试
@@ -0,0 +1,181 @@
+// Package metrics 定义 Prometheus 指标（通过 API 服务的 /metrics 暴露）
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "nofx"
+
+// 交易员账户指标（每个决策周期更新）
+var (
+	TraderEquity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "trader_equity_usdt",
+		Help:      "账户净值（USDT）",
+	}, []string{"trader_id"})
+
+	TraderMarginUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "trader_margin_used_usdt",
+		Help:      "持仓占用保证金（USDT）",
+	}, []string{"trader_id"})
+
+	TraderOpenPositions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "trader_open_positions",
+		Help:      "当前持仓数量",
+	}, []string{"trader_id"})
+
+	TraderUnrealizedPnL = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "trader_unrealized_pnl_usdt",
+		Help:      "未实现盈亏（USDT）",
+	}, []string{"trader_id"})
+)
+
+// 决策周期指标
+var (
+	Cycles = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "trader_cycles_total",
+		Help:      "决策周期总数",
+	}, []string{"trader_id"})
+
+	CyclesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "trader_cycles_failed_total",
+		Help:      "失败的决策周期数",
+	}, []string{"trader_id"})
+
+	Decisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "trader_decisions_total",
+		Help:      "AI决策数（按动作）",
+	}, []string{"trader_id", "action"})
+
+	// Orders 决策执行结果：success 成功、failed 交易所执行失败、rejected 被风控/敞口/保证金拒绝、skipped 停止中跳过
+	Orders = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "trader_orders_total",
+		Help:      "决策执行结果（按动作和结果）",
+	}, []string{"trader_id", "action", "result"})
+)
+
+// AI调用指标
+var (
+	AIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "AI API调用耗时（含重试）",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 45, 60, 90, 120, 180, 300},
+	}, []string{"provider", "result"})
+
+	AITokens = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_tokens",
+		Help:      "单次AI调用的token数（prompt/completion）",
+		Buckets:   prometheus.ExponentialBuckets(256, 2, 9), // 256 ~ 65536
+	}, []string{"provider", "type"})
+)
+
+// 交易所API指标
+var (
+	ExchangeRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "exchange_request_duration_seconds",
+		Help:      "交易所API调用耗时（按接口）",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"exchange", "endpoint"})
+
+	ExchangeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exchange_errors_total",
+		Help:      "交易所API调用失败数（按接口）",
+	}, []string{"exchange", "endpoint"})
+)
+
+// 交易所限流指标（按主机）
+var (
+	ExchangeRateLimitUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "exchange_ratelimit_used_weight",
+		Help:      "当前窗口已用权重（本地估算与交易所返回值取较大者）",
+	}, []string{"host"})
+
+	ExchangeRateLimitCapacity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "exchange_ratelimit_limit_weight",
+		Help:      "每个窗口的权重上限",
+	}, []string{"host"})
+
+	ExchangeRateLimitWaits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exchange_ratelimit_waits_total",
+		Help:      "因额度不足或暂停而排队的请求数（按优先级）",
+	}, []string{"host", "priority"})
+
+	ExchangeRateLimitThrottled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exchange_ratelimit_throttled_total",
+		Help:      "交易所返回 429/418 的次数",
+	}, []string{"host", "status"})
+)
+
+// 行情数据指标
+var (
+	MarketFetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "market_fetch_failures_total",
+		Help:      "行情数据获取失败数（按数据源和数据类型）",
+	}, []string{"source", "kind"})
+
+	// MarketDataRequests 币种行情请求来源：fetched 实际请求、shared 复用其他交易员进行中的请求、cache_hit 命中缓存
+	MarketDataRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "market_data_requests_total",
+		Help:      "币种行情数据请求数（按数据源和来源）",
+	}, []string{"source", "result"})
+)
+
+// Handler /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveExchange 记录一次交易所API调用
+func ObserveExchange(exchange, endpoint string, start time.Time, err error) {
+	ExchangeRequestDuration.WithLabelValues(exchange, endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ExchangeErrors.WithLabelValues(exchange, endpoint).Inc()
+	}
+}
+
+// ObserveAI 记录一次AI调用
+func ObserveAI(provider string, start time.Time, promptTokens, completionTokens int, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	AIRequestDuration.WithLabelValues(provider, result).Observe(time.Since(start).Seconds())
+	if err == nil {
+		AITokens.WithLabelValues(provider, "prompt").Observe(float64(promptTokens))
+		AITokens.WithLabelValues(provider, "completion").Observe(float64(completionTokens))
+	}
+}
+
+// DeleteTrader 移除交易员的所有指标（交易员删除后不再上报旧值）
+func DeleteTrader(traderID string) {
+	labels := prometheus.Labels{"trader_id": traderID}
+	for _, vec := range []*prometheus.GaugeVec{TraderEquity, TraderMarginUsed, TraderOpenPositions, TraderUnrealizedPnL} {
+		vec.DeletePartialMatch(labels)
+	}
+	for _, vec := range []*prometheus.CounterVec{Cycles, CyclesFailed, Decisions, Orders} {
+		vec.DeletePartialMatch(labels)
+	}
+}
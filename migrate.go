@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"nofx/config"
+	"os"
+)
+
+// runMigrate 执行数据库迁移子命令
+// 用法: nofx migrate <up|status> [数据库文件路径]
+func runMigrate(args []string) int {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, "用法: nofx migrate <up|status> [数据库文件路径]")
+		return 2
+	}
+
+	dbPath := "config.db"
+	if len(args) > 1 {
+		dbPath = args[1]
+	}
+
+	database, err := config.ConnectDatabase(config.ResolveDatabaseOptions(dbPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 连接数据库失败: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	if args[0] == "up" {
+		executed, err := database.MigrateUp()
+		for _, m := range executed {
+			fmt.Printf("✓ %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 迁移失败: %v\n", err)
+			return 1
+		}
+		if len(executed) == 0 {
+			fmt.Println("✓ 数据库已是最新版本，无需迁移")
+		}
+	}
+
+	return printMigrationStatus(database)
+}
+
+// printMigrationStatus 打印迁移执行状态
+func printMigrationStatus(database *config.Database) int {
+	statuses, err := database.MigrationStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 读取迁移状态失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("📋 数据库后端: %s\n", database.Backend())
+	pending := 0
+	for _, status := range statuses {
+		switch {
+		case status.Unknown:
+			fmt.Printf("  ⚠️  %04d  (程序中不存在)  已执行于 %s\n", status.Version, status.AppliedAt.Format("2006-01-02 15:04:05"))
+		case status.Applied:
+			fmt.Printf("  ✅ %04d_%s  已执行于 %s\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+		default:
+			pending++
+			fmt.Printf("  ⏳ %04d_%s  未执行\n", status.Version, status.Name)
+		}
+	}
+	if pending > 0 {
+		fmt.Printf("共 %d 个未执行的迁移，运行 nofx migrate up 执行\n", pending)
+	}
+	return 0
+}
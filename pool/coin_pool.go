@@ -1,17 +1,24 @@
 package pool
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"nofx/logging"
+	"nofx/ratelimit"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+// poolLog 币种池日志
+var poolLog = logging.For("pool")
+
 // defaultMainstreamCoins 默认主流币种池（从配置文件读取）
 var defaultMainstreamCoins = []string{
 	"BTCUSDT",
@@ -27,16 +34,63 @@ var defaultMainstreamCoins = []string{
 // CoinPoolConfig 币种池配置
 type CoinPoolConfig struct {
 	APIURL          string
+	OITopAPIURL     string
+	ScreenerBaseURL string // 本地选币器使用的 Binance fapi 兼容地址（为空使用 Binance）
 	Timeout         time.Duration
 	CacheDir        string
-	UseDefaultCoins bool // 是否使用默认主流币种
+	UseDefaultCoins bool     // 是否使用默认主流币种
+	DefaultCoins    []string // 默认主流币种（为空时使用内置列表）
+}
+
+// 全局默认配置（启动时从系统配置设置），新建的币种池以此为基础
+var (
+	defaultConfigMu sync.RWMutex
+	defaultConfig   = CoinPoolConfig{
+		APIURL:          "",
+		OITopAPIURL:     "",
+		Timeout:         30 * time.Second, // 增加到30秒
+		CacheDir:        "coin_pool_cache",
+		UseDefaultCoins: false, // 默认不使用
+	}
+)
+
+// DefaultConfig 获取全局默认币种池配置的副本
+func DefaultConfig() CoinPoolConfig {
+	defaultConfigMu.RLock()
+	defer defaultConfigMu.RUnlock()
+
+	cfg := defaultConfig
+	cfg.DefaultCoins = append([]string(nil), defaultMainstreamCoins...)
+	return cfg
+}
+
+// Pool 币种池（每个交易员持有独立实例，配置互不影响）
+type Pool struct {
+	config CoinPoolConfig
+	client *http.Client
+}
+
+// NewPool 根据配置创建币种池
+func NewPool(config CoinPoolConfig) *Pool {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.CacheDir == "" {
+		config.CacheDir = "coin_pool_cache"
+	}
+	if len(config.DefaultCoins) == 0 {
+		config.DefaultCoins = DefaultConfig().DefaultCoins
+	}
+	config.DefaultCoins = append([]string(nil), config.DefaultCoins...)
+	return &Pool{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout, Transport: ratelimit.NewTransport(nil)},
+	}
 }
 
-var coinPoolConfig = CoinPoolConfig{
-	APIURL:          "",
-	Timeout:         30 * time.Second, // 增加到30秒
-	CacheDir:        "coin_pool_cache",
-	UseDefaultCoins: false, // 默认不使用
+// Config 获取币种池配置
+func (p *Pool) Config() CoinPoolConfig {
+	return p.config
 }
 
 // CoinPoolCache 币种池缓存
@@ -68,41 +122,49 @@ type CoinPoolAPIResponse struct {
 	} `json:"data"`
 }
 
-// SetCoinPoolAPI 设置币种池API
+// SetCoinPoolAPI 设置默认币种池API（只影响之后创建的币种池）
 func SetCoinPoolAPI(apiURL string) {
-	coinPoolConfig.APIURL = apiURL
+	defaultConfigMu.Lock()
+	defer defaultConfigMu.Unlock()
+	defaultConfig.APIURL = apiURL
 }
 
-// SetOITopAPI 设置OI Top API
+// SetOITopAPI 设置默认OI Top API（只影响之后创建的币种池）
 func SetOITopAPI(apiURL string) {
-	oiTopConfig.APIURL = apiURL
+	defaultConfigMu.Lock()
+	defer defaultConfigMu.Unlock()
+	defaultConfig.OITopAPIURL = apiURL
 }
 
-// SetUseDefaultCoins 设置是否使用默认主流币种
+// SetUseDefaultCoins 设置默认是否使用默认主流币种（只影响之后创建的币种池）
 func SetUseDefaultCoins(useDefault bool) {
-	coinPoolConfig.UseDefaultCoins = useDefault
+	defaultConfigMu.Lock()
+	defer defaultConfigMu.Unlock()
+	defaultConfig.UseDefaultCoins = useDefault
 }
 
-// SetDefaultCoins 设置默认主流币种列表
+// SetDefaultCoins 设置默认主流币种列表（只影响之后创建的币种池）
 func SetDefaultCoins(coins []string) {
 	if len(coins) > 0 {
-		defaultMainstreamCoins = coins
-		log.Printf("✓ 已设置默认币种池（共%d个币种）: %v", len(coins), coins)
+		defaultConfigMu.Lock()
+		defaultMainstreamCoins = append([]string(nil), coins...)
+		defaultConfigMu.Unlock()
+		poolLog.Infof(context.Background(), "✓ 已设置默认币种池（共%d个币种）: %v", len(coins), coins)
 	}
 }
 
 // GetCoinPool 获取币种池列表（带重试和缓存机制）
-func GetCoinPool() ([]CoinInfo, error) {
+func (p *Pool) GetCoinPool(ctx context.Context) ([]CoinInfo, error) {
 	// 优先检查是否启用默认币种列表
-	if coinPoolConfig.UseDefaultCoins {
-		log.Printf("✓ 已启用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+	if p.config.UseDefaultCoins {
+		poolLog.Infof(ctx, "✓ 已启用默认主流币种列表")
+		return convertSymbolsToCoins(p.config.DefaultCoins), nil
 	}
 
 	// 检查API URL是否配置
-	if strings.TrimSpace(coinPoolConfig.APIURL) == "" {
-		log.Printf("⚠️  未配置币种池API URL，使用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+	if strings.TrimSpace(p.config.APIURL) == "" {
+		poolLog.Warnf(ctx, "⚠️  未配置币种池API URL，使用默认主流币种列表")
+		return convertSymbolsToCoins(p.config.DefaultCoins), nil
 	}
 
 	maxRetries := 3
@@ -111,48 +173,50 @@ func GetCoinPool() ([]CoinInfo, error) {
 	// 尝试从API获取
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		if attempt > 1 {
-			log.Printf("⚠️  第%d次重试获取币种池（共%d次）...", attempt, maxRetries)
-			time.Sleep(2 * time.Second) // 重试前等待2秒
+			poolLog.Warnf(ctx, "⚠️  第%d次重试获取币种池（共%d次）...", attempt, maxRetries)
+			if err := sleepContext(ctx, 2*time.Second); err != nil { // 重试前等待2秒
+				return nil, err
+			}
 		}
 
-		coins, err := fetchCoinPool()
+		coins, err := p.fetchCoinPool(ctx)
 		if err == nil {
 			if attempt > 1 {
-				log.Printf("✓ 第%d次重试成功", attempt)
+				poolLog.Infof(ctx, "✓ 第%d次重试成功", attempt)
 			}
 			// 成功获取后保存到缓存
-			if err := saveCoinPoolCache(coins); err != nil {
-				log.Printf("⚠️  保存币种池缓存失败: %v", err)
+			if err := p.saveCoinPoolCache(ctx, coins); err != nil {
+				poolLog.Warnf(ctx, "⚠️  保存币种池缓存失败: %v", err)
 			}
 			return coins, nil
 		}
 
 		lastErr = err
-		log.Printf("❌ 第%d次请求失败: %v", attempt, err)
+		poolLog.Errorf(ctx, "❌ 第%d次请求失败: %v", attempt, err)
 	}
 
 	// API获取失败，尝试使用缓存
-	log.Printf("⚠️  API请求全部失败，尝试使用历史缓存数据...")
-	cachedCoins, err := loadCoinPoolCache()
+	poolLog.Warnf(ctx, "⚠️  API请求全部失败，尝试使用历史缓存数据...")
+	cachedCoins, err := p.loadCoinPoolCache(ctx)
 	if err == nil {
-		log.Printf("✓ 使用历史缓存数据（共%d个币种）", len(cachedCoins))
+		poolLog.Infof(ctx, "✓ 使用历史缓存数据（共%d个币种）", len(cachedCoins))
 		return cachedCoins, nil
 	}
 
 	// 缓存也失败，使用默认主流币种
-	log.Printf("⚠️  无法加载缓存数据（最后错误: %v），使用默认主流币种列表", lastErr)
-	return convertSymbolsToCoins(defaultMainstreamCoins), nil
+	poolLog.Warnf(ctx, "⚠️  无法加载缓存数据（最后错误: %v），使用默认主流币种列表", lastErr)
+	return convertSymbolsToCoins(p.config.DefaultCoins), nil
 }
 
 // fetchCoinPool 实际执行币种池请求
-func fetchCoinPool() ([]CoinInfo, error) {
-	log.Printf("🔄 正在请求AI500币种池...")
+func (p *Pool) fetchCoinPool(ctx context.Context) ([]CoinInfo, error) {
+	poolLog.Infof(ctx, "🔄 正在请求AI500币种池...")
 
-	client := &http.Client{
-		Timeout: coinPoolConfig.Timeout,
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.APIURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建币种池请求失败: %w", err)
 	}
-
-	resp, err := client.Get(coinPoolConfig.APIURL)
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求币种池API失败: %w", err)
 	}
@@ -187,14 +251,44 @@ func fetchCoinPool() ([]CoinInfo, error) {
 		coins[i].IsAvailable = true
 	}
 
-	log.Printf("✓ 成功获取%d个币种", len(coins))
+	poolLog.Infof(ctx, "✓ 成功获取%d个币种", len(coins))
 	return coins, nil
 }
 
+// sleepContext 等待指定时间，ctx 取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cachePath 缓存文件路径（按API地址区分，不同配置的币种池互不覆盖）
+func (p *Pool) cachePath(prefix, apiURL string) string {
+	return filepath.Join(p.config.CacheDir, fmt.Sprintf("%s_%08x.json", prefix, crc32.ChecksumIEEE([]byte(apiURL))))
+}
+
+// writeCacheFile 先写临时文件再重命名，避免多个交易员同时写入时读到半个文件
+func writeCacheFile(path string, data []byte) error {
+	tmpPath := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // saveCoinPoolCache 保存币种池到缓存文件
-func saveCoinPoolCache(coins []CoinInfo) error {
+func (p *Pool) saveCoinPoolCache(ctx context.Context, coins []CoinInfo) error {
 	// 确保缓存目录存在
-	if err := os.MkdirAll(coinPoolConfig.CacheDir, 0755); err != nil {
+	if err := os.MkdirAll(p.config.CacheDir, 0755); err != nil {
 		return fmt.Errorf("创建缓存目录失败: %w", err)
 	}
 
@@ -209,18 +303,18 @@ func saveCoinPoolCache(coins []CoinInfo) error {
 		return fmt.Errorf("序列化缓存数据失败: %w", err)
 	}
 
-	cachePath := filepath.Join(coinPoolConfig.CacheDir, "latest.json")
-	if err := ioutil.WriteFile(cachePath, data, 0644); err != nil {
+	cachePath := p.cachePath("latest", p.config.APIURL)
+	if err := writeCacheFile(cachePath, data); err != nil {
 		return fmt.Errorf("写入缓存文件失败: %w", err)
 	}
 
-	log.Printf("💾 已保存币种池缓存（%d个币种）", len(coins))
+	poolLog.Infof(ctx, "💾 已保存币种池缓存（%d个币种）", len(coins))
 	return nil
 }
 
 // loadCoinPoolCache 从缓存文件加载币种池
-func loadCoinPoolCache() ([]CoinInfo, error) {
-	cachePath := filepath.Join(coinPoolConfig.CacheDir, "latest.json")
+func (p *Pool) loadCoinPoolCache(ctx context.Context) ([]CoinInfo, error) {
+	cachePath := p.cachePath("latest", p.config.APIURL)
 
 	// 检查文件是否存在
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
@@ -240,9 +334,9 @@ func loadCoinPoolCache() ([]CoinInfo, error) {
 	// 检查缓存年龄
 	cacheAge := time.Since(cache.FetchedAt)
 	if cacheAge > 24*time.Hour {
-		log.Printf("⚠️  缓存数据较旧（%.1f小时前），但仍可使用", cacheAge.Hours())
+		poolLog.Warnf(ctx, "⚠️  缓存数据较旧（%.1f小时前），但仍可使用", cacheAge.Hours())
 	} else {
-		log.Printf("📂 缓存数据时间: %s（%.1f分钟前）",
+		poolLog.Infof(ctx, "📂 缓存数据时间: %s（%.1f分钟前）",
 			cache.FetchedAt.Format("2006-01-02 15:04:05"),
 			cacheAge.Minutes())
 	}
@@ -251,8 +345,8 @@ func loadCoinPoolCache() ([]CoinInfo, error) {
 }
 
 // GetAvailableCoins 获取可用的币种列表（过滤不可用的）
-func GetAvailableCoins() ([]string, error) {
-	coins, err := GetCoinPool()
+func (p *Pool) GetAvailableCoins(ctx context.Context) ([]string, error) {
+	coins, err := p.GetCoinPool(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -274,12 +368,16 @@ func GetAvailableCoins() ([]string, error) {
 }
 
 // GetTopRatedCoins 获取评分最高的N个币种（按评分从大到小排序）
-func GetTopRatedCoins(limit int) ([]string, error) {
-	coins, err := GetCoinPool()
+func (p *Pool) GetTopRatedCoins(ctx context.Context, limit int) ([]string, error) {
+	coins, err := p.GetCoinPool(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return topRatedSymbols(coins, limit)
+}
 
+// topRatedSymbols 从币种列表中取评分最高的N个币种
+func topRatedSymbols(coins []CoinInfo, limit int) ([]string, error) {
 	// 过滤可用的币种
 	var availableCoins []CoinInfo
 	for _, coin := range coins {
@@ -408,21 +506,11 @@ type OITopCache struct {
 	SourceType string       `json:"source_type"`
 }
 
-var oiTopConfig = struct {
-	APIURL   string
-	Timeout  time.Duration
-	CacheDir string
-}{
-	APIURL:   "",
-	Timeout:  30 * time.Second,
-	CacheDir: "coin_pool_cache",
-}
-
 // GetOITopPositions 获取持仓量增长Top20数据（带重试和缓存）
-func GetOITopPositions() ([]OIPosition, error) {
+func (p *Pool) GetOITopPositions(ctx context.Context) ([]OIPosition, error) {
 	// 检查API URL是否配置
-	if strings.TrimSpace(oiTopConfig.APIURL) == "" {
-		log.Printf("⚠️  未配置OI Top API URL，跳过OI Top数据获取")
+	if strings.TrimSpace(p.config.OITopAPIURL) == "" {
+		poolLog.Warnf(ctx, "⚠️  未配置OI Top API URL，跳过OI Top数据获取")
 		return []OIPosition{}, nil // 返回空列表，不是错误
 	}
 
@@ -432,48 +520,50 @@ func GetOITopPositions() ([]OIPosition, error) {
 	// 尝试从API获取
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		if attempt > 1 {
-			log.Printf("⚠️  第%d次重试获取OI Top数据（共%d次）...", attempt, maxRetries)
-			time.Sleep(2 * time.Second)
+			poolLog.Warnf(ctx, "⚠️  第%d次重试获取OI Top数据（共%d次）...", attempt, maxRetries)
+			if err := sleepContext(ctx, 2*time.Second); err != nil {
+				return nil, err
+			}
 		}
 
-		positions, err := fetchOITop()
+		positions, err := p.fetchOITop(ctx)
 		if err == nil {
 			if attempt > 1 {
-				log.Printf("✓ 第%d次重试成功", attempt)
+				poolLog.Infof(ctx, "✓ 第%d次重试成功", attempt)
 			}
 			// 成功获取后保存到缓存
-			if err := saveOITopCache(positions); err != nil {
-				log.Printf("⚠️  保存OI Top缓存失败: %v", err)
+			if err := p.saveOITopCache(ctx, positions); err != nil {
+				poolLog.Warnf(ctx, "⚠️  保存OI Top缓存失败: %v", err)
 			}
 			return positions, nil
 		}
 
 		lastErr = err
-		log.Printf("❌ 第%d次请求OI Top失败: %v", attempt, err)
+		poolLog.Errorf(ctx, "❌ 第%d次请求OI Top失败: %v", attempt, err)
 	}
 
 	// API获取失败，尝试使用缓存
-	log.Printf("⚠️  OI Top API请求全部失败，尝试使用历史缓存数据...")
-	cachedPositions, err := loadOITopCache()
+	poolLog.Warnf(ctx, "⚠️  OI Top API请求全部失败，尝试使用历史缓存数据...")
+	cachedPositions, err := p.loadOITopCache(ctx)
 	if err == nil {
-		log.Printf("✓ 使用历史OI Top缓存数据（共%d个币种）", len(cachedPositions))
+		poolLog.Infof(ctx, "✓ 使用历史OI Top缓存数据（共%d个币种）", len(cachedPositions))
 		return cachedPositions, nil
 	}
 
 	// 缓存也失败，返回空列表（OI Top是可选的）
-	log.Printf("⚠️  无法加载OI Top缓存数据（最后错误: %v），跳过OI Top数据", lastErr)
+	poolLog.Warnf(ctx, "⚠️  无法加载OI Top缓存数据（最后错误: %v），跳过OI Top数据", lastErr)
 	return []OIPosition{}, nil
 }
 
 // fetchOITop 实际执行OI Top请求
-func fetchOITop() ([]OIPosition, error) {
-	log.Printf("🔄 正在请求OI Top数据...")
+func (p *Pool) fetchOITop(ctx context.Context) ([]OIPosition, error) {
+	poolLog.Infof(ctx, "🔄 正在请求OI Top数据...")
 
-	client := &http.Client{
-		Timeout: oiTopConfig.Timeout,
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.OITopAPIURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建OI Top请求失败: %w", err)
 	}
-
-	resp, err := client.Get(oiTopConfig.APIURL)
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求OI Top API失败: %w", err)
 	}
@@ -502,14 +592,14 @@ func fetchOITop() ([]OIPosition, error) {
 		return nil, fmt.Errorf("OI Top持仓列表为空")
 	}
 
-	log.Printf("✓ 成功获取%d个OI Top币种（时间范围: %s）",
+	poolLog.Infof(ctx, "✓ 成功获取%d个OI Top币种（时间范围: %s）",
 		len(response.Data.Positions), response.Data.TimeRange)
 	return response.Data.Positions, nil
 }
 
 // saveOITopCache 保存OI Top数据到缓存
-func saveOITopCache(positions []OIPosition) error {
-	if err := os.MkdirAll(oiTopConfig.CacheDir, 0755); err != nil {
+func (p *Pool) saveOITopCache(ctx context.Context, positions []OIPosition) error {
+	if err := os.MkdirAll(p.config.CacheDir, 0755); err != nil {
 		return fmt.Errorf("创建缓存目录失败: %w", err)
 	}
 
@@ -524,18 +614,18 @@ func saveOITopCache(positions []OIPosition) error {
 		return fmt.Errorf("序列化OI Top缓存数据失败: %w", err)
 	}
 
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
-	if err := ioutil.WriteFile(cachePath, data, 0644); err != nil {
+	cachePath := p.cachePath("oi_top_latest", p.config.OITopAPIURL)
+	if err := writeCacheFile(cachePath, data); err != nil {
 		return fmt.Errorf("写入OI Top缓存文件失败: %w", err)
 	}
 
-	log.Printf("💾 已保存OI Top缓存（%d个币种）", len(positions))
+	poolLog.Infof(ctx, "💾 已保存OI Top缓存（%d个币种）", len(positions))
 	return nil
 }
 
 // loadOITopCache 从缓存加载OI Top数据
-func loadOITopCache() ([]OIPosition, error) {
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
+func (p *Pool) loadOITopCache(ctx context.Context) ([]OIPosition, error) {
+	cachePath := p.cachePath("oi_top_latest", p.config.OITopAPIURL)
 
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("OI Top缓存文件不存在")
@@ -553,9 +643,9 @@ func loadOITopCache() ([]OIPosition, error) {
 
 	cacheAge := time.Since(cache.FetchedAt)
 	if cacheAge > 24*time.Hour {
-		log.Printf("⚠️  OI Top缓存数据较旧（%.1f小时前），但仍可使用", cacheAge.Hours())
+		poolLog.Warnf(ctx, "⚠️  OI Top缓存数据较旧（%.1f小时前），但仍可使用", cacheAge.Hours())
 	} else {
-		log.Printf("📂 OI Top缓存数据时间: %s（%.1f分钟前）",
+		poolLog.Infof(ctx, "📂 OI Top缓存数据时间: %s（%.1f分钟前）",
 			cache.FetchedAt.Format("2006-01-02 15:04:05"),
 			cacheAge.Minutes())
 	}
@@ -564,8 +654,8 @@ func loadOITopCache() ([]OIPosition, error) {
 }
 
 // GetOITopSymbols 获取OI Top的币种符号列表
-func GetOITopSymbols() ([]string, error) {
-	positions, err := GetOITopPositions()
+func (p *Pool) GetOITopSymbols(ctx context.Context) ([]string, error) {
+	positions, err := p.GetOITopPositions(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -579,67 +669,114 @@ func GetOITopSymbols() ([]string, error) {
 	return symbols, nil
 }
 
-// MergedCoinPool 合并的币种池（AI500 + OI Top）
+// MergedCoinPool 合并的币种池（AI500 + OI Top + 静态列表 + 本地选币器）
 type MergedCoinPool struct {
 	AI500Coins    []CoinInfo          // AI500评分币种
 	OITopCoins    []OIPosition        // 持仓量增长Top20
-	AllSymbols    []string            // 所有不重复的币种符号
-	SymbolSources map[string][]string // 每个币种的来源（"ai500"/"oi_top"）
+	AllSymbols    []string            // 所有不重复的币种符号（按来源优先级排序）
+	SymbolSources map[string][]string // 每个币种的来源（"ai500"/"oi_top"/"static"/"screener"）
 }
 
 // GetMergedCoinPool 获取合并后的币种池（AI500 + OI Top，去重）
-func GetMergedCoinPool(ai500Limit int) (*MergedCoinPool, error) {
-	// 1. 获取AI500数据
-	ai500TopSymbols, err := GetTopRatedCoins(ai500Limit)
-	if err != nil {
-		log.Printf("⚠️  获取AI500数据失败: %v", err)
-		ai500TopSymbols = []string{} // 失败时用空列表
-	}
+func (p *Pool) GetMergedCoinPool(ctx context.Context, ai500Limit int) (*MergedCoinPool, error) {
+	return p.GetUniverse(ctx, Universe{AI500Limit: ai500Limit})
+}
 
-	// 2. 获取OI Top数据
-	oiTopSymbols, err := GetOITopSymbols()
-	if err != nil {
-		log.Printf("⚠️  获取OI Top数据失败: %v", err)
-		oiTopSymbols = []string{} // 失败时用空列表
+// GetUniverse 按交易员的币种范围配置构建候选币种池
+// 顺序：静态白名单 → AI500（按评分）→ 本地选币器（按评分）→ OI Top（按排名），去重后剔除黑名单并截断到候选上限
+func (p *Pool) GetUniverse(ctx context.Context, universe Universe) (*MergedCoinPool, error) {
+	merged := &MergedCoinPool{
+		AI500Coins:    []CoinInfo{},
+		OITopCoins:    []OIPosition{},
+		AllSymbols:    []string{},
+		SymbolSources: make(map[string][]string),
 	}
 
-	// 3. 合并并去重
-	symbolSet := make(map[string]bool)
-	symbolSources := make(map[string][]string)
+	var staticSymbols, ai500TopSymbols, screenerSymbols, oiTopSymbols []string
+
+	// 1. 静态白名单
+	if universe.HasSource(SourceStatic) {
+		for _, symbol := range universe.Whitelist {
+			staticSymbols = append(staticSymbols, normalizeSymbol(symbol))
+		}
+	}
 
-	// 添加AI500币种
-	for _, symbol := range ai500TopSymbols {
-		symbolSet[symbol] = true
-		symbolSources[symbol] = append(symbolSources[symbol], "ai500")
+	// 2. 获取AI500数据
+	if universe.HasSource(SourceAI500) {
+		coins, err := p.GetCoinPool(ctx)
+		if err == nil {
+			merged.AI500Coins = coins
+			ai500TopSymbols, err = topRatedSymbols(coins, universe.ai500Limit())
+		}
+		if err != nil {
+			poolLog.Warnf(ctx, "⚠️  获取AI500数据失败: %v", err)
+			ai500TopSymbols = []string{} // 失败时用空列表
+		}
 	}
 
-	// 添加OI Top币种
-	for _, symbol := range oiTopSymbols {
-		if !symbolSet[symbol] {
-			symbolSet[symbol] = true
+	// 3. 获取OI Top数据
+	if universe.HasSource(SourceOITop) {
+		positions, err := p.GetOITopPositions(ctx)
+		if err != nil {
+			poolLog.Warnf(ctx, "⚠️  获取OI Top数据失败: %v", err)
+			positions = []OIPosition{} // 失败时用空列表
+		}
+		merged.OITopCoins = positions
+		for _, pos := range positions {
+			oiTopSymbols = append(oiTopSymbols, normalizeSymbol(pos.Symbol))
 		}
-		symbolSources[symbol] = append(symbolSources[symbol], "oi_top")
 	}
 
-	// 转换为数组
-	var allSymbols []string
-	for symbol := range symbolSet {
-		allSymbols = append(allSymbols, symbol)
+	// 4. 本地选币器（OI增长数据补充到OI Top中，已有的币种以OI Top接口为准）
+	if universe.HasSource(SourceScreener) {
+		result, err := NewScreener(p.config.ScreenerBaseURL, universe.screenerConfig()).Screen(ctx)
+		if err != nil {
+			poolLog.Warnf(ctx, "⚠️  本地选币器失败: %v", err)
+		} else {
+			for _, coin := range result.Coins {
+				screenerSymbols = append(screenerSymbols, normalizeSymbol(coin.Pair))
+			}
+			existing := make(map[string]bool)
+			for _, pos := range merged.OITopCoins {
+				existing[normalizeSymbol(pos.Symbol)] = true
+			}
+			for _, pos := range result.OITop {
+				if !existing[pos.Symbol] {
+					merged.OITopCoins = append(merged.OITopCoins, pos)
+				}
+			}
+		}
 	}
 
-	// 获取完整数据
-	ai500Coins, _ := GetCoinPool()
-	oiTopPositions, _ := GetOITopPositions()
+	// 5. 合并去重并过滤黑名单
+	blacklisted := make(map[string]bool)
+	add := func(symbols []string, source string) {
+		for _, symbol := range symbols {
+			if universe.IsBlacklisted(symbol) {
+				blacklisted[symbol] = true
+				continue
+			}
+			if _, exists := merged.SymbolSources[symbol]; !exists {
+				merged.AllSymbols = append(merged.AllSymbols, symbol)
+			}
+			merged.SymbolSources[symbol] = append(merged.SymbolSources[symbol], source)
+		}
+	}
+	add(staticSymbols, SourceStatic)
+	add(ai500TopSymbols, SourceAI500)
+	add(screenerSymbols, SourceScreener)
+	add(oiTopSymbols, SourceOITop)
 
-	merged := &MergedCoinPool{
-		AI500Coins:    ai500Coins,
-		OITopCoins:    oiTopPositions,
-		AllSymbols:    allSymbols,
-		SymbolSources: symbolSources,
+	// 6. 截断到候选上限
+	if universe.MaxCandidates > 0 && len(merged.AllSymbols) > universe.MaxCandidates {
+		for _, symbol := range merged.AllSymbols[universe.MaxCandidates:] {
+			delete(merged.SymbolSources, symbol)
+		}
+		merged.AllSymbols = merged.AllSymbols[:universe.MaxCandidates]
 	}
 
-	log.Printf("📊 币种池合并完成: AI500=%d, OI_Top=%d, 总计(去重)=%d",
-		len(ai500TopSymbols), len(oiTopSymbols), len(allSymbols))
+	poolLog.Infof(ctx, "📊 币种池合并完成: 静态=%d, AI500=%d, 选币器=%d, OI_Top=%d, 黑名单剔除=%d, 总计(去重)=%d",
+		len(staticSymbols), len(ai500TopSymbols), len(screenerSymbols), len(oiTopSymbols), len(blacklisted), len(merged.AllSymbols))
 
 	return merged, nil
 }
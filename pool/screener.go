@@ -0,0 +1,574 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"nofx/ratelimit"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ========== 本地选币器（AI500/OI Top HTTP接口的本地替代） ==========
+
+// ScreenerWeights 选币因子权重（按横截面排名百分位加权，权重之和不必为1）
+type ScreenerWeights struct {
+	OIChange    float64 `json:"oi_change"`    // 持仓量变化（最近4小时）
+	VolumeSurge float64 `json:"volume_surge"` // 成交量放大（最近1小时成交额 / 前24小时均值）
+	Volatility  float64 `json:"volatility"`   // 24小时振幅
+	Momentum    float64 `json:"momentum"`     // 4小时涨跌幅绝对值
+	Funding     float64 `json:"funding"`      // 资金费率绝对值（极端费率）
+}
+
+// ScreenerConfig 本地选币器配置
+type ScreenerConfig struct {
+	Weights        ScreenerWeights `json:"weights"`
+	MinQuoteVolume float64         `json:"min_quote_volume"` // 24小时成交额下限（USDT）
+	Preselect      int             `json:"preselect"`        // 按成交额预选的币种数（只对这些币种请求OI历史和K线）
+	Limit          int             `json:"limit"`            // 输出评分最高的币种数
+	OITopLimit     int             `json:"oi_top_limit"`     // 输出持仓量增长最多的币种数
+}
+
+// DefaultScreenerConfig 默认选币器配置
+func DefaultScreenerConfig() ScreenerConfig {
+	return ScreenerConfig{
+		Weights: ScreenerWeights{
+			OIChange:    0.30,
+			VolumeSurge: 0.25,
+			Volatility:  0.15,
+			Momentum:    0.20,
+			Funding:     0.10,
+		},
+		MinQuoteVolume: 50_000_000,
+		Preselect:      60,
+		Limit:          20,
+		OITopLimit:     20,
+	}
+}
+
+// Validate 校验选币器配置
+func (c *ScreenerConfig) Validate() error {
+	w := c.Weights
+	for name, v := range map[string]float64{
+		"oi_change": w.OIChange, "volume_surge": w.VolumeSurge, "volatility": w.Volatility,
+		"momentum": w.Momentum, "funding": w.Funding,
+	} {
+		if v < 0 {
+			return fmt.Errorf("因子权重 %s 不能为负数: %.2f", name, v)
+		}
+	}
+	if w.OIChange+w.VolumeSurge+w.Volatility+w.Momentum+w.Funding <= 0 {
+		return fmt.Errorf("至少需要一个因子权重大于0")
+	}
+	if c.MinQuoteVolume < 0 {
+		return fmt.Errorf("min_quote_volume 不能为负数: %.0f", c.MinQuoteVolume)
+	}
+	if c.Preselect <= 0 || c.Limit <= 0 || c.OITopLimit < 0 {
+		return fmt.Errorf("preselect 和 limit 必须大于0，oi_top_limit 不能为负数")
+	}
+	if c.Limit > c.Preselect {
+		return fmt.Errorf("limit(%d) 不能大于 preselect(%d)", c.Limit, c.Preselect)
+	}
+	return nil
+}
+
+// ScreenerResult 选币结果（与AI500/OI Top接口相同的数据结构）
+type ScreenerResult struct {
+	Coins     []CoinInfo   `json:"coins"`      // 按评分降序
+	OITop     []OIPosition `json:"oi_top"`     // 按持仓量增长降序
+	FetchedAt time.Time    `json:"fetched_at"` // 计算时间
+}
+
+// screenerCacheTTL 选币结果缓存时间（同一配置的多个交易员共享）
+const screenerCacheTTL = 5 * time.Minute
+
+// screenerWorkers 并发请求单币种数据的协程数
+const screenerWorkers = 8
+
+// screenerTimeout 单次全市场扫描的超时时间（扫描不随单个调用方取消而中断）
+const screenerTimeout = 2 * time.Minute
+
+var (
+	screenerCacheMu sync.Mutex
+	screenerCache   = make(map[string]*ScreenerResult)
+	screenerFlight  singleflight.Group // 缓存过期时合并相同配置的并发扫描
+
+	// screenerClient 选币器共用的HTTP客户端（与行情、交易器共享按主机的限流额度）
+	screenerClient = &http.Client{Timeout: 15 * time.Second, Transport: ratelimit.NewTransport(nil)}
+)
+
+// Screener 本地选币器：使用交易所公开的行情、持仓量和资金费率数据对所有USDT永续合约打分
+type Screener struct {
+	baseURL string
+	client  *http.Client
+	config  ScreenerConfig
+}
+
+// NewScreener 创建选币器（baseURL 为 Binance fapi 兼容地址，为空时使用 Binance）
+func NewScreener(baseURL string, config ScreenerConfig) *Screener {
+	if baseURL == "" {
+		baseURL = "https://fapi.binance.com"
+	}
+	return &Screener{
+		baseURL: baseURL,
+		client:  screenerClient,
+		config:  config,
+	}
+}
+
+// screenerMetrics 单个币种的原始因子
+type screenerMetrics struct {
+	symbol         string
+	lastPrice      float64
+	highPrice      float64
+	lowPrice       float64
+	priceChange24h float64 // 24小时涨跌幅（%）
+	quoteVolume    float64 // 24小时成交额
+	fundingRate    float64
+	startPrice     float64 // 4小时前价格
+	momentum       float64 // 4小时涨跌幅（%）
+	volumeSurge    float64
+	volatility     float64 // 24小时振幅（%）
+	currentOI      float64
+	oiDelta        float64
+	oiDeltaPercent float64
+	score          float64
+}
+
+// panicError 扫描 goroutine 中恢复的 panic，在调用方 goroutine 中重新抛出，
+// 由交易员主循环转为错误退出并交给监督器重启（否则会直接终止整个进程）
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverPanic 将 recover() 的结果包装为 panicError 并记录堆栈（没有 panic 时返回 nil）
+func recoverPanic(r interface{}) *panicError {
+	if r == nil {
+		return nil
+	}
+	if pe, ok := r.(*panicError); ok {
+		return pe
+	}
+	pe := &panicError{value: r, stack: debug.Stack()}
+	poolLog.Errorf(context.Background(), "❌ 本地选币器panic: %v\n%s", pe.value, pe.stack)
+	return pe
+}
+
+// Screen 执行选币（结果缓存5分钟）
+// 相同配置的并发调用共享同一次扫描；扫描期间不持有缓存锁，ctx 取消时调用方立即返回；扫描中的 panic 在调用方重新抛出
+func (s *Screener) Screen(ctx context.Context) (*ScreenerResult, error) {
+	keyData, _ := json.Marshal(struct {
+		BaseURL string
+		Config  ScreenerConfig
+	}{s.baseURL, s.config})
+	key := string(keyData)
+
+	screenerCacheMu.Lock()
+	cached, ok := screenerCache[key]
+	screenerCacheMu.Unlock()
+	if ok && time.Since(cached.FetchedAt) < screenerCacheTTL {
+		return cached, nil
+	}
+
+	ch := screenerFlight.DoChan(key, func() (v interface{}, err error) {
+		// singleflight 会在新 goroutine 中重新抛出 panic，这里转为错误交给调用方处理
+		defer func() {
+			if pe := recoverPanic(recover()); pe != nil {
+				err = pe
+			}
+		}()
+		scanCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), screenerTimeout)
+		defer cancel()
+		result, err := s.screen(scanCtx)
+		if err != nil {
+			return nil, err
+		}
+		screenerCacheMu.Lock()
+		screenerCache[key] = result
+		screenerCacheMu.Unlock()
+		return result, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		if pe, ok := r.Err.(*panicError); ok {
+			panic(pe)
+		}
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.(*ScreenerResult), nil
+	}
+}
+
+// screen 拉取全市场数据并计算评分
+func (s *Screener) screen(ctx context.Context) (*ScreenerResult, error) {
+	poolLog.Infof(ctx, "🔄 本地选币器正在扫描USDT永续合约...")
+
+	symbols, err := s.fetchPerpetualSymbols(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tickers, err := s.fetchTickers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fundingRates, err := s.fetchFundingRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 按24小时成交额预选
+	var candidates []*screenerMetrics
+	for symbol := range symbols {
+		m, ok := tickers[symbol]
+		if !ok || m.quoteVolume < s.config.MinQuoteVolume || m.lastPrice <= 0 {
+			continue
+		}
+		m.fundingRate = fundingRates[symbol]
+		if m.lowPrice > 0 {
+			m.volatility = (m.highPrice - m.lowPrice) / m.lastPrice * 100
+		}
+		candidates = append(candidates, m)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].quoteVolume > candidates[j].quoteVolume
+	})
+	if len(candidates) > s.config.Preselect {
+		candidates = candidates[:s.config.Preselect]
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("没有满足成交额下限的币种")
+	}
+
+	// 2. 并发获取预选币种的K线和持仓量历史（单币种失败只丢失对应因子）
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		panicked *panicError
+	)
+	jobs := make(chan *screenerMetrics)
+	for i := 0; i < screenerWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if pe := recoverPanic(recover()); pe != nil {
+					mu.Lock()
+					if panicked == nil {
+						panicked = pe
+					}
+					mu.Unlock()
+					for range jobs { // 继续消费剩余任务，避免阻塞发送方
+					}
+				}
+			}()
+			for m := range jobs {
+				if err := s.fillKlineFactors(ctx, m); err != nil {
+					poolLog.Warnf(ctx, "⚠️  选币器获取%s K线失败: %v", m.symbol, err)
+				}
+				if err := s.fillOIFactors(ctx, m); err != nil {
+					poolLog.Warnf(ctx, "⚠️  选币器获取%s 持仓量历史失败: %v", m.symbol, err)
+				}
+			}
+		}()
+	}
+	for _, m := range candidates {
+		jobs <- m
+	}
+	close(jobs)
+	wg.Wait()
+	if panicked != nil {
+		return nil, panicked
+	}
+
+	// 3. 横截面排名打分
+	scoreCandidates(candidates, s.config.Weights)
+
+	result := &ScreenerResult{FetchedAt: time.Now()}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	for i, m := range candidates {
+		if i >= s.config.Limit {
+			break
+		}
+		result.Coins = append(result.Coins, CoinInfo{
+			Pair:            m.symbol,
+			Score:           m.score,
+			StartTime:       result.FetchedAt.Add(-4 * time.Hour).Unix(),
+			StartPrice:      m.startPrice,
+			LastScore:       m.score,
+			MaxScore:        m.score,
+			MaxPrice:        m.highPrice,
+			IncreasePercent: m.priceChange24h,
+			IsAvailable:     true,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].oiDeltaPercent > candidates[j].oiDeltaPercent
+	})
+	for _, m := range candidates {
+		if len(result.OITop) >= s.config.OITopLimit {
+			break
+		}
+		if m.oiDelta <= 0 {
+			break // 只输出持仓量增长的币种
+		}
+		result.OITop = append(result.OITop, OIPosition{
+			Symbol:            m.symbol,
+			Rank:              len(result.OITop) + 1,
+			CurrentOI:         m.currentOI,
+			OIDelta:           m.oiDelta,
+			OIDeltaPercent:    m.oiDeltaPercent,
+			OIDeltaValue:      m.oiDelta * m.lastPrice,
+			PriceDeltaPercent: m.momentum,
+		})
+	}
+
+	poolLog.Infof(ctx, "✓ 本地选币器完成: 预选%d个币种，输出评分Top%d、OI增长Top%d",
+		len(candidates), len(result.Coins), len(result.OITop))
+	return result, nil
+}
+
+// scoreCandidates 对每个因子做横截面排名百分位（0~1），按权重加权得到0~100的评分
+func scoreCandidates(candidates []*screenerMetrics, weights ScreenerWeights) {
+	factors := []struct {
+		weight float64
+		value  func(m *screenerMetrics) float64
+	}{
+		{weights.OIChange, func(m *screenerMetrics) float64 { return m.oiDeltaPercent }},
+		{weights.VolumeSurge, func(m *screenerMetrics) float64 { return m.volumeSurge }},
+		{weights.Volatility, func(m *screenerMetrics) float64 { return m.volatility }},
+		{weights.Momentum, func(m *screenerMetrics) float64 { return math.Abs(m.momentum) }},
+		{weights.Funding, func(m *screenerMetrics) float64 { return math.Abs(m.fundingRate) }},
+	}
+
+	totalWeight := 0.0
+	for _, f := range factors {
+		totalWeight += f.weight
+	}
+	if totalWeight <= 0 || len(candidates) == 0 {
+		return
+	}
+
+	ranked := append([]*screenerMetrics(nil), candidates...)
+	for _, f := range factors {
+		if f.weight <= 0 {
+			continue
+		}
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return f.value(ranked[i]) < f.value(ranked[j])
+		})
+		for i, m := range ranked {
+			percentile := 1.0
+			if len(ranked) > 1 {
+				percentile = float64(i) / float64(len(ranked)-1)
+			}
+			m.score += f.weight * percentile
+		}
+	}
+	for _, m := range candidates {
+		m.score = m.score / totalWeight * 100
+	}
+}
+
+// get 发送GET请求并返回响应体
+func (s *Screener) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求%s失败: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// fetchPerpetualSymbols 获取所有交易中的USDT永续合约
+func (s *Screener) fetchPerpetualSymbols(ctx context.Context) (map[string]bool, error) {
+	body, err := s.get(ctx, "/fapi/v1/exchangeInfo")
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Symbols []struct {
+			Symbol       string `json:"symbol"`
+			ContractType string `json:"contractType"`
+			QuoteAsset   string `json:"quoteAsset"`
+			Status       string `json:"status"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("解析交易对信息失败: %w", err)
+	}
+
+	symbols := make(map[string]bool)
+	for _, sym := range info.Symbols {
+		if sym.ContractType == "PERPETUAL" && sym.QuoteAsset == "USDT" && sym.Status == "TRADING" {
+			symbols[sym.Symbol] = true
+		}
+	}
+	return symbols, nil
+}
+
+// fetchTickers 获取全市场24小时行情
+func (s *Screener) fetchTickers(ctx context.Context) (map[string]*screenerMetrics, error) {
+	body, err := s.get(ctx, "/fapi/v1/ticker/24hr")
+	if err != nil {
+		return nil, err
+	}
+
+	var tickers []struct {
+		Symbol             string `json:"symbol"`
+		PriceChangePercent string `json:"priceChangePercent"`
+		LastPrice          string `json:"lastPrice"`
+		HighPrice          string `json:"highPrice"`
+		LowPrice           string `json:"lowPrice"`
+		QuoteVolume        string `json:"quoteVolume"`
+	}
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, fmt.Errorf("解析24小时行情失败: %w", err)
+	}
+
+	result := make(map[string]*screenerMetrics, len(tickers))
+	for _, t := range tickers {
+		m := &screenerMetrics{symbol: t.Symbol}
+		m.priceChange24h, _ = strconv.ParseFloat(t.PriceChangePercent, 64)
+		m.lastPrice, _ = strconv.ParseFloat(t.LastPrice, 64)
+		m.highPrice, _ = strconv.ParseFloat(t.HighPrice, 64)
+		m.lowPrice, _ = strconv.ParseFloat(t.LowPrice, 64)
+		m.quoteVolume, _ = strconv.ParseFloat(t.QuoteVolume, 64)
+		result[t.Symbol] = m
+	}
+	return result, nil
+}
+
+// fetchFundingRates 获取全市场最新资金费率
+func (s *Screener) fetchFundingRates(ctx context.Context) (map[string]float64, error) {
+	body, err := s.get(ctx, "/fapi/v1/premiumIndex")
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []struct {
+		Symbol          string `json:"symbol"`
+		LastFundingRate string `json:"lastFundingRate"`
+	}
+	if err := json.Unmarshal(body, &indexes); err != nil {
+		return nil, fmt.Errorf("解析资金费率失败: %w", err)
+	}
+
+	rates := make(map[string]float64, len(indexes))
+	for _, idx := range indexes {
+		rates[idx.Symbol], _ = strconv.ParseFloat(idx.LastFundingRate, 64)
+	}
+	return rates, nil
+}
+
+// fillKlineFactors 根据最近25根1小时K线计算动量和成交量放大
+func (s *Screener) fillKlineFactors(ctx context.Context, m *screenerMetrics) error {
+	body, err := s.get(ctx, fmt.Sprintf("/fapi/v1/klines?symbol=%s&interval=1h&limit=25", m.symbol))
+	if err != nil {
+		return err
+	}
+
+	var raw [][]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return fmt.Errorf("解析K线失败: %w", err)
+	}
+	if len(raw) < 6 {
+		return fmt.Errorf("K线数量不足: %d", len(raw))
+	}
+
+	closes := make([]float64, len(raw))
+	quoteVolumes := make([]float64, len(raw))
+	for i, k := range raw {
+		if len(k) < 8 {
+			return fmt.Errorf("K线格式错误")
+		}
+		closes[i] = parseKlineFloat(k[4])
+		quoteVolumes[i] = parseKlineFloat(k[7])
+	}
+
+	last := len(raw) - 1
+	m.startPrice = closes[last-4]
+	if m.startPrice > 0 {
+		m.momentum = (closes[last] - m.startPrice) / m.startPrice * 100
+	}
+
+	avgVolume := 0.0
+	for _, v := range quoteVolumes[:last] {
+		avgVolume += v
+	}
+	avgVolume /= float64(last)
+	if avgVolume > 0 {
+		m.volumeSurge = quoteVolumes[last] / avgVolume
+	}
+	return nil
+}
+
+// fillOIFactors 根据最近5个1小时持仓量快照计算4小时持仓量变化
+func (s *Screener) fillOIFactors(ctx context.Context, m *screenerMetrics) error {
+	body, err := s.get(ctx, fmt.Sprintf("/futures/data/openInterestHist?symbol=%s&period=1h&limit=5", m.symbol))
+	if err != nil {
+		return err
+	}
+
+	var history []struct {
+		SumOpenInterest string `json:"sumOpenInterest"`
+	}
+	if err := json.Unmarshal(body, &history); err != nil {
+		return fmt.Errorf("解析持仓量历史失败: %w", err)
+	}
+	if len(history) < 2 {
+		return fmt.Errorf("持仓量历史不足: %d", len(history))
+	}
+
+	first, _ := strconv.ParseFloat(history[0].SumOpenInterest, 64)
+	m.currentOI, _ = strconv.ParseFloat(history[len(history)-1].SumOpenInterest, 64)
+	m.oiDelta = m.currentOI - first
+	if first > 0 {
+		m.oiDeltaPercent = m.oiDelta / first * 100
+	}
+	return nil
+}
+
+// parseKlineFloat 解析K线字段（字符串或数字）
+func parseKlineFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	case float64:
+		return val
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// screenerMarket 模拟的单币种行情
+type screenerMarket struct {
+	quoteVolume float64
+	momentum    float64 // 最近4小时涨跌幅（%）
+	volumeSurge float64 // 最近1小时成交额 / 之前均值
+	oiChange    float64 // 4小时持仓量变化（%）
+}
+
+// screenerFixture 模拟 Binance fapi 公共接口并统计各路径的请求次数
+type screenerFixture struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newScreenerFixture(t *testing.T, markets map[string]screenerMarket) *screenerFixture {
+	t.Helper()
+	f := &screenerFixture{requests: make(map[string]int)}
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			t.Errorf("写入响应失败: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fapi/v1/exchangeInfo", func(w http.ResponseWriter, r *http.Request) {
+		symbols := []map[string]string{
+			{"symbol": "BTCUSD_PERP", "contractType": "PERPETUAL", "quoteAsset": "USD", "status": "TRADING"},
+		}
+		for symbol := range markets {
+			symbols = append(symbols, map[string]string{"symbol": symbol, "contractType": "PERPETUAL", "quoteAsset": "USDT", "status": "TRADING"})
+		}
+		writeJSON(w, map[string]interface{}{"symbols": symbols})
+	})
+	mux.HandleFunc("/fapi/v1/ticker/24hr", func(w http.ResponseWriter, r *http.Request) {
+		var tickers []map[string]string
+		for symbol, m := range markets {
+			tickers = append(tickers, map[string]string{
+				"symbol": symbol, "priceChangePercent": "1.5", "lastPrice": "100",
+				"highPrice": "105", "lowPrice": "95", "quoteVolume": strconv.FormatFloat(m.quoteVolume, 'f', -1, 64),
+			})
+		}
+		writeJSON(w, tickers)
+	})
+	mux.HandleFunc("/fapi/v1/premiumIndex", func(w http.ResponseWriter, r *http.Request) {
+		var indexes []map[string]string
+		for symbol := range markets {
+			indexes = append(indexes, map[string]string{"symbol": symbol, "lastFundingRate": "0.0001"})
+		}
+		writeJSON(w, indexes)
+	})
+	mux.HandleFunc("/fapi/v1/klines", func(w http.ResponseWriter, r *http.Request) {
+		m := markets[r.URL.Query().Get("symbol")]
+		klines := make([][]interface{}, 25)
+		for i := range klines {
+			closePrice, quoteVolume := 100.0, 1000.0
+			if i == len(klines)-1 {
+				closePrice = 100 * (1 + m.momentum/100)
+				quoteVolume = 1000 * m.volumeSurge
+			}
+			close := strconv.FormatFloat(closePrice, 'f', -1, 64)
+			klines[i] = []interface{}{i, "100", close, "100", close, "10", i, strconv.FormatFloat(quoteVolume, 'f', -1, 64)}
+		}
+		writeJSON(w, klines)
+	})
+	mux.HandleFunc("/futures/data/openInterestHist", func(w http.ResponseWriter, r *http.Request) {
+		m := markets[r.URL.Query().Get("symbol")]
+		history := make([]map[string]string, 5)
+		for i := range history {
+			oi := 1000.0
+			if i == len(history)-1 {
+				oi = 1000 * (1 + m.oiChange/100)
+			}
+			history[i] = map[string]string{"sumOpenInterest": strconv.FormatFloat(oi, 'f', -1, 64)}
+		}
+		writeJSON(w, history)
+	})
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests[r.URL.Path+"?"+r.URL.Query().Get("symbol")]++
+		f.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// count 指定路径（和币种）的请求次数
+func (f *screenerFixture) count(path, symbol string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path+"?"+symbol]
+}
+
+func testScreenerConfig() ScreenerConfig {
+	cfg := DefaultScreenerConfig()
+	cfg.MinQuoteVolume = 1_000_000
+	cfg.Preselect = 10
+	cfg.Limit = 2
+	cfg.OITopLimit = 5
+	return cfg
+}
+
+func TestScreenerScoresAndCaches(t *testing.T) {
+	srv := newScreenerFixture(t, map[string]screenerMarket{
+		"AAAUSDT": {quoteVolume: 9_000_000, momentum: 8, volumeSurge: 5, oiChange: 40},
+		"BBBUSDT": {quoteVolume: 8_000_000, momentum: 3, volumeSurge: 2, oiChange: 10},
+		"CCCUSDT": {quoteVolume: 7_000_000, momentum: 0.5, volumeSurge: 1, oiChange: -5},
+		"DDDUSDT": {quoteVolume: 500_000, momentum: 20, volumeSurge: 10, oiChange: 90}, // 成交额不足，不参与预选
+	})
+	screener := NewScreener(srv.URL, testScreenerConfig())
+
+	result, err := screener.Screen(context.Background())
+	if err != nil {
+		t.Fatalf("Screen失败: %v", err)
+	}
+
+	if len(result.Coins) != 2 || result.Coins[0].Pair != "AAAUSDT" || result.Coins[1].Pair != "BBBUSDT" {
+		t.Errorf("评分结果错误: %+v", result.Coins)
+	}
+	// 只输出持仓量增长的币种，按增长幅度排序
+	if len(result.OITop) != 2 || result.OITop[0].Symbol != "AAAUSDT" || result.OITop[1].Symbol != "BBBUSDT" {
+		t.Errorf("OI Top结果错误: %+v", result.OITop)
+	}
+	if result.OITop[0].Rank != 1 || result.OITop[0].OIDeltaPercent != 40 {
+		t.Errorf("OI Top数据错误: %+v", result.OITop[0])
+	}
+	if n := srv.count("/fapi/v1/klines", "DDDUSDT"); n != 0 {
+		t.Errorf("成交额不足的币种不应请求K线, 请求次数 = %d", n)
+	}
+
+	// 5分钟内使用缓存
+	if _, err := screener.Screen(context.Background()); err != nil {
+		t.Fatalf("Screen失败: %v", err)
+	}
+	if n := srv.count("/fapi/v1/exchangeInfo", ""); n != 1 {
+		t.Errorf("缓存期内交易对请求次数 = %d, want 1", n)
+	}
+}
+
+func TestScreenerSharesConcurrentScans(t *testing.T) {
+	srv := newScreenerFixture(t, map[string]screenerMarket{
+		"AAAUSDT": {quoteVolume: 9_000_000, momentum: 8, volumeSurge: 5, oiChange: 40},
+		"BBBUSDT": {quoteVolume: 8_000_000, momentum: 3, volumeSurge: 2, oiChange: 10},
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := NewScreener(srv.URL, testScreenerConfig()).Screen(context.Background()); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Screen失败: %v", err)
+	}
+
+	// 相同配置的并发扫描只请求一次全市场数据
+	if n := srv.count("/fapi/v1/exchangeInfo", ""); n != 1 {
+		t.Errorf("交易对请求次数 = %d, want 1", n)
+	}
+}
+
+func TestScreenerCanceledContext(t *testing.T) {
+	srv := newScreenerFixture(t, map[string]screenerMarket{
+		"AAAUSDT": {quoteVolume: 9_000_000},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := testScreenerConfig()
+	cfg.Limit = 1
+	if _, err := NewScreener(srv.URL, cfg).Screen(ctx); err == nil {
+		t.Error("ctx 已取消时应返回错误")
+	}
+}
+
+func TestScreenerAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, fmt.Sprintf(`{"code":-1003,"msg":"%s"}`, "Too many requests"), http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	if _, err := NewScreener(srv.URL, testScreenerConfig()).Screen(context.Background()); err == nil {
+		t.Error("接口返回错误时应返回错误")
+	}
+}
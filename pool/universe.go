@@ -0,0 +1,107 @@
+package pool
+
+import "fmt"
+
+// 候选币种来源
+const (
+	SourceAI500    = "ai500"    // AI500评分币种
+	SourceOITop    = "oi_top"   // 持仓量增长Top
+	SourceStatic   = "static"   // 静态白名单
+	SourceScreener = "screener" // 本地选币器
+)
+
+// DefaultAI500Limit AI500默认取评分最高的币种数量
+const DefaultAI500Limit = 20
+
+// Universe 交易员的币种范围配置
+type Universe struct {
+	Sources            []string        `json:"sources"`               // 候选来源（ai500/oi_top/static/screener），为空时使用 ai500 + oi_top
+	Whitelist          []string        `json:"whitelist"`             // 静态白名单（来源包含 static 时加入候选，优先级最高）
+	Blacklist          []string        `json:"blacklist"`             // 黑名单（从所有来源中剔除，也不允许开仓）
+	AI500Limit         int             `json:"ai500_limit"`           // AI500取评分最高的前N个，0表示默认20
+	MaxCandidates      int             `json:"max_candidates"`        // 候选币种上限，0表示不限制
+	MinOpenInterestUSD float64         `json:"min_open_interest_usd"` // 最低持仓价值（USD），与风控策略取较大值，0表示只用风控策略
+	Screener           *ScreenerConfig `json:"screener,omitempty"`    // 本地选币器配置（来源包含 screener 时使用，为空使用默认配置）
+}
+
+// DefaultUniverse 默认币种范围（AI500前20 + OI Top，与之前的固定行为一致）
+func DefaultUniverse() Universe {
+	return Universe{
+		Sources:    []string{SourceAI500, SourceOITop},
+		AI500Limit: DefaultAI500Limit,
+	}
+}
+
+// Validate 校验币种范围配置
+func (u *Universe) Validate() error {
+	for _, source := range u.Sources {
+		switch source {
+		case SourceAI500, SourceOITop, SourceStatic, SourceScreener:
+		default:
+			return fmt.Errorf("未知的候选来源: %s（支持 %s/%s/%s/%s）", source, SourceAI500, SourceOITop, SourceStatic, SourceScreener)
+		}
+	}
+	if u.Screener != nil {
+		if err := u.Screener.Validate(); err != nil {
+			return fmt.Errorf("选币器配置无效: %w", err)
+		}
+	}
+	if u.HasSource(SourceStatic) && len(u.Whitelist) == 0 {
+		return fmt.Errorf("候选来源包含 %s 时白名单不能为空", SourceStatic)
+	}
+	if u.AI500Limit < 0 {
+		return fmt.Errorf("ai500_limit 不能为负数: %d", u.AI500Limit)
+	}
+	if u.MaxCandidates < 0 {
+		return fmt.Errorf("max_candidates 不能为负数: %d", u.MaxCandidates)
+	}
+	if u.MinOpenInterestUSD < 0 {
+		return fmt.Errorf("min_open_interest_usd 不能为负数: %.0f", u.MinOpenInterestUSD)
+	}
+	for _, symbol := range u.Whitelist {
+		if u.IsBlacklisted(symbol) {
+			return fmt.Errorf("%s 同时出现在白名单和黑名单中", normalizeSymbol(symbol))
+		}
+	}
+	return nil
+}
+
+// HasSource 是否启用某个候选来源（未配置来源时默认启用 ai500 和 oi_top）
+func (u *Universe) HasSource(source string) bool {
+	if len(u.Sources) == 0 {
+		return source == SourceAI500 || source == SourceOITop
+	}
+	for _, s := range u.Sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBlacklisted 币种是否在黑名单中（大小写和USDT后缀不敏感）
+func (u *Universe) IsBlacklisted(symbol string) bool {
+	symbol = normalizeSymbol(symbol)
+	for _, s := range u.Blacklist {
+		if normalizeSymbol(s) == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// screenerConfig 本地选币器配置（未配置时使用默认值）
+func (u *Universe) screenerConfig() ScreenerConfig {
+	if u.Screener != nil {
+		return *u.Screener
+	}
+	return DefaultScreenerConfig()
+}
+
+// ai500Limit AI500取前N个（未配置时使用默认值）
+func (u *Universe) ai500Limit() int {
+	if u.AI500Limit > 0 {
+		return u.AI500Limit
+	}
+	return DefaultAI500Limit
+}
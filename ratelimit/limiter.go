@@ -0,0 +1,276 @@
+// Package ratelimit 交易所API客户端限流（按主机共享，同一IP上的所有交易员和行情请求共用额度）
+//
+// 每个主机一个按固定时间窗口计算权重的限流器：
+//   - 请求前按估算权重占用额度，额度不足时排队等待下一个窗口
+//   - 响应头中有交易所统计的已用权重（如 Binance 的 X-MBX-USED-WEIGHT-1M）时以其为准
+//   - 收到 429/418 时按 Retry-After 暂停该主机的所有请求
+//   - 下单类请求（PriorityOrder）可使用全部额度，行情等数据请求只能使用 (1-OrderReserve) 部分，保证下单不被数据请求挤占
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"nofx/logging"
+	"nofx/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Priority 请求优先级
+type Priority int
+
+const (
+	PriorityData  Priority = iota // 行情、账户查询等数据请求
+	PriorityOrder                 // 下单、撤单、止损止盈、杠杆设置
+)
+
+func (p Priority) String() string {
+	if p == PriorityOrder {
+		return "order"
+	}
+	return "data"
+}
+
+type priorityKey struct{}
+
+// WithPriority 设置 ctx 下请求的优先级
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom 读取 ctx 中的请求优先级（默认 PriorityData）
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityData
+}
+
+// Limit 单个主机的限流配置
+type Limit struct {
+	Weight       int                     // 每个窗口的权重上限
+	Window       time.Duration           // 窗口长度（按整点对齐，与交易所统计方式一致）
+	OrderReserve float64                 // 为下单请求保留的额度比例
+	UsedHeader   string                  // 响应头中交易所统计的当前窗口已用权重（为空表示交易所不返回）
+	RequestCost  func(*http.Request) int // 估算单个请求的权重（为空时按 1 计算）
+}
+
+// defaultLimit 未配置主机的保守默认值
+var defaultLimit = Limit{Weight: 1200, Window: time.Minute, OrderReserve: 0.2}
+
+// limits 已知交易所主机的限流配置（略低于官方上限，给同IP的其他程序留余量）
+var limits = map[string]Limit{
+	"fapi.binance.com":            {Weight: 2200, Window: time.Minute, OrderReserve: 0.2, UsedHeader: "X-MBX-USED-WEIGHT-1M", RequestCost: binanceCost},
+	"fapi.asterdex.com":           {Weight: 2200, Window: time.Minute, OrderReserve: 0.2, UsedHeader: "X-MBX-USED-WEIGHT-1M", RequestCost: binanceCost},
+	"api.hyperliquid.xyz":         {Weight: 1100, Window: time.Minute, OrderReserve: 0.2, RequestCost: hyperliquidCost},
+	"api.hyperliquid-testnet.xyz": {Weight: 1100, Window: time.Minute, OrderReserve: 0.2, RequestCost: hyperliquidCost},
+	"api.bybit.com":               {Weight: 550, Window: 5 * time.Second, OrderReserve: 0.2},
+	"api-testnet.bybit.com":       {Weight: 550, Window: 5 * time.Second, OrderReserve: 0.2},
+	"www.okx.com":                 {Weight: 40, Window: 2 * time.Second, OrderReserve: 0.25},
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Limiter)
+	limitLog   = logging.For("ratelimit")
+)
+
+// Configure 设置主机的限流配置（需在该主机的第一个请求之前调用）
+func Configure(host string, limit Limit) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	limits[host] = limit
+	delete(registry, host)
+}
+
+// For 返回主机共享的限流器
+func For(host string) *Limiter {
+	host = strings.ToLower(host)
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if l, ok := registry[host]; ok {
+		return l
+	}
+	limit, ok := limits[host]
+	if !ok {
+		limit = defaultLimit
+	}
+	l := &Limiter{host: host, limit: limit}
+	registry[host] = l
+	metrics.ExchangeRateLimitCapacity.WithLabelValues(host).Set(float64(limit.Weight))
+	return l
+}
+
+// Wait 按主机等待额度（用于无法替换 HTTP 客户端的 SDK 调用）
+func Wait(ctx context.Context, host string, weight int) error {
+	return For(host).Wait(ctx, weight)
+}
+
+// Limiter 单个主机的限流器
+type Limiter struct {
+	host  string
+	limit Limit
+
+	mu           sync.Mutex
+	windowStart  time.Time
+	used         int       // 当前窗口已用权重（本地估算与交易所返回值取较大者）
+	backoffUntil time.Time // 429/418 后暂停到该时间
+	waiting      int       // 排队中的请求数
+}
+
+// State 限流器状态快照
+type State struct {
+	Host         string     `json:"host"`
+	Used         int        `json:"used"`
+	Limit        int        `json:"limit"`
+	Window       string     `json:"window"`
+	Waiting      int        `json:"waiting"`
+	BackoffUntil *time.Time `json:"backoff_until,omitempty"`
+}
+
+// Wait 占用 weight 额度，额度不足或处于暂停期时排队等待，ctx 取消时返回错误
+func (l *Limiter) Wait(ctx context.Context, weight int) error {
+	if weight <= 0 {
+		weight = 1
+	}
+	priority := PriorityFrom(ctx)
+	capacity := l.limit.Weight
+	if priority == PriorityData {
+		capacity = int(float64(l.limit.Weight) * (1 - l.limit.OrderReserve))
+	}
+	if weight > capacity {
+		weight = capacity // 超大请求独占一个窗口
+	}
+
+	waited := false
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.rollWindow(now)
+		var wakeAt time.Time
+		switch {
+		case now.Before(l.backoffUntil):
+			wakeAt = l.backoffUntil
+		case l.used+weight > capacity:
+			wakeAt = l.windowStart.Add(l.limit.Window)
+		default:
+			l.used += weight
+			used := l.used
+			if waited {
+				l.waiting--
+			}
+			l.mu.Unlock()
+			metrics.ExchangeRateLimitUsed.WithLabelValues(l.host).Set(float64(used))
+			return nil
+		}
+		if !waited {
+			waited = true
+			l.waiting++
+			metrics.ExchangeRateLimitWaits.WithLabelValues(l.host, priority.String()).Inc()
+			limitLog.Debugf(ctx, "⏳ %s 限流等待 %v (已用 %d/%d, 优先级 %s)", l.host, time.Until(wakeAt).Round(time.Millisecond), l.used, capacity, priority)
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(wakeAt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.mu.Lock()
+			l.waiting--
+			l.mu.Unlock()
+			return fmt.Errorf("%s 限流等待被取消: %w", l.host, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Observe 根据响应更新已用权重，429/418 时暂停该主机的请求
+func (l *Limiter) Observe(ctx context.Context, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.rollWindow(now)
+	if l.limit.UsedHeader != "" {
+		if v, err := strconv.Atoi(resp.Header.Get(l.limit.UsedHeader)); err == nil && v > l.used {
+			l.used = v
+		}
+	}
+	used := l.used
+	var pause time.Duration
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot {
+		pause = retryAfter(resp)
+		if until := now.Add(pause); until.After(l.backoffUntil) {
+			l.backoffUntil = until
+		}
+	}
+	l.mu.Unlock()
+
+	metrics.ExchangeRateLimitUsed.WithLabelValues(l.host).Set(float64(used))
+	if pause > 0 {
+		metrics.ExchangeRateLimitThrottled.WithLabelValues(l.host, strconv.Itoa(resp.StatusCode)).Inc()
+		limitLog.Warnf(ctx, "⚠️  %s 返回 %d（请求过于频繁），暂停该主机的请求 %v", l.host, resp.StatusCode, pause)
+	}
+}
+
+// State 当前状态
+func (l *Limiter) State() State {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollWindow(time.Now())
+	s := State{
+		Host:    l.host,
+		Used:    l.used,
+		Limit:   l.limit.Weight,
+		Window:  l.limit.Window.String(),
+		Waiting: l.waiting,
+	}
+	if time.Now().Before(l.backoffUntil) {
+		until := l.backoffUntil
+		s.BackoffUntil = &until
+	}
+	return s
+}
+
+// rollWindow 进入新窗口时清零已用权重（调用方持有锁）
+func (l *Limiter) rollWindow(now time.Time) {
+	start := now.Truncate(l.limit.Window)
+	if start.After(l.windowStart) {
+		l.windowStart = start
+		l.used = 0
+	}
+}
+
+// retryAfter 429/418 后的暂停时长（优先使用 Retry-After 响应头）
+func retryAfter(resp *http.Response) time.Duration {
+	if v, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	if resp.StatusCode == http.StatusTeapot {
+		// 418 表示IP已被封禁，保守等待
+		return 2 * time.Minute
+	}
+	return 30 * time.Second
+}
+
+// Snapshot 所有已使用主机的限流状态（按主机排序）
+func Snapshot() []State {
+	registryMu.Lock()
+	limiters := make([]*Limiter, 0, len(registry))
+	for _, l := range registry {
+		limiters = append(limiters, l)
+	}
+	registryMu.Unlock()
+
+	states := make([]State, 0, len(limiters))
+	for _, l := range limiters {
+		states = append(states, l.State())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
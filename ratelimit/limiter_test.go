@@ -0,0 +1,218 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newTestLimiter 不经过全局注册表的限流器
+func newTestLimiter(limit Limit) *Limiter {
+	return &Limiter{host: "test.local", limit: limit}
+}
+
+// waitBriefly 带短超时的 Wait，用于判断请求是否会被阻塞
+func waitBriefly(l *Limiter, p Priority, weight int) error {
+	ctx, cancel := context.WithTimeout(WithPriority(context.Background(), p), 20*time.Millisecond)
+	defer cancel()
+	return l.Wait(ctx, weight)
+}
+
+func TestLimiterRollWindow(t *testing.T) {
+	l := newTestLimiter(Limit{Weight: 10, Window: time.Minute})
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	l.rollWindow(start.Add(10 * time.Second))
+	l.used = 7
+	l.rollWindow(start.Add(59 * time.Second))
+	if l.used != 7 || !l.windowStart.Equal(start) {
+		t.Errorf("同一窗口内不应清零: used=%d windowStart=%v", l.used, l.windowStart)
+	}
+	// 窗口按整点对齐，与第一个请求的时间无关
+	l.rollWindow(start.Add(time.Minute))
+	if l.used != 0 || !l.windowStart.Equal(start.Add(time.Minute)) {
+		t.Errorf("进入新窗口应清零: used=%d windowStart=%v", l.used, l.windowStart)
+	}
+	// 时间回退（如读取到更早的时间）不回到旧窗口
+	l.used = 3
+	l.rollWindow(start.Add(30 * time.Second))
+	if l.used != 3 {
+		t.Errorf("旧窗口时间不应清零: used=%d", l.used)
+	}
+}
+
+func TestLimiterWaitsForNextWindow(t *testing.T) {
+	l := newTestLimiter(Limit{Weight: 2, Window: 50 * time.Millisecond})
+	if err := l.Wait(context.Background(), 2); err != nil {
+		t.Fatalf("Wait失败: %v", err)
+	}
+	startWindow := l.windowStart
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.Wait(ctx, 1); err != nil {
+		t.Fatalf("下一个窗口应获得额度: %v", err)
+	}
+	if !l.windowStart.After(startWindow) || l.used != 1 {
+		t.Errorf("应在新窗口中占用额度: windowStart=%v used=%d", l.windowStart, l.used)
+	}
+	if l.waiting != 0 {
+		t.Errorf("排队计数未归零: %d", l.waiting)
+	}
+}
+
+func TestLimiterOrderReserve(t *testing.T) {
+	l := newTestLimiter(Limit{Weight: 10, Window: time.Hour, OrderReserve: 0.2})
+
+	// 数据请求最多使用 8/10
+	for i := 0; i < 8; i++ {
+		if err := waitBriefly(l, PriorityData, 1); err != nil {
+			t.Fatalf("第 %d 个数据请求失败: %v", i+1, err)
+		}
+	}
+	if err := waitBriefly(l, PriorityData, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("数据请求不应使用保留额度, got %v", err)
+	}
+	// 保留的额度仍可用于下单
+	if err := waitBriefly(l, PriorityOrder, 2); err != nil {
+		t.Fatalf("下单请求应使用保留额度: %v", err)
+	}
+	if err := waitBriefly(l, PriorityOrder, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("额度用完后下单请求应等待, got %v", err)
+	}
+	if got := l.State(); got.Used != 10 || got.Waiting != 0 {
+		t.Errorf("状态 = %+v, want used=10 waiting=0", got)
+	}
+
+	// 超过数据额度的单个请求按数据额度计算，不会永远等待
+	big := newTestLimiter(Limit{Weight: 10, Window: time.Hour, OrderReserve: 0.2})
+	if err := waitBriefly(big, PriorityData, 50); err != nil {
+		t.Fatalf("超大请求应独占一个窗口: %v", err)
+	}
+	if big.used != 8 {
+		t.Errorf("超大请求占用 = %d, want 8", big.used)
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	l := newTestLimiter(Limit{Weight: 1, Window: time.Hour})
+	if err := l.Wait(context.Background(), 1); err != nil {
+		t.Fatalf("Wait失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(ctx, 1) }()
+
+	// 等待请求进入排队后取消
+	deadline := time.Now().Add(time.Second)
+	for l.State().Waiting == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if l.State().Waiting != 1 {
+		t.Fatal("请求未进入排队")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("取消后应返回 context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ctx 取消后 Wait 未返回")
+	}
+	if got := l.State(); got.Waiting != 0 || got.Used != 1 {
+		t.Errorf("取消后状态 = %+v, want waiting=0 used=1", got)
+	}
+}
+
+func TestLimiterObserve(t *testing.T) {
+	response := func(status int, headers map[string]string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: make(http.Header)}
+		for k, v := range headers {
+			resp.Header.Set(k, v)
+		}
+		return resp
+	}
+
+	t.Run("已用权重响应头", func(t *testing.T) {
+		l := newTestLimiter(Limit{Weight: 100, Window: time.Hour, UsedHeader: "X-MBX-USED-WEIGHT-1M"})
+		if err := l.Wait(context.Background(), 5); err != nil {
+			t.Fatalf("Wait失败: %v", err)
+		}
+		steps := []struct {
+			header string
+			want   int
+		}{
+			{"40", 40}, // 交易所统计了同IP其他程序的请求
+			{"12", 40}, // 较小的值不覆盖本地估算
+			{"abc", 40},
+			{"", 40},
+			{"41", 41},
+		}
+		for _, s := range steps {
+			l.Observe(context.Background(), response(http.StatusOK, map[string]string{"X-MBX-USED-WEIGHT-1M": s.header}))
+			if got := l.State().Used; got != s.want {
+				t.Errorf("响应头 %q: used = %d, want %d", s.header, got, s.want)
+			}
+		}
+	})
+
+	t.Run("未配置响应头时忽略", func(t *testing.T) {
+		l := newTestLimiter(Limit{Weight: 100, Window: time.Hour})
+		l.Observe(context.Background(), response(http.StatusOK, map[string]string{"X-MBX-USED-WEIGHT-1M": "90"}))
+		if got := l.State().Used; got != 0 {
+			t.Errorf("used = %d, want 0", got)
+		}
+	})
+
+	cases := []struct {
+		name    string
+		resp    *http.Response
+		wantFor time.Duration // 0 表示不暂停
+	}{
+		{"429 Retry-After", response(http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}), 5 * time.Second},
+		{"429 无 Retry-After", response(http.StatusTooManyRequests, nil), 30 * time.Second},
+		{"418 封禁", response(http.StatusTeapot, nil), 2 * time.Minute},
+		{"418 Retry-After", response(http.StatusTeapot, map[string]string{"Retry-After": " 120 "}), 120 * time.Second},
+		{"Retry-After 无效", response(http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}), 30 * time.Second},
+		{"500 不暂停", response(http.StatusInternalServerError, map[string]string{"Retry-After": "5"}), 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := newTestLimiter(Limit{Weight: 100, Window: time.Hour})
+			before := time.Now()
+			l.Observe(context.Background(), c.resp)
+			state := l.State()
+			if c.wantFor == 0 {
+				if state.BackoffUntil != nil {
+					t.Errorf("不应暂停, BackoffUntil = %v", state.BackoffUntil)
+				}
+				return
+			}
+			if state.BackoffUntil == nil {
+				t.Fatal("应暂停该主机的请求")
+			}
+			if got := state.BackoffUntil.Sub(before); got < c.wantFor || got > c.wantFor+time.Second {
+				t.Errorf("暂停 %v, want %v", got, c.wantFor)
+			}
+			// 暂停期间下单请求也需要等待
+			if err := waitBriefly(l, PriorityOrder, 1); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("暂停期间请求应等待, got %v", err)
+			}
+		})
+	}
+
+	t.Run("较短的暂停不缩短已有暂停", func(t *testing.T) {
+		l := newTestLimiter(Limit{Weight: 100, Window: time.Hour})
+		l.Observe(context.Background(), response(http.StatusTeapot, nil))
+		until := *l.State().BackoffUntil
+		l.Observe(context.Background(), response(http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}))
+		if got := *l.State().BackoffUntil; !got.Equal(until) {
+			t.Errorf("BackoffUntil = %v, want %v", got, until)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Transport 按请求主机限流的 http.RoundTripper
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport 包装 base（为 nil 时使用 http.DefaultTransport）
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+// RoundTrip 请求前等待额度，响应后根据响应头更新已用权重
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := For(req.URL.Hostname())
+	if err := limiter.Wait(req.Context(), limiter.cost(req)); err != nil {
+		return nil, err
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if err == nil {
+		limiter.Observe(req.Context(), resp)
+	}
+	return resp, err
+}
+
+// cost 估算请求权重
+func (l *Limiter) cost(req *http.Request) int {
+	if l.limit.RequestCost == nil {
+		return 1
+	}
+	return l.limit.RequestCost(req)
+}
+
+// binanceCost Binance（及兼容的 Aster）合约接口的请求权重
+func binanceCost(req *http.Request) int {
+	query := req.URL.Query()
+	path := req.URL.Path
+	switch {
+	case path == "/fapi/v1/klines":
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		switch {
+		case limit == 0: // 默认500
+			return 2
+		case limit < 100:
+			return 1
+		case limit < 500:
+			return 2
+		case limit <= 1000:
+			return 5
+		default:
+			return 10
+		}
+	case path == "/fapi/v1/depth":
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		switch {
+		case limit <= 50:
+			return 2
+		case limit <= 100:
+			return 5
+		case limit <= 500:
+			return 10
+		default:
+			return 20
+		}
+	case strings.HasSuffix(path, "/account"), strings.HasSuffix(path, "/balance"), strings.HasSuffix(path, "/positionRisk"):
+		return 5
+	case path == "/fapi/v1/openOrders", path == "/fapi/v1/ticker/24hr", path == "/fapi/v1/ticker/price":
+		if query.Get("symbol") == "" {
+			return 40
+		}
+		return 1
+	default:
+		return 1
+	}
+}
+
+// hyperliquidCost Hyperliquid 接口的请求权重（info 请求按类型计算，exchange 请求为 1）
+func hyperliquidCost(req *http.Request) int {
+	if !strings.HasSuffix(req.URL.Path, "/info") {
+		return 1
+	}
+	if req.GetBody == nil {
+		return 20
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return 20
+	}
+	defer body.Close()
+	var payload struct {
+		Type string `json:"type"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, 4096)).Decode(&payload); err != nil {
+		return 20
+	}
+	switch payload.Type {
+	case "l2Book", "allMids", "clearinghouseState", "orderStatus", "spotClearinghouseState", "exchangeStatus":
+		return 2
+	case "userRole":
+		return 60
+	default:
+		return 20
+	}
+}
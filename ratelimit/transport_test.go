@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// configureForTest 设置主机限流配置，测试结束后移除
+func configureForTest(t *testing.T, host string, limit Limit) {
+	t.Helper()
+	Configure(host, limit)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(limits, host)
+		delete(registry, host)
+	})
+}
+
+func TestBinanceCost(t *testing.T) {
+	cases := []struct {
+		url  string
+		want int
+	}{
+		{"/fapi/v1/klines?symbol=BTCUSDT", 2},
+		{"/fapi/v1/klines?symbol=BTCUSDT&limit=99", 1},
+		{"/fapi/v1/klines?symbol=BTCUSDT&limit=100", 2},
+		{"/fapi/v1/klines?symbol=BTCUSDT&limit=500", 5},
+		{"/fapi/v1/klines?symbol=BTCUSDT&limit=1000", 5},
+		{"/fapi/v1/klines?symbol=BTCUSDT&limit=1500", 10},
+		{"/fapi/v1/depth?symbol=BTCUSDT", 2},
+		{"/fapi/v1/depth?symbol=BTCUSDT&limit=100", 5},
+		{"/fapi/v1/depth?symbol=BTCUSDT&limit=500", 10},
+		{"/fapi/v1/depth?symbol=BTCUSDT&limit=1000", 20},
+		{"/fapi/v2/account", 5},
+		{"/fapi/v2/balance", 5},
+		{"/fapi/v2/positionRisk", 5},
+		{"/fapi/v1/openOrders", 40},
+		{"/fapi/v1/openOrders?symbol=BTCUSDT", 1},
+		{"/fapi/v1/ticker/24hr", 40},
+		{"/fapi/v1/ticker/price?symbol=BTCUSDT", 1},
+		{"/fapi/v1/order", 1},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "https://fapi.binance.com"+c.url, nil)
+		if got := binanceCost(req); got != c.want {
+			t.Errorf("binanceCost(%s) = %d, want %d", c.url, got, c.want)
+		}
+	}
+}
+
+func TestHyperliquidCost(t *testing.T) {
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/info", `{"type":"l2Book","coin":"BTC"}`, 2},
+		{"/info", `{"type":"clearinghouseState","user":"0x1"}`, 2},
+		{"/info", `{"type":"userRole","user":"0x1"}`, 60},
+		{"/info", `{"type":"meta"}`, 20},
+		{"/info", `not json`, 20},
+		{"/exchange", `{"action":{}}`, 1},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(http.MethodPost, "https://api.hyperliquid.xyz"+c.path, strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hyperliquidCost(req); got != c.want {
+			t.Errorf("hyperliquidCost(%s %s) = %d, want %d", c.path, c.body, got, c.want)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var served atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+		if used := r.URL.Query().Get("used"); used != "" {
+			w.Header().Set("X-MBX-USED-WEIGHT-1M", used)
+		}
+		if r.URL.Path == "/throttle" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	// 同一个测试服务器通过两个主机名访问，验证额度按主机独立计算
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostA, hostB := "127.0.0.1", "localhost"
+	urlA := server.URL
+	urlB := "http://" + hostB + ":" + u.Port()
+	limit := Limit{Weight: 10, Window: time.Hour, UsedHeader: "X-MBX-USED-WEIGHT-1M", RequestCost: binanceCost}
+	configureForTest(t, hostA, limit)
+	configureForTest(t, hostB, limit)
+
+	client := &http.Client{Transport: NewTransport(nil)}
+	get := func(rawURL string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	// 按 binanceCost 占用权重：账户接口 5
+	if err := get(urlA + "/fapi/v2/account"); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if got := For(hostA).State().Used; got != 5 {
+		t.Errorf("%s used = %d, want 5", hostA, got)
+	}
+	// 响应头中的已用权重覆盖本地估算
+	if err := get(urlA + "/fapi/v1/order?used=10"); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if got := For(hostA).State().Used; got != 10 {
+		t.Errorf("%s used = %d, want 10", hostA, got)
+	}
+
+	// 额度用完后请求在发出前被阻塞，ctx 到期返回错误
+	before := served.Load()
+	if err := get(urlA + "/fapi/v1/order"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("额度用完后应等待, got %v", err)
+	}
+	if served.Load() != before {
+		t.Error("被限流的请求不应发出")
+	}
+
+	// 其他主机不受影响；429 后暂停该主机
+	if err := get(urlB + "/throttle"); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if For(hostB).State().BackoffUntil == nil {
+		t.Fatalf("%s 返回429后应暂停", hostB)
+	}
+	if err := get(urlB + "/fapi/v1/order"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("暂停期间请求应等待, got %v", err)
+	}
+	if got := For(hostA).State().BackoffUntil; got != nil {
+		t.Errorf("%s 不应受其他主机的暂停影响: %v", hostA, got)
+	}
+}
@@ -125,6 +125,8 @@ check_database() {
     else
         print_success "数据库文件存在"
     fi
+    # 凭证加密密钥文件目录（与数据库分开保存）
+    mkdir -p secrets && chmod 700 secrets
 }
 
 # ------------------------------------------------------------------------
@@ -0,0 +1,90 @@
+// Package tracing OpenTelemetry 链路追踪
+//
+// 配置（环境变量，遵循 OpenTelemetry 标准）：
+//   - OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_EXPORTER_OTLP_TRACES_ENDPOINT  设置后通过 OTLP/HTTP 导出；均未设置时不导出（no-op）
+//   - OTEL_SERVICE_NAME   服务名（默认 nofx）
+//   - OTEL_TRACES_SAMPLER 等其他标准变量由 SDK 自行读取
+//
+// 一个决策周期为一条 trace：trader.cycle → 账户/币种池/行情/AI/下单各阶段子 span。
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "nofx"
+	defaultServiceName  = "nofx"
+)
+
+// Enabled 是否配置了 OTLP 导出
+func Enabled() bool {
+	return strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")) != "" ||
+		strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")) != ""
+}
+
+// Setup 按环境变量初始化全局 TracerProvider，返回的 shutdown 在退出时调用以导出剩余 span
+// 未配置 OTLP 端点时保持 OpenTelemetry 默认的 no-op 实现
+func Setup(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	shutdown = func(context.Context) error { return nil }
+	if !Enabled() {
+		return shutdown, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return shutdown, fmt.Errorf("创建OTLP导出器失败: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME / OTEL_RESOURCE_ATTRIBUTES 覆盖默认值
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return shutdown, fmt.Errorf("创建trace资源失败: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start 开始一个 span（未配置导出时为 no-op）
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 不为 nil 时记录错误并标记失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID 返回 ctx 中 span 的 trace ID（未采样或未启用追踪时返回空字符串）
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() || !sc.IsSampled() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -8,11 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math"
 	"math/big"
 	"net/http"
 	"net/url"
+	"nofx/ratelimit"
 	"sort"
 	"strconv"
 	"strings"
@@ -46,6 +46,27 @@ type SymbolPrecision struct {
 	StepSize          float64 // 数量步进值
 }
 
+func init() {
+	RegisterExchange(ExchangeAdapter{
+		ID:           "aster",
+		DisplayName:  "Aster",
+		Type:         "dex",
+		MarketSource: "aster",
+		Fields: []CredentialField{
+			{Key: "user", Label: "主钱包地址", Required: true, Pattern: `^0x[0-9a-fA-F]{40}$`, LegacyKey: "asterUser"},
+			{Key: "signer", Label: "API钱包地址", Required: true, Pattern: `^0x[0-9a-fA-F]{40}$`, LegacyKey: "asterSigner"},
+			{Key: "private_key", Label: "API钱包私钥", Secret: true, Required: true, Pattern: `^(0x)?[0-9a-fA-F]{64}$`, LegacyKey: "asterPrivateKey"},
+		},
+		Capabilities: ExchangeCapabilities{
+			ConditionalOrders: true,
+			IsolatedMargin:    true,
+		},
+		Factory: func(creds map[string]string, testnet bool) (Trader, error) {
+			return NewAsterTrader(creds["user"], creds["signer"], creds["private_key"])
+		},
+	})
+}
+
 // NewAsterTrader 创建Aster交易器
 // user: 主钱包地址 (登录地址)
 // signer: API钱包地址 (从 https://www.asterdex.com/en/api-wallet 获取)
@@ -65,11 +86,12 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		symbolPrecision: make(map[string]SymbolPrecision),
 		client: &http.Client{
 			Timeout: 30 * time.Second, // 增加到30秒
-			Transport: &http.Transport{
+			// 按交易所主机共享限流额度
+			Transport: ratelimit.NewTransport(&http.Transport{
 				TLSHandshakeTimeout:   10 * time.Second,
 				ResponseHeaderTimeout: 10 * time.Second,
 				IdleConnTimeout:       90 * time.Second,
-			},
+			}),
 		},
 		baseURL: "https://fapi.asterdex.com",
 	}, nil
@@ -322,7 +344,7 @@ func (t *AsterTrader) sign(params map[string]interface{}, nonce uint64) error {
 }
 
 // request 发送HTTP请求（带重试机制）
-func (t *AsterTrader) request(method, endpoint string, params map[string]interface{}) ([]byte, error) {
+func (t *AsterTrader) request(ctx context.Context, method, endpoint string, params map[string]interface{}) ([]byte, error) {
 	const maxRetries = 3
 	var lastErr error
 
@@ -339,7 +361,7 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 			return nil, err
 		}
 
-		body, err := t.doRequest(method, endpoint, paramsCopy)
+		body, err := t.doRequest(ctx, method, endpoint, paramsCopy)
 		if err == nil {
 			return body, nil
 		}
@@ -350,7 +372,7 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 		if strings.Contains(err.Error(), "timeout") ||
 			strings.Contains(err.Error(), "connection reset") ||
 			strings.Contains(err.Error(), "EOF") {
-			if attempt < maxRetries {
+			if attempt < maxRetries && ctx.Err() == nil {
 				waitTime := time.Duration(attempt) * time.Second
 				time.Sleep(waitTime)
 				continue
@@ -365,7 +387,7 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 }
 
 // doRequest 执行实际的HTTP请求
-func (t *AsterTrader) doRequest(method, endpoint string, params map[string]interface{}) ([]byte, error) {
+func (t *AsterTrader) doRequest(ctx context.Context, method, endpoint string, params map[string]interface{}) ([]byte, error) {
 	fullURL := t.baseURL + endpoint
 	method = strings.ToUpper(method)
 
@@ -376,7 +398,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 		for k, v := range params {
 			form.Set(k, fmt.Sprintf("%v", v))
 		}
-		req, err := http.NewRequest("POST", fullURL, strings.NewReader(form.Encode()))
+		req, err := http.NewRequestWithContext(ctx, "POST", fullURL, strings.NewReader(form.Encode()))
 		if err != nil {
 			return nil, err
 		}
@@ -403,7 +425,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 		u, _ := url.Parse(fullURL)
 		u.RawQuery = q.Encode()
 
-		req, err := http.NewRequest(method, u.String(), nil)
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
 		if err != nil {
 			return nil, err
 		}
@@ -426,9 +448,9 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 }
 
 // GetBalance 获取账户余额
-func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
+func (t *AsterTrader) GetBalance(ctx context.Context) (*Balance, error) {
 	params := make(map[string]interface{})
-	body, err := t.request("GET", "/fapi/v3/balance", params)
+	body, err := t.request(ctx, "GET", "/fapi/v3/balance", params)
 	if err != nil {
 		return nil, err
 	}
@@ -458,18 +480,18 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 		}
 	}
 
-	// 返回与Binance相同的字段名，确保AutoTrader能正确解析
-	return map[string]interface{}{
-		"totalWalletBalance":    totalBalance,
-		"availableBalance":      availableBalance,
-		"totalUnrealizedProfit": crossUnPnl,
+	return &Balance{
+		TotalEquity:      totalBalance + crossUnPnl,
+		WalletBalance:    totalBalance,
+		UnrealizedPnL:    crossUnPnl,
+		AvailableBalance: availableBalance,
 	}, nil
 }
 
 // GetPositions 获取持仓信息
-func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *AsterTrader) GetPositions(ctx context.Context) ([]Position, error) {
 	params := make(map[string]interface{})
-	body, err := t.request("GET", "/fapi/v3/positionRisk", params)
+	body, err := t.request(ctx, "GET", "/fapi/v3/positionRisk", params)
 	if err != nil {
 		return nil, err
 	}
@@ -479,7 +501,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, err
 	}
 
-	result := []map[string]interface{}{}
+	result := []Position{}
 	for _, pos := range positions {
 		posAmtStr, ok := pos["positionAmt"].(string)
 		if !ok {
@@ -494,26 +516,26 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 		entryPrice, _ := strconv.ParseFloat(pos["entryPrice"].(string), 64)
 		markPrice, _ := strconv.ParseFloat(pos["markPrice"].(string), 64)
 		unRealizedProfit, _ := strconv.ParseFloat(pos["unRealizedProfit"].(string), 64)
-		leverageVal, _ := strconv.ParseFloat(pos["leverage"].(string), 64)
+		leverageVal, _ := strconv.Atoi(pos["leverage"].(string))
 		liquidationPrice, _ := strconv.ParseFloat(pos["liquidationPrice"].(string), 64)
 
 		// 判断方向（与Binance一致）
-		side := "long"
+		side := SideLong
 		if posAmt < 0 {
-			side = "short"
+			side = SideShort
 			posAmt = -posAmt
 		}
 
-		// 返回与Binance相同的字段名
-		result = append(result, map[string]interface{}{
-			"symbol":            pos["symbol"],
-			"side":              side,
-			"positionAmt":       posAmt,
-			"entryPrice":        entryPrice,
-			"markPrice":         markPrice,
-			"unRealizedProfit":  unRealizedProfit,
-			"leverage":          leverageVal,
-			"liquidationPrice":  liquidationPrice,
+		symbol, _ := pos["symbol"].(string)
+		result = append(result, Position{
+			Symbol:           symbol,
+			Side:             side,
+			Quantity:         posAmt,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedPnL:    unRealizedProfit,
+			Leverage:         leverageVal,
+			LiquidationPrice: liquidationPrice,
 		})
 	}
 
@@ -521,19 +543,19 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 // OpenLong 开多单
-func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败(继续开仓): %v", err)
 	}
 
 	// 先设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
 		return nil, fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
 	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -561,7 +583,7 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
 	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
 
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
+	exchangeLog.Infof(ctx, "  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
 		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
 
 	params := map[string]interface{}{
@@ -574,13 +596,13 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	body, err := t.request(ctx, "POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrder(body, formattedQty)
+	if err != nil {
 		return nil, err
 	}
 
@@ -588,19 +610,19 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 }
 
 // OpenShort 开空单
-func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenShort(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败(继续开仓): %v", err)
 	}
 
 	// 先设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
 		return nil, fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
 	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -628,7 +650,7 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
 	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
 
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
+	exchangeLog.Infof(ctx, "  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
 		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
 
 	params := map[string]interface{}{
@@ -641,13 +663,13 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	body, err := t.request(ctx, "POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrder(body, formattedQty)
+	if err != nil {
 		return nil, err
 	}
 
@@ -655,28 +677,25 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 }
 
 // CloseLong 平多单
-func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseLong(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
-		positions, err := t.GetPositions()
+		positions, err := t.GetPositions(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, SideLong); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
 			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
 		}
-		log.Printf("  📊 获取到多仓数量: %.8f", quantity)
+		exchangeLog.Infof(ctx, "  📊 获取到多仓数量: %.8f", quantity)
 	}
 
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -703,7 +722,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
 	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
 
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
+	exchangeLog.Infof(ctx, "  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
 		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
 
 	params := map[string]interface{}{
@@ -716,50 +735,46 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	body, err := t.request(ctx, "POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrder(body, formattedQty)
+	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, qtyStr)
+	exchangeLog.Infof(ctx, "✓ 平多仓成功: %s 数量: %s", symbol, qtyStr)
 
 	// 平仓后取消该币种的所有挂单(止损止盈单)
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
 	}
 
 	return result, nil
 }
 
 // CloseShort 平空单
-func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseShort(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
-		positions, err := t.GetPositions()
+		positions, err := t.GetPositions(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				// Aster的GetPositions已经将空仓数量转换为正数，直接使用
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, SideShort); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
 			return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
 		}
-		log.Printf("  📊 获取到空仓数量: %.8f", quantity)
+		exchangeLog.Infof(ctx, "  📊 获取到空仓数量: %.8f", quantity)
 	}
 
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -786,7 +801,7 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
 	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
 
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
+	exchangeLog.Infof(ctx, "  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
 		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
 
 	params := map[string]interface{}{
@@ -799,28 +814,46 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		"price":        priceStr,
 	}
 
-	body, err := t.request("POST", "/fapi/v3/order", params)
+	body, err := t.request(ctx, "POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrder(body, formattedQty)
+	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, qtyStr)
+	exchangeLog.Infof(ctx, "✓ 平空仓成功: %s 数量: %s", symbol, qtyStr)
 
 	// 平仓后取消该币种的所有挂单(止损止盈单)
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
 	}
 
 	return result, nil
 }
 
+// parseAsterOrder 解析下单返回
+func parseAsterOrder(body []byte, quantity float64) (*OrderResult, error) {
+	var order struct {
+		OrderID int64  `json:"orderId"`
+		Symbol  string `json:"symbol"`
+		Status  string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, fmt.Errorf("解析下单结果失败: %w", err)
+	}
+	return &OrderResult{
+		OrderID:  strconv.FormatInt(order.OrderID, 10),
+		Symbol:   order.Symbol,
+		Status:   order.Status,
+		Quantity: quantity,
+	}, nil
+}
+
 // SetMarginMode 设置仓位模式
-func (t *AsterTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+func (t *AsterTrader) SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) error {
 	// Aster支持仓位模式设置
 	// API格式与币安相似：CROSSED(全仓) / ISOLATED(逐仓)
 	marginType := "CROSSED"
@@ -834,38 +867,43 @@ func (t *AsterTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	}
 	
 	// 使用request方法调用API
-	_, err := t.request("POST", "/fapi/v3/marginType", params)
+	_, err := t.request(ctx, "POST", "/fapi/v3/marginType", params)
 	if err != nil {
-		// 如果错误表示无需更改，忽略错误
-		if strings.Contains(err.Error(), "No need to change") || 
-		   strings.Contains(err.Error(), "Margin type cannot be changed") {
-			log.Printf("  ✓ %s 仓位模式已是 %s 或有持仓无法更改", symbol, marginType)
+		// 如果错误表示无需更改，说明仓位模式已经是目标值
+		if strings.Contains(err.Error(), "No need to change") {
+			exchangeLog.Infof(ctx, "  ✓ %s 仓位模式已是 %s", symbol, marginType)
 			return nil
 		}
-		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
-		// 不返回错误，让交易继续
-		return nil
+		// 有持仓时无法更改仓位模式，当前模式未确认，交由调用方决定是否继续
+		if strings.Contains(err.Error(), "Margin type cannot be changed") {
+			return fmt.Errorf("%s 有持仓，无法切换为 %s: %w", symbol, marginType, err)
+		}
+		return fmt.Errorf("设置仓位模式失败: %w", err)
 	}
 	
-	log.Printf("  ✓ %s 仓位模式已设置为 %s", symbol, marginType)
+	exchangeLog.Infof(ctx, "  ✓ %s 仓位模式已设置为 %s", symbol, marginType)
 	return nil
 }
 
 // SetLeverage 设置杠杆倍数
-func (t *AsterTrader) SetLeverage(symbol string, leverage int) error {
+func (t *AsterTrader) SetLeverage(ctx context.Context, symbol string, leverage int) error {
 	params := map[string]interface{}{
 		"symbol":   symbol,
 		"leverage": leverage,
 	}
 
-	_, err := t.request("POST", "/fapi/v3/leverage", params)
+	_, err := t.request(ctx, "POST", "/fapi/v3/leverage", params)
 	return err
 }
 
 // GetMarketPrice 获取市场价格
-func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
+func (t *AsterTrader) GetMarketPrice(ctx context.Context, symbol string) (float64, error) {
 	// 使用ticker接口获取当前价格
-	resp, err := t.client.Get(fmt.Sprintf("%s/fapi/v3/ticker/price?symbol=%s", t.baseURL, symbol))
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/fapi/v3/ticker/price?symbol=%s", t.baseURL, symbol), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := t.client.Do(req)
 	if err != nil {
 		return 0, err
 	}
@@ -890,9 +928,9 @@ func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 }
 
 // SetStopLoss 设置止损
-func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+func (t *AsterTrader) SetStopLoss(ctx context.Context, symbol string, positionSide PositionSide, quantity, stopPrice float64) error {
 	side := "SELL"
-	if positionSide == "SHORT" {
+	if positionSide == SideShort {
 		side = "BUY"
 	}
 
@@ -926,14 +964,14 @@ func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity,
 		"timeInForce":  "GTC",
 	}
 
-	_, err = t.request("POST", "/fapi/v3/order", params)
+	_, err = t.request(ctx, "POST", "/fapi/v3/order", params)
 	return err
 }
 
 // SetTakeProfit 设置止盈
-func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+func (t *AsterTrader) SetTakeProfit(ctx context.Context, symbol string, positionSide PositionSide, quantity, takeProfitPrice float64) error {
 	side := "SELL"
-	if positionSide == "SHORT" {
+	if positionSide == SideShort {
 		side = "BUY"
 	}
 
@@ -967,17 +1005,17 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 		"timeInForce":  "GTC",
 	}
 
-	_, err = t.request("POST", "/fapi/v3/order", params)
+	_, err = t.request(ctx, "POST", "/fapi/v3/order", params)
 	return err
 }
 
 // CancelAllOrders 取消所有订单
-func (t *AsterTrader) CancelAllOrders(symbol string) error {
+func (t *AsterTrader) CancelAllOrders(ctx context.Context, symbol string) error {
 	params := map[string]interface{}{
 		"symbol": symbol,
 	}
 
-	_, err := t.request("DELETE", "/fapi/v3/allOpenOrders", params)
+	_, err := t.request(ctx, "DELETE", "/fapi/v3/allOpenOrders", params)
 	return err
 }
 
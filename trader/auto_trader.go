@@ -1,16 +1,23 @@
 package trader
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"nofx/decision"
 	"nofx/logger"
+	"nofx/logging"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/metrics"
 	"nofx/pool"
+	"nofx/ratelimit"
+	"nofx/tracing"
 	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // AutoTraderConfig 自动交易配置（简化版 - AI全权决策）
@@ -21,21 +28,17 @@ type AutoTraderConfig struct {
 	AIModel string // AI模型: "qwen" 或 "deepseek"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
+	Exchange string // 已注册的交易所ID，如 "binance", "hyperliquid", "aster", "bybit", "okx"
 
-	// 币安API配置
-	BinanceAPIKey    string
-	BinanceSecretKey string
+	// 行情数据源（为空时使用交易所注册的默认数据源）
+	MarketSource string
 
-	// Hyperliquid配置
-	HyperliquidPrivateKey string
-	HyperliquidWalletAddr string
-	HyperliquidTestnet    bool
+	// 指标配置（为空时使用默认的 3分钟 + 4小时 数据，需已通过 Validate）
+	IndicatorSpec *market.IndicatorSpec
 
-	// Aster配置
-	AsterUser       string // Aster主钱包地址
-	AsterSigner     string // Aster API钱包地址
-	AsterPrivateKey string // Aster API钱包私钥
+	// 交易平台凭证（键名由交易所注册表中的凭证字段定义，见 registry.go）
+	ExchangeCredentials map[string]string
+	ExchangeTestnet     bool // 测试网/模拟盘
 
 	CoinPoolAPIURL string
 
@@ -66,8 +69,47 @@ type AutoTraderConfig struct {
 
 	// 仓位模式
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
+
+	// 保证金分配策略（为空时使用默认的90%组合预算，需已通过 Validate）
+	MarginPolicy *MarginPolicy
+
+	// 组合敞口限制（为空时使用默认限制，需已通过 Validate）
+	ExposureLimits *decision.ExposureLimits
+
+	// 止损止盈校验阈值（为空时使用默认阈值，需已通过 Validate）
+	RiskRewardRules *decision.RiskRewardRules
+
+	// 风控策略（为空时按杠杆配置使用默认策略，需已通过 Validate）
+	RiskPolicy *decision.RiskPolicy
+
+	// 币种范围（为空时使用 AI500前20 + OI Top，需已通过 Validate）
+	CoinUniverse *pool.Universe
+
+	// 周期调度（为空时按 ScanInterval 固定执行，需已通过 Validate）
+	Schedule *Schedule
 }
 
+// TraderState 交易员运行状态
+type TraderState string
+
+const (
+	StateStarting TraderState = "starting" // 已调用Run，尚未进入主循环
+	StateRunning  TraderState = "running"  // 主循环运行中
+	StateStopping TraderState = "stopping" // 已请求停止，等待当前周期结束
+	StateStopped  TraderState = "stopped"  // 已停止（或从未启动）
+	StateErrored  TraderState = "errored"  // 主循环异常退出
+)
+
+// orderTimeout 停止期间单个决策执行的最长时间（下单不随停止信号中断，但不能无限等待）
+const orderTimeout = 30 * time.Second
+
+// closedDone 从未启动的交易员返回的已关闭通道
+var closedDone = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
 // AutoTrader 自动交易器
 type AutoTrader struct {
 	id                    string // Trader唯一标识
@@ -75,19 +117,48 @@ type AutoTrader struct {
 	aiModel               string // AI模型名称
 	exchange              string // 交易平台名称
 	config                AutoTraderConfig
-	trader                Trader // 使用Trader接口（支持多平台）
+	trader                Trader          // 使用Trader接口（支持多平台）
+	marketProvider        market.Provider // 行情数据源（与交易所一致）
 	mcpClient             *mcp.Client
 	decisionLogger        *logger.DecisionLogger // 决策日志记录器
+	log                   *logging.Logger        // 带 trader_id 的日志
 	initialBalance        float64
-	dailyPnL              float64
-	customPrompt          string // 自定义交易策略prompt
-	overrideBasePrompt    bool   // 是否覆盖基础prompt
-	lastResetTime         time.Time
-	stopUntil             time.Time
-	isRunning             bool
-	startTime             time.Time        // 系统启动时间
-	callCount             int              // AI调用次数
-	positionFirstSeenTime map[string]int64 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	customPrompt          string                    // 自定义交易策略prompt
+	overrideBasePrompt    bool                      // 是否覆盖基础prompt
+	indicatorSpec         *market.IndicatorSpec     // 指标配置
+	marginPolicy          MarginPolicy              // 保证金分配策略
+	exposureLimits        *decision.ExposureLimits  // 组合敞口限制
+	riskRewardRules       *decision.RiskRewardRules // 止损止盈校验阈值
+	riskPolicy            *decision.RiskPolicy      // 风控策略
+	coinPool              *pool.Pool                // 交易员独立的币种池
+	coinUniverse          *pool.Universe            // 币种范围
+	promptTemplate        *decision.PromptTemplate  // 提示词模板
+	startTime             time.Time                 // 系统启动时间
+	positionMu            sync.Mutex                // 保护 positionFirstSeenTime 和 recentCloses（提示词预览与主循环并发构建上下文）
+	positionFirstSeenTime map[string]int64          // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	recentCloses          map[string]time.Time      // 各币种最近一次平仓时间（用于冷却判断，启动时从决策日志恢复）
+
+	// 事件触发使用的止损价（主循环写入，事件轮询 goroutine 读取）
+	eventMu    sync.Mutex
+	stopLosses map[string]stopLossLevel // symbol_side -> 止损价
+
+	// 生命周期状态（API goroutine 与主循环并发访问，由 mu 保护）
+	// customPrompt 至 promptTemplate 等可由API在运行中修改的配置同样由 mu 保护，周期内使用 settingsSnapshot 的快照
+	mu            sync.RWMutex
+	state         TraderState
+	cancel        context.CancelFunc // 取消当前主循环
+	done          chan struct{}      // 当前主循环退出时关闭
+	lastErr       error              // 主循环异常退出的原因
+	callCount     int                // AI调用次数
+	health        TraderHealth       // 健康统计
+	cycleHook     func(h TraderHealth)
+	schedule      *Schedule     // 周期调度（nil 表示按扫描间隔）
+	lastCycle     time.Time     // 上一周期开始时间（含因交易窗口跳过的周期）
+	nextCycle     time.Time     // 下一个定时周期时间（零值表示无定时周期）
+	dailyPnL      float64       // 日盈亏
+	lastResetTime time.Time     // 日盈亏上次重置时间
+	stopUntil     time.Time     // 风险控制暂停截止时间
+	reschedule    chan struct{} // 调度配置变更时通知主循环重新计算下一周期
 }
 
 // NewAutoTrader 创建自动交易器
@@ -108,164 +179,438 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	}
 
 	mcpClient := mcp.New()
+	traderLog := logging.For("trader").With("trader_id", config.ID, "trader_name", config.Name)
+	initCtx := context.Background()
 
 	// 初始化AI
 	if config.AIModel == "custom" {
 		// 使用自定义API
 		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
-		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
+		traderLog.Infof(initCtx, "🤖 使用自定义AI API: %s (模型: %s)", config.CustomAPIURL, config.CustomModelName)
 	} else if config.UseQwen || config.AIModel == "qwen" {
 		// 使用Qwen
 		mcpClient.SetQwenAPIKey(config.QwenKey, "")
-		log.Printf("🤖 [%s] 使用阿里云Qwen AI", config.Name)
+		traderLog.Infof(initCtx, "🤖 使用阿里云Qwen AI")
 	} else {
 		// 默认使用DeepSeek
 		mcpClient.SetDeepSeekAPIKey(config.DeepSeekKey)
-		log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
+		traderLog.Infof(initCtx, "🤖 使用DeepSeek AI")
 	}
 
-	// 初始化币种池API
+	// 初始化交易员自己的币种池（基于全局默认配置，覆盖交易员指定的API）
+	coinPoolConfig := pool.DefaultConfig()
 	if config.CoinPoolAPIURL != "" {
-		pool.SetCoinPoolAPI(config.CoinPoolAPIURL)
+		coinPoolConfig.APIURL = config.CoinPoolAPIURL
 	}
+	coinPool := pool.NewPool(coinPoolConfig)
 
 	// 设置默认交易平台
 	if config.Exchange == "" {
 		config.Exchange = "binance"
 	}
 
-	// 根据配置创建对应的交易器
-	var trader Trader
-	var err error
-
 	// 记录仓位模式（通用）
 	marginModeStr := "全仓"
 	if !config.IsCrossMargin {
 		marginModeStr = "逐仓"
 	}
-	log.Printf("📊 [%s] 仓位模式: %s", config.Name, marginModeStr)
+	traderLog.Infof(initCtx, "📊 仓位模式: %s", marginModeStr)
 
-	switch config.Exchange {
-	case "binance":
-		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
-		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey)
-	case "hyperliquid":
-		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
-		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
-		if err != nil {
-			return nil, fmt.Errorf("初始化Hyperliquid交易器失败: %w", err)
-		}
-	case "aster":
-		log.Printf("🏦 [%s] 使用Aster交易", config.Name)
-		trader, err = NewAsterTrader(config.AsterUser, config.AsterSigner, config.AsterPrivateKey)
-		if err != nil {
-			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
-		}
-	default:
-		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
+	// 根据配置通过交易所注册表创建对应的交易器
+	adapter, err := GetExchangeAdapter(config.Exchange)
+	if err != nil {
+		return nil, err
+	}
+	traderLog.Infof(initCtx, "🏦 使用%s交易", adapter.DisplayName)
+	trader, err := NewExchangeTrader(config.Exchange, config.ExchangeCredentials, config.ExchangeTestnet)
+	if err != nil {
+		return nil, err
 	}
 
+	// 选择行情数据源（默认使用交易所自身的行情）
+	if config.MarketSource == "" {
+		config.MarketSource = adapter.MarketSource
+	}
+	marketProvider, err := market.NewProvider(config.MarketSource, config.ExchangeTestnet)
+	if err != nil {
+		return nil, err
+	}
+	traderLog.Infof(initCtx, "📡 行情数据源: %s", marketProvider.Name())
+
 	// 验证初始金额配置
 	if config.InitialBalance <= 0 {
 		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
 	}
 
+	marginPolicy := DefaultMarginPolicy()
+	if config.MarginPolicy != nil {
+		marginPolicy = *config.MarginPolicy
+	}
+
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
 	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
 	decisionLogger := logger.NewDecisionLogger(logDir)
 
-	return &AutoTrader{
+	at := &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
 		aiModel:               config.AIModel,
 		exchange:              config.Exchange,
 		config:                config,
 		trader:                trader,
+		marketProvider:        marketProvider,
+		indicatorSpec:         config.IndicatorSpec,
+		marginPolicy:          marginPolicy,
+		exposureLimits:        config.ExposureLimits,
+		riskRewardRules:       config.RiskRewardRules,
+		riskPolicy:            config.RiskPolicy,
+		coinPool:              coinPool,
+		coinUniverse:          config.CoinUniverse,
+		promptTemplate:        decision.DefaultPromptTemplate(),
 		mcpClient:             mcpClient,
 		decisionLogger:        decisionLogger,
+		log:                   traderLog,
 		initialBalance:        config.InitialBalance,
 		lastResetTime:         time.Now(),
 		startTime:             time.Now(),
-		callCount:             0,
-		isRunning:             false,
+		state:                 StateStopped,
+		health:                TraderHealth{Status: HealthHealthy},
 		positionFirstSeenTime: make(map[string]int64),
-	}, nil
+		recentCloses:          make(map[string]time.Time),
+		stopLosses:            make(map[string]stopLossLevel),
+		schedule:              config.Schedule,
+		reschedule:            make(chan struct{}, 1),
+	}
+	at.restorePositionHistory(initCtx)
+	return at, nil
 }
 
-// Run 运行自动交易主循环
-func (at *AutoTrader) Run() error {
-	at.isRunning = true
-	log.Println("🚀 AI驱动自动交易系统启动")
-	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
-	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
-	log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
+// positionHistoryLookback 启动时用于恢复持仓状态的决策记录数
+const positionHistoryLookback = 200
 
-	ticker := time.NewTicker(at.config.ScanInterval)
-	defer ticker.Stop()
+// restorePositionHistory 从决策日志恢复最近平仓时间和持仓首次出现时间（重启后冷却期和持仓时长不丢失）
+// 恢复的持仓在首个周期中若已不存在（停机期间被止盈止损），按平仓处理
+func (at *AutoTrader) restorePositionHistory(ctx context.Context) {
+	history, err := at.decisionLogger.RebuildPositionHistory(positionHistoryLookback)
+	if err != nil {
+		at.log.Warnf(ctx, "⚠️ 从决策日志恢复持仓状态失败: %v", err)
+		return
+	}
+	at.positionMu.Lock()
+	defer at.positionMu.Unlock()
+	for symbol, closedAt := range history.RecentCloses {
+		at.recentCloses[symbol] = closedAt
+	}
+	for key, since := range history.OpenSince {
+		at.positionFirstSeenTime[key] = since.UnixMilli()
+	}
+	if len(history.RecentCloses) > 0 || len(history.OpenSince) > 0 {
+		at.log.Infof(ctx, "🔄 已从决策日志恢复 %d 个平仓记录、%d 个持仓", len(history.RecentCloses), len(history.OpenSince))
+	}
+}
+
+// markOpened 记录持仓首次出现时间（开仓成功时）
+func (at *AutoTrader) markOpened(posKey string) {
+	at.positionMu.Lock()
+	defer at.positionMu.Unlock()
+	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+}
 
-	// 首次立即执行
-	if err := at.runCycle(); err != nil {
-		log.Printf("❌ 执行失败: %v", err)
+// markClosed 记录币种的平仓时间（用于冷却判断）
+func (at *AutoTrader) markClosed(symbol string) {
+	at.positionMu.Lock()
+	defer at.positionMu.Unlock()
+	at.recentCloses[symbol] = time.Now()
+}
+
+// Run 运行自动交易主循环，直到 ctx 被取消或调用 Stop
+// 取消会传递到行情、AI和交易所请求；已开始执行的下单不会被中断
+func (at *AutoTrader) Run(ctx context.Context) (err error) {
+	at.mu.Lock()
+	if at.state == StateStarting || at.state == StateRunning || at.state == StateStopping {
+		state := at.state
+		at.mu.Unlock()
+		return fmt.Errorf("%w: %s（状态: %s）", ErrAlreadyRunning, at.name, state)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	at.state = StateStarting
+	at.cancel = cancel
+	at.done = done
+	at.lastErr = nil
+	at.lastCycle = time.Time{} // interval 模式启动后立即执行首个周期
+	at.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("交易主循环panic: %v", r)
+		}
+		cancel()
+		at.finish(err)
+		close(done)
+	}()
+
+	// 该 context 下的所有日志（行情、AI、交易所）都带上 trader_id
+	runCtx = logging.WithAttrs(runCtx, "trader_id", at.id)
+
+	at.log.Infof(runCtx, "🚀 AI驱动自动交易系统启动")
+	at.log.Infof(runCtx, "💰 初始余额: %.2f USDT", at.initialBalance)
+	schedule := at.GetSchedule()
+	at.log.Infof(runCtx, "⚙️  周期调度: %s", schedule.Describe(at.scanInterval()))
+	at.log.Infof(runCtx, "🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
+
+	if !at.transition(StateStarting, StateRunning) || runCtx.Err() != nil {
+		// 启动过程中已被停止
+		return nil
 	}
 
-	for at.isRunning {
+	// 事件触发：价格异动或触及止损时立即执行一个周期（事件监控 panic 时主循环异常退出，由监督器重启）
+	events := make(chan string, 1)
+	watchErr := make(chan error, 1)
+	go at.watchEvents(runCtx, events, watchErr)
+
+	for {
+		schedule := at.GetSchedule()
+		last, _ := at.cycleTimes()
+		next := schedule.Next(time.Now(), last, at.scanInterval())
+		at.setNextCycle(next)
+
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timerC = timer.C
+		}
+
+		trigger := ""
 		select {
-		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
-				log.Printf("❌ 执行失败: %v", err)
+		case <-runCtx.Done():
+		case <-timerC:
+			trigger = "schedule"
+		case reason := <-events:
+			at.log.Infof(runCtx, "⚡ 事件触发决策周期: %s", reason)
+			trigger = "event: " + reason
+		case err := <-watchErr:
+			if timer != nil {
+				timer.Stop()
 			}
+			return err
+		case <-at.reschedule:
+			// 调度配置已变更，重新计算下一周期
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if runCtx.Err() != nil {
+			return nil
+		}
+		if trigger == "" {
+			continue
 		}
+
+		if gate := schedule.Gate(time.Now()); gate.Pause {
+			at.log.Infof(runCtx, "⏸ %s，跳过本周期", gate.Reason)
+			at.markCycle() // 跳过的周期同样占用本次调度时间
+			continue
+		}
+		at.cycle(runCtx, trigger)
+	}
+}
+
+// scanInterval 扫描间隔（未配置时默认3分钟）
+func (at *AutoTrader) scanInterval() time.Duration {
+	if at.config.ScanInterval > 0 {
+		return at.config.ScanInterval
 	}
+	return 3 * time.Minute
+}
 
-	return nil
+// cycle 执行一个交易周期并记录结果（停止导致的中断不计为失败）
+func (at *AutoTrader) cycle(runCtx context.Context, trigger string) {
+	at.markCycle()
+	err := at.runCycle(runCtx, trigger)
+	if runCtx.Err() != nil {
+		return
+	}
+	if err != nil {
+		at.log.Errorf(runCtx, "❌ 执行失败: %v", err)
+		metrics.CyclesFailed.WithLabelValues(at.id).Inc()
+	}
+	at.recordCycle(err)
 }
 
-// Stop 停止自动交易
+// Stop 请求停止自动交易（不等待，使用 Done 等待主循环退出）
 func (at *AutoTrader) Stop() {
-	at.isRunning = false
-	log.Println("⏹ 自动交易系统停止")
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	if at.state != StateStarting && at.state != StateRunning {
+		return
+	}
+	at.state = StateStopping
+	at.cancel()
+	at.log.Infof(context.Background(), "⏹ 正在停止自动交易，等待当前周期结束...")
 }
 
-// runCycle 运行一个交易周期（使用AI全权决策）
-func (at *AutoTrader) runCycle() error {
+// Done 返回主循环退出时关闭的通道（从未启动时返回已关闭的通道）
+func (at *AutoTrader) Done() <-chan struct{} {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+
+	if at.done == nil {
+		return closedDone
+	}
+	return at.done
+}
+
+// State 当前运行状态
+func (at *AutoTrader) State() TraderState {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.state
+}
+
+// IsRunning 是否正在运行（启动中、运行中或停止中都视为运行）
+func (at *AutoTrader) IsRunning() bool {
+	switch at.State() {
+	case StateStarting, StateRunning, StateStopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// LastError 主循环最近一次异常退出的原因
+func (at *AutoTrader) LastError() error {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.lastErr
+}
+
+// transition 仅当当前状态为 from 时切换到 to
+func (at *AutoTrader) transition(from, to TraderState) bool {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	if at.state != from {
+		return false
+	}
+	at.state = to
+	return true
+}
+
+// finish 主循环退出后记录最终状态
+func (at *AutoTrader) finish(err error) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	at.cancel = nil
+	if err != nil {
+		at.state = StateErrored
+		at.lastErr = err
+		at.log.Errorf(context.Background(), "❌ 自动交易异常退出: %v", err)
+		return
+	}
+	at.state = StateStopped
+	at.log.Infof(context.Background(), "⏹ 自动交易系统已停止")
+}
+
+// nextCallCount AI调用次数加一并返回
+func (at *AutoTrader) nextCallCount() int {
+	at.mu.Lock()
+	defer at.mu.Unlock()
 	at.callCount++
+	return at.callCount
+}
 
-	log.Printf("\n" + strings.Repeat("=", 70))
-	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
-	log.Printf(strings.Repeat("=", 70))
+// pauseRemaining 风险控制暂停的剩余时间（未暂停时为0）
+func (at *AutoTrader) pauseRemaining(now time.Time) time.Duration {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if now.Before(at.stopUntil) {
+		return at.stopUntil.Sub(now)
+	}
+	return 0
+}
+
+// resetDailyPnL 距上次重置超过24小时时清零日盈亏，返回是否重置
+func (at *AutoTrader) resetDailyPnL(now time.Time) bool {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if now.Sub(at.lastResetTime) <= 24*time.Hour {
+		return false
+	}
+	at.dailyPnL = 0
+	at.lastResetTime = now
+	return true
+}
 
-	// 创建决策记录
+// riskState 在锁内读取日盈亏和风险控制状态
+func (at *AutoTrader) riskState() (dailyPnL float64, lastResetTime, stopUntil time.Time) {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.dailyPnL, at.lastResetTime, at.stopUntil
+}
+
+// getCallCount 当前AI调用次数
+func (at *AutoTrader) getCallCount() int {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.callCount
+}
+
+// runCycle 运行一个交易周期（使用AI全权决策）
+// runCtx 取消后不再发起新的行情/AI请求，也不再开新仓；已发出的下单使用独立超时执行完毕
+func (at *AutoTrader) runCycle(runCtx context.Context, trigger string) (err error) {
+	callCount := at.nextCallCount()
+	metrics.Cycles.WithLabelValues(at.id).Inc()
+
+	runCtx, span := tracing.Start(runCtx, "trader.cycle",
+		attribute.String("trader.id", at.id),
+		attribute.Int("cycle", callCount),
+		attribute.String("trigger", trigger),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	runCtx = logging.WithAttrs(runCtx, "cycle", callCount)
+	at.log.Infof(runCtx, "⏰ AI决策周期 #%d 开始", callCount)
+
+	// 本周期使用的配置快照（API 在周期执行中修改配置时从下一周期生效）
+	settings := at.settingsSnapshot()
+
+	// 创建决策记录（trace ID 供前端关联到链路追踪）
 	record := &logger.DecisionRecord{
+		TraceID:      tracing.TraceID(runCtx),
+		Trigger:      trigger,
 		ExecutionLog: []string{},
 		Success:      true,
 	}
 
 	// 1. 检查是否需要停止交易
-	if time.Now().Before(at.stopUntil) {
-		remaining := at.stopUntil.Sub(time.Now())
-		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+	if remaining := at.pauseRemaining(time.Now()); remaining > 0 {
+		at.log.Warnf(runCtx, "⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
-		at.decisionLogger.LogDecision(record)
+		at.decisionLogger.LogDecision(runCtx, record)
 		return nil
 	}
 
 	// 2. 重置日盈亏（每天重置）
-	if time.Since(at.lastResetTime) > 24*time.Hour {
-		at.dailyPnL = 0
-		at.lastResetTime = time.Now()
-		log.Println("📅 日盈亏已重置")
+	if at.resetDailyPnL(time.Now()) {
+		at.log.Infof(runCtx, "📅 日盈亏已重置")
 	}
 
 	// 3. 收集交易上下文
-	ctx, err := at.buildTradingContext()
+	ctx, err := at.buildTradingContext(runCtx, settings)
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
-		at.decisionLogger.LogDecision(record)
+		at.decisionLogger.LogDecision(runCtx, record)
 		return fmt.Errorf("构建交易上下文失败: %w", err)
 	}
 
+	at.pruneStopLosses(ctx.Positions)
+
 	// 保存账户状态快照
 	record.AccountState = logger.AccountSnapshot{
 		TotalBalance:          ctx.Account.TotalEquity,
@@ -293,16 +638,25 @@ func (at *AutoTrader) runCycle() error {
 	for _, coin := range ctx.CandidateCoins {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
 	}
+	record.MarketSource = ctx.MarketSource
+	record.PromptTemplate = settings.promptTemplate.Ref()
 
-	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
+	at.log.Infof(runCtx, "📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
+	at.recordAccountMetrics(ctx)
 
 	// 4. 调用AI获取完整决策
-	log.Println("🤖 正在请求AI分析并决策...")
-	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt)
+	at.log.Infof(runCtx, "🤖 正在请求AI分析并决策...")
+	decision, err := decision.GetFullDecisionWithCustomPrompt(runCtx, ctx, at.mcpClient, settings.customPrompt, settings.overrideBasePrompt)
 
-	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
+	// 即使有错误，也保存思维链、决策、输入prompt和AI用量（解析失败时AI调用仍然产生了费用）
 	if decision != nil {
+		usage := decision.AIUsage
+		record.AIUsage = logger.AIUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			CostUSD:          usage.CostUSD(at.mcpClient.Provider),
+		}
 		record.InputPrompt = decision.UserPrompt
 		record.CoTTrace = decision.CoTTrace
 		if len(decision.Decisions) > 0 {
@@ -317,45 +671,63 @@ func (at *AutoTrader) runCycle() error {
 
 		// 打印AI思维链（即使有错误）
 		if decision != nil && decision.CoTTrace != "" {
-			log.Printf("\n" + strings.Repeat("-", 70))
-			log.Println("💭 AI思维链分析（错误情况）:")
-			log.Println(strings.Repeat("-", 70))
-			log.Println(decision.CoTTrace)
-			log.Printf(strings.Repeat("-", 70) + "\n")
+			at.log.Infof(runCtx, "💭 AI思维链分析（错误情况）:\n%s", decision.CoTTrace)
 		}
 
-		at.decisionLogger.LogDecision(record)
+		at.decisionLogger.LogDecision(runCtx, record)
 		return fmt.Errorf("获取AI决策失败: %w", err)
 	}
 
 	// 5. 打印AI思维链
-	log.Printf("\n" + strings.Repeat("-", 70))
-	log.Println("💭 AI思维链分析:")
-	log.Println(strings.Repeat("-", 70))
-	log.Println(decision.CoTTrace)
-	log.Printf(strings.Repeat("-", 70) + "\n")
+	at.log.Infof(runCtx, "💭 AI思维链分析:\n%s", decision.CoTTrace)
 
 	// 6. 打印AI决策
-	log.Printf("📋 AI决策列表 (%d 个):\n", len(decision.Decisions))
+	at.log.Infof(runCtx, "📋 AI决策列表 (%d 个)", len(decision.Decisions))
 	for i, d := range decision.Decisions {
-		log.Printf("  [%d] %s: %s - %s", i+1, d.Symbol, d.Action, d.Reasoning)
+		metrics.Decisions.WithLabelValues(at.id, d.Action).Inc()
+		at.log.Infof(runCtx, "  [%d] %s: %s - %s", i+1, d.Symbol, d.Action, d.Reasoning)
 		if d.Action == "open_long" || d.Action == "open_short" {
-			log.Printf("      杠杆: %dx | 仓位: %.2f USDT | 止损: %.4f | 止盈: %.4f",
+			at.log.Infof(runCtx, "      杠杆: %dx | 仓位: %.2f USDT | 止损: %.4f | 止盈: %.4f",
 				d.Leverage, d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
 		}
 	}
-	log.Println()
+
+	// 未通过验证的决策只剔除该决策，其余照常执行
+	for _, rej := range decision.Rejected {
+		metrics.Decisions.WithLabelValues(at.id, rej.Decision.Action).Inc()
+		at.log.Warnf(runCtx, "🚫 决策验证未通过 (%s %s): %s", rej.Decision.Symbol, rej.Decision.Action, rej.Reason)
+		at.recordOrder(rej.Decision.Action, "rejected")
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 %s %s 被拒绝: %s", rej.Decision.Symbol, rej.Decision.Action, rej.Reason))
+		record.Decisions = append(record.Decisions, logger.DecisionAction{
+			Action:    rej.Decision.Action,
+			Symbol:    rej.Decision.Symbol,
+			Leverage:  rej.Decision.Leverage,
+			Timestamp: time.Now(),
+			Error:     rej.Reason,
+		})
+	}
 
 	// 7. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
 
-	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
+	at.log.Infof(runCtx, "🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
-		log.Printf("  [%d] %s %s", i+1, d.Symbol, d.Action)
+		at.log.Infof(runCtx, "  [%d] %s %s", i+1, d.Symbol, d.Action)
 	}
-	log.Println()
+
+	// 按组合保证金预算和单币种上限分配开仓保证金
+	allocator := NewMarginAllocator(settings.marginPolicy, at.config.IsCrossMargin, ctx.Account, ctx.Positions)
+
+	// 交易窗口限制（如周末不开新仓）
+	schedule := at.GetSchedule()
+	gate := schedule.Gate(time.Now())
 
 	// 执行决策并记录结果
+	// 各币种的持仓方向数（双向持仓的币种只占一个持仓名额）
+	held := make(map[string]int)
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol]++
+	}
 	for _, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
@@ -367,13 +739,129 @@ func (at *AutoTrader) runCycle() error {
 			Success:   false,
 		}
 
-		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
-			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+		var alloc MarginAllocation
+		isOpen := d.Action == "open_long" || d.Action == "open_short"
+		if isOpen && runCtx.Err() != nil {
+			// 停止中：平仓照常执行，不再开新仓
+			at.log.Infof(runCtx, "⏹ 交易员停止中，跳过开仓 (%s %s)", d.Symbol, d.Action)
+			actionRecord.Error = "交易员停止中，跳过开仓"
+			at.recordOrder(d.Action, "skipped")
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏹ %s %s 已跳过: 交易员停止中", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+		if isOpen && gate.NoOpen {
+			at.log.Infof(runCtx, "⏹ %s，跳过开仓 (%s %s)", gate.Reason, d.Symbol, d.Action)
+			actionRecord.Error = gate.Reason + "，跳过开仓"
+			at.recordOrder(d.Action, "skipped")
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏹ %s %s 已跳过: %s", d.Symbol, d.Action, gate.Reason))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+		if isOpen {
+			if err := ctx.CheckOpen(&d, held); err != nil {
+				at.log.Warnf(runCtx, "🚫 风控策略拒绝 (%s %s): %v", d.Symbol, d.Action, err)
+				actionRecord.Error = "风控策略拒绝: " + err.Error()
+				at.recordOrder(d.Action, "rejected")
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 %s %s 被拒绝: %v", d.Symbol, d.Action, err))
+				record.Decisions = append(record.Decisions, actionRecord)
+				continue
+			}
+		}
+		if isOpen && ctx.PortfolioRisk != nil {
+			check := ctx.PortfolioRisk.Check(&d)
+			actionRecord.ExposureReason = check.Reason
+			if check.Rejected {
+				at.log.Warnf(runCtx, "🚫 组合敞口检查拒绝 (%s %s): %s", d.Symbol, d.Action, check.Reason)
+				actionRecord.ExposureAdjust = "rejected"
+				actionRecord.Error = "组合敞口检查拒绝: " + check.Reason
+				at.recordOrder(d.Action, "rejected")
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 %s %s 被拒绝: %s", d.Symbol, d.Action, check.Reason))
+				record.Decisions = append(record.Decisions, actionRecord)
+				continue
+			}
+			if check.Resized {
+				at.log.Warnf(runCtx, "⚠️ 组合敞口调整 (%s %s): %.2f → %.2f USDT (%s)", d.Symbol, d.Action, check.RequestedSizeUSD, check.SizeUSD, check.Reason)
+				actionRecord.ExposureAdjust = "resized"
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %s %s 敞口调整: %.2f → %.2f USDT (%s)",
+					d.Symbol, d.Action, check.RequestedSizeUSD, check.SizeUSD, check.Reason))
+				d.PositionSizeUSD = check.SizeUSD
+			}
+		}
+		if isOpen {
+			alloc = allocator.Allocate(&d)
+			actionRecord.RequestedSizeUSD = alloc.RequestedSizeUSD
+			actionRecord.SizeUSD = alloc.SizeUSD
+			actionRecord.MarginUSD = alloc.MarginUSD
+			actionRecord.MarginReason = alloc.Reason
+			if alloc.Rejected {
+				at.log.Warnf(runCtx, "🚫 保证金分配拒绝 (%s %s): %s", d.Symbol, d.Action, alloc.Reason)
+				actionRecord.MarginAdjust = "rejected"
+				actionRecord.Error = "保证金分配拒绝: " + alloc.Reason
+				at.recordOrder(d.Action, "rejected")
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 %s %s 被拒绝: %s", d.Symbol, d.Action, alloc.Reason))
+				record.Decisions = append(record.Decisions, actionRecord)
+				at.rollbackExposure(ctx, &d)
+				continue
+			}
+			if alloc.Resized {
+				at.log.Warnf(runCtx, "⚠️ 仓位调整 (%s %s): %.2f → %.2f USDT (%s)", d.Symbol, d.Action, alloc.RequestedSizeUSD, alloc.SizeUSD, alloc.Reason)
+				actionRecord.MarginAdjust = "resized"
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %s %s 仓位调整: %.2f → %.2f USDT (%s)",
+					d.Symbol, d.Action, alloc.RequestedSizeUSD, alloc.SizeUSD, alloc.Reason))
+				d.PositionSizeUSD = alloc.SizeUSD
+			}
+		}
+
+		// 下单不随停止信号中断，避免开仓成功但止损止盈未设置
+		execCtx, cancelExec := context.WithTimeout(context.WithoutCancel(runCtx), orderTimeout)
+		execCtx, execSpan := tracing.Start(execCtx, "trader.execute",
+			attribute.String("symbol", d.Symbol),
+			attribute.String("action", d.Action),
+		)
+		err := at.executeDecisionWithRecord(execCtx, &d, &actionRecord)
+		if actionRecord.OrderID != 0 {
+			execSpan.SetAttributes(attribute.Int64("order.id", actionRecord.OrderID))
+		}
+		tracing.End(execSpan, err)
+		cancelExec()
+		if err != nil {
+			at.log.Errorf(runCtx, "❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
 			actionRecord.Error = err.Error()
+			at.recordOrder(d.Action, "failed")
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			if isOpen {
+				allocator.Rollback(d.Symbol, alloc)
+				at.rollbackExposure(ctx, &d)
+			}
 		} else {
 			actionRecord.Success = true
+			at.recordOrder(d.Action, "success")
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			switch d.Action {
+			case "open_long":
+				allocator.Commit(d.Symbol, SideLong, alloc)
+				held[d.Symbol]++
+			case "open_short":
+				allocator.Commit(d.Symbol, SideShort, alloc)
+				held[d.Symbol]++
+			case "close_long":
+				at.markClosed(d.Symbol)
+				at.untrackStopLoss(d.Symbol, SideLong)
+				held[d.Symbol]--
+				allocator.Release(d.Symbol, SideLong)
+				if ctx.PortfolioRisk != nil {
+					ctx.PortfolioRisk.Release(d.Symbol, "long")
+				}
+			case "close_short":
+				at.markClosed(d.Symbol)
+				at.untrackStopLoss(d.Symbol, SideShort)
+				held[d.Symbol]--
+				allocator.Release(d.Symbol, SideShort)
+				if ctx.PortfolioRisk != nil {
+					ctx.PortfolioRisk.Release(d.Symbol, "short")
+				}
+			}
 			// 成功执行后短暂延迟
 			time.Sleep(1 * time.Second)
 		}
@@ -382,41 +870,49 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 8. 保存决策记录
-	if err := at.decisionLogger.LogDecision(record); err != nil {
-		log.Printf("⚠ 保存决策记录失败: %v", err)
+	if err := at.decisionLogger.LogDecision(runCtx, record); err != nil {
+		at.log.Warnf(runCtx, "⚠ 保存决策记录失败: %v", err)
 	}
 
 	return nil
 }
 
-// buildTradingContext 构建交易上下文
-func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
-	// 1. 获取账户信息
-	balance, err := at.trader.GetBalance()
-	if err != nil {
-		return nil, fmt.Errorf("获取账户余额失败: %w", err)
+// recordAccountMetrics 更新账户指标
+func (at *AutoTrader) recordAccountMetrics(ctx *decision.Context) {
+	unrealizedPnL := 0.0
+	for _, pos := range ctx.Positions {
+		unrealizedPnL += pos.UnrealizedPnL
 	}
+	metrics.TraderEquity.WithLabelValues(at.id).Set(ctx.Account.TotalEquity)
+	metrics.TraderMarginUsed.WithLabelValues(at.id).Set(ctx.Account.MarginUsed)
+	metrics.TraderOpenPositions.WithLabelValues(at.id).Set(float64(ctx.Account.PositionCount))
+	metrics.TraderUnrealizedPnL.WithLabelValues(at.id).Set(unrealizedPnL)
+}
 
-	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
+// recordOrder 记录开平仓决策的执行结果（hold/wait 不计入）
+func (at *AutoTrader) recordOrder(action, result string) {
+	switch action {
+	case "open_long", "open_short", "close_long", "close_short":
+		metrics.Orders.WithLabelValues(at.id, action, result).Inc()
 	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
+}
+
+// buildTradingContext 按配置快照构建交易上下文
+func (at *AutoTrader) buildTradingContext(runCtx context.Context, settings traderSettings) (_ *decision.Context, err error) {
+	runCtx, span := tracing.Start(runCtx, "trader.build_context")
+	defer func() { tracing.End(span, err) }()
+
+	// 1. 获取账户信息
+	balance, err := at.trader.GetBalance(runCtx)
+	if err != nil {
+		return nil, fmt.Errorf("获取账户余额失败: %w", err)
 	}
 
-	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	totalEquity := balance.TotalEquity
+	availableBalance := balance.AvailableBalance
 
 	// 2. 获取持仓信息
-	positions, err := at.trader.GetPositions()
+	positions, err := at.trader.GetPositions(runCtx)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -427,36 +923,14 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 当前持仓的key集合（用于清理已平仓的记录）
 	currentPositionKeys := make(map[string]bool)
 
+	at.positionMu.Lock()
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
-
-		// 计算盈亏百分比
-		pnlPct := 0.0
-		if side == "long" {
-			pnlPct = ((markPrice - entryPrice) / entryPrice) * 100
-		} else {
-			pnlPct = ((entryPrice - markPrice) / entryPrice) * 100
-		}
-
 		// 计算占用保证金（估算）
-		leverage := 10 // 默认值，实际应该从持仓信息获取
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.MarginUsed()
 		totalMarginUsed += marginUsed
 
 		// 跟踪持仓首次出现时间
-		posKey := symbol + "_" + side
+		posKey := pos.Key()
 		currentPositionKeys[posKey] = true
 		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
 			// 新持仓，记录当前时间
@@ -465,34 +939,46 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		updateTime := at.positionFirstSeenTime[posKey]
 
 		positionInfos = append(positionInfos, decision.PositionInfo{
-			Symbol:           symbol,
-			Side:             side,
-			EntryPrice:       entryPrice,
-			MarkPrice:        markPrice,
-			Quantity:         quantity,
-			Leverage:         leverage,
-			UnrealizedPnL:    unrealizedPnl,
-			UnrealizedPnLPct: pnlPct,
-			LiquidationPrice: liquidationPrice,
+			Symbol:           pos.Symbol,
+			Side:             string(pos.Side),
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			Quantity:         pos.Quantity,
+			Leverage:         pos.EffectiveLeverage(),
+			UnrealizedPnL:    pos.UnrealizedPnL,
+			UnrealizedPnLPct: pos.PriceChangePct(),
+			LiquidationPrice: pos.LiquidationPrice,
 			MarginUsed:       marginUsed,
 			UpdateTime:       updateTime,
 		})
 	}
 
-	// 清理已平仓的持仓记录
-	for key := range at.positionFirstSeenTime {
+	// 清理已平仓的持仓记录（止盈止损等在交易所触发的平仓也计入冷却）
+	for key, firstSeen := range at.positionFirstSeenTime {
 		if !currentPositionKeys[key] {
+			symbol := key[:strings.LastIndex(key, "_")]
+			if at.recentCloses[symbol].UnixMilli() < firstSeen {
+				at.recentCloses[symbol] = time.Now()
+			}
 			delete(at.positionFirstSeenTime, key)
 		}
 	}
+	recentCloses := make(map[string]time.Time, len(at.recentCloses))
+	for symbol, closedAt := range at.recentCloses {
+		recentCloses[symbol] = closedAt
+	}
+	at.positionMu.Unlock()
 
-	// 3. 获取合并的候选币种池（AI500 + OI Top，去重）
+	// 3. 按交易员的币种范围获取候选币种池（静态白名单 + AI500 + OI Top，去重并剔除黑名单）
 	// 无论有没有持仓，都分析相同数量的币种（让AI看到所有好机会）
 	// AI会根据保证金使用率和现有持仓情况，自己决定是否要换仓
-	const ai500Limit = 20 // AI500取前20个评分最高的币种
-
-	// 获取合并后的币种池（AI500 + OI Top）
-	mergedPool, err := pool.GetMergedCoinPool(ai500Limit)
+	universe := settings.coinUniverse
+	_, poolSpan := tracing.Start(runCtx, "pool.universe", attribute.StringSlice("sources", universe.Sources))
+	mergedPool, err := at.coinPool.GetUniverse(runCtx, universe)
+	if mergedPool != nil {
+		poolSpan.SetAttributes(attribute.Int("symbols", len(mergedPool.AllSymbols)))
+	}
+	tracing.End(poolSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("获取合并币种池失败: %w", err)
 	}
@@ -503,12 +989,11 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		sources := mergedPool.SymbolSources[symbol]
 		candidateCoins = append(candidateCoins, decision.CandidateCoin{
 			Symbol:  symbol,
-			Sources: sources, // "ai500" 和/或 "oi_top"
+			Sources: sources, // "static"、"ai500"、"screener" 和/或 "oi_top"
 		})
 	}
 
-	log.Printf("📋 合并币种池: AI500前%d + OI_Top20 = 总计%d个候选币种",
-		ai500Limit, len(candidateCoins))
+	at.log.Infof(runCtx, "📋 合并币种池: 来源%v = 总计%d个候选币种", universe.Sources, len(candidateCoins))
 
 	// 4. 计算总盈亏
 	totalPnL := totalEquity - at.initialBalance
@@ -526,7 +1011,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
 	performance, err := at.decisionLogger.AnalyzePerformance(100)
 	if err != nil {
-		log.Printf("⚠️  分析历史表现失败: %v", err)
+		at.log.Warnf(runCtx, "⚠️  分析历史表现失败: %v", err)
 		// 不影响主流程，继续执行（但设置performance为nil以避免传递错误数据）
 		performance = nil
 	}
@@ -535,7 +1020,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	ctx := &decision.Context{
 		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
 		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
-		CallCount:       at.callCount,
+		CallCount:       at.getCallCount(),
 		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
 		Account: decision.AccountInfo{
@@ -550,22 +1035,34 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		Positions:      positionInfos,
 		CandidateCoins: candidateCoins,
 		Performance:    performance, // 添加历史表现分析
+		MarketProvider: at.marketProvider,
+		MarketSource:   at.marketProvider.Name(),
+		IndicatorSpec:  settings.indicatorSpec,
+
+		MaxMarginUsagePct: settings.marginPolicy.MaxTotalMarginPct,
+		ExposureLimits:    settings.exposureLimits,
+		Universe:          &universe,
+		OITopPositions:    mergedPool.OITopCoins,
+		RiskPolicy:        settings.riskPolicy,
+		RecentCloses:      recentCloses,
+		RiskRewardRules:   settings.riskRewardRules,
+		PromptTemplate:    settings.promptTemplate,
 	}
 
 	return ctx, nil
 }
 
 // executeDecisionWithRecord 执行AI决策并记录详细信息
-func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+func (at *AutoTrader) executeDecisionWithRecord(ctx context.Context, decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	switch decision.Action {
 	case "open_long":
-		return at.executeOpenLongWithRecord(decision, actionRecord)
+		return at.executeOpenLongWithRecord(ctx, decision, actionRecord)
 	case "open_short":
-		return at.executeOpenShortWithRecord(decision, actionRecord)
+		return at.executeOpenShortWithRecord(ctx, decision, actionRecord)
 	case "close_long":
-		return at.executeCloseLongWithRecord(decision, actionRecord)
+		return at.executeCloseLongWithRecord(ctx, decision, actionRecord)
 	case "close_short":
-		return at.executeCloseShortWithRecord(decision, actionRecord)
+		return at.executeCloseShortWithRecord(ctx, decision, actionRecord)
 	case "hold", "wait":
 		// 无需执行，仅记录
 		return nil
@@ -574,22 +1071,33 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 	}
 }
 
+// setMarginMode 开仓前设置仓位模式（全仓模式下设置失败不影响交易）
+func (at *AutoTrader) setMarginMode(ctx context.Context, symbol string) error {
+	err := at.trader.SetMarginMode(ctx, symbol, at.config.IsCrossMargin)
+	if err == nil {
+		return nil
+	}
+	if !at.config.IsCrossMargin {
+		return fmt.Errorf("设置逐仓模式失败，拒绝开仓: %w", err)
+	}
+	at.log.Warnf(ctx, "  ⚠️ 设置仓位模式失败: %v", err)
+	return nil
+}
+
 // executeOpenLongWithRecord 执行开多仓并记录详细信息
-func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  📈 开多仓: %s", decision.Symbol)
+func (at *AutoTrader) executeOpenLongWithRecord(ctx context.Context, decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.log.Infof(ctx, "  📈 开多仓: %s", decision.Symbol)
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
+	positions, err := at.trader.GetPositions(ctx)
 	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
-				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
-			}
+		if _, exists := FindPosition(positions, decision.Symbol, SideLong); exists {
+			return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
 		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithProvider(ctx, at.marketProvider, decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -599,56 +1107,57 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
-	// 设置仓位模式
-	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
-		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
-		// 继续执行，不影响交易
+	// 设置仓位模式（逐仓时仓位保证金即分配的保证金，设置失败则不开仓，避免按全仓占用）
+	if err := at.setMarginMode(ctx, decision.Symbol); err != nil {
+		return err
 	}
 
 	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
+	order, err := PlaceOrder(ctx, at.trader, OrderRequest{
+		Symbol:   decision.Symbol,
+		Side:     SideLong,
+		Quantity: quantity,
+		Leverage: decision.Leverage,
+	})
 	if err != nil {
 		return err
 	}
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = order.NumericID()
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	at.log.Infof(ctx, "  ✓ 开仓成功，订单ID: %s, 数量: %.4f", order.OrderID, quantity)
 
 	// 记录开仓时间
-	posKey := decision.Symbol + "_long"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	posKey := Position{Symbol: decision.Symbol, Side: SideLong}.Key()
+	at.markOpened(posKey)
 
 	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
+	if err := at.trader.SetStopLoss(ctx, decision.Symbol, SideLong, quantity, decision.StopLoss); err != nil {
+		at.log.Warnf(ctx, "  ⚠ 设置止损失败: %v", err)
 	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	at.trackStopLoss(decision.Symbol, SideLong, decision.StopLoss)
+	if err := at.trader.SetTakeProfit(ctx, decision.Symbol, SideLong, quantity, decision.TakeProfit); err != nil {
+		at.log.Warnf(ctx, "  ⚠ 设置止盈失败: %v", err)
 	}
 
 	return nil
 }
 
 // executeOpenShortWithRecord 执行开空仓并记录详细信息
-func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  📉 开空仓: %s", decision.Symbol)
+func (at *AutoTrader) executeOpenShortWithRecord(ctx context.Context, decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.log.Infof(ctx, "  📉 开空仓: %s", decision.Symbol)
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
+	positions, err := at.trader.GetPositions(ctx)
 	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
-				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
-			}
+		if _, exists := FindPosition(positions, decision.Symbol, SideShort); exists {
+			return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
 		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithProvider(ctx, at.marketProvider, decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -658,89 +1167,96 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
-	// 设置仓位模式
-	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
-		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
-		// 继续执行，不影响交易
+	// 设置仓位模式（逐仓时仓位保证金即分配的保证金，设置失败则不开仓，避免按全仓占用）
+	if err := at.setMarginMode(ctx, decision.Symbol); err != nil {
+		return err
 	}
 
 	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
+	order, err := PlaceOrder(ctx, at.trader, OrderRequest{
+		Symbol:   decision.Symbol,
+		Side:     SideShort,
+		Quantity: quantity,
+		Leverage: decision.Leverage,
+	})
 	if err != nil {
 		return err
 	}
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = order.NumericID()
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	at.log.Infof(ctx, "  ✓ 开仓成功，订单ID: %s, 数量: %.4f", order.OrderID, quantity)
 
 	// 记录开仓时间
-	posKey := decision.Symbol + "_short"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	posKey := Position{Symbol: decision.Symbol, Side: SideShort}.Key()
+	at.markOpened(posKey)
 
 	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
+	if err := at.trader.SetStopLoss(ctx, decision.Symbol, SideShort, quantity, decision.StopLoss); err != nil {
+		at.log.Warnf(ctx, "  ⚠ 设置止损失败: %v", err)
 	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	at.trackStopLoss(decision.Symbol, SideShort, decision.StopLoss)
+	if err := at.trader.SetTakeProfit(ctx, decision.Symbol, SideShort, quantity, decision.TakeProfit); err != nil {
+		at.log.Warnf(ctx, "  ⚠ 设置止盈失败: %v", err)
 	}
 
 	return nil
 }
 
 // executeCloseLongWithRecord 执行平多仓并记录详细信息
-func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🔄 平多仓: %s", decision.Symbol)
+func (at *AutoTrader) executeCloseLongWithRecord(ctx context.Context, decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.log.Infof(ctx, "  🔄 平多仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithProvider(ctx, at.marketProvider, decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
 	// 平仓
-	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = 全部平仓
+	order, err := PlaceOrder(ctx, at.trader, OrderRequest{
+		Symbol:     decision.Symbol,
+		Side:       SideLong,
+		ReduceOnly: true, // 数量为0 = 全部平仓
+	})
 	if err != nil {
 		return err
 	}
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = order.NumericID()
 
-	log.Printf("  ✓ 平仓成功")
+	at.log.Infof(ctx, "  ✓ 平仓成功")
 	return nil
 }
 
 // executeCloseShortWithRecord 执行平空仓并记录详细信息
-func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🔄 平空仓: %s", decision.Symbol)
+func (at *AutoTrader) executeCloseShortWithRecord(ctx context.Context, decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.log.Infof(ctx, "  🔄 平空仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetWithProvider(ctx, at.marketProvider, decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
 	// 平仓
-	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = 全部平仓
+	order, err := PlaceOrder(ctx, at.trader, OrderRequest{
+		Symbol:     decision.Symbol,
+		Side:       SideShort,
+		ReduceOnly: true, // 数量为0 = 全部平仓
+	})
 	if err != nil {
 		return err
 	}
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = order.NumericID()
 
-	log.Printf("  ✓ 平仓成功")
+	at.log.Infof(ctx, "  ✓ 平仓成功")
 	return nil
 }
 
@@ -761,14 +1277,203 @@ func (at *AutoTrader) GetAIModel() string {
 
 // SetCustomPrompt 设置自定义交易策略prompt
 func (at *AutoTrader) SetCustomPrompt(prompt string) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
 	at.customPrompt = prompt
 }
 
 // SetOverrideBasePrompt 设置是否覆盖基础prompt
 func (at *AutoTrader) SetOverrideBasePrompt(override bool) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
 	at.overrideBasePrompt = override
 }
 
+// SetIndicatorSpec 设置指标配置（nil 表示使用默认数据）
+func (at *AutoTrader) SetIndicatorSpec(spec *market.IndicatorSpec) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.indicatorSpec = spec
+}
+
+// rollbackExposure 开仓未执行时撤销组合敞口检查计入的敞口
+func (at *AutoTrader) rollbackExposure(ctx *decision.Context, d *decision.Decision) {
+	if ctx.PortfolioRisk == nil {
+		return
+	}
+	if d.Action == "open_long" {
+		ctx.PortfolioRisk.Rollback(d.Symbol, "long")
+	} else {
+		ctx.PortfolioRisk.Rollback(d.Symbol, "short")
+	}
+}
+
+// SetExposureLimits 设置组合敞口限制（nil 表示使用默认限制）
+func (at *AutoTrader) SetExposureLimits(limits *decision.ExposureLimits) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.exposureLimits = limits
+}
+
+// SetRiskRewardRules 设置止损止盈校验阈值（nil 表示使用默认阈值）
+func (at *AutoTrader) SetRiskRewardRules(rules *decision.RiskRewardRules) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.riskRewardRules = rules
+}
+
+// GetRiskPolicy 获取当前生效的风控策略（未配置时按杠杆配置生成默认策略）
+func (at *AutoTrader) GetRiskPolicy() decision.RiskPolicy {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.riskPolicy != nil {
+		return *at.riskPolicy
+	}
+	return decision.DefaultRiskPolicy(at.config.BTCETHLeverage, at.config.AltcoinLeverage)
+}
+
+// SetRiskPolicy 设置风控策略（nil 表示使用默认策略）
+func (at *AutoTrader) SetRiskPolicy(policy *decision.RiskPolicy) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.riskPolicy = policy
+}
+
+// GetPromptTemplate 获取当前使用的提示词模板
+func (at *AutoTrader) GetPromptTemplate() *decision.PromptTemplate {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.promptTemplate
+}
+
+// SetPromptTemplate 设置提示词模板（nil 表示使用默认模板）
+func (at *AutoTrader) SetPromptTemplate(tpl *decision.PromptTemplate) {
+	if tpl == nil {
+		tpl = decision.DefaultPromptTemplate()
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.promptTemplate = tpl
+}
+
+// PreviewPrompts 按当前账户和行情渲染提示词（不调用AI、不执行交易）
+// tpl 为空时使用当前模板
+func (at *AutoTrader) PreviewPrompts(runCtx context.Context, tpl *decision.PromptTemplate) (string, string, error) {
+	settings := at.settingsSnapshot()
+	ctx, err := at.buildTradingContext(runCtx, settings)
+	if err != nil {
+		return "", "", fmt.Errorf("构建交易上下文失败: %w", err)
+	}
+	if tpl != nil {
+		ctx.PromptTemplate = tpl
+	}
+	return decision.BuildPrompts(runCtx, ctx, settings.customPrompt, settings.overrideBasePrompt)
+}
+
+// GetCoinUniverse 获取当前生效的币种范围（未配置时返回默认币种范围）
+func (at *AutoTrader) GetCoinUniverse() pool.Universe {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.coinUniverse != nil {
+		return *at.coinUniverse
+	}
+	return pool.DefaultUniverse()
+}
+
+// SetCoinUniverse 设置币种范围（nil 表示使用默认币种范围）
+func (at *AutoTrader) SetCoinUniverse(universe *pool.Universe) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.coinUniverse = universe
+}
+
+// GetSchedule 获取当前生效的周期调度（未配置时返回默认调度）
+func (at *AutoTrader) GetSchedule() Schedule {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.schedule != nil {
+		return *at.schedule
+	}
+	return DefaultSchedule()
+}
+
+// SetSchedule 设置周期调度（nil 表示按扫描间隔执行），运行中立即按新配置重新计算下一周期
+func (at *AutoTrader) SetSchedule(schedule *Schedule) {
+	at.mu.Lock()
+	at.schedule = schedule
+	at.mu.Unlock()
+	select {
+	case at.reschedule <- struct{}{}:
+	default:
+	}
+}
+
+// cycleTimes 上一周期开始时间和下一个定时周期时间
+func (at *AutoTrader) cycleTimes() (last, next time.Time) {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.lastCycle, at.nextCycle
+}
+
+// markCycle 记录周期开始时间（interval 模式的下一周期和事件触发冷却都以此为准）
+func (at *AutoTrader) markCycle() {
+	at.mu.Lock()
+	at.lastCycle = time.Now()
+	at.mu.Unlock()
+}
+
+// setNextCycle 记录下一个定时周期时间
+func (at *AutoTrader) setNextCycle(next time.Time) {
+	at.mu.Lock()
+	at.nextCycle = next
+	at.mu.Unlock()
+}
+
+// SetMarginPolicy 设置保证金分配策略（nil 表示使用默认策略）
+func (at *AutoTrader) SetMarginPolicy(policy *MarginPolicy) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if policy == nil {
+		at.marginPolicy = DefaultMarginPolicy()
+		return
+	}
+	at.marginPolicy = *policy
+}
+
+// traderSettings 周期开始时读取的可运行中修改的配置快照
+type traderSettings struct {
+	customPrompt       string
+	overrideBasePrompt bool
+	indicatorSpec      *market.IndicatorSpec
+	marginPolicy       MarginPolicy
+	exposureLimits     *decision.ExposureLimits
+	riskRewardRules    *decision.RiskRewardRules
+	riskPolicy         *decision.RiskPolicy
+	coinUniverse       pool.Universe
+	promptTemplate     *decision.PromptTemplate
+}
+
+// settingsSnapshot 在锁内读取当前配置（Set* 只替换指针不修改对象，快照可在锁外安全使用）
+func (at *AutoTrader) settingsSnapshot() traderSettings {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	universe := pool.DefaultUniverse()
+	if at.coinUniverse != nil {
+		universe = *at.coinUniverse
+	}
+	return traderSettings{
+		customPrompt:       at.customPrompt,
+		overrideBasePrompt: at.overrideBasePrompt,
+		indicatorSpec:      at.indicatorSpec,
+		marginPolicy:       at.marginPolicy,
+		exposureLimits:     at.exposureLimits,
+		riskRewardRules:    at.riskRewardRules,
+		riskPolicy:         at.riskPolicy,
+		coinUniverse:       universe,
+		promptTemplate:     at.promptTemplate,
+	}
+}
+
 // GetDecisionLogger 获取决策日志记录器
 func (at *AutoTrader) GetDecisionLogger() *logger.DecisionLogger {
 	return at.decisionLogger
@@ -781,50 +1486,47 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		aiProvider = "Qwen"
 	}
 
+	schedule := at.GetSchedule()
+	_, lastResetTime, stopUntil := at.riskState()
+	var nextCycle string
+	if _, next := at.cycleTimes(); !next.IsZero() && at.IsRunning() {
+		nextCycle = next.Format(time.RFC3339)
+	}
+
 	return map[string]interface{}{
 		"trader_id":       at.id,
 		"trader_name":     at.name,
 		"ai_model":        at.aiModel,
 		"exchange":        at.exchange,
-		"is_running":      at.isRunning,
+		"is_running":      at.IsRunning(),
+		"state":           at.State(),
+		"health":          at.Health(),
 		"start_time":      at.startTime.Format(time.RFC3339),
 		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
-		"call_count":      at.callCount,
+		"call_count":      at.getCallCount(),
 		"initial_balance": at.initialBalance,
 		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
-		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
+		"schedule":        schedule,
+		"next_cycle":      nextCycle, // 下一个定时周期（event 模式或未运行时为空）
+		"trading_gate":    schedule.Gate(time.Now()),
+		"stop_until":      stopUntil.Format(time.RFC3339),
+		"last_reset_time": lastResetTime.Format(time.RFC3339),
 		"ai_provider":     aiProvider,
+		"rate_limits":     ratelimit.Snapshot(), // 按交易所主机共享的限流状态
 	}
 }
 
 // GetAccountInfo 获取账户信息（用于API）
-func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
-	balance, err := at.trader.GetBalance()
+func (at *AutoTrader) GetAccountInfo(ctx context.Context) (map[string]interface{}, error) {
+	balance, err := at.trader.GetBalance(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取余额失败: %w", err)
 	}
 
-	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
-
-	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	totalEquity := balance.TotalEquity
 
 	// 获取持仓计算总保证金
-	positions, err := at.trader.GetPositions()
+	positions, err := at.trader.GetPositions(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -832,20 +1534,8 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	totalMarginUsed := 0.0
 	totalUnrealizedPnL := 0.0
 	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		totalUnrealizedPnL += unrealizedPnl
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
+		totalUnrealizedPnL += pos.UnrealizedPnL
+		totalMarginUsed += pos.MarginUsed()
 	}
 
 	totalPnL := totalEquity - at.initialBalance
@@ -859,19 +1549,20 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		marginUsedPct = (totalMarginUsed / totalEquity) * 100
 	}
 
+	dailyPnL, _, _ := at.riskState()
 	return map[string]interface{}{
 		// 核心字段
-		"total_equity":      totalEquity,           // 账户净值 = wallet + unrealized
-		"wallet_balance":    totalWalletBalance,    // 钱包余额（不含未实现盈亏）
-		"unrealized_profit": totalUnrealizedProfit, // 未实现盈亏（从API）
-		"available_balance": availableBalance,      // 可用余额
+		"total_equity":      totalEquity,              // 账户净值 = wallet + unrealized
+		"wallet_balance":    balance.WalletBalance,    // 钱包余额（不含未实现盈亏）
+		"unrealized_profit": balance.UnrealizedPnL,    // 未实现盈亏（从API）
+		"available_balance": balance.AvailableBalance, // 可用余额
 
 		// 盈亏统计
 		"total_pnl":            totalPnL,           // 总盈亏 = equity - initial
 		"total_pnl_pct":        totalPnLPct,        // 总盈亏百分比
 		"total_unrealized_pnl": totalUnrealizedPnL, // 未实现盈亏（从持仓计算）
 		"initial_balance":      at.initialBalance,  // 初始余额
-		"daily_pnl":            at.dailyPnL,        // 日盈亏
+		"daily_pnl":            dailyPnL,           // 日盈亏
 
 		// 持仓信息
 		"position_count":  len(positions),  // 持仓数量
@@ -881,50 +1572,35 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取持仓列表（用于API）
-func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
-	positions, err := at.trader.GetPositions()
+func (at *AutoTrader) GetPositions(ctx context.Context) ([]map[string]interface{}, error) {
+	positions, err := at.trader.GetPositions(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
+	// 保持原有的API字段格式
 	var result []map[string]interface{}
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-
 		// 计算占用保证金
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.MarginUsed()
 
 		// 计算盈亏百分比（基于保证金）
 		// 收益率 = 未实现盈亏 / 保证金 × 100%
 		pnlPct := 0.0
 		if marginUsed > 0 {
-			pnlPct = (unrealizedPnl / marginUsed) * 100
+			pnlPct = (pos.UnrealizedPnL / marginUsed) * 100
 		}
 
 		result = append(result, map[string]interface{}{
-			"symbol":             symbol,
-			"side":               side,
-			"entry_price":        entryPrice,
-			"mark_price":         markPrice,
-			"quantity":           quantity,
-			"leverage":           leverage,
-			"unrealized_pnl":     unrealizedPnl,
+			"symbol":             pos.Symbol,
+			"side":               string(pos.Side),
+			"entry_price":        pos.EntryPrice,
+			"mark_price":         pos.MarkPrice,
+			"quantity":           pos.Quantity,
+			"leverage":           pos.EffectiveLeverage(),
+			"unrealized_pnl":     pos.UnrealizedPnL,
 			"unrealized_pnl_pct": pnlPct,
-			"liquidation_price":  liquidationPrice,
+			"liquidation_price":  pos.LiquidationPrice,
 			"margin_used":        marginUsed,
 		})
 	}
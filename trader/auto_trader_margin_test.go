@@ -0,0 +1,96 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/logging"
+	"nofx/market"
+)
+
+// flatProvider 返回固定价格K线的行情数据源
+type flatProvider struct{}
+
+func (flatProvider) Name() string { return "flat" }
+
+func (flatProvider) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]market.Kline, error) {
+	klines := make([]market.Kline, limit)
+	for i := range klines {
+		klines[i] = market.Kline{Open: 100, High: 100, Low: 100, Close: 100, Volume: 1}
+	}
+	return klines, nil
+}
+
+func (flatProvider) GetOpenInterest(ctx context.Context, symbol string) (*market.OIData, error) {
+	return &market.OIData{}, nil
+}
+
+func (flatProvider) GetFundingRate(ctx context.Context, symbol string) (float64, error) {
+	return 0, nil
+}
+
+// marginModeTrader 无法确认仓位模式的交易所，记录开仓调用
+type marginModeTrader struct {
+	Trader
+	marginErr error
+	opened    []string
+}
+
+func (t *marginModeTrader) GetPositions(ctx context.Context) ([]Position, error) {
+	return nil, nil
+}
+
+func (t *marginModeTrader) SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) error {
+	return t.marginErr
+}
+
+func (t *marginModeTrader) OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	t.opened = append(t.opened, symbol)
+	return &OrderResult{OrderID: "1", Symbol: symbol, Quantity: quantity}, nil
+}
+
+func (t *marginModeTrader) SetStopLoss(ctx context.Context, symbol string, side PositionSide, quantity, stopPrice float64) error {
+	return nil
+}
+
+func (t *marginModeTrader) SetTakeProfit(ctx context.Context, symbol string, side PositionSide, quantity, takeProfitPrice float64) error {
+	return nil
+}
+
+func newMarginModeAutoTrader(ft *marginModeTrader, isCrossMargin bool) *AutoTrader {
+	return &AutoTrader{
+		config:                AutoTraderConfig{IsCrossMargin: isCrossMargin},
+		trader:                ft,
+		marketProvider:        flatProvider{},
+		log:                   logging.For("trader"),
+		positionFirstSeenTime: make(map[string]int64),
+		stopLosses:            make(map[string]stopLossLevel),
+	}
+}
+
+func TestOpenRefusedWhenIsolatedModeUnconfirmed(t *testing.T) {
+	marginErr := errors.New("Margin type cannot be changed if there exists position")
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100}
+
+	// 逐仓：交易所未确认仓位模式时拒绝开仓
+	ft := &marginModeTrader{marginErr: marginErr}
+	err := newMarginModeAutoTrader(ft, false).executeOpenLongWithRecord(context.Background(), d, &logger.DecisionAction{})
+	if !errors.Is(err, marginErr) {
+		t.Errorf("逐仓模式未确认时应拒绝开仓, got %v", err)
+	}
+	if len(ft.opened) != 0 {
+		t.Errorf("逐仓模式未确认时不应下单: %v", ft.opened)
+	}
+
+	// 全仓：设置失败只记录警告，继续开仓
+	ft = &marginModeTrader{marginErr: marginErr}
+	if err := newMarginModeAutoTrader(ft, true).executeOpenLongWithRecord(context.Background(), d, &logger.DecisionAction{}); err != nil {
+		t.Errorf("全仓模式设置失败不应影响开仓: %v", err)
+	}
+	if len(ft.opened) != 1 {
+		t.Errorf("全仓开仓次数 = %d, want 1", len(ft.opened))
+	}
+}
@@ -0,0 +1,77 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"nofx/logger"
+	"nofx/logging"
+)
+
+// unreachableTrader 获取余额总是失败的交易所（周期在构建上下文时结束）
+type unreachableTrader struct {
+	Trader
+}
+
+func (unreachableTrader) GetBalance(ctx context.Context) (*Balance, error) {
+	return nil, errors.New("connection refused")
+}
+
+// 运行 go test -race 时检查 API 读取状态与主循环写入风控字段之间没有数据竞争
+func TestGetStatusConcurrentWithCycle(t *testing.T) {
+	at := &AutoTrader{
+		id:             "race",
+		trader:         unreachableTrader{},
+		marketProvider: flatProvider{},
+		decisionLogger: logger.NewDecisionLogger(t.TempDir()),
+		log:            logging.For("trader"),
+		state:          StateRunning,
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				status := at.GetStatus()
+				if _, ok := status["stop_until"]; !ok {
+					t.Error("状态缺少 stop_until")
+				}
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		// 交替触发风控暂停和日盈亏重置两条写路径
+		at.mu.Lock()
+		if i%2 == 0 {
+			at.stopUntil = time.Now().Add(time.Minute)
+		} else {
+			at.stopUntil = time.Time{}
+			at.lastResetTime = time.Now().Add(-25 * time.Hour)
+		}
+		at.mu.Unlock()
+
+		err := at.runCycle(context.Background(), "interval")
+		if i%2 == 0 && err != nil {
+			t.Errorf("暂停中的周期不应返回错误: %v", err)
+		}
+		if i%2 == 1 && err == nil {
+			t.Error("获取余额失败时周期应返回错误")
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if dailyPnL, lastReset, _ := at.riskState(); dailyPnL != 0 || time.Since(lastReset) > time.Minute {
+		t.Errorf("日盈亏应已重置: pnl=%v lastReset=%v", dailyPnL, lastReset)
+	}
+}
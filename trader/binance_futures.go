@@ -3,7 +3,8 @@ package trader
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
+	"nofx/ratelimit"
 	"strconv"
 	"sync"
 	"time"
@@ -16,12 +17,12 @@ type FuturesTrader struct {
 	client *futures.Client
 
 	// 余额缓存
-	cachedBalance     map[string]interface{}
+	cachedBalance     *Balance
 	balanceCacheTime  time.Time
 	balanceCacheMutex sync.RWMutex
 
 	// 持仓缓存
-	cachedPositions     []map[string]interface{}
+	cachedPositions     []Position
 	positionsCacheTime  time.Time
 	positionsCacheMutex sync.RWMutex
 
@@ -29,9 +30,33 @@ type FuturesTrader struct {
 	cacheDuration time.Duration
 }
 
+func init() {
+	RegisterExchange(ExchangeAdapter{
+		ID:           "binance",
+		DisplayName:  "Binance",
+		Type:         "cex",
+		MarketSource: "binance",
+		Fields: []CredentialField{
+			{Key: "api_key", Label: "API Key", Required: true, LegacyKey: "apiKey"},
+			{Key: "secret_key", Label: "Secret Key", Secret: true, Required: true, LegacyKey: "secretKey"},
+		},
+		Capabilities: ExchangeCapabilities{
+			HedgeMode:         true,
+			ConditionalOrders: true,
+			IsolatedMargin:    true,
+			MarketOrders:      true,
+		},
+		Factory: func(creds map[string]string, testnet bool) (Trader, error) {
+			return NewFuturesTrader(creds["api_key"], creds["secret_key"]), nil
+		},
+	})
+}
+
 // NewFuturesTrader 创建合约交易器
 func NewFuturesTrader(apiKey, secretKey string) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
+	// 按交易所主机共享限流额度（读取 X-MBX-USED-WEIGHT-1M）
+	client.HTTPClient = &http.Client{Timeout: 30 * time.Second, Transport: ratelimit.NewTransport(nil)}
 	return &FuturesTrader{
 		client:        client,
 		cacheDuration: 15 * time.Second, // 15秒缓存
@@ -39,31 +64,32 @@ func NewFuturesTrader(apiKey, secretKey string) *FuturesTrader {
 }
 
 // GetBalance 获取账户余额（带缓存）
-func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
+func (t *FuturesTrader) GetBalance(ctx context.Context) (*Balance, error) {
 	// 先检查缓存是否有效
 	t.balanceCacheMutex.RLock()
 	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
 		cacheAge := time.Since(t.balanceCacheTime)
 		t.balanceCacheMutex.RUnlock()
-		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		exchangeLog.Infof(ctx, "✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
 		return t.cachedBalance, nil
 	}
 	t.balanceCacheMutex.RUnlock()
 
 	// 缓存过期或不存在，调用API
-	log.Printf("🔄 缓存过期，正在调用币安API获取账户余额...")
-	account, err := t.client.NewGetAccountService().Do(context.Background())
+	exchangeLog.Infof(ctx, "🔄 缓存过期，正在调用币安API获取账户余额...")
+	account, err := t.client.NewGetAccountService().Do(ctx)
 	if err != nil {
-		log.Printf("❌ 币安API调用失败: %v", err)
+		exchangeLog.Errorf(ctx, "❌ 币安API调用失败: %v", err)
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	result := make(map[string]interface{})
-	result["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
-	result["availableBalance"], _ = strconv.ParseFloat(account.AvailableBalance, 64)
-	result["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+	result := &Balance{}
+	result.WalletBalance, _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	result.AvailableBalance, _ = strconv.ParseFloat(account.AvailableBalance, 64)
+	result.UnrealizedPnL, _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+	result.TotalEquity = result.WalletBalance + result.UnrealizedPnL
 
-	log.Printf("✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
+	exchangeLog.Infof(ctx, "✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
 		account.TotalWalletBalance,
 		account.AvailableBalance,
 		account.TotalUnrealizedProfit)
@@ -78,48 +104,44 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取所有持仓（带缓存）
-func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *FuturesTrader) GetPositions(ctx context.Context) ([]Position, error) {
 	// 先检查缓存是否有效
 	t.positionsCacheMutex.RLock()
 	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
 		cacheAge := time.Since(t.positionsCacheTime)
 		t.positionsCacheMutex.RUnlock()
-		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		exchangeLog.Infof(ctx, "✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
 		return t.cachedPositions, nil
 	}
 	t.positionsCacheMutex.RUnlock()
 
 	// 缓存过期或不存在，调用API
-	log.Printf("🔄 缓存过期，正在调用币安API获取持仓信息...")
-	positions, err := t.client.NewGetPositionRiskService().Do(context.Background())
+	exchangeLog.Infof(ctx, "🔄 缓存过期，正在调用币安API获取持仓信息...")
+	positions, err := t.client.NewGetPositionRiskService().Do(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 	for _, pos := range positions {
 		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
 		if posAmt == 0 {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-		posMap["symbol"] = pos.Symbol
-		posMap["positionAmt"], _ = strconv.ParseFloat(pos.PositionAmt, 64)
-		posMap["entryPrice"], _ = strconv.ParseFloat(pos.EntryPrice, 64)
-		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
-		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
-		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
-		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
-
-		// 判断方向
-		if posAmt > 0 {
-			posMap["side"] = "long"
-		} else {
-			posMap["side"] = "short"
+		p := Position{Symbol: pos.Symbol, Side: SideLong, Quantity: posAmt}
+		// 判断方向（空仓数量为负，转为正数）
+		if posAmt < 0 {
+			p.Side = SideShort
+			p.Quantity = -posAmt
 		}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		p.UnrealizedPnL, _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+		p.Leverage, _ = strconv.Atoi(pos.Leverage)
 
-		result = append(result, posMap)
+		result = append(result, p)
 	}
 
 	// 更新缓存
@@ -132,7 +154,7 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 // SetMarginMode 设置仓位模式
-func (t *FuturesTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+func (t *FuturesTrader) SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) error {
 	var marginType futures.MarginType
 	if isCrossMargin {
 		marginType = futures.MarginTypeCrossed
@@ -144,7 +166,7 @@ func (t *FuturesTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	err := t.client.NewChangeMarginTypeService().
 		Symbol(symbol).
 		MarginType(marginType).
-		Do(context.Background())
+		Do(ctx)
 	
 	marginModeStr := "全仓"
 	if !isCrossMargin {
@@ -154,42 +176,37 @@ func (t *FuturesTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	if err != nil {
 		// 如果错误信息包含"No need to change"，说明仓位模式已经是目标值
 		if contains(err.Error(), "No need to change margin type") {
-			log.Printf("  ✓ %s 仓位模式已是 %s", symbol, marginModeStr)
+			exchangeLog.Infof(ctx, "  ✓ %s 仓位模式已是 %s", symbol, marginModeStr)
 			return nil
 		}
-		// 如果有持仓，无法更改仓位模式，但不影响交易
+		// 有持仓时无法更改仓位模式，当前模式未确认，交由调用方决定是否继续
 		if contains(err.Error(), "Margin type cannot be changed if there exists position") {
-			log.Printf("  ⚠️ %s 有持仓，无法更改仓位模式，继续使用当前模式", symbol)
-			return nil
+			return fmt.Errorf("%s 有持仓，无法切换为%s模式: %w", symbol, marginModeStr, err)
 		}
-		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
-		// 不返回错误，让交易继续
-		return nil
+		return fmt.Errorf("设置仓位模式失败: %w", err)
 	}
 	
-	log.Printf("  ✓ %s 仓位模式已设置为 %s", symbol, marginModeStr)
+	exchangeLog.Infof(ctx, "  ✓ %s 仓位模式已设置为 %s", symbol, marginModeStr)
 	return nil
 }
 
 // SetLeverage 设置杠杆（智能判断+冷却期）
-func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
+func (t *FuturesTrader) SetLeverage(ctx context.Context, symbol string, leverage int) error {
 	// 先尝试获取当前杠杆（从持仓信息）
 	currentLeverage := 0
-	positions, err := t.GetPositions()
+	positions, err := t.GetPositions(ctx)
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == symbol {
-				if lev, ok := pos["leverage"].(float64); ok {
-					currentLeverage = int(lev)
-					break
-				}
+			if pos.Symbol == symbol {
+				currentLeverage = pos.Leverage
+				break
 			}
 		}
 	}
 
 	// 如果当前杠杆已经是目标杠杆，跳过
 	if currentLeverage == leverage && currentLeverage > 0 {
-		log.Printf("  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
+		exchangeLog.Infof(ctx, "  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
 		return nil
 	}
 
@@ -197,42 +214,42 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	_, err = t.client.NewChangeLeverageService().
 		Symbol(symbol).
 		Leverage(leverage).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		// 如果错误信息包含"No need to change"，说明杠杆已经是目标值
 		if contains(err.Error(), "No need to change") {
-			log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+			exchangeLog.Infof(ctx, "  ✓ %s 杠杆已是 %dx", symbol, leverage)
 			return nil
 		}
 		return fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
-	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	exchangeLog.Infof(ctx, "  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
 
 	// 切换杠杆后等待5秒（避免冷却期错误）
-	log.Printf("  ⏱ 等待5秒冷却期...")
+	exchangeLog.Infof(ctx, "  ⏱ 等待5秒冷却期...")
 	time.Sleep(5 * time.Second)
 
 	return nil
 }
 
 // OpenLong 开多仓
-func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
 	}
 
 	// 设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
 		return nil, err
 	}
 
 	// 注意：仓位模式应该由调用方（AutoTrader）在开仓前通过 SetMarginMode 设置
 
 	// 格式化数量到正确精度
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	quantityStr, err := t.formatQuantity(ctx, symbol, quantity)
 	if err != nil {
 		return nil, err
 	}
@@ -244,38 +261,34 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
 
-	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
-	log.Printf("  订单ID: %d", order.OrderID)
+	exchangeLog.Infof(ctx, "✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
+	exchangeLog.Infof(ctx, "  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return newBinanceOrderResult(order, quantityStr), nil
 }
 
 // OpenShort 开空仓
-func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenShort(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
 	}
 
 	// 设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
 		return nil, err
 	}
 
 	// 注意：仓位模式应该由调用方（AutoTrader）在开仓前通过 SetMarginMode 设置
 
 	// 格式化数量到正确精度
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	quantityStr, err := t.formatQuantity(ctx, symbol, quantity)
 	if err != nil {
 		return nil, err
 	}
@@ -287,36 +300,29 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
 
-	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
-	log.Printf("  订单ID: %d", order.OrderID)
+	exchangeLog.Infof(ctx, "✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
+	exchangeLog.Infof(ctx, "  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return newBinanceOrderResult(order, quantityStr), nil
 }
 
 // CloseLong 平多仓
-func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseLong(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
-		positions, err := t.GetPositions()
+		positions, err := t.GetPositions(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, SideLong); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -325,7 +331,7 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 	}
 
 	// 格式化数量
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	quantityStr, err := t.formatQuantity(ctx, symbol, quantity)
 	if err != nil {
 		return nil, err
 	}
@@ -337,40 +343,33 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
 
-	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
+	exchangeLog.Infof(ctx, "✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
 
 	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return newBinanceOrderResult(order, quantityStr), nil
 }
 
 // CloseShort 平空仓
-func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseShort(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
-		positions, err := t.GetPositions()
+		positions, err := t.GetPositions(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = -pos["positionAmt"].(float64) // 空仓数量是负的，取绝对值
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, SideShort); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -379,7 +378,7 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	}
 
 	// 格式化数量
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	quantityStr, err := t.formatQuantity(ctx, symbol, quantity)
 	if err != nil {
 		return nil, err
 	}
@@ -391,43 +390,50 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
 
-	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
+	exchangeLog.Infof(ctx, "✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
 
 	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return newBinanceOrderResult(order, quantityStr), nil
+}
+
+// newBinanceOrderResult 转换币安下单返回
+func newBinanceOrderResult(order *futures.CreateOrderResponse, quantityStr string) *OrderResult {
+	quantity, _ := strconv.ParseFloat(quantityStr, 64)
+	return &OrderResult{
+		OrderID:  strconv.FormatInt(order.OrderID, 10),
+		Symbol:   order.Symbol,
+		Status:   string(order.Status),
+		Quantity: quantity,
+	}
 }
 
 // CancelAllOrders 取消该币种的所有挂单
-func (t *FuturesTrader) CancelAllOrders(symbol string) error {
+func (t *FuturesTrader) CancelAllOrders(ctx context.Context, symbol string) error {
 	err := t.client.NewCancelAllOpenOrdersService().
 		Symbol(symbol).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		return fmt.Errorf("取消挂单失败: %w", err)
 	}
 
-	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	exchangeLog.Infof(ctx, "  ✓ 已取消 %s 的所有挂单", symbol)
 	return nil
 }
 
 // GetMarketPrice 获取市场价格
-func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
-	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+func (t *FuturesTrader) GetMarketPrice(ctx context.Context, symbol string) (float64, error) {
+	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("获取价格失败: %w", err)
 	}
@@ -453,11 +459,11 @@ func (t *FuturesTrader) CalculatePositionSize(balance, riskPercent, price float6
 }
 
 // SetStopLoss 设置止损单
-func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+func (t *FuturesTrader) SetStopLoss(ctx context.Context, symbol string, positionSide PositionSide, quantity, stopPrice float64) error {
 	var side futures.SideType
 	var posSide futures.PositionSideType
 
-	if positionSide == "LONG" {
+	if positionSide == SideLong {
 		side = futures.SideTypeSell
 		posSide = futures.PositionSideTypeLong
 	} else {
@@ -466,7 +472,7 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 	}
 
 	// 格式化数量
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	quantityStr, err := t.formatQuantity(ctx, symbol, quantity)
 	if err != nil {
 		return err
 	}
@@ -480,22 +486,22 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		return fmt.Errorf("设置止损失败: %w", err)
 	}
 
-	log.Printf("  止损价设置: %.4f", stopPrice)
+	exchangeLog.Infof(ctx, "  止损价设置: %.4f", stopPrice)
 	return nil
 }
 
 // SetTakeProfit 设置止盈单
-func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+func (t *FuturesTrader) SetTakeProfit(ctx context.Context, symbol string, positionSide PositionSide, quantity, takeProfitPrice float64) error {
 	var side futures.SideType
 	var posSide futures.PositionSideType
 
-	if positionSide == "LONG" {
+	if positionSide == SideLong {
 		side = futures.SideTypeSell
 		posSide = futures.PositionSideTypeLong
 	} else {
@@ -504,7 +510,7 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	}
 
 	// 格式化数量
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	quantityStr, err := t.formatQuantity(ctx, symbol, quantity)
 	if err != nil {
 		return err
 	}
@@ -518,19 +524,19 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
-		Do(context.Background())
+		Do(ctx)
 
 	if err != nil {
 		return fmt.Errorf("设置止盈失败: %w", err)
 	}
 
-	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	exchangeLog.Infof(ctx, "  止盈价设置: %.4f", takeProfitPrice)
 	return nil
 }
 
 // GetSymbolPrecision 获取交易对的数量精度
-func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
-	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+func (t *FuturesTrader) GetSymbolPrecision(ctx context.Context, symbol string) (int, error) {
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("获取交易规则失败: %w", err)
 	}
@@ -542,14 +548,14 @@ func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 				if filter["filterType"] == "LOT_SIZE" {
 					stepSize := filter["stepSize"].(string)
 					precision := calculatePrecision(stepSize)
-					log.Printf("  %s 数量精度: %d (stepSize: %s)", symbol, precision, stepSize)
+					exchangeLog.Debugf(ctx, "  %s 数量精度: %d (stepSize: %s)", symbol, precision, stepSize)
 					return precision, nil
 				}
 			}
 		}
 	}
 
-	log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
+	exchangeLog.Warnf(ctx, "  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
 	return 3, nil // 默认精度为3
 }
 
@@ -598,7 +604,12 @@ func trimTrailingZeros(s string) string {
 
 // FormatQuantity 格式化数量到正确的精度
 func (t *FuturesTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	precision, err := t.GetSymbolPrecision(symbol)
+	return t.formatQuantity(context.Background(), symbol, quantity)
+}
+
+// formatQuantity 按交易对精度格式化数量（下单路径使用调用方的ctx）
+func (t *FuturesTrader) formatQuantity(ctx context.Context, symbol string, quantity float64) (string, error) {
+	precision, err := t.GetSymbolPrecision(ctx, symbol)
 	if err != nil {
 		// 如果获取失败，使用默认格式
 		return fmt.Sprintf("%.3f", quantity), nil
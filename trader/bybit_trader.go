@@ -0,0 +1,757 @@
+package trader
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"nofx/ratelimit"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bybit v5 持仓索引（双向持仓模式）
+const (
+	bybitPositionIdxLong  = 1
+	bybitPositionIdxShort = 2
+)
+
+// BybitTrader Bybit USDT永续合约交易器（v5 API，双向持仓模式）
+type BybitTrader struct {
+	apiKey     string
+	secretKey  string
+	baseURL    string
+	recvWindow string
+	client     *http.Client
+
+	// 缓存交易对精度信息
+	symbolPrecision map[string]SymbolPrecision
+	mu              sync.RWMutex
+}
+
+// bybitResponse Bybit v5 统一响应结构
+type bybitResponse struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+}
+
+func init() {
+	RegisterExchange(ExchangeAdapter{
+		ID:           "bybit",
+		DisplayName:  "Bybit",
+		Type:         "cex",
+		MarketSource: "bybit",
+		Fields: []CredentialField{
+			{Key: "api_key", Label: "API Key", Required: true, LegacyKey: "apiKey"},
+			{Key: "secret_key", Label: "Secret Key", Secret: true, Required: true, LegacyKey: "secretKey"},
+		},
+		Capabilities: ExchangeCapabilities{
+			HedgeMode:         true,
+			ConditionalOrders: true,
+			IsolatedMargin:    true,
+			MarketOrders:      true,
+			Testnet:           true,
+		},
+		Factory: func(creds map[string]string, testnet bool) (Trader, error) {
+			return NewBybitTrader(creds["api_key"], creds["secret_key"], testnet)
+		},
+	})
+}
+
+// NewBybitTrader 创建Bybit交易器
+func NewBybitTrader(apiKey, secretKey string, testnet bool) (*BybitTrader, error) {
+	baseURL := "https://api.bybit.com"
+	if testnet {
+		baseURL = "https://api-testnet.bybit.com"
+	}
+	return newBybitTrader(context.Background(), apiKey, secretKey, baseURL)
+}
+
+// newBybitTrader 创建指向 baseURL 的Bybit交易器（测试中指向本地模拟服务）
+func newBybitTrader(ctx context.Context, apiKey, secretKey, baseURL string) (*BybitTrader, error) {
+	if apiKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("Bybit API Key和Secret Key不能为空")
+	}
+
+	t := &BybitTrader{
+		apiKey:          apiKey,
+		secretKey:       secretKey,
+		baseURL:         baseURL,
+		recvWindow:      "5000",
+		symbolPrecision: make(map[string]SymbolPrecision),
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			// 按交易所主机共享限流额度
+			Transport: ratelimit.NewTransport(&http.Transport{
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 10 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+			}),
+		},
+	}
+
+	// 切换为双向持仓模式（与币安的 LONG/SHORT 持仓方向保持一致）
+	if err := t.setHedgeMode(ctx); err != nil {
+		return nil, fmt.Errorf("设置双向持仓模式失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ Bybit交易器初始化成功 (%s)", baseURL)
+	return t, nil
+}
+
+// sign 生成v5签名: HMAC_SHA256(timestamp + apiKey + recvWindow + payload)
+func (t *BybitTrader) sign(timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + t.apiKey + t.recvWindow + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// request 发送签名请求（GET参数放在querystring，POST参数放在JSON body）
+func (t *BybitTrader) request(ctx context.Context, method, endpoint string, params map[string]interface{}) (json.RawMessage, error) {
+	const maxRetries = 3
+	var lastErr error
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		result, err := t.doRequest(ctx, method, endpoint, params)
+		if err == nil {
+			return result, nil
+		}
+
+		lastErr = err
+
+		// 如果是网络超时或临时错误，重试
+		if strings.Contains(err.Error(), "timeout") ||
+			strings.Contains(err.Error(), "connection reset") ||
+			strings.Contains(err.Error(), "EOF") {
+			if attempt < maxRetries && ctx.Err() == nil {
+				time.Sleep(time.Duration(attempt) * time.Second)
+				continue
+			}
+		}
+
+		// 其他错误（如参数错误/鉴权失败）不重试
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("请求失败（已重试%d次）: %w", maxRetries, lastErr)
+}
+
+// doRequest 执行实际的HTTP请求
+func (t *BybitTrader) doRequest(ctx context.Context, method, endpoint string, params map[string]interface{}) (json.RawMessage, error) {
+	method = strings.ToUpper(method)
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	var req *http.Request
+	var err error
+
+	switch method {
+	case "GET":
+		query := encodeSortedQuery(params)
+		fullURL := t.baseURL + endpoint
+		if query != "" {
+			fullURL += "?" + query
+		}
+		req, err = http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-BAPI-SIGN", t.sign(timestamp, query))
+
+	case "POST":
+		body, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("序列化请求失败: %w", err)
+		}
+		req, err = http.NewRequestWithContext(ctx, "POST", t.baseURL+endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-BAPI-SIGN", t.sign(timestamp, string(body)))
+
+	default:
+		return nil, fmt.Errorf("不支持的HTTP方法: %s", method)
+	}
+
+	req.Header.Set("X-BAPI-API-KEY", t.apiKey)
+	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+	req.Header.Set("X-BAPI-RECV-WINDOW", t.recvWindow)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return parseBybitResponse(respBody)
+}
+
+// parseBybitResponse 解析v5统一响应，retCode非0时返回错误
+func parseBybitResponse(body []byte) (json.RawMessage, error) {
+	var resp bybitResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析Bybit响应失败: %w", err)
+	}
+	if resp.RetCode != 0 {
+		return nil, fmt.Errorf("Bybit API错误 (retCode %d): %s", resp.RetCode, resp.RetMsg)
+	}
+	return resp.Result, nil
+}
+
+// encodeSortedQuery 按key排序编码querystring（签名与实际请求必须一致）
+func encodeSortedQuery(params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(fmt.Sprintf("%v", params[k])))
+	}
+	return strings.Join(parts, "&")
+}
+
+// floorToStepSize 将数量向下取整到step size的整数倍（避免超出可用保证金）
+func floorToStepSize(value float64, stepSize float64) float64 {
+	if stepSize <= 0 {
+		return value
+	}
+	// 加一个极小值，避免浮点误差导致少一个step
+	steps := math.Floor(value/stepSize + 1e-9)
+	return steps * stepSize
+}
+
+// setHedgeMode 切换USDT永续为双向持仓模式
+func (t *BybitTrader) setHedgeMode(ctx context.Context) error {
+	params := map[string]interface{}{
+		"category": "linear",
+		"coin":     "USDT",
+		"mode":     3, // 3=双向持仓
+	}
+
+	_, err := t.request(ctx, "POST", "/v5/position/switch-mode", params)
+	if err != nil {
+		// 110025: Position mode is not modified（已是双向持仓）
+		if strings.Contains(err.Error(), "110025") {
+			return nil
+		}
+		return err
+	}
+
+	exchangeLog.Infof(ctx, "  ✓ Bybit已切换为双向持仓模式")
+	return nil
+}
+
+// getPrecision 获取交易对精度信息
+func (t *BybitTrader) getPrecision(ctx context.Context, symbol string) (SymbolPrecision, error) {
+	t.mu.RLock()
+	if prec, ok := t.symbolPrecision[symbol]; ok {
+		t.mu.RUnlock()
+		return prec, nil
+	}
+	t.mu.RUnlock()
+
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	}
+	result, err := t.request(ctx, "GET", "/v5/market/instruments-info", params)
+	if err != nil {
+		return SymbolPrecision{}, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	var info struct {
+		List []struct {
+			Symbol      string `json:"symbol"`
+			PriceFilter struct {
+				TickSize string `json:"tickSize"`
+			} `json:"priceFilter"`
+			LotSizeFilter struct {
+				QtyStep     string `json:"qtyStep"`
+				MinOrderQty string `json:"minOrderQty"`
+			} `json:"lotSizeFilter"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return SymbolPrecision{}, fmt.Errorf("解析交易规则失败: %w", err)
+	}
+
+	for _, s := range info.List {
+		if s.Symbol != symbol {
+			continue
+		}
+
+		prec := SymbolPrecision{
+			PricePrecision:    calculatePrecision(s.PriceFilter.TickSize),
+			QuantityPrecision: calculatePrecision(s.LotSizeFilter.QtyStep),
+		}
+		prec.TickSize, _ = strconv.ParseFloat(s.PriceFilter.TickSize, 64)
+		prec.StepSize, _ = strconv.ParseFloat(s.LotSizeFilter.QtyStep, 64)
+
+		t.mu.Lock()
+		t.symbolPrecision[symbol] = prec
+		t.mu.Unlock()
+		return prec, nil
+	}
+
+	return SymbolPrecision{}, fmt.Errorf("未找到交易对 %s 的精度信息", symbol)
+}
+
+// formatPrice 格式化价格到tick size
+func (t *BybitTrader) formatPrice(ctx context.Context, symbol string, price float64) (string, error) {
+	prec, err := t.getPrecision(ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+	price = roundToTickSize(price, prec.TickSize)
+	return strconv.FormatFloat(price, 'f', prec.PricePrecision, 64), nil
+}
+
+// FormatQuantity 格式化数量到正确的精度（向下取整到qtyStep）
+func (t *BybitTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return t.formatQuantity(context.Background(), symbol, quantity)
+}
+
+// formatQuantity 按调用方的 ctx 获取精度并格式化数量
+func (t *BybitTrader) formatQuantity(ctx context.Context, symbol string, quantity float64) (string, error) {
+	prec, err := t.getPrecision(ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+	quantity = floorToStepSize(quantity, prec.StepSize)
+	if quantity <= 0 {
+		return "", fmt.Errorf("%s 数量过小，低于最小下单步进 %v", symbol, prec.StepSize)
+	}
+	return strconv.FormatFloat(quantity, 'f', prec.QuantityPrecision, 64), nil
+}
+
+// GetBalance 获取账户余额（统一账户）
+func (t *BybitTrader) GetBalance(ctx context.Context) (*Balance, error) {
+	params := map[string]interface{}{
+		"accountType": "UNIFIED",
+	}
+	result, err := t.request(ctx, "GET", "/v5/account/wallet-balance", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	var wallet struct {
+		List []struct {
+			TotalWalletBalance    string `json:"totalWalletBalance"`
+			TotalAvailableBalance string `json:"totalAvailableBalance"`
+			TotalPerpUPL          string `json:"totalPerpUPL"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(result, &wallet); err != nil {
+		return nil, fmt.Errorf("解析账户信息失败: %w", err)
+	}
+	if len(wallet.List) == 0 {
+		return nil, fmt.Errorf("未找到统一账户信息")
+	}
+
+	acc := wallet.List[0]
+	totalWalletBalance, _ := strconv.ParseFloat(acc.TotalWalletBalance, 64)
+	availableBalance, _ := strconv.ParseFloat(acc.TotalAvailableBalance, 64)
+	totalUnrealizedProfit, _ := strconv.ParseFloat(acc.TotalPerpUPL, 64)
+
+	exchangeLog.Infof(ctx, "✓ Bybit API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
+		acc.TotalWalletBalance, acc.TotalAvailableBalance, acc.TotalPerpUPL)
+
+	return &Balance{
+		TotalEquity:      totalWalletBalance + totalUnrealizedProfit,
+		WalletBalance:    totalWalletBalance,
+		UnrealizedPnL:    totalUnrealizedProfit,
+		AvailableBalance: availableBalance,
+	}, nil
+}
+
+// GetPositions 获取所有持仓
+func (t *BybitTrader) GetPositions(ctx context.Context) ([]Position, error) {
+	params := map[string]interface{}{
+		"category":   "linear",
+		"settleCoin": "USDT",
+	}
+	result, err := t.request(ctx, "GET", "/v5/position/list", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var positions struct {
+		List []struct {
+			Symbol        string `json:"symbol"`
+			Side          string `json:"side"`
+			Size          string `json:"size"`
+			AvgPrice      string `json:"avgPrice"`
+			MarkPrice     string `json:"markPrice"`
+			UnrealisedPnl string `json:"unrealisedPnl"`
+			Leverage      string `json:"leverage"`
+			LiqPrice      string `json:"liqPrice"`
+			PositionIdx   int    `json:"positionIdx"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(result, &positions); err != nil {
+		return nil, fmt.Errorf("解析持仓失败: %w", err)
+	}
+
+	var out []Position
+	for _, pos := range positions.List {
+		size, _ := strconv.ParseFloat(pos.Size, 64)
+		if size == 0 {
+			continue // 跳过空仓位
+		}
+
+		entryPrice, _ := strconv.ParseFloat(pos.AvgPrice, 64)
+		markPrice, _ := strconv.ParseFloat(pos.MarkPrice, 64)
+		unRealizedProfit, _ := strconv.ParseFloat(pos.UnrealisedPnl, 64)
+		leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+		liquidationPrice, _ := strconv.ParseFloat(pos.LiqPrice, 64)
+
+		// 双向持仓下 positionIdx 决定方向，单向持仓下按 side 判断
+		side := SideLong
+		if pos.PositionIdx == bybitPositionIdxShort || (pos.PositionIdx == 0 && pos.Side == "Sell") {
+			side = SideShort
+		}
+
+		out = append(out, Position{
+			Symbol:           pos.Symbol,
+			Side:             side,
+			Quantity:         size,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedPnL:    unRealizedProfit,
+			Leverage:         int(leverage),
+			LiquidationPrice: liquidationPrice,
+		})
+	}
+
+	return out, nil
+}
+
+// SetMarginMode 确认仓位模式
+// 统一账户的仓位模式是账户级别设置，不能按币种切换；这里只读取账户当前模式，
+// 与请求的模式不一致时返回错误，避免为单个币种改动整个账户的保证金模式
+func (t *BybitTrader) SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) error {
+	result, err := t.request(ctx, "GET", "/v5/account/info", nil)
+	if err != nil {
+		return fmt.Errorf("查询账户仓位模式失败: %w", err)
+	}
+
+	var info struct {
+		MarginMode string `json:"marginMode"`
+	}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return fmt.Errorf("解析账户信息失败: %w", err)
+	}
+
+	// REGULAR_MARGIN / PORTFOLIO_MARGIN 均为全仓
+	isIsolated := info.MarginMode == "ISOLATED_MARGIN"
+	if isIsolated == isCrossMargin {
+		wanted := "全仓"
+		if !isCrossMargin {
+			wanted = "逐仓"
+		}
+		return fmt.Errorf("Bybit账户仓位模式为 %s，与请求的%s模式不一致（需在Bybit账户设置中切换）", info.MarginMode, wanted)
+	}
+
+	exchangeLog.Infof(ctx, "  ✓ %s 仓位模式已确认为 %s", symbol, info.MarginMode)
+	return nil
+}
+
+// SetLeverage 设置杠杆（多空两个方向同时设置）
+func (t *BybitTrader) SetLeverage(ctx context.Context, symbol string, leverage int) error {
+	params := map[string]interface{}{
+		"category":     "linear",
+		"symbol":       symbol,
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	}
+
+	_, err := t.request(ctx, "POST", "/v5/position/set-leverage", params)
+	if err != nil {
+		// 110043: leverage not modified（杠杆已是目标值）
+		if strings.Contains(err.Error(), "110043") {
+			exchangeLog.Infof(ctx, "  ✓ %s 杠杆已是 %dx", symbol, leverage)
+			return nil
+		}
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// placeOrder 下市价单
+func (t *BybitTrader) placeOrder(ctx context.Context, symbol, side string, positionIdx int, quantity float64, reduceOnly bool) (*OrderResult, error) {
+	qtyStr, err := t.formatQuantity(ctx, symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"category":    "linear",
+		"symbol":      symbol,
+		"side":        side,
+		"orderType":   "Market",
+		"qty":         qtyStr,
+		"positionIdx": positionIdx,
+	}
+	if reduceOnly {
+		params["reduceOnly"] = true
+	}
+
+	result, err := t.request(ctx, "POST", "/v5/order/create", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var order struct {
+		OrderID     string `json:"orderId"`
+		OrderLinkID string `json:"orderLinkId"`
+	}
+	if err := json.Unmarshal(result, &order); err != nil {
+		return nil, fmt.Errorf("解析下单结果失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "  订单ID: %s", order.OrderID)
+
+	filledQty, _ := strconv.ParseFloat(qtyStr, 64)
+	return &OrderResult{
+		OrderID:  order.OrderID,
+		Symbol:   symbol,
+		Quantity: filledQty,
+	}, nil
+}
+
+// OpenLong 开多仓
+func (t *BybitTrader) OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "Buy", bybitPositionIdxLong, quantity, false)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 开多仓成功: %s 数量: %v", symbol, result.Quantity)
+	return result, nil
+}
+
+// OpenShort 开空仓
+func (t *BybitTrader) OpenShort(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "Sell", bybitPositionIdxShort, quantity, false)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 开空仓成功: %s 数量: %v", symbol, result.Quantity)
+	return result, nil
+}
+
+// CloseLong 平多仓
+func (t *BybitTrader) CloseLong(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
+	// 如果数量为0，获取当前持仓数量
+	if quantity == 0 {
+		positions, err := t.GetPositions(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if pos, ok := FindPosition(positions, symbol, SideLong); ok {
+			quantity = pos.Quantity
+		}
+
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
+		}
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "Sell", bybitPositionIdxLong, quantity, true)
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 平多仓成功: %s 数量: %v", symbol, result.Quantity)
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
+	}
+
+	return result, nil
+}
+
+// CloseShort 平空仓
+func (t *BybitTrader) CloseShort(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
+	// 如果数量为0，获取当前持仓数量
+	if quantity == 0 {
+		positions, err := t.GetPositions(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if pos, ok := FindPosition(positions, symbol, SideShort); ok {
+			quantity = pos.Quantity
+		}
+
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+		}
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "Buy", bybitPositionIdxShort, quantity, true)
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 平空仓成功: %s 数量: %v", symbol, result.Quantity)
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
+	}
+
+	return result, nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单（包括条件单）
+func (t *BybitTrader) CancelAllOrders(ctx context.Context, symbol string) error {
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	}
+
+	if _, err := t.request(ctx, "POST", "/v5/order/cancel-all", params); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// GetMarketPrice 获取市场价格
+func (t *BybitTrader) GetMarketPrice(ctx context.Context, symbol string) (float64, error) {
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	}
+	result, err := t.request(ctx, "GET", "/v5/market/tickers", params)
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+
+	var tickers struct {
+		List []struct {
+			LastPrice string `json:"lastPrice"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(result, &tickers); err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	if len(tickers.List) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+
+	return strconv.ParseFloat(tickers.List[0].LastPrice, 64)
+}
+
+// placeConditionalOrder 下条件市价单（触发后只减仓）
+// triggerDirection: 1=价格上涨到触发价时触发, 2=价格下跌到触发价时触发
+func (t *BybitTrader) placeConditionalOrder(ctx context.Context, symbol string, positionSide PositionSide, quantity, triggerPrice float64, triggerDirection int) error {
+	side := "Sell"
+	positionIdx := bybitPositionIdxLong
+	if positionSide == SideShort {
+		side = "Buy"
+		positionIdx = bybitPositionIdxShort
+	}
+
+	qtyStr, err := t.formatQuantity(ctx, symbol, quantity)
+	if err != nil {
+		return err
+	}
+	priceStr, err := t.formatPrice(ctx, symbol, triggerPrice)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]interface{}{
+		"category":         "linear",
+		"symbol":           symbol,
+		"side":             side,
+		"orderType":        "Market",
+		"qty":              qtyStr,
+		"positionIdx":      positionIdx,
+		"triggerPrice":     priceStr,
+		"triggerDirection": triggerDirection,
+		"triggerBy":        "LastPrice",
+		"reduceOnly":       true,
+		"closeOnTrigger":   true,
+	}
+
+	_, err = t.request(ctx, "POST", "/v5/order/create", params)
+	return err
+}
+
+// SetStopLoss 设置止损单
+func (t *BybitTrader) SetStopLoss(ctx context.Context, symbol string, positionSide PositionSide, quantity, stopPrice float64) error {
+	// 多仓止损：价格下跌触发；空仓止损：价格上涨触发
+	triggerDirection := 2
+	if positionSide == SideShort {
+		triggerDirection = 1
+	}
+
+	if err := t.placeConditionalOrder(ctx, symbol, positionSide, quantity, stopPrice, triggerDirection); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单
+func (t *BybitTrader) SetTakeProfit(ctx context.Context, symbol string, positionSide PositionSide, quantity, takeProfitPrice float64) error {
+	// 多仓止盈：价格上涨触发；空仓止盈：价格下跌触发
+	triggerDirection := 1
+	if positionSide == SideShort {
+		triggerDirection = 2
+	}
+
+	if err := t.placeConditionalOrder(ctx, symbol, positionSide, quantity, takeProfitPrice, triggerDirection); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
@@ -0,0 +1,219 @@
+package trader
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+// bybitRoutes 统一账户的接口响应
+func bybitRoutes() map[string]string {
+	return map[string]string{
+		"POST /v5/position/switch-mode":   "switch_mode.json",
+		"GET /v5/account/wallet-balance":  "wallet_balance.json",
+		"GET /v5/account/info":            "account_info.json",
+		"GET /v5/position/list":           "position_list.json",
+		"GET /v5/market/instruments-info": "instruments_info.json",
+		"POST /v5/order/create":           "order_create.json",
+	}
+}
+
+func newTestBybitTrader(t *testing.T, routes map[string]string) (*BybitTrader, *fixtureServer) {
+	t.Helper()
+	srv := newFixtureServer(t, "bybit", routes)
+	trader, err := newBybitTrader(context.Background(), "bybit-key", "bybit-secret", srv.URL)
+	if err != nil {
+		t.Fatalf("创建Bybit交易器失败: %v", err)
+	}
+	return trader, srv
+}
+
+func TestBybitSign(t *testing.T) {
+	trader := &BybitTrader{apiKey: "bybit-key", secretKey: "bybit-secret", recvWindow: "5000"}
+
+	cases := []struct {
+		payload, want string
+	}{
+		{"accountType=UNIFIED", "5773c60bf9c398171a57ab777cc02e8d98942467f3ca7a294e254b76aa6b8054"},
+		{`{"category":"linear"}`, "21145a41563b296e4babfafeaac2778d43a362db333a129c1719c395cc9c5b01"},
+	}
+	for _, c := range cases {
+		if got := trader.sign("1704164645678", c.payload); got != c.want {
+			t.Errorf("sign(%s) = %s, want %s", c.payload, got, c.want)
+		}
+	}
+}
+
+func TestBybitRequestHeaders(t *testing.T) {
+	trader, srv := newTestBybitTrader(t, bybitRoutes())
+	if _, err := trader.GetPositions(context.Background()); err != nil {
+		t.Fatalf("GetPositions失败: %v", err)
+	}
+
+	reqs := srv.requestsTo("GET", "/v5/position/list")
+	if len(reqs) != 1 {
+		t.Fatalf("持仓请求次数 = %d, want 1", len(reqs))
+	}
+	// GET 参数按key排序，签名覆盖实际发送的 querystring
+	if reqs[0].Query != "category=linear&settleCoin=USDT" {
+		t.Errorf("querystring = %s", reqs[0].Query)
+	}
+	h := reqs[0].Header
+	if want := trader.sign(h.Get("X-BAPI-TIMESTAMP"), reqs[0].Query); h.Get("X-BAPI-SIGN") != want {
+		t.Errorf("X-BAPI-SIGN = %s, want %s", h.Get("X-BAPI-SIGN"), want)
+	}
+	if h.Get("X-BAPI-API-KEY") != "bybit-key" || h.Get("X-BAPI-RECV-WINDOW") != "5000" {
+		t.Errorf("鉴权请求头错误: %v", h)
+	}
+
+	// POST 签名覆盖 JSON body
+	switches := srv.requestsTo("POST", "/v5/position/switch-mode")
+	if len(switches) != 1 {
+		t.Fatalf("切换持仓模式请求次数 = %d, want 1", len(switches))
+	}
+	sh := switches[0].Header
+	if want := trader.sign(sh.Get("X-BAPI-TIMESTAMP"), switches[0].Body); sh.Get("X-BAPI-SIGN") != want {
+		t.Errorf("POST X-BAPI-SIGN = %s, want %s", sh.Get("X-BAPI-SIGN"), want)
+	}
+}
+
+func TestBybitGetBalance(t *testing.T) {
+	trader, _ := newTestBybitTrader(t, bybitRoutes())
+
+	balance, err := trader.GetBalance(context.Background())
+	if err != nil {
+		t.Fatalf("GetBalance失败: %v", err)
+	}
+	if balance.WalletBalance != 1000.25 || balance.AvailableBalance != 812.5 || balance.UnrealizedPnL != 12.05 {
+		t.Errorf("余额解析错误: %+v", balance)
+	}
+	if math.Abs(balance.TotalEquity-1012.3) > 1e-9 {
+		t.Errorf("TotalEquity = %v, want 1012.3", balance.TotalEquity)
+	}
+}
+
+func TestBybitGetPositions(t *testing.T) {
+	trader, _ := newTestBybitTrader(t, bybitRoutes())
+
+	positions, err := trader.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions失败: %v", err)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("持仓数量 = %d, want 2: %+v", len(positions), positions)
+	}
+
+	btc, ok := FindPosition(positions, "BTCUSDT", SideLong)
+	if !ok {
+		t.Fatalf("未找到 BTCUSDT 多仓: %+v", positions)
+	}
+	if btc.Quantity != 0.05 || btc.EntryPrice != 42150.5 || btc.MarkPrice != 42300.1 || btc.Leverage != 5 || btc.LiquidationPrice != 34012.3 {
+		t.Errorf("BTC持仓解析错误: %+v", btc)
+	}
+
+	// positionIdx=2 为空仓
+	eth, ok := FindPosition(positions, "ETHUSDT", SideShort)
+	if !ok {
+		t.Fatalf("未找到 ETHUSDT 空仓: %+v", positions)
+	}
+	if eth.Quantity != 0.3 || eth.UnrealizedPnL != 3.09 || eth.Leverage != 3 {
+		t.Errorf("ETH持仓解析错误: %+v", eth)
+	}
+}
+
+func TestBybitGetPrecision(t *testing.T) {
+	trader, srv := newTestBybitTrader(t, bybitRoutes())
+	ctx := context.Background()
+
+	prec, err := trader.getPrecision(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("getPrecision失败: %v", err)
+	}
+	if prec.PricePrecision != 1 || prec.QuantityPrecision != 3 || prec.TickSize != 0.1 || prec.StepSize != 0.001 {
+		t.Errorf("精度解析错误: %+v", prec)
+	}
+
+	// 精度缓存后不再请求
+	if _, err := trader.getPrecision(ctx, "BTCUSDT"); err != nil {
+		t.Fatalf("getPrecision失败: %v", err)
+	}
+	if n := len(srv.requestsTo("GET", "/v5/market/instruments-info")); n != 1 {
+		t.Errorf("交易规则请求次数 = %d, want 1", n)
+	}
+
+	if _, err := trader.getPrecision(ctx, "ETHUSDT"); err == nil {
+		t.Error("未返回的交易对应返回错误")
+	}
+}
+
+func TestBybitPlaceOrder(t *testing.T) {
+	trader, srv := newTestBybitTrader(t, bybitRoutes())
+
+	result, err := trader.placeOrder(context.Background(), "BTCUSDT", "Sell", bybitPositionIdxLong, 0.05678, true)
+	if err != nil {
+		t.Fatalf("placeOrder失败: %v", err)
+	}
+	if result.OrderID != "1321003749386327552" {
+		t.Errorf("OrderID = %s", result.OrderID)
+	}
+	// 返回向下取整到 qtyStep 后的数量
+	if result.Quantity != 0.056 {
+		t.Errorf("Quantity = %v, want 0.056", result.Quantity)
+	}
+
+	reqs := srv.requestsTo("POST", "/v5/order/create")
+	if len(reqs) != 1 {
+		t.Fatalf("下单请求次数 = %d, want 1", len(reqs))
+	}
+	body := decodeBody(t, reqs[0])
+	if body["qty"] != "0.056" || body["side"] != "Sell" || body["positionIdx"] != float64(bybitPositionIdxLong) ||
+		body["reduceOnly"] != true || body["orderType"] != "Market" || body["category"] != "linear" {
+		t.Errorf("下单参数错误: %v", body)
+	}
+}
+
+func TestBybitConditionalOrder(t *testing.T) {
+	trader, srv := newTestBybitTrader(t, bybitRoutes())
+
+	if err := trader.SetStopLoss(context.Background(), "BTCUSDT", SideShort, 0.05, 45678.123); err != nil {
+		t.Fatalf("SetStopLoss失败: %v", err)
+	}
+
+	reqs := srv.requestsTo("POST", "/v5/order/create")
+	if len(reqs) != 1 {
+		t.Fatalf("下单请求次数 = %d, want 1", len(reqs))
+	}
+	body := decodeBody(t, reqs[0])
+	// 空仓止损：价格上涨触发，买入平仓
+	if body["side"] != "Buy" || body["positionIdx"] != float64(bybitPositionIdxShort) ||
+		body["triggerPrice"] != "45678.1" || body["triggerDirection"] != float64(1) || body["reduceOnly"] != true {
+		t.Errorf("条件单参数错误: %v", body)
+	}
+}
+
+func TestBybitOrderRejected(t *testing.T) {
+	trader, srv := newTestBybitTrader(t, bybitRoutes())
+	srv.setRoute("POST /v5/order/create", "order_create_rejected.json")
+
+	_, err := trader.placeOrder(context.Background(), "BTCUSDT", "Buy", bybitPositionIdxLong, 0.05, false)
+	if err == nil || !strings.Contains(err.Error(), "110007") {
+		t.Errorf("应返回 retCode 110007 错误, got %v", err)
+	}
+}
+
+func TestBybitSetMarginModeReadsAccountMode(t *testing.T) {
+	trader, srv := newTestBybitTrader(t, bybitRoutes())
+
+	// 账户为全仓模式：全仓请求通过，逐仓请求返回错误
+	if err := trader.SetMarginMode(context.Background(), "BTCUSDT", true); err != nil {
+		t.Errorf("全仓账户确认全仓模式失败: %v", err)
+	}
+	if err := trader.SetMarginMode(context.Background(), "BTCUSDT", false); err == nil {
+		t.Error("全仓账户请求逐仓模式时应返回错误")
+	}
+	// 只读取账户模式，不修改账户级别设置
+	if n := len(srv.requestsTo("POST", "/v5/account/set-margin-mode")); n != 0 {
+		t.Errorf("不应修改账户仓位模式, 请求次数 = %d", n)
+	}
+}
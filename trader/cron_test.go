@@ -0,0 +1,164 @@
+package trader
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // 测试环境可能没有系统时区数据
+)
+
+func mustParseCron(t *testing.T, expr string) *cronExpr {
+	t.Helper()
+	c, err := parseCron(expr)
+	if err != nil {
+		t.Fatalf("parseCron(%q)失败: %v", expr, err)
+	}
+	return c
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("加载时区 %s 失败: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseCronField(t *testing.T) {
+	bitsOf := func(values ...int) uint64 {
+		var bits uint64
+		for _, v := range values {
+			bits |= 1 << uint(v)
+		}
+		return bits
+	}
+	cases := []struct {
+		field    string
+		min, max int
+		want     uint64
+	}{
+		{"*", 0, 7, bitsOf(0, 1, 2, 3, 4, 5, 6, 7)},
+		{"5", 0, 59, bitsOf(5)},
+		{"1,15,30", 0, 59, bitsOf(1, 15, 30)},
+		{"1-5", 0, 7, bitsOf(1, 2, 3, 4, 5)},
+		{"*/15", 0, 59, bitsOf(0, 15, 30, 45)},
+		{"0-30/10", 0, 59, bitsOf(0, 10, 20, 30)},
+		{"10/20", 0, 59, bitsOf(10, 30, 50)}, // 起始值 + 步长一直到最大值
+		{"1-3,20-22/2", 1, 31, bitsOf(1, 2, 3, 20, 22)},
+	}
+	for _, c := range cases {
+		got, err := parseCronField(c.field, c.min, c.max)
+		if err != nil {
+			t.Errorf("parseCronField(%q)失败: %v", c.field, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseCronField(%q) = %b, want %b", c.field, got, c.want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",        // 段数不足
+		"* * * * * *",    // 段数过多
+		"60 * * * *",     // 分钟超出范围
+		"* 24 * * *",     // 小时超出范围
+		"* * 0 * *",      // 日从1开始
+		"* * * 13 *",     // 月超出范围
+		"* * * * 8",      // 周超出范围
+		"5-1 * * * *",    // 范围颠倒
+		"*/0 * * * *",    // 步长为0
+		"*/x * * * *",    // 步长非数字
+		"a * * * *",      // 取值非数字
+		"1-b * * * *",    // 范围非数字
+		"1,,2 * * * *",   // 空列表项
+		"* * * * mon",    // 不支持星期名称
+		"-1 * * * *",     // 负数
+		"0 0 1-32/2 * *", // 范围越界
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) 应返回错误", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2025-01-01 是周三
+	base := time.Date(2025, 1, 1, 10, 7, 30, 0, time.UTC)
+	cases := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"每分钟", "* * * * *", base, time.Date(2025, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"步长", "*/15 * * * *", base, time.Date(2025, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"整点不含当前时刻", "0 * * * *", time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"小时列表", "30 9,21 * * *", base, time.Date(2025, 1, 1, 21, 30, 0, 0, time.UTC)},
+		{"跨天", "0 8 * * *", base, time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{"工作日范围", "0 9 * * 1-5", time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC), time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)},
+		{"周日为7", "0 0 * * 7", base, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"跨月", "0 0 1 * *", base, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"跨年", "0 0 1 1 *", base, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"闰日", "0 0 29 2 *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"无匹配日期", "0 0 31 2 *", base, time.Time{}},
+		// 日和周都不为 * 时按“或”匹配：每月15日或每个周五
+		{"日或周-先到周五", "0 0 15 * 5", base, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"日或周-先到15日", "0 0 15 * 5", time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+		// 只有一个字段受限时按该字段匹配
+		{"只限制日", "0 0 15 * *", base, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"只限制周", "0 0 * * 5", time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := mustParseCron(t, c.expr).Next(c.after); !got.Equal(c.want) {
+				t.Errorf("Next(%v) = %v, want %v", c.after, got, c.want)
+			}
+		})
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+
+	// 2025-03-09 02:00 EST 拨快到 03:00 EDT：02:30 当天不存在，顺延到次日
+	spring := mustParseCron(t, "30 2 * * *")
+	got := spring.Next(time.Date(2025, 3, 9, 0, 0, 0, 0, ny))
+	if want := time.Date(2025, 3, 10, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("夏令时开始: Next = %v, want %v", got, want)
+	}
+	// 跳过的小时之后的时刻照常执行
+	hourly := mustParseCron(t, "0 * * * *")
+	got = hourly.Next(time.Date(2025, 3, 9, 1, 30, 0, 0, ny))
+	if want := time.Date(2025, 3, 9, 3, 0, 0, 0, ny); !got.Equal(want) || got.Sub(time.Date(2025, 3, 9, 1, 30, 0, 0, ny)) != 30*time.Minute {
+		t.Errorf("夏令时开始: 整点 Next = %v, want %v", got, want)
+	}
+
+	// 2025-11-02 02:00 EDT 拨回到 01:00 EST：01:30 出现两次，只执行第一次
+	fall := mustParseCron(t, "30 1 * * *")
+	first := fall.Next(time.Date(2025, 11, 2, 0, 0, 0, 0, ny))
+	if _, offset := first.Zone(); first.Hour() != 1 || first.Minute() != 30 || offset != -4*3600 {
+		t.Errorf("夏令时结束: 第一次执行 = %v, want 01:30 EDT", first)
+	}
+	if got := fall.Next(first); !got.Equal(time.Date(2025, 11, 3, 1, 30, 0, 0, ny)) {
+		t.Errorf("夏令时结束: 重复的 01:30 不应再次执行, Next = %v", got)
+	}
+
+	// 智利夏令时在午夜开始（2024-09-08 00:00 不存在），跳到次日仍能找到匹配
+	santiago := mustLoadLocation(t, "America/Santiago")
+	sunday := mustParseCron(t, "0 12 * * 0")
+	if got := sunday.Next(time.Date(2024, 9, 7, 13, 0, 0, 0, santiago)); !got.Equal(time.Date(2024, 9, 8, 12, 0, 0, 0, santiago)) {
+		t.Errorf("午夜夏令时: Next = %v", got)
+	}
+
+	// 按配置时区计算：纽约时间 09:30 对应 UTC 14:30（冬令时）/ 13:30（夏令时）
+	open := mustParseCron(t, "30 9 * * 1-5")
+	if got := open.Next(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC).In(ny)); !got.Equal(time.Date(2025, 1, 6, 14, 30, 0, 0, time.UTC)) {
+		t.Errorf("冬令时 Next = %v", got.UTC())
+	}
+	if got := open.Next(time.Date(2025, 7, 7, 0, 0, 0, 0, time.UTC).In(ny)); !got.Equal(time.Date(2025, 7, 7, 13, 30, 0, 0, time.UTC)) {
+		t.Errorf("夏令时 Next = %v", got.UTC())
+	}
+}
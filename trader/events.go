@@ -0,0 +1,163 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"nofx/decision"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// stopLossLevel 本交易员开仓时设置的止损价（用于事件触发）
+type stopLossLevel struct {
+	Symbol string
+	Side   PositionSide
+	Price  float64
+}
+
+// trackStopLoss 记录持仓的止损价
+func (at *AutoTrader) trackStopLoss(symbol string, side PositionSide, price float64) {
+	if price <= 0 {
+		return
+	}
+	at.eventMu.Lock()
+	defer at.eventMu.Unlock()
+	at.stopLosses[Position{Symbol: symbol, Side: side}.Key()] = stopLossLevel{Symbol: symbol, Side: side, Price: price}
+}
+
+// untrackStopLoss 平仓后移除止损价
+func (at *AutoTrader) untrackStopLoss(symbol string, side PositionSide) {
+	at.eventMu.Lock()
+	defer at.eventMu.Unlock()
+	delete(at.stopLosses, Position{Symbol: symbol, Side: side}.Key())
+}
+
+// pruneStopLosses 移除已不在持仓中的止损价（如已被交易所止损/止盈平仓）
+func (at *AutoTrader) pruneStopLosses(positions []decision.PositionInfo) {
+	open := make(map[string]bool, len(positions))
+	for _, pos := range positions {
+		open[Position{Symbol: pos.Symbol, Side: PositionSide(pos.Side)}.Key()] = true
+	}
+	at.eventMu.Lock()
+	defer at.eventMu.Unlock()
+	for key := range at.stopLosses {
+		if !open[key] {
+			delete(at.stopLosses, key)
+		}
+	}
+}
+
+// watchEvents 按事件触发配置轮询价格，满足条件时向 events 发送触发原因（直到 ctx 取消）
+// panic 时通过 errc 通知主循环退出
+func (at *AutoTrader) watchEvents(ctx context.Context, events chan<- string, errc chan<- error) {
+	defer func() {
+		if r := recover(); r != nil {
+			at.log.Errorf(ctx, "❌ 事件监控panic: %v\n%s", r, debug.Stack())
+			errc <- fmt.Errorf("事件监控panic: %v", r)
+		}
+	}()
+	refPrices := make(map[string]float64) // 上一周期之后的基准价格
+	var refSince time.Time                // 基准价格对应的周期开始时间
+
+	for {
+		schedule := at.GetSchedule()
+		poll := defaultEventPollSeconds * time.Second
+		if schedule.Events != nil {
+			poll = schedule.Events.pollInterval()
+		}
+		timer := time.NewTimer(poll)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if schedule.Events == nil {
+			continue
+		}
+
+		// 新周期开始后重新记录基准价格
+		lastCycle, _ := at.cycleTimes()
+		if lastCycle.After(refSince) {
+			refPrices = make(map[string]float64)
+			refSince = lastCycle
+		}
+		if time.Since(lastCycle) < schedule.Events.cooldown() {
+			continue
+		}
+
+		if reason := at.checkEvents(ctx, schedule.Events, refPrices); reason != "" {
+			select {
+			case events <- reason:
+			default: // 已有待处理的触发
+			}
+		}
+	}
+}
+
+// checkEvents 检查一次事件条件，返回触发原因（未触发返回空字符串）
+func (at *AutoTrader) checkEvents(ctx context.Context, cfg *EventTrigger, refPrices map[string]float64) string {
+	at.eventMu.Lock()
+	levels := make([]stopLossLevel, 0, len(at.stopLosses))
+	for _, level := range at.stopLosses {
+		levels = append(levels, level)
+	}
+	at.eventMu.Unlock()
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Symbol < levels[j].Symbol })
+
+	symbols := cfg.Symbols
+	if len(symbols) == 0 {
+		for _, level := range levels {
+			symbols = append(symbols, level.Symbol)
+		}
+	}
+	prices := make(map[string]float64)
+	priceOf := func(symbol string) (float64, bool) {
+		if p, ok := prices[symbol]; ok {
+			return p, p > 0
+		}
+		p, err := at.trader.GetMarketPrice(ctx, symbol)
+		if err != nil {
+			if ctx.Err() == nil {
+				at.log.Warnf(ctx, "⚠️ 事件检查获取 %s 价格失败: %v", symbol, err)
+			}
+			p = 0
+		}
+		prices[symbol] = p
+		return p, p > 0
+	}
+
+	if cfg.OnStopLoss {
+		for _, level := range levels {
+			price, ok := priceOf(level.Symbol)
+			if !ok {
+				continue
+			}
+			if (level.Side == SideLong && price <= level.Price) || (level.Side == SideShort && price >= level.Price) {
+				at.untrackStopLoss(level.Symbol, level.Side)
+				return fmt.Sprintf("%s %s 触及止损 %.4f（当前 %.4f）", level.Symbol, level.Side, level.Price, price)
+			}
+		}
+	}
+
+	if cfg.MovePct > 0 {
+		for _, symbol := range symbols {
+			price, ok := priceOf(symbol)
+			if !ok {
+				continue
+			}
+			ref, seen := refPrices[symbol]
+			if !seen {
+				refPrices[symbol] = price
+				continue
+			}
+			if change := (price - ref) / ref * 100; math.Abs(change) >= cfg.MovePct {
+				refPrices[symbol] = price
+				return fmt.Sprintf("%s 价格变动 %+.2f%%（%.4f → %.4f）", symbol, change, ref, price)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package trader
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// recordedRequest 模拟交易所收到的请求
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   string
+}
+
+// fixtureServer 按 "METHOD /path" 返回 testdata 中录制的交易所响应，并记录收到的请求
+type fixtureServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]string
+	requests []recordedRequest
+}
+
+// newFixtureServer 启动模拟交易所，dir 为 testdata 下的子目录
+func newFixtureServer(t *testing.T, dir string, routes map[string]string) *fixtureServer {
+	t.Helper()
+	fs := &fixtureServer{routes: routes}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		key := r.Method + " " + r.URL.Path
+
+		fs.mu.Lock()
+		fs.requests = append(fs.requests, recordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Header: r.Header.Clone(),
+			Body:   string(body),
+		})
+		fixture, ok := fs.routes[key]
+		fs.mu.Unlock()
+
+		if !ok {
+			t.Errorf("未预期的请求: %s", key)
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", dir, fixture))
+		if err != nil {
+			t.Errorf("读取fixture失败: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// setRoute 替换某个接口的响应
+func (fs *fixtureServer) setRoute(key, fixture string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.routes[key] = fixture
+}
+
+// requestsTo 返回发往指定路径的请求
+func (fs *fixtureServer) requestsTo(method, path string) []recordedRequest {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var out []recordedRequest
+	for _, req := range fs.requests {
+		if req.Method == method && req.Path == path {
+			out = append(out, req)
+		}
+	}
+	return out
+}
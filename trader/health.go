@@ -0,0 +1,69 @@
+package trader
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrAlreadyRunning 交易员已在运行中
+var ErrAlreadyRunning = errors.New("交易员已在运行中")
+
+// HealthStatus 交易员健康状态
+type HealthStatus string
+
+const (
+	HealthHealthy  HealthStatus = "healthy"  // 正常
+	HealthDegraded HealthStatus = "degraded" // 连续周期失败或刚从异常中重启
+	HealthErrored  HealthStatus = "errored"  // 连续失败过多或重启次数耗尽
+)
+
+// TraderHealth 交易员健康统计
+type TraderHealth struct {
+	Status              HealthStatus `json:"status"`
+	ConsecutiveFailures int          `json:"consecutive_failures"` // 连续失败的决策周期数
+	Restarts            int          `json:"restarts"`             // 主循环异常退出后被重启的次数
+	LastError           string       `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time   `json:"last_error_at,omitempty"`
+}
+
+// Health 当前健康统计
+func (at *AutoTrader) Health() TraderHealth {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.health
+}
+
+// UpdateHealth 修改健康统计并返回修改后的快照
+func (at *AutoTrader) UpdateHealth(update func(h *TraderHealth)) TraderHealth {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	update(&at.health)
+	return at.health
+}
+
+// SetCycleHook 设置每个决策周期结束后的回调（停止导致的中断不回调）
+func (at *AutoTrader) SetCycleHook(hook func(h TraderHealth)) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.cycleHook = hook
+}
+
+// recordCycle 记录决策周期结果
+func (at *AutoTrader) recordCycle(err error) {
+	at.mu.Lock()
+	if err != nil {
+		now := time.Now()
+		at.health.ConsecutiveFailures++
+		at.health.LastError = err.Error()
+		at.health.LastErrorAt = &now
+	} else {
+		at.health.ConsecutiveFailures = 0
+	}
+	h := at.health
+	hook := at.cycleHook
+	at.mu.Unlock()
+
+	if hook != nil {
+		hook(h)
+	}
+}
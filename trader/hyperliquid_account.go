@@ -0,0 +1,107 @@
+package trader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/ratelimit"
+	"strings"
+	"time"
+)
+
+var hyperliquidInfoClient = &http.Client{Timeout: 10 * time.Second, Transport: ratelimit.NewTransport(nil)}
+
+// hyperliquidUserRole info接口 userRole 返回
+type hyperliquidUserRole struct {
+	Role string `json:"role"` // missing / user / agent / vault / subAccount
+	Data struct {
+		User   string `json:"user"`   // role=agent 时为授权的主钱包
+		Master string `json:"master"` // role=subAccount 时为所属主钱包
+	} `json:"data"`
+}
+
+// postHyperliquidInfo 调用 info 接口
+func postHyperliquidInfo(ctx context.Context, apiURL string, payload map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/info", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := hyperliquidInfoClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// getHyperliquidUserRole 查询地址的角色
+func getHyperliquidUserRole(ctx context.Context, apiURL, addr string) (*hyperliquidUserRole, error) {
+	var role hyperliquidUserRole
+	if err := postHyperliquidInfo(ctx, apiURL, map[string]interface{}{"type": "userRole", "user": addr}, &role); err != nil {
+		return nil, fmt.Errorf("查询Hyperliquid账户角色失败: %w", err)
+	}
+	return &role, nil
+}
+
+// verifyHyperliquidAccount 校验签名钱包和交易账户的归属关系
+// - 签名地址与主钱包不同时，必须是主钱包已授权的API钱包（agent）
+// - Vault 账户要求主钱包是该 Vault 的 leader
+// - 子账户要求该地址是主钱包名下的子账户
+func verifyHyperliquidAccount(ctx context.Context, apiURL, signerAddr string, account HyperliquidAccount) error {
+	if !strings.EqualFold(signerAddr, account.WalletAddr) {
+		role, err := getHyperliquidUserRole(ctx, apiURL, signerAddr)
+		if err != nil {
+			return err
+		}
+		if role.Role != "agent" || !strings.EqualFold(role.Data.User, account.WalletAddr) {
+			return fmt.Errorf("私钥对应的地址 %s 不是主钱包 %s 授权的API钱包，请在Hyperliquid上授权后再试，或使用主钱包私钥", signerAddr, account.WalletAddr)
+		}
+		exchangeLog.Infof(ctx, "✓ Hyperliquid API钱包 %s 已获主钱包 %s 授权", signerAddr, account.WalletAddr)
+	}
+
+	switch account.AccountType {
+	case "", HyperliquidAccountMain:
+		return nil
+
+	case HyperliquidAccountVault:
+		var vault struct {
+			Leader string `json:"leader"`
+		}
+		if err := postHyperliquidInfo(ctx, apiURL, map[string]interface{}{"type": "vaultDetails", "vaultAddress": account.VaultAddr}, &vault); err != nil {
+			return fmt.Errorf("查询Hyperliquid Vault信息失败: %w", err)
+		}
+		if !strings.EqualFold(vault.Leader, account.WalletAddr) {
+			return fmt.Errorf("主钱包 %s 不是Vault %s 的leader，无法代为交易", account.WalletAddr, account.VaultAddr)
+		}
+		return nil
+
+	case HyperliquidAccountSubAccount:
+		role, err := getHyperliquidUserRole(ctx, apiURL, account.VaultAddr)
+		if err != nil {
+			return err
+		}
+		if role.Role != "subAccount" || !strings.EqualFold(role.Data.Master, account.WalletAddr) {
+			return fmt.Errorf("%s 不是主钱包 %s 的子账户", account.VaultAddr, account.WalletAddr)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("未知的Hyperliquid账户类型: %s", account.AccountType)
+}
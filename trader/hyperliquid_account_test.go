@@ -0,0 +1,203 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	hlMaster   = "0x1111111111111111111111111111111111111111"
+	hlAgent    = "0x2222222222222222222222222222222222222222"
+	hlVault    = "0x3333333333333333333333333333333333333333"
+	hlSub      = "0x4444444444444444444444444444444444444444"
+	hlStranger = "0x5555555555555555555555555555555555555555"
+)
+
+// hyperliquidInfoServer 模拟 Hyperliquid info 接口：按 "type 地址" 返回响应并记录收到的请求
+type hyperliquidInfoServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newHyperliquidInfoServer(t *testing.T, responses map[string]string) *hyperliquidInfoServer {
+	t.Helper()
+	s := &hyperliquidInfoServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/info" {
+			t.Errorf("未预期的请求: %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		var payload struct {
+			Type         string `json:"type"`
+			User         string `json:"user"`
+			VaultAddress string `json:"vaultAddress"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		key := payload.Type + " " + payload.User + payload.VaultAddress
+
+		s.mu.Lock()
+		s.requests = append(s.requests, key)
+		s.mu.Unlock()
+
+		resp, ok := responses[key]
+		if !ok {
+			http.Error(w, "unknown request", http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *hyperliquidInfoServer) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func TestVerifyHyperliquidAccount(t *testing.T) {
+	responses := map[string]string{
+		"userRole " + hlAgent:        `{"role":"agent","data":{"user":"` + hlMaster + `"}}`,
+		"userRole " + hlStranger:     `{"role":"missing"}`,
+		"userRole " + hlSub:          `{"role":"subAccount","data":{"master":"` + hlMaster + `"}}`,
+		"userRole " + hlMaster:       `{"role":"user"}`,
+		"vaultDetails " + hlVault:    `{"name":"test vault","leader":"` + hlMaster + `"}`,
+		"vaultDetails " + hlStranger: `{"name":"other vault","leader":"` + hlAgent + `"}`,
+	}
+
+	cases := []struct {
+		name         string
+		signer       string
+		account      HyperliquidAccount
+		wantErr      string // 为空表示通过
+		wantRequests []string
+	}{
+		{
+			name:    "主钱包私钥不查询角色",
+			signer:  hlMaster,
+			account: HyperliquidAccount{WalletAddr: hlMaster},
+		},
+		{
+			name:         "已授权的API钱包（地址不区分大小写）",
+			signer:       hlAgent,
+			account:      HyperliquidAccount{WalletAddr: strings.ToUpper(hlMaster)},
+			wantRequests: []string{"userRole " + hlAgent},
+		},
+		{
+			name:         "未授权的API钱包",
+			signer:       hlStranger,
+			account:      HyperliquidAccount{WalletAddr: hlMaster},
+			wantErr:      "不是主钱包 " + hlMaster + " 授权的API钱包",
+			wantRequests: []string{"userRole " + hlStranger},
+		},
+		{
+			name:         "API钱包授权给其他主钱包",
+			signer:       hlAgent,
+			account:      HyperliquidAccount{WalletAddr: hlStranger},
+			wantErr:      "授权的API钱包",
+			wantRequests: []string{"userRole " + hlAgent},
+		},
+		{
+			name:         "普通用户地址不能作为API钱包",
+			signer:       hlMaster,
+			account:      HyperliquidAccount{WalletAddr: hlStranger},
+			wantErr:      "授权的API钱包",
+			wantRequests: []string{"userRole " + hlMaster},
+		},
+		{
+			name:         "Vault leader",
+			signer:       hlMaster,
+			account:      HyperliquidAccount{WalletAddr: hlMaster, AccountType: HyperliquidAccountVault, VaultAddr: hlVault},
+			wantRequests: []string{"vaultDetails " + hlVault},
+		},
+		{
+			name:         "API钱包代Vault leader交易",
+			signer:       hlAgent,
+			account:      HyperliquidAccount{WalletAddr: hlMaster, AccountType: HyperliquidAccountVault, VaultAddr: hlVault},
+			wantRequests: []string{"userRole " + hlAgent, "vaultDetails " + hlVault},
+		},
+		{
+			name:         "不是Vault leader",
+			signer:       hlMaster,
+			account:      HyperliquidAccount{WalletAddr: hlMaster, AccountType: HyperliquidAccountVault, VaultAddr: hlStranger},
+			wantErr:      "不是Vault " + hlStranger + " 的leader",
+			wantRequests: []string{"vaultDetails " + hlStranger},
+		},
+		{
+			name:         "Vault不存在",
+			signer:       hlMaster,
+			account:      HyperliquidAccount{WalletAddr: hlMaster, AccountType: HyperliquidAccountVault, VaultAddr: hlSub},
+			wantErr:      "查询Hyperliquid Vault信息失败: HTTP 422",
+			wantRequests: []string{"vaultDetails " + hlSub},
+		},
+		{
+			name:         "子账户",
+			signer:       hlMaster,
+			account:      HyperliquidAccount{WalletAddr: hlMaster, AccountType: HyperliquidAccountSubAccount, VaultAddr: hlSub},
+			wantRequests: []string{"userRole " + hlSub},
+		},
+		{
+			name:         "不是主钱包的子账户",
+			signer:       hlMaster,
+			account:      HyperliquidAccount{WalletAddr: hlMaster, AccountType: HyperliquidAccountSubAccount, VaultAddr: hlStranger},
+			wantErr:      hlStranger + " 不是主钱包 " + hlMaster + " 的子账户",
+			wantRequests: []string{"userRole " + hlStranger},
+		},
+		{
+			name:    "未知账户类型",
+			signer:  hlMaster,
+			account: HyperliquidAccount{WalletAddr: hlMaster, AccountType: "portfolio"},
+			wantErr: "未知的Hyperliquid账户类型: portfolio",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := newHyperliquidInfoServer(t, responses)
+			err := verifyHyperliquidAccount(context.Background(), server.URL, c.signer, c.account)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Errorf("应通过校验: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("错误 = %v, want 包含 %q", err, c.wantErr)
+			}
+			if got := server.recorded(); strings.Join(got, ",") != strings.Join(c.wantRequests, ",") {
+				t.Errorf("info 请求 = %v, want %v", got, c.wantRequests)
+			}
+		})
+	}
+}
+
+func TestVerifyHyperliquidAccountCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := verifyHyperliquidAccount(ctx, server.URL, hlAgent, HyperliquidAccount{WalletAddr: hlMaster})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ctx 到期后应返回 context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -2,28 +2,92 @@ package trader
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"net/url"
+	"nofx/ratelimit"
 	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
 )
 
+// Hyperliquid 账户类型
+const (
+	HyperliquidAccountMain       = "main"       // 主账户
+	HyperliquidAccountVault      = "vault"      // Vault（需为Vault的leader）
+	HyperliquidAccountSubAccount = "subaccount" // 子账户（需为主钱包名下的子账户）
+)
+
+// HyperliquidAccount Hyperliquid 账户配置
+type HyperliquidAccount struct {
+	PrivateKey  string // 签名私钥（主钱包私钥或已授权的API钱包私钥，不含0x）
+	WalletAddr  string // 主钱包地址
+	AccountType string // 账户类型，见 HyperliquidAccount* 常量，为空时视为主账户
+	VaultAddr   string // Vault 或子账户地址（AccountType 为 vault/subaccount 时必填）
+}
+
 // HyperliquidTrader Hyperliquid交易器
 type HyperliquidTrader struct {
 	exchange      *hyperliquid.Exchange
-	ctx           context.Context
-	walletAddr    string
+	walletAddr    string            // 主钱包地址
+	accountAddr   string            // 实际交易的账户地址（主账户/Vault/子账户），余额和持仓从这里读取
 	meta          *hyperliquid.Meta // 缓存meta信息（包含精度等）
-	isCrossMargin bool             // 是否为全仓模式
+	isCrossMargin bool              // 是否为全仓模式
+	apiHost       string            // API主机（SDK 不支持替换 HTTP 客户端，按主机手动占用限流额度）
+}
+
+func init() {
+	RegisterExchange(ExchangeAdapter{
+		ID:           "hyperliquid",
+		DisplayName:  "Hyperliquid",
+		Type:         "dex",
+		MarketSource: "hyperliquid",
+		Fields: []CredentialField{
+			// 旧版数据中私钥存放在 api_key 列
+			{Key: "private_key", Label: "私钥（主钱包或API钱包）", Secret: true, Required: true, Pattern: `^(0x)?[0-9a-fA-F]{64}$`, LegacyKey: "apiKey"},
+			{Key: "wallet_addr", Label: "主钱包地址", Required: true, Pattern: `^0x[0-9a-fA-F]{40}$`, LegacyKey: "hyperliquidWalletAddr"},
+			{Key: "account_type", Label: "账户类型", Options: []string{HyperliquidAccountMain, HyperliquidAccountVault, HyperliquidAccountSubAccount}},
+			{Key: "vault_address", Label: "Vault/子账户地址", Pattern: `^0x[0-9a-fA-F]{40}$`},
+		},
+		Capabilities: ExchangeCapabilities{
+			ConditionalOrders: true,
+			IsolatedMargin:    true,
+			MarketOrders:      true,
+			Testnet:           true,
+		},
+		Validate: func(creds map[string]string) error {
+			accountType := creds["account_type"]
+			if accountType == HyperliquidAccountVault || accountType == HyperliquidAccountSubAccount {
+				if creds["vault_address"] == "" {
+					return fmt.Errorf("使用Hyperliquid %s 账户时必须配置Vault/子账户地址 (vault_address)", accountType)
+				}
+			}
+			return nil
+		},
+		Factory: func(creds map[string]string, testnet bool) (Trader, error) {
+			return NewHyperliquidTraderWithAccount(HyperliquidAccount{
+				PrivateKey:  strings.TrimPrefix(creds["private_key"], "0x"),
+				WalletAddr:  creds["wallet_addr"],
+				AccountType: creds["account_type"],
+				VaultAddr:   creds["vault_address"],
+			}, testnet)
+		},
+	})
 }
 
-// NewHyperliquidTrader 创建Hyperliquid交易器
+// NewHyperliquidTrader 创建Hyperliquid交易器（主账户）
 func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool) (*HyperliquidTrader, error) {
+	return NewHyperliquidTraderWithAccount(HyperliquidAccount{
+		PrivateKey: privateKeyHex,
+		WalletAddr: walletAddr,
+	}, testnet)
+}
+
+// NewHyperliquidTraderWithAccount 创建Hyperliquid交易器（支持API钱包、Vault和子账户）
+func NewHyperliquidTraderWithAccount(account HyperliquidAccount, testnet bool) (*HyperliquidTrader, error) {
 	// 解析私钥
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	privateKey, err := crypto.HexToECDSA(account.PrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("解析私钥失败: %w", err)
 	}
@@ -34,28 +98,46 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 		apiURL = hyperliquid.TestnetAPIURL
 	}
 
-	// // 从私钥生成钱包地址
-	// pubKey := privateKey.Public()
-	// publicKeyECDSA, ok := pubKey.(*ecdsa.PublicKey)
-	// if !ok {
-	// 	return nil, fmt.Errorf("无法转换公钥")
-	// }
-	// walletAddr := crypto.PubkeyToAddress(*publicKeyECDSA).Hex()
-
 	ctx := context.Background()
 
+	// 签名地址与主钱包不同时，签名方必须是主钱包授权的API钱包；Vault/子账户需归属于主钱包
+	signerAddr := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	if err := verifyHyperliquidAccount(ctx, apiURL, signerAddr, account); err != nil {
+		return nil, err
+	}
+
+	// 下单时携带 vaultAddress，余额和持仓从 Vault/子账户读取
+	vaultAddr := ""
+	accountAddr := account.WalletAddr
+	if account.AccountType == HyperliquidAccountVault || account.AccountType == HyperliquidAccountSubAccount {
+		vaultAddr = account.VaultAddr
+		accountAddr = account.VaultAddr
+	}
+
+	apiHost := hostOf(apiURL)
+
+	// NewExchange 会自动获取 meta 和 spotMeta，随后再获取一次 meta（info 请求各 20 权重）
+	if err := ratelimit.Wait(ctx, apiHost, 60); err != nil {
+		return nil, err
+	}
+
 	// 创建Exchange客户端（Exchange包含Info功能）
 	exchange := hyperliquid.NewExchange(
 		ctx,
 		privateKey,
 		apiURL,
-		nil,        // Meta will be fetched automatically
-		"",         // vault address (empty for personal account)
-		walletAddr, // wallet address
-		nil,        // SpotMeta will be fetched automatically
+		nil,         // Meta will be fetched automatically
+		vaultAddr,   // vault address (empty for personal account)
+		accountAddr, // account address
+		nil,         // SpotMeta will be fetched automatically
 	)
 
-	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s)", testnet, walletAddr)
+	accountType := account.AccountType
+	if accountType == "" {
+		accountType = HyperliquidAccountMain
+	}
+	exchangeLog.Infof(ctx, "✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s, signer=%s, account=%s %s)",
+		testnet, account.WalletAddr, signerAddr, accountType, accountAddr)
 
 	// 获取meta信息（包含精度等配置）
 	meta, err := exchange.Info().Meta(ctx)
@@ -65,32 +147,29 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 
 	return &HyperliquidTrader{
 		exchange:      exchange,
-		ctx:           ctx,
-		walletAddr:    walletAddr,
+		walletAddr:    account.WalletAddr,
+		accountAddr:   accountAddr,
 		meta:          meta,
 		isCrossMargin: true, // 默认使用全仓模式
+		apiHost:       apiHost,
 	}, nil
 }
 
 // GetBalance 获取账户余额
-func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
-	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
+func (t *HyperliquidTrader) GetBalance(ctx context.Context) (*Balance, error) {
+	exchangeLog.Infof(ctx, "🔄 正在调用Hyperliquid API获取账户余额...")
 
+	if err := t.throttle(ctx, 2); err != nil {
+		return nil, err
+	}
 	// 获取账户状态
-	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
+	accountState, err := t.exchange.Info().UserState(ctx, t.accountAddr)
 	if err != nil {
-		log.Printf("❌ Hyperliquid API调用失败: %v", err)
+		exchangeLog.Errorf(ctx, "❌ Hyperliquid API调用失败: %v", err)
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
 	// 解析余额信息（MarginSummary字段都是string）
-	result := make(map[string]interface{})
-
-	// 🔍 调试：打印API返回的完整CrossMarginSummary结构
-	summaryJSON, _ := json.MarshalIndent(accountState.MarginSummary, "  ", "  ")
-	log.Printf("🔍 [DEBUG] Hyperliquid API CrossMarginSummary完整数据:")
-	log.Printf("%s", string(summaryJSON))
-
 	accountValue, _ := strconv.ParseFloat(accountState.MarginSummary.AccountValue, 64)
 	totalMarginUsed, _ := strconv.ParseFloat(accountState.MarginSummary.TotalMarginUsed, 64)
 
@@ -104,34 +183,34 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	// ✅ 正确理解Hyperliquid字段：
 	// AccountValue = 总账户净值（已包含空闲资金+持仓价值+未实现盈亏）
 	// TotalMarginUsed = 持仓占用的保证金（已包含在AccountValue中，仅用于显示）
-	//
-	// 为了兼容auto_trader.go的计算逻辑（totalEquity = totalWalletBalance + totalUnrealizedProfit）
-	// 需要返回"不包含未实现盈亏的钱包余额"
-	walletBalanceWithoutUnrealized := accountValue - totalUnrealizedPnl
-
-	result["totalWalletBalance"] = walletBalanceWithoutUnrealized // 钱包余额（不含未实现盈亏）
-	result["availableBalance"] = accountValue - totalMarginUsed   // 可用余额（总净值 - 占用保证金）
-	result["totalUnrealizedProfit"] = totalUnrealizedPnl          // 未实现盈亏
+	// Hyperliquid 不返回不含未实现盈亏的钱包余额（totalRawUsd 含空仓名义价值，不能替代），WalletBalance 保持为0
+	result := &Balance{
+		TotalEquity:      accountValue,
+		UnrealizedPnL:    totalUnrealizedPnl,
+		AvailableBalance: accountValue - totalMarginUsed, // 可用余额（总净值 - 占用保证金）
+	}
 
-	log.Printf("✓ Hyperliquid 账户: 总净值=%.2f (钱包%.2f+未实现%.2f), 可用=%.2f, 保证金占用=%.2f",
+	exchangeLog.Infof(ctx, "✓ Hyperliquid 账户: 总净值=%.2f (未实现%.2f), 可用=%.2f, 保证金占用=%.2f",
 		accountValue,
-		walletBalanceWithoutUnrealized,
 		totalUnrealizedPnl,
-		result["availableBalance"],
+		result.AvailableBalance,
 		totalMarginUsed)
 
 	return result, nil
 }
 
 // GetPositions 获取所有持仓
-func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetPositions(ctx context.Context) ([]Position, error) {
+	if err := t.throttle(ctx, 2); err != nil {
+		return nil, err
+	}
 	// 获取账户状态
-	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
+	accountState, err := t.exchange.Info().UserState(ctx, t.accountAddr)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 
 	// 遍历所有持仓
 	for _, assetPos := range accountState.AssetPositions {
@@ -144,19 +223,16 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-
 		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
-		posMap["symbol"] = symbol
+		p := Position{Symbol: position.Coin + "USDT"}
 
 		// 持仓数量和方向
 		if posAmt > 0 {
-			posMap["side"] = "long"
-			posMap["positionAmt"] = posAmt
+			p.Side = SideLong
+			p.Quantity = posAmt
 		} else {
-			posMap["side"] = "short"
-			posMap["positionAmt"] = -posAmt // 转为正数
+			p.Side = SideShort
+			p.Quantity = -posAmt // 转为正数
 		}
 
 		// 价格信息（EntryPx和LiquidationPx是指针类型）
@@ -177,55 +253,59 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			markPrice = positionValue / absFloat(posAmt)
 		}
 
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
-		posMap["unRealizedProfit"] = unrealizedPnl
-		posMap["leverage"] = float64(position.Leverage.Value)
-		posMap["liquidationPrice"] = liquidationPx
+		p.EntryPrice = entryPrice
+		p.MarkPrice = markPrice
+		p.UnrealizedPnL = unrealizedPnl
+		p.Leverage = position.Leverage.Value
+		p.LiquidationPrice = liquidationPx
 
-		result = append(result, posMap)
+		result = append(result, p)
 	}
 
 	return result, nil
 }
 
 // SetMarginMode 设置仓位模式 (在SetLeverage时一并设置)
-func (t *HyperliquidTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+// 模式由开仓前的 UpdateLeverage 提交，切换失败时 SetLeverage 返回错误，开仓随之中止
+func (t *HyperliquidTrader) SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) error {
 	// Hyperliquid的仓位模式在SetLeverage时设置，这里只记录
 	t.isCrossMargin = isCrossMargin
 	marginModeStr := "全仓"
 	if !isCrossMargin {
 		marginModeStr = "逐仓"
 	}
-	log.Printf("  ✓ %s 将使用 %s 模式", symbol, marginModeStr)
+	exchangeLog.Infof(ctx, "  ✓ %s 将使用 %s 模式", symbol, marginModeStr)
 	return nil
 }
 
 // SetLeverage 设置杠杆
-func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
+func (t *HyperliquidTrader) SetLeverage(ctx context.Context, symbol string, leverage int) error {
 	// Hyperliquid symbol格式（去掉USDT后缀）
 	coin := convertSymbolToHyperliquid(symbol)
 
+	if err := t.throttle(ctx, 1); err != nil {
+		return err
+	}
 	// 调用UpdateLeverage (leverage int, name string, isCross bool)
 	// 第三个参数: true=全仓模式, false=逐仓模式
-	_, err := t.exchange.UpdateLeverage(t.ctx, leverage, coin, t.isCrossMargin)
+	_, err := t.exchange.UpdateLeverage(ctx, leverage, coin, t.isCrossMargin)
 	if err != nil {
 		return fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
-	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	exchangeLog.Infof(ctx, "  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
 	return nil
 }
 
 // OpenLong 开多仓
-func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败: %v", err)
 	}
 
 	// 设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
 		return nil, err
 	}
 
@@ -233,18 +313,18 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取当前价格（用于市价单）
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
 
 	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	roundedQuantity := t.roundToSzDecimals(ctx, coin, quantity)
+	exchangeLog.Infof(ctx, "  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(ctx, coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
+	exchangeLog.Infof(ctx, "  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
 
 	// 创建市价买入订单（使用IOC limit order with aggressive price）
 	order := hyperliquid.CreateOrderRequest{
@@ -260,30 +340,28 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	if err := t.throttle(ctx, 1); err != nil {
+		return nil, err
+	}
+	status, err := t.exchange.Order(ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
 
-	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
-
-	result := make(map[string]interface{})
-	result["orderId"] = 0 // Hyperliquid没有返回order ID
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	exchangeLog.Infof(ctx, "✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	return result, nil
+	return newHyperliquidOrderResult(symbol, roundedQuantity, status), nil
 }
 
 // OpenShort 开空仓
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenShort(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败: %v", err)
 	}
 
 	// 设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
 		return nil, err
 	}
 
@@ -291,18 +369,18 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
 
 	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	roundedQuantity := t.roundToSzDecimals(ctx, coin, quantity)
+	exchangeLog.Infof(ctx, "  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(ctx, coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
+	exchangeLog.Infof(ctx, "  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
 
 	// 创建市价卖出订单
 	order := hyperliquid.CreateOrderRequest{
@@ -318,35 +396,30 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	if err := t.throttle(ctx, 1); err != nil {
+		return nil, err
+	}
+	status, err := t.exchange.Order(ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
 
-	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
-
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	exchangeLog.Infof(ctx, "✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	return result, nil
+	return newHyperliquidOrderResult(symbol, roundedQuantity, status), nil
 }
 
 // CloseLong 平多仓
-func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseLong(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
-		positions, err := t.GetPositions()
+		positions, err := t.GetPositions(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, SideLong); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -358,18 +431,18 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
 
 	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	roundedQuantity := t.roundToSzDecimals(ctx, coin, quantity)
+	exchangeLog.Infof(ctx, "  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(ctx, coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
+	exchangeLog.Infof(ctx, "  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
 
 	// 创建平仓订单（卖出 + ReduceOnly）
 	order := hyperliquid.CreateOrderRequest{
@@ -385,40 +458,35 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		ReduceOnly: true, // 只平仓，不开新仓
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	if err := t.throttle(ctx, 1); err != nil {
+		return nil, err
+	}
+	status, err := t.exchange.Order(ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
 
-	log.Printf("✓ 平多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
+	exchangeLog.Infof(ctx, "✓ 平多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
 	// 平仓后取消该币种的所有挂单
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return newHyperliquidOrderResult(symbol, roundedQuantity, status), nil
 }
 
 // CloseShort 平空仓
-func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseShort(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
-		positions, err := t.GetPositions()
+		positions, err := t.GetPositions(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, SideShort); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -430,18 +498,18 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
+	price, err := t.GetMarketPrice(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
 
 	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	roundedQuantity := t.roundToSzDecimals(ctx, coin, quantity)
+	exchangeLog.Infof(ctx, "  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(ctx, coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
+	exchangeLog.Infof(ctx, "  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
 
 	// 创建平仓订单（买入 + ReduceOnly）
 	order := hyperliquid.CreateOrderRequest{
@@ -457,32 +525,46 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		ReduceOnly: true,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	if err := t.throttle(ctx, 1); err != nil {
+		return nil, err
+	}
+	status, err := t.exchange.Order(ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
 
-	log.Printf("✓ 平空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
+	exchangeLog.Infof(ctx, "✓ 平空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
 	// 平仓后取消该币种的所有挂单
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	return newHyperliquidOrderResult(symbol, roundedQuantity, status), nil
+}
 
-	return result, nil
+// newHyperliquidOrderResult 转换下单返回（IOC单成交后返回filled，未完全成交时可能只有resting）
+func newHyperliquidOrderResult(symbol string, quantity float64, status hyperliquid.OrderStatus) *OrderResult {
+	result := &OrderResult{Symbol: symbol, Quantity: quantity, Status: "FILLED"}
+	switch {
+	case status.Filled != nil:
+		result.OrderID = strconv.Itoa(status.Filled.Oid)
+	case status.Resting != nil:
+		result.OrderID = strconv.FormatInt(status.Resting.Oid, 10)
+		result.Status = "NEW"
+	}
+	return result
 }
 
 // CancelAllOrders 取消该币种的所有挂单
-func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
+func (t *HyperliquidTrader) CancelAllOrders(ctx context.Context, symbol string) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
+	if err := t.throttle(ctx, 20); err != nil {
+		return err
+	}
 	// 获取所有挂单
-	openOrders, err := t.exchange.Info().OpenOrders(t.ctx, t.walletAddr)
+	openOrders, err := t.exchange.Info().OpenOrders(ctx, t.accountAddr)
 	if err != nil {
 		return fmt.Errorf("获取挂单失败: %w", err)
 	}
@@ -490,23 +572,29 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	// 取消该币种的所有挂单
 	for _, order := range openOrders {
 		if order.Coin == coin {
-			_, err := t.exchange.Cancel(t.ctx, coin, order.Oid)
+			if err := t.throttle(ctx, 1); err != nil {
+				return err
+			}
+			_, err := t.exchange.Cancel(ctx, coin, order.Oid)
 			if err != nil {
-				log.Printf("  ⚠ 取消订单失败 (oid=%d): %v", order.Oid, err)
+				exchangeLog.Warnf(ctx, "  ⚠ 取消订单失败 (oid=%d): %v", order.Oid, err)
 			}
 		}
 	}
 
-	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	exchangeLog.Infof(ctx, "  ✓ 已取消 %s 的所有挂单", symbol)
 	return nil
 }
 
 // GetMarketPrice 获取市场价格
-func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
+func (t *HyperliquidTrader) GetMarketPrice(ctx context.Context, symbol string) (float64, error) {
 	coin := convertSymbolToHyperliquid(symbol)
 
+	if err := t.throttle(ctx, 2); err != nil {
+		return 0, err
+	}
 	// 获取所有市场价格
-	allMids, err := t.exchange.Info().AllMids(t.ctx)
+	allMids, err := t.exchange.Info().AllMids(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("获取价格失败: %w", err)
 	}
@@ -524,13 +612,13 @@ func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 }
 
 // SetStopLoss 设置止损单
-func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+func (t *HyperliquidTrader) SetStopLoss(ctx context.Context, symbol string, positionSide PositionSide, quantity, stopPrice float64) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
-	isBuy := positionSide == "SHORT" // 空仓止损=买入，多仓止损=卖出
+	isBuy := positionSide == SideShort // 空仓止损=买入，多仓止损=卖出
 
 	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
+	roundedQuantity := t.roundToSzDecimals(ctx, coin, quantity)
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	roundedStopPrice := t.roundPriceToSigfigs(stopPrice)
@@ -551,23 +639,26 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 		ReduceOnly: true,
 	}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	if err := t.throttle(ctx, 1); err != nil {
+		return err
+	}
+	_, err := t.exchange.Order(ctx, order, nil)
 	if err != nil {
 		return fmt.Errorf("设置止损失败: %w", err)
 	}
 
-	log.Printf("  止损价设置: %.4f", roundedStopPrice)
+	exchangeLog.Infof(ctx, "  止损价设置: %.4f", roundedStopPrice)
 	return nil
 }
 
 // SetTakeProfit 设置止盈单
-func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+func (t *HyperliquidTrader) SetTakeProfit(ctx context.Context, symbol string, positionSide PositionSide, quantity, takeProfitPrice float64) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
-	isBuy := positionSide == "SHORT" // 空仓止盈=买入，多仓止盈=卖出
+	isBuy := positionSide == SideShort // 空仓止盈=买入，多仓止盈=卖出
 
 	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
+	roundedQuantity := t.roundToSzDecimals(ctx, coin, quantity)
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
 	roundedTakeProfitPrice := t.roundPriceToSigfigs(takeProfitPrice)
@@ -588,19 +679,35 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 		ReduceOnly: true,
 	}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	if err := t.throttle(ctx, 1); err != nil {
+		return err
+	}
+	_, err := t.exchange.Order(ctx, order, nil)
 	if err != nil {
 		return fmt.Errorf("设置止盈失败: %w", err)
 	}
 
-	log.Printf("  止盈价设置: %.4f", roundedTakeProfitPrice)
+	exchangeLog.Infof(ctx, "  止盈价设置: %.4f", roundedTakeProfitPrice)
 	return nil
 }
 
+// throttle 占用 Hyperliquid 限流额度（与行情数据源共享同一主机的额度）
+func (t *HyperliquidTrader) throttle(ctx context.Context, weight int) error {
+	return ratelimit.Wait(ctx, t.apiHost, weight)
+}
+
+// hostOf 解析URL中的主机名
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return rawURL
+}
+
 // FormatQuantity 格式化数量到正确的精度
 func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	coin := convertSymbolToHyperliquid(symbol)
-	szDecimals := t.getSzDecimals(coin)
+	szDecimals := t.getSzDecimals(context.Background(), coin)
 
 	// 使用szDecimals格式化数量
 	formatStr := fmt.Sprintf("%%.%df", szDecimals)
@@ -608,9 +715,9 @@ func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (str
 }
 
 // getSzDecimals 获取币种的数量精度
-func (t *HyperliquidTrader) getSzDecimals(coin string) int {
+func (t *HyperliquidTrader) getSzDecimals(ctx context.Context, coin string) int {
 	if t.meta == nil {
-		log.Printf("⚠️  meta信息为空，使用默认精度4")
+		exchangeLog.Warnf(ctx, "⚠️  meta信息为空，使用默认精度4")
 		return 4 // 默认精度
 	}
 
@@ -621,13 +728,13 @@ func (t *HyperliquidTrader) getSzDecimals(coin string) int {
 		}
 	}
 
-	log.Printf("⚠️  未找到 %s 的精度信息，使用默认精度4", coin)
+	exchangeLog.Warnf(ctx, "⚠️  未找到 %s 的精度信息，使用默认精度4", coin)
 	return 4 // 默认精度
 }
 
 // roundToSzDecimals 将数量四舍五入到正确的精度
-func (t *HyperliquidTrader) roundToSzDecimals(coin string, quantity float64) float64 {
-	szDecimals := t.getSzDecimals(coin)
+func (t *HyperliquidTrader) roundToSzDecimals(ctx context.Context, coin string, quantity float64) float64 {
+	szDecimals := t.getSzDecimals(ctx, coin)
 
 	// 计算倍数（10^szDecimals）
 	multiplier := 1.0
@@ -0,0 +1,125 @@
+package trader
+
+import (
+	"context"
+	"nofx/logging"
+	"nofx/metrics"
+	"nofx/ratelimit"
+	"nofx/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exchangeLog 交易所适配器日志（带调用方 context 中的 trader_id、cycle）
+var exchangeLog = logging.For("exchange")
+
+// instrumentedTrader 为交易器的每个接口调用记录耗时、失败数和 trace span（endpoint 为接口方法名）
+type instrumentedTrader struct {
+	Trader
+	exchange string
+}
+
+// instrument 包装交易器以记录交易所API指标
+func instrument(exchange string, t Trader) Trader {
+	return &instrumentedTrader{Trader: t, exchange: exchange}
+}
+
+func (t *instrumentedTrader) GetBalance(ctx context.Context) (balance *Balance, err error) {
+	ctx, span := t.start(ctx, "GetBalance", "")
+	defer t.end(span, "GetBalance", time.Now(), nil, &err)
+	return t.Trader.GetBalance(ctx)
+}
+
+func (t *instrumentedTrader) GetPositions(ctx context.Context) (positions []Position, err error) {
+	ctx, span := t.start(ctx, "GetPositions", "")
+	defer t.end(span, "GetPositions", time.Now(), nil, &err)
+	return t.Trader.GetPositions(ctx)
+}
+
+func (t *instrumentedTrader) OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (result *OrderResult, err error) {
+	ctx, span := t.start(ctx, "OpenLong", symbol)
+	defer t.end(span, "OpenLong", time.Now(), &result, &err)
+	return t.Trader.OpenLong(ctx, symbol, quantity, leverage)
+}
+
+func (t *instrumentedTrader) OpenShort(ctx context.Context, symbol string, quantity float64, leverage int) (result *OrderResult, err error) {
+	ctx, span := t.start(ctx, "OpenShort", symbol)
+	defer t.end(span, "OpenShort", time.Now(), &result, &err)
+	return t.Trader.OpenShort(ctx, symbol, quantity, leverage)
+}
+
+func (t *instrumentedTrader) CloseLong(ctx context.Context, symbol string, quantity float64) (result *OrderResult, err error) {
+	ctx, span := t.start(ctx, "CloseLong", symbol)
+	defer t.end(span, "CloseLong", time.Now(), &result, &err)
+	return t.Trader.CloseLong(ctx, symbol, quantity)
+}
+
+func (t *instrumentedTrader) CloseShort(ctx context.Context, symbol string, quantity float64) (result *OrderResult, err error) {
+	ctx, span := t.start(ctx, "CloseShort", symbol)
+	defer t.end(span, "CloseShort", time.Now(), &result, &err)
+	return t.Trader.CloseShort(ctx, symbol, quantity)
+}
+
+func (t *instrumentedTrader) SetLeverage(ctx context.Context, symbol string, leverage int) (err error) {
+	ctx, span := t.start(ctx, "SetLeverage", symbol)
+	defer t.end(span, "SetLeverage", time.Now(), nil, &err)
+	return t.Trader.SetLeverage(ctx, symbol, leverage)
+}
+
+func (t *instrumentedTrader) SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) (err error) {
+	ctx, span := t.start(ctx, "SetMarginMode", symbol)
+	defer t.end(span, "SetMarginMode", time.Now(), nil, &err)
+	return t.Trader.SetMarginMode(ctx, symbol, isCrossMargin)
+}
+
+func (t *instrumentedTrader) GetMarketPrice(ctx context.Context, symbol string) (price float64, err error) {
+	ctx, span := t.start(ctx, "GetMarketPrice", symbol)
+	defer t.end(span, "GetMarketPrice", time.Now(), nil, &err)
+	return t.Trader.GetMarketPrice(ctx, symbol)
+}
+
+func (t *instrumentedTrader) SetStopLoss(ctx context.Context, symbol string, side PositionSide, quantity, stopPrice float64) (err error) {
+	ctx, span := t.start(ctx, "SetStopLoss", symbol)
+	defer t.end(span, "SetStopLoss", time.Now(), nil, &err)
+	return t.Trader.SetStopLoss(ctx, symbol, side, quantity, stopPrice)
+}
+
+func (t *instrumentedTrader) SetTakeProfit(ctx context.Context, symbol string, side PositionSide, quantity, takeProfitPrice float64) (err error) {
+	ctx, span := t.start(ctx, "SetTakeProfit", symbol)
+	defer t.end(span, "SetTakeProfit", time.Now(), nil, &err)
+	return t.Trader.SetTakeProfit(ctx, symbol, side, quantity, takeProfitPrice)
+}
+
+func (t *instrumentedTrader) CancelAllOrders(ctx context.Context, symbol string) (err error) {
+	ctx, span := t.start(ctx, "CancelAllOrders", symbol)
+	defer t.end(span, "CancelAllOrders", time.Now(), nil, &err)
+	return t.Trader.CancelAllOrders(ctx, symbol)
+}
+
+// start 为一次交易所调用开始 span；下单类接口使用限流的高优先级
+func (t *instrumentedTrader) start(ctx context.Context, endpoint, symbol string) (context.Context, trace.Span) {
+	switch endpoint {
+	case "GetBalance", "GetPositions", "GetMarketPrice":
+	default:
+		ctx = ratelimit.WithPriority(ctx, ratelimit.PriorityOrder)
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("exchange", t.exchange),
+		attribute.String("endpoint", endpoint),
+	}
+	if symbol != "" {
+		attrs = append(attrs, attribute.String("symbol", symbol))
+	}
+	return tracing.Start(ctx, "exchange."+endpoint, attrs...)
+}
+
+// end 在调用返回后记录指标并结束 span（result、err 取返回时的值，result 为 nil 表示非下单接口）
+func (t *instrumentedTrader) end(span trace.Span, endpoint string, start time.Time, result **OrderResult, err *error) {
+	metrics.ObserveExchange(t.exchange, endpoint, start, *err)
+	if result != nil && *result != nil {
+		span.SetAttributes(attribute.String("order.id", (*result).OrderID))
+	}
+	tracing.End(span, *err)
+}
@@ -1,43 +1,46 @@
 package trader
 
+import "context"
+
 // Trader 交易器统一接口
 // 支持多个交易平台（币安、Hyperliquid等）
+// 涉及网络请求的方法都接收 ctx，取消时中断请求
 type Trader interface {
 	// GetBalance 获取账户余额
-	GetBalance() (map[string]interface{}, error)
+	GetBalance(ctx context.Context) (*Balance, error)
 
 	// GetPositions 获取所有持仓
-	GetPositions() ([]map[string]interface{}, error)
+	GetPositions(ctx context.Context) ([]Position, error)
 
 	// OpenLong 开多仓
-	OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error)
 
 	// OpenShort 开空仓
-	OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	OpenShort(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error)
 
 	// CloseLong 平多仓（quantity=0表示全部平仓）
-	CloseLong(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseLong(ctx context.Context, symbol string, quantity float64) (*OrderResult, error)
 
 	// CloseShort 平空仓（quantity=0表示全部平仓）
-	CloseShort(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseShort(ctx context.Context, symbol string, quantity float64) (*OrderResult, error)
 
 	// SetLeverage 设置杠杆
-	SetLeverage(symbol string, leverage int) error
+	SetLeverage(ctx context.Context, symbol string, leverage int) error
 
 	// SetMarginMode 设置仓位模式 (true=全仓, false=逐仓)
-	SetMarginMode(symbol string, isCrossMargin bool) error
+	SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) error
 
 	// GetMarketPrice 获取市场价格
-	GetMarketPrice(symbol string) (float64, error)
+	GetMarketPrice(ctx context.Context, symbol string) (float64, error)
 
 	// SetStopLoss 设置止损单
-	SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error
+	SetStopLoss(ctx context.Context, symbol string, side PositionSide, quantity, stopPrice float64) error
 
 	// SetTakeProfit 设置止盈单
-	SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error
+	SetTakeProfit(ctx context.Context, symbol string, side PositionSide, quantity, takeProfitPrice float64) error
 
 	// CancelAllOrders 取消该币种的所有挂单
-	CancelAllOrders(symbol string) error
+	CancelAllOrders(ctx context.Context, symbol string) error
 
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
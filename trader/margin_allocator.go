@@ -0,0 +1,183 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+)
+
+// MarginPolicy 保证金分配策略（百分比均相对账户净值）
+type MarginPolicy struct {
+	MaxTotalMarginPct  float64            `json:"max_total_margin_pct"`          // 组合保证金预算（总保证金使用率上限），默认90
+	MaxSymbolMarginPct float64            `json:"max_symbol_margin_pct"`         // 单币种保证金上限，0表示只受组合预算限制
+	SymbolMarginPct    map[string]float64 `json:"symbol_margin_pct,omitempty"`   // 指定币种的保证金上限（覆盖 MaxSymbolMarginPct）
+	IsolatedMarginUSD  float64            `json:"isolated_margin_usd,omitempty"` // 逐仓模式下每个仓位的保证金上限（USDT），0表示按AI给出的仓位大小计算
+	MinPositionUSD     float64            `json:"min_position_usd"`              // 缩减后仓位价值低于此值时拒绝开仓，默认10
+}
+
+// DefaultMarginPolicy 默认保证金策略（与系统提示词中的"总使用率 ≤ 90%"一致）
+func DefaultMarginPolicy() MarginPolicy {
+	return MarginPolicy{
+		MaxTotalMarginPct: 90,
+		MinPositionUSD:    10,
+	}
+}
+
+// Validate 校验并补全默认值
+func (p *MarginPolicy) Validate() error {
+	if p.MaxTotalMarginPct == 0 {
+		p.MaxTotalMarginPct = 90
+	}
+	if p.MinPositionUSD == 0 {
+		p.MinPositionUSD = 10
+	}
+	if p.MaxTotalMarginPct < 0 || p.MaxTotalMarginPct > 100 {
+		return fmt.Errorf("组合保证金预算必须在0-100%%之间: %.2f", p.MaxTotalMarginPct)
+	}
+	if p.MaxSymbolMarginPct < 0 || p.MaxSymbolMarginPct > 100 {
+		return fmt.Errorf("单币种保证金上限必须在0-100%%之间: %.2f", p.MaxSymbolMarginPct)
+	}
+	for symbol, pct := range p.SymbolMarginPct {
+		if pct <= 0 || pct > 100 {
+			return fmt.Errorf("%s 的保证金上限必须在0-100%%之间: %.2f", symbol, pct)
+		}
+	}
+	if p.IsolatedMarginUSD < 0 || p.MinPositionUSD < 0 {
+		return fmt.Errorf("保证金金额不能为负数")
+	}
+	return nil
+}
+
+// symbolCapPct 指定币种的保证金上限（0表示不限制）
+func (p *MarginPolicy) symbolCapPct(symbol string) float64 {
+	if pct, ok := p.SymbolMarginPct[symbol]; ok {
+		return pct
+	}
+	return p.MaxSymbolMarginPct
+}
+
+// MarginAllocation 单个开仓决策的保证金分配结果
+type MarginAllocation struct {
+	RequestedSizeUSD float64 // AI给出的仓位价值
+	SizeUSD          float64 // 分配后的仓位价值
+	MarginUSD        float64 // 分配的保证金（逐仓模式下即该仓位的保证金）
+	Resized          bool    // 是否被缩减/调整
+	Rejected         bool    // 是否被拒绝
+	Reason           string  // 调整或拒绝原因
+}
+
+// MarginAllocator 按组合保证金预算和单币种上限为开仓决策分配保证金
+// 初始占用来自账户的 MarginUsedPct，执行过程中随开平仓更新
+type MarginAllocator struct {
+	policy        MarginPolicy
+	isCrossMargin bool
+	equity        float64
+	available     float64            // 交易所可用余额
+	used          float64            // 已占用保证金
+	symbolUsed    map[string]float64 // 各币种已占用保证金
+	positionUsed  map[string]float64 // 各持仓已占用保证金（symbol_side）
+}
+
+// NewMarginAllocator 根据交易上下文创建保证金分配器
+func NewMarginAllocator(policy MarginPolicy, isCrossMargin bool, account decision.AccountInfo, positions []decision.PositionInfo) *MarginAllocator {
+	a := &MarginAllocator{
+		policy:        policy,
+		isCrossMargin: isCrossMargin,
+		equity:        account.TotalEquity,
+		available:     account.AvailableBalance,
+		used:          account.TotalEquity * account.MarginUsedPct / 100,
+		symbolUsed:    make(map[string]float64),
+		positionUsed:  make(map[string]float64),
+	}
+	for _, pos := range positions {
+		a.symbolUsed[pos.Symbol] += pos.MarginUsed
+		a.positionUsed[pos.Symbol+"_"+pos.Side] = pos.MarginUsed
+	}
+	return a
+}
+
+// Allocate 为开仓决策分配保证金（接受时立即计入占用）
+// 只会缩减不会放大已通过风控和敞口检查的仓位；逐仓模式下交易所按 仓位价值/杠杆 冻结保证金，即 MarginUSD
+func (a *MarginAllocator) Allocate(d *decision.Decision) MarginAllocation {
+	alloc := MarginAllocation{RequestedSizeUSD: d.PositionSizeUSD, SizeUSD: d.PositionSizeUSD}
+	if d.Leverage <= 0 || a.equity <= 0 {
+		alloc.Rejected = true
+		alloc.Reason = "账户净值或杠杆无效，无法分配保证金"
+		return alloc
+	}
+	leverage := float64(d.Leverage)
+	margin := d.PositionSizeUSD / leverage
+
+	// 逐仓模式下每个仓位的保证金上限
+	if !a.isCrossMargin && a.policy.IsolatedMarginUSD > 0 && margin > a.policy.IsolatedMarginUSD {
+		margin = a.policy.IsolatedMarginUSD
+		alloc.Resized = true
+		alloc.Reason = fmt.Sprintf("逐仓单仓保证金上限 %.2f USDT", margin)
+	}
+
+	// 可分配额度 = min(组合预算剩余, 单币种上限剩余, 交易所可用余额)
+	limit := a.equity*a.policy.MaxTotalMarginPct/100 - a.used
+	limitReason := fmt.Sprintf("组合保证金预算 %.0f%% 剩余 %.2f USDT", a.policy.MaxTotalMarginPct, math.Max(limit, 0))
+	if capPct := a.policy.symbolCapPct(d.Symbol); capPct > 0 {
+		symbolLimit := a.equity*capPct/100 - a.symbolUsed[d.Symbol]
+		if symbolLimit < limit {
+			limit = symbolLimit
+			limitReason = fmt.Sprintf("%s 保证金上限 %.0f%% 剩余 %.2f USDT", d.Symbol, capPct, math.Max(limit, 0))
+		}
+	}
+	if a.available < limit {
+		limit = a.available
+		limitReason = fmt.Sprintf("可用余额仅 %.2f USDT", math.Max(limit, 0))
+	}
+
+	if margin > limit {
+		margin = limit
+		alloc.Resized = true
+		alloc.Reason = limitReason
+	}
+
+	alloc.SizeUSD = margin * leverage
+	alloc.MarginUSD = margin
+	if margin <= 0 || alloc.SizeUSD < a.policy.MinPositionUSD {
+		alloc.Rejected = true
+		alloc.Reason = fmt.Sprintf("%s，仓位价值 %.2f USDT 低于最小值 %.2f USDT", limitReason, math.Max(alloc.SizeUSD, 0), a.policy.MinPositionUSD)
+		alloc.SizeUSD = 0
+		alloc.MarginUSD = 0
+		return alloc
+	}
+
+	a.used += margin
+	a.available -= margin
+	a.symbolUsed[d.Symbol] += margin
+	return alloc
+}
+
+// Commit 开仓成功后记录该持仓的保证金（Allocate 已计入占用，这里只登记持仓用于平仓释放）
+func (a *MarginAllocator) Commit(symbol string, side PositionSide, alloc MarginAllocation) {
+	a.positionUsed[symbol+"_"+string(side)] += alloc.MarginUSD
+}
+
+// Rollback 开仓失败时退回分配的保证金
+func (a *MarginAllocator) Rollback(symbol string, alloc MarginAllocation) {
+	a.used -= alloc.MarginUSD
+	a.available += alloc.MarginUSD
+	a.symbolUsed[symbol] -= alloc.MarginUSD
+}
+
+// Release 平仓成功后释放该持仓占用的保证金
+func (a *MarginAllocator) Release(symbol string, side PositionSide) {
+	key := symbol + "_" + string(side)
+	margin := a.positionUsed[key]
+	delete(a.positionUsed, key)
+	a.used -= margin
+	a.available += margin
+	a.symbolUsed[symbol] -= margin
+}
+
+// UsedPct 当前保证金使用率
+func (a *MarginAllocator) UsedPct() float64 {
+	if a.equity <= 0 {
+		return 0
+	}
+	return a.used / a.equity * 100
+}
@@ -0,0 +1,32 @@
+package trader
+
+import (
+	"nofx/decision"
+	"testing"
+)
+
+func TestMarginAllocatorNeverRaisesSize(t *testing.T) {
+	account := decision.AccountInfo{TotalEquity: 1000, AvailableBalance: 1000}
+	policy := DefaultMarginPolicy()
+	policy.IsolatedMarginUSD = 50
+
+	// 逐仓单仓保证金上限高于所需保证金时保持AI给出的仓位
+	allocator := NewMarginAllocator(policy, false, account, nil)
+	alloc := allocator.Allocate(&decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100})
+	if alloc.Rejected || alloc.Resized || alloc.SizeUSD != 100 || alloc.MarginUSD != 20 {
+		t.Errorf("仓位不应被放大: %+v", alloc)
+	}
+
+	// 超过上限时缩减到 上限×杠杆
+	alloc = allocator.Allocate(&decision.Decision{Symbol: "ETHUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500})
+	if !alloc.Resized || alloc.SizeUSD != 250 || alloc.MarginUSD != 50 {
+		t.Errorf("仓位应缩减到逐仓上限: %+v", alloc)
+	}
+
+	// 全仓模式不受逐仓上限影响
+	cross := NewMarginAllocator(policy, true, account, nil)
+	alloc = cross.Allocate(&decision.Decision{Symbol: "ETHUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500})
+	if alloc.Resized || alloc.SizeUSD != 500 {
+		t.Errorf("全仓模式不应应用逐仓上限: %+v", alloc)
+	}
+}
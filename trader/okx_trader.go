@@ -0,0 +1,848 @@
+package trader
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/ratelimit"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OKX 账户持仓模式
+const (
+	okxLongShortMode = "long_short_mode" // 双向持仓：下单需指定 posSide
+	okxNetMode       = "net_mode"        // 单向持仓：不能指定 posSide，平仓/止损止盈使用 reduceOnly
+)
+
+// okxBaseURL OKX v5 API 地址（模拟盘通过请求头区分）
+const okxBaseURL = "https://www.okx.com"
+
+// OKXTrader OKX USDT永续合约交易器（v5 API，优先使用双向持仓模式）
+type OKXTrader struct {
+	apiKey     string
+	secretKey  string
+	passphrase string
+	baseURL    string
+	simulated  bool // 模拟盘
+	client     *http.Client
+
+	// 保证金模式（由SetMarginMode设置，下单时通过tdMode传递）
+	tdMode string
+
+	// 账户持仓模式（有持仓或挂单时无法切换为双向持仓，保持账户当前模式）
+	posMode string
+
+	// 缓存合约信息
+	instruments map[string]okxInstrument
+	mu          sync.RWMutex
+}
+
+// okxInstrument OKX合约信息（下单数量单位为张）
+type okxInstrument struct {
+	CtVal          float64 // 合约面值（每张对应的币数量）
+	LotSz          float64 // 下单数量精度（张）
+	MinSz          float64 // 最小下单数量（张）
+	TickSz         float64 // 价格精度
+	PricePrecision int
+}
+
+// okxResponse OKX v5 统一响应结构
+type okxResponse struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+func init() {
+	RegisterExchange(ExchangeAdapter{
+		ID:           "okx",
+		DisplayName:  "OKX",
+		Type:         "cex",
+		MarketSource: "okx",
+		Fields: []CredentialField{
+			{Key: "api_key", Label: "API Key", Required: true, LegacyKey: "apiKey"},
+			{Key: "secret_key", Label: "Secret Key", Secret: true, Required: true, LegacyKey: "secretKey"},
+			{Key: "passphrase", Label: "Passphrase", Secret: true, Required: true, LegacyKey: "passphrase"},
+		},
+		Capabilities: ExchangeCapabilities{
+			HedgeMode:         true,
+			ConditionalOrders: true,
+			IsolatedMargin:    true,
+			MarketOrders:      true,
+			Testnet:           true,
+		},
+		Factory: func(creds map[string]string, testnet bool) (Trader, error) {
+			return NewOKXTrader(creds["api_key"], creds["secret_key"], creds["passphrase"], testnet)
+		},
+	})
+}
+
+// NewOKXTrader 创建OKX交易器
+func NewOKXTrader(apiKey, secretKey, passphrase string, simulated bool) (*OKXTrader, error) {
+	return newOKXTrader(context.Background(), apiKey, secretKey, passphrase, simulated, okxBaseURL)
+}
+
+// newOKXTrader 创建指向 baseURL 的OKX交易器（测试中指向本地模拟服务）
+func newOKXTrader(ctx context.Context, apiKey, secretKey, passphrase string, simulated bool, baseURL string) (*OKXTrader, error) {
+	if apiKey == "" || secretKey == "" || passphrase == "" {
+		return nil, fmt.Errorf("OKX API Key、Secret Key和Passphrase不能为空")
+	}
+
+	t := &OKXTrader{
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		passphrase:  passphrase,
+		baseURL:     baseURL,
+		simulated:   simulated,
+		tdMode:      "cross",
+		instruments: make(map[string]okxInstrument),
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			// 按交易所主机共享限流额度
+			Transport: ratelimit.NewTransport(&http.Transport{
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 10 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+			}),
+		},
+	}
+
+	// 切换为双向持仓模式（与币安的 LONG/SHORT 持仓方向保持一致）
+	if err := t.setHedgeMode(ctx); err != nil {
+		return nil, fmt.Errorf("设置双向持仓模式失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ OKX交易器初始化成功 (模拟盘=%v, 持仓模式=%s)", simulated, t.getPosMode())
+	return t, nil
+}
+
+// convertSymbolToOKX 将 BTCUSDT 转换为 OKX 永续合约ID BTC-USDT-SWAP
+func convertSymbolToOKX(symbol string) string {
+	base := strings.TrimSuffix(strings.ToUpper(symbol), "USDT")
+	return base + "-USDT-SWAP"
+}
+
+// convertSymbolFromOKX 将 BTC-USDT-SWAP 转换为 BTCUSDT
+func convertSymbolFromOKX(instID string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(instID, "-SWAP"), "-", "")
+}
+
+// sign 生成v5签名: Base64(HMAC_SHA256(timestamp + method + requestPath + body))
+func (t *OKXTrader) sign(timestamp, method, requestPath, body string) string {
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + method + requestPath + body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// request 发送签名请求（带重试机制）
+// GET请求的参数需已包含在requestPath中；POST请求的payload序列化为JSON body
+func (t *OKXTrader) request(ctx context.Context, method, requestPath string, payload interface{}) (json.RawMessage, error) {
+	const maxRetries = 3
+	var lastErr error
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		data, err := t.doRequest(ctx, method, requestPath, payload)
+		if err == nil {
+			return data, nil
+		}
+
+		lastErr = err
+
+		// 如果是网络超时或临时错误，重试
+		if strings.Contains(err.Error(), "timeout") ||
+			strings.Contains(err.Error(), "connection reset") ||
+			strings.Contains(err.Error(), "EOF") {
+			if attempt < maxRetries && ctx.Err() == nil {
+				time.Sleep(time.Duration(attempt) * time.Second)
+				continue
+			}
+		}
+
+		// 其他错误（如参数错误/鉴权失败）不重试
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("请求失败（已重试%d次）: %w", maxRetries, lastErr)
+}
+
+// doRequest 执行实际的HTTP请求
+func (t *OKXTrader) doRequest(ctx context.Context, method, requestPath string, payload interface{}) (json.RawMessage, error) {
+	method = strings.ToUpper(method)
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+
+	body := ""
+	if method == "POST" && payload != nil {
+		bs, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("序列化请求失败: %w", err)
+		}
+		body = string(bs)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+requestPath, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OK-ACCESS-KEY", t.apiKey)
+	req.Header.Set("OK-ACCESS-SIGN", t.sign(timestamp, method, requestPath, body))
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", t.passphrase)
+	if t.simulated {
+		req.Header.Set("x-simulated-trading", "1")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return parseOKXResponse(respBody)
+}
+
+// parseOKXResponse 解析v5统一响应，code非0时返回错误（包含下单结果中的sCode/sMsg）
+func parseOKXResponse(body []byte) (json.RawMessage, error) {
+	var resp okxResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析OKX响应失败: %w", err)
+	}
+	if resp.Code != "0" {
+		// 交易类接口的具体错误在data[].sCode/sMsg中
+		var items []struct {
+			SCode string `json:"sCode"`
+			SMsg  string `json:"sMsg"`
+		}
+		if err := json.Unmarshal(resp.Data, &items); err == nil {
+			for _, item := range items {
+				if item.SCode != "" && item.SCode != "0" {
+					return nil, fmt.Errorf("OKX API错误 (code %s, sCode %s): %s", resp.Code, item.SCode, item.SMsg)
+				}
+			}
+		}
+		return nil, fmt.Errorf("OKX API错误 (code %s): %s", resp.Code, resp.Msg)
+	}
+	return resp.Data, nil
+}
+
+// setHedgeMode 切换为双向持仓模式（long_short_mode），无法切换时记录账户当前的持仓模式
+func (t *OKXTrader) setHedgeMode(ctx context.Context) error {
+	payload := map[string]string{
+		"posMode": okxLongShortMode,
+	}
+
+	if _, err := t.request(ctx, "POST", "/api/v5/account/set-position-mode", payload); err != nil {
+		// 59000: 有持仓或挂单时无法切换，保持当前模式（下单参数按当前模式生成）
+		if !strings.Contains(err.Error(), "59000") {
+			return err
+		}
+		posMode, err := t.fetchPosMode(ctx)
+		if err != nil {
+			return fmt.Errorf("无法切换持仓模式且查询当前模式失败: %w", err)
+		}
+		t.mu.Lock()
+		t.posMode = posMode
+		t.mu.Unlock()
+		exchangeLog.Warnf(ctx, "  ⚠️ OKX有持仓或挂单，无法切换持仓模式，继续使用当前模式: %s", posMode)
+		return nil
+	}
+
+	t.mu.Lock()
+	t.posMode = okxLongShortMode
+	t.mu.Unlock()
+	exchangeLog.Infof(ctx, "  ✓ OKX已切换为双向持仓模式")
+	return nil
+}
+
+// fetchPosMode 查询账户当前的持仓模式
+func (t *OKXTrader) fetchPosMode(ctx context.Context) (string, error) {
+	data, err := t.request(ctx, "GET", "/api/v5/account/config", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var configs []struct {
+		PosMode string `json:"posMode"`
+	}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return "", fmt.Errorf("解析账户配置失败: %w", err)
+	}
+	if len(configs) == 0 {
+		return "", fmt.Errorf("账户配置为空")
+	}
+	switch configs[0].PosMode {
+	case okxLongShortMode, okxNetMode:
+		return configs[0].PosMode, nil
+	default:
+		return "", fmt.Errorf("未知的持仓模式: %s", configs[0].PosMode)
+	}
+}
+
+// getPosMode 获取账户持仓模式
+func (t *OKXTrader) getPosMode() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.posMode
+}
+
+// setPositionParams 按持仓模式设置下单的方向参数
+// 双向持仓指定 posSide；单向持仓不能指定 posSide，减仓单使用 reduceOnly 防止反向开仓
+func (t *OKXTrader) setPositionParams(payload map[string]string, positionSide PositionSide, reduceOnly bool) {
+	if t.getPosMode() == okxNetMode {
+		if reduceOnly {
+			payload["reduceOnly"] = "true"
+		}
+		return
+	}
+	payload["posSide"] = string(positionSide)
+}
+
+// getInstrument 获取合约信息（面值、数量精度、价格精度）
+func (t *OKXTrader) getInstrument(ctx context.Context, symbol string) (okxInstrument, error) {
+	instID := convertSymbolToOKX(symbol)
+
+	t.mu.RLock()
+	if inst, ok := t.instruments[instID]; ok {
+		t.mu.RUnlock()
+		return inst, nil
+	}
+	t.mu.RUnlock()
+
+	data, err := t.request(ctx, "GET", "/api/v5/public/instruments?instType=SWAP&instId="+instID, nil)
+	if err != nil {
+		return okxInstrument{}, fmt.Errorf("获取合约信息失败: %w", err)
+	}
+
+	var list []struct {
+		InstID string `json:"instId"`
+		CtVal  string `json:"ctVal"`
+		LotSz  string `json:"lotSz"`
+		MinSz  string `json:"minSz"`
+		TickSz string `json:"tickSz"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return okxInstrument{}, fmt.Errorf("解析合约信息失败: %w", err)
+	}
+
+	for _, item := range list {
+		if item.InstID != instID {
+			continue
+		}
+
+		inst := okxInstrument{
+			PricePrecision: calculatePrecision(item.TickSz),
+		}
+		inst.CtVal, _ = strconv.ParseFloat(item.CtVal, 64)
+		inst.LotSz, _ = strconv.ParseFloat(item.LotSz, 64)
+		inst.MinSz, _ = strconv.ParseFloat(item.MinSz, 64)
+		inst.TickSz, _ = strconv.ParseFloat(item.TickSz, 64)
+		if inst.CtVal <= 0 {
+			return okxInstrument{}, fmt.Errorf("%s 合约面值异常: %s", instID, item.CtVal)
+		}
+
+		t.mu.Lock()
+		t.instruments[instID] = inst
+		t.mu.Unlock()
+		return inst, nil
+	}
+
+	return okxInstrument{}, fmt.Errorf("未找到合约 %s 的信息", instID)
+}
+
+// toContracts 将币数量转换为合约张数（向下取整到lotSz），同时返回取整后对应的币数量
+func (t *OKXTrader) toContracts(ctx context.Context, symbol string, quantity float64) (string, float64, error) {
+	inst, err := t.getInstrument(ctx, symbol)
+	if err != nil {
+		return "", 0, err
+	}
+
+	contracts := floorToStepSize(quantity/inst.CtVal, inst.LotSz)
+	if contracts < inst.MinSz || contracts <= 0 {
+		return "", 0, fmt.Errorf("%s 数量 %.8f 不足最小下单量 %v 张（面值 %v）", symbol, quantity, inst.MinSz, inst.CtVal)
+	}
+
+	sz := strconv.FormatFloat(contracts, 'f', calculatePrecision(strconv.FormatFloat(inst.LotSz, 'f', -1, 64)), 64)
+	return sz, contracts * inst.CtVal, nil
+}
+
+// formatPrice 格式化价格到tickSz
+func (t *OKXTrader) formatPrice(ctx context.Context, symbol string, price float64) (string, error) {
+	inst, err := t.getInstrument(ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+	price = roundToTickSize(price, inst.TickSz)
+	return strconv.FormatFloat(price, 'f', inst.PricePrecision, 64), nil
+}
+
+// FormatQuantity 格式化数量到正确的精度（返回按合约张数取整后的币数量）
+func (t *OKXTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	inst, err := t.getInstrument(context.Background(), symbol)
+	if err != nil {
+		return "", err
+	}
+
+	contracts := floorToStepSize(quantity/inst.CtVal, inst.LotSz)
+	return strconv.FormatFloat(contracts*inst.CtVal, 'f', -1, 64), nil
+}
+
+// GetBalance 获取账户余额（USDT）
+func (t *OKXTrader) GetBalance(ctx context.Context) (*Balance, error) {
+	data, err := t.request(ctx, "GET", "/api/v5/account/balance?ccy=USDT", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	var accounts []struct {
+		Details []struct {
+			Ccy      string `json:"ccy"`
+			CashBal  string `json:"cashBal"`
+			AvailEq  string `json:"availEq"`
+			AvailBal string `json:"availBal"`
+			Upl      string `json:"upl"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("解析账户信息失败: %w", err)
+	}
+
+	totalWalletBalance := 0.0
+	availableBalance := 0.0
+	totalUnrealizedProfit := 0.0
+
+	for _, acc := range accounts {
+		for _, d := range acc.Details {
+			if d.Ccy != "USDT" {
+				continue
+			}
+			totalWalletBalance, _ = strconv.ParseFloat(d.CashBal, 64)
+			// 单币种/跨币种保证金账户返回availEq，简单交易模式只返回availBal
+			avail := d.AvailEq
+			if avail == "" {
+				avail = d.AvailBal
+			}
+			availableBalance, _ = strconv.ParseFloat(avail, 64)
+			totalUnrealizedProfit, _ = strconv.ParseFloat(d.Upl, 64)
+		}
+	}
+
+	exchangeLog.Infof(ctx, "✓ OKX API返回: 总余额=%.4f, 可用=%.4f, 未实现盈亏=%.4f",
+		totalWalletBalance, availableBalance, totalUnrealizedProfit)
+
+	return &Balance{
+		TotalEquity:      totalWalletBalance + totalUnrealizedProfit,
+		WalletBalance:    totalWalletBalance,
+		UnrealizedPnL:    totalUnrealizedProfit,
+		AvailableBalance: availableBalance,
+	}, nil
+}
+
+// GetPositions 获取所有持仓（数量已从合约张数换算为币数量）
+func (t *OKXTrader) GetPositions(ctx context.Context) ([]Position, error) {
+	data, err := t.request(ctx, "GET", "/api/v5/account/positions?instType=SWAP", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var positions []struct {
+		InstID  string `json:"instId"`
+		PosSide string `json:"posSide"`
+		Pos     string `json:"pos"`
+		AvgPx   string `json:"avgPx"`
+		MarkPx  string `json:"markPx"`
+		Upl     string `json:"upl"`
+		Lever   string `json:"lever"`
+		LiqPx   string `json:"liqPx"`
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("解析持仓失败: %w", err)
+	}
+
+	var result []Position
+	for _, pos := range positions {
+		if !strings.HasSuffix(pos.InstID, "-USDT-SWAP") {
+			continue // 只处理USDT永续
+		}
+
+		contracts, _ := strconv.ParseFloat(pos.Pos, 64)
+		if contracts == 0 {
+			continue // 跳过空仓位
+		}
+
+		symbol := convertSymbolFromOKX(pos.InstID)
+		inst, err := t.getInstrument(ctx, symbol)
+		if err != nil {
+			exchangeLog.Warnf(ctx, "  ⚠️ %v", err)
+			continue
+		}
+
+		// 双向持仓下 posSide 为 long/short；单向持仓(net)按张数正负判断
+		side := PositionSide(pos.PosSide)
+		if pos.PosSide == "net" || pos.PosSide == "" {
+			side = SideLong
+			if contracts < 0 {
+				side = SideShort
+			}
+		}
+		if contracts < 0 {
+			contracts = -contracts
+		}
+
+		entryPrice, _ := strconv.ParseFloat(pos.AvgPx, 64)
+		markPrice, _ := strconv.ParseFloat(pos.MarkPx, 64)
+		unRealizedProfit, _ := strconv.ParseFloat(pos.Upl, 64)
+		leverage, _ := strconv.ParseFloat(pos.Lever, 64)
+		liquidationPrice, _ := strconv.ParseFloat(pos.LiqPx, 64)
+
+		result = append(result, Position{
+			Symbol:           symbol,
+			Side:             side,
+			Quantity:         contracts * inst.CtVal,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedPnL:    unRealizedProfit,
+			Leverage:         int(leverage),
+			LiquidationPrice: liquidationPrice,
+		})
+	}
+
+	return result, nil
+}
+
+// SetMarginMode 设置仓位模式（OKX按订单的tdMode区分全仓/逐仓）
+// 模式随每笔订单提交，无法按逐仓下单时交易所直接拒单，开仓失败而不会退回全仓
+func (t *OKXTrader) SetMarginMode(ctx context.Context, symbol string, isCrossMargin bool) error {
+	t.mu.Lock()
+	if isCrossMargin {
+		t.tdMode = "cross"
+	} else {
+		t.tdMode = "isolated"
+	}
+	t.mu.Unlock()
+
+	marginModeStr := "全仓"
+	if !isCrossMargin {
+		marginModeStr = "逐仓"
+	}
+	exchangeLog.Infof(ctx, "  ✓ %s 仓位模式已设置为 %s", symbol, marginModeStr)
+	return nil
+}
+
+// getTdMode 获取当前保证金模式
+func (t *OKXTrader) getTdMode() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tdMode
+}
+
+// SetLeverage 设置杠杆（双向持仓的逐仓模式下多空方向需分别设置）
+func (t *OKXTrader) SetLeverage(ctx context.Context, symbol string, leverage int) error {
+	instID := convertSymbolToOKX(symbol)
+	tdMode := t.getTdMode()
+
+	posSides := []string{""}
+	if tdMode == "isolated" && t.getPosMode() != okxNetMode {
+		posSides = []string{"long", "short"}
+	}
+
+	for _, posSide := range posSides {
+		payload := map[string]string{
+			"instId":  instID,
+			"lever":   strconv.Itoa(leverage),
+			"mgnMode": tdMode,
+		}
+		if posSide != "" {
+			payload["posSide"] = posSide
+		}
+
+		if _, err := t.request(ctx, "POST", "/api/v5/account/set-leverage", payload); err != nil {
+			return fmt.Errorf("设置杠杆失败: %w", err)
+		}
+	}
+
+	exchangeLog.Infof(ctx, "  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// placeOrder 下市价单（返回的数量为按合约张数取整后的币数量）
+func (t *OKXTrader) placeOrder(ctx context.Context, symbol, side string, positionSide PositionSide, quantity float64, reduceOnly bool) (*OrderResult, error) {
+	sz, filledQty, err := t.toContracts(ctx, symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]string{
+		"instId":  convertSymbolToOKX(symbol),
+		"tdMode":  t.getTdMode(),
+		"side":    side,
+		"ordType": "market",
+		"sz":      sz,
+	}
+	t.setPositionParams(payload, positionSide, reduceOnly)
+
+	data, err := t.request(ctx, "POST", "/api/v5/trade/order", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []struct {
+		OrdID string `json:"ordId"`
+	}
+	if err := json.Unmarshal(data, &orders); err != nil || len(orders) == 0 {
+		return nil, fmt.Errorf("解析下单结果失败: %s", string(data))
+	}
+
+	exchangeLog.Infof(ctx, "  订单ID: %s (%s 张)", orders[0].OrdID, sz)
+
+	return &OrderResult{
+		OrderID:  orders[0].OrdID,
+		Symbol:   symbol,
+		Quantity: filledQty,
+	}, nil
+}
+
+// OpenLong 开多仓
+func (t *OKXTrader) OpenLong(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "buy", SideLong, quantity, false)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 开多仓成功: %s 数量: %.8f", symbol, result.Quantity)
+	return result, nil
+}
+
+// OpenShort 开空仓
+func (t *OKXTrader) OpenShort(ctx context.Context, symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(ctx, symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "sell", SideShort, quantity, false)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 开空仓成功: %s 数量: %.8f", symbol, result.Quantity)
+	return result, nil
+}
+
+// CloseLong 平多仓
+func (t *OKXTrader) CloseLong(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
+	// 如果数量为0，获取当前持仓数量
+	if quantity == 0 {
+		positions, err := t.GetPositions(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if pos, ok := FindPosition(positions, symbol, SideLong); ok {
+			quantity = pos.Quantity
+		}
+
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
+		}
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "sell", SideLong, quantity, true)
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 平多仓成功: %s 数量: %.8f", symbol, result.Quantity)
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
+	}
+
+	return result, nil
+}
+
+// CloseShort 平空仓
+func (t *OKXTrader) CloseShort(ctx context.Context, symbol string, quantity float64) (*OrderResult, error) {
+	// 如果数量为0，获取当前持仓数量
+	if quantity == 0 {
+		positions, err := t.GetPositions(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if pos, ok := FindPosition(positions, symbol, SideShort); ok {
+			quantity = pos.Quantity
+		}
+
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+		}
+	}
+
+	result, err := t.placeOrder(ctx, symbol, "buy", SideShort, quantity, true)
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "✓ 平空仓成功: %s 数量: %.8f", symbol, result.Quantity)
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(ctx, symbol); err != nil {
+		exchangeLog.Warnf(ctx, "  ⚠ 取消挂单失败: %v", err)
+	}
+
+	return result, nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单（普通委托 + 条件单）
+func (t *OKXTrader) CancelAllOrders(ctx context.Context, symbol string) error {
+	instID := convertSymbolToOKX(symbol)
+
+	// 1. 普通委托
+	data, err := t.request(ctx, "GET", "/api/v5/trade/orders-pending?instType=SWAP&instId="+instID, nil)
+	if err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+	var pending []struct {
+		OrdID string `json:"ordId"`
+	}
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return fmt.Errorf("解析挂单失败: %w", err)
+	}
+	if len(pending) > 0 {
+		batch := make([]map[string]string, 0, len(pending))
+		for _, o := range pending {
+			batch = append(batch, map[string]string{"instId": instID, "ordId": o.OrdID})
+		}
+		if _, err := t.request(ctx, "POST", "/api/v5/trade/cancel-batch-orders", batch); err != nil {
+			return fmt.Errorf("取消挂单失败: %w", err)
+		}
+	}
+
+	// 2. 条件单（止损止盈）
+	data, err = t.request(ctx, "GET", "/api/v5/trade/orders-algo-pending?ordType=conditional,oco&instType=SWAP&instId="+instID, nil)
+	if err != nil {
+		return fmt.Errorf("获取条件单失败: %w", err)
+	}
+	var algos []struct {
+		AlgoID string `json:"algoId"`
+	}
+	if err := json.Unmarshal(data, &algos); err != nil {
+		return fmt.Errorf("解析条件单失败: %w", err)
+	}
+	if len(algos) > 0 {
+		batch := make([]map[string]string, 0, len(algos))
+		for _, o := range algos {
+			batch = append(batch, map[string]string{"instId": instID, "algoId": o.AlgoID})
+		}
+		if _, err := t.request(ctx, "POST", "/api/v5/trade/cancel-algos", batch); err != nil {
+			return fmt.Errorf("取消条件单失败: %w", err)
+		}
+	}
+
+	if len(pending) > 0 || len(algos) > 0 {
+		exchangeLog.Infof(ctx, "  ✓ 已取消 %s 的 %d 个挂单和 %d 个条件单", symbol, len(pending), len(algos))
+	}
+	return nil
+}
+
+// GetMarketPrice 获取市场价格
+func (t *OKXTrader) GetMarketPrice(ctx context.Context, symbol string) (float64, error) {
+	data, err := t.request(ctx, "GET", "/api/v5/market/ticker?instId="+convertSymbolToOKX(symbol), nil)
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+
+	var tickers []struct {
+		Last string `json:"last"`
+	}
+	if err := json.Unmarshal(data, &tickers); err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	if len(tickers) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+
+	return strconv.ParseFloat(tickers[0].Last, 64)
+}
+
+// placeAlgoOrder 下条件单（触发后以市价平仓）
+// triggerKey: "sl" 或 "tp"
+func (t *OKXTrader) placeAlgoOrder(ctx context.Context, symbol string, positionSide PositionSide, triggerKey string, quantity, triggerPrice float64) error {
+	side := "sell"
+	if positionSide == SideShort {
+		side = "buy"
+	}
+
+	sz, _, err := t.toContracts(ctx, symbol, quantity)
+	if err != nil {
+		return err
+	}
+	priceStr, err := t.formatPrice(ctx, symbol, triggerPrice)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]string{
+		"instId":                     convertSymbolToOKX(symbol),
+		"tdMode":                     t.getTdMode(),
+		"side":                       side,
+		"ordType":                    "conditional",
+		"sz":                         sz,
+		triggerKey + "TriggerPx":     priceStr,
+		triggerKey + "OrdPx":         "-1", // -1 表示触发后市价成交
+		triggerKey + "TriggerPxType": "last",
+	}
+	t.setPositionParams(payload, positionSide, true)
+
+	_, err = t.request(ctx, "POST", "/api/v5/trade/order-algo", payload)
+	return err
+}
+
+// SetStopLoss 设置止损单
+func (t *OKXTrader) SetStopLoss(ctx context.Context, symbol string, positionSide PositionSide, quantity, stopPrice float64) error {
+	if err := t.placeAlgoOrder(ctx, symbol, positionSide, "sl", quantity, stopPrice); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单
+func (t *OKXTrader) SetTakeProfit(ctx context.Context, symbol string, positionSide PositionSide, quantity, takeProfitPrice float64) error {
+	if err := t.placeAlgoOrder(ctx, symbol, positionSide, "tp", quantity, takeProfitPrice); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+
+	exchangeLog.Infof(ctx, "  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
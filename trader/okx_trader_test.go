@@ -0,0 +1,247 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// okxRoutes 双向持仓账户的接口响应
+func okxRoutes() map[string]string {
+	return map[string]string{
+		"POST /api/v5/account/set-position-mode": "set_position_mode.json",
+		"GET /api/v5/account/config":             "account_config_net.json",
+		"GET /api/v5/public/instruments":         "instruments.json",
+		"GET /api/v5/account/balance":            "balance.json",
+		"GET /api/v5/account/positions":          "positions.json",
+		"POST /api/v5/trade/order":               "order.json",
+		"POST /api/v5/trade/order-algo":          "algo_order.json",
+	}
+}
+
+func newTestOKXTrader(t *testing.T, routes map[string]string) (*OKXTrader, *fixtureServer) {
+	t.Helper()
+	srv := newFixtureServer(t, "okx", routes)
+	trader, err := newOKXTrader(context.Background(), "okx-key", "okx-secret", "okx-pass", true, srv.URL)
+	if err != nil {
+		t.Fatalf("创建OKX交易器失败: %v", err)
+	}
+	return trader, srv
+}
+
+// decodeBody 解析请求体中的JSON对象
+func decodeBody(t *testing.T, req recordedRequest) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+		t.Fatalf("解析请求体失败: %v (%s)", err, req.Body)
+	}
+	return body
+}
+
+func TestOKXSign(t *testing.T) {
+	trader := &OKXTrader{secretKey: "okx-secret"}
+
+	cases := []struct {
+		method, path, body, want string
+	}{
+		{"GET", "/api/v5/account/balance?ccy=USDT", "", "ykI8R08bnJd8ScohCKrqwHSjGXeJW/gIjLGHtqdTnm0="},
+		{"POST", "/api/v5/trade/order", `{"instId":"BTC-USDT-SWAP"}`, "+KhsMr/0ZKX41UkezRu1jiloDMi0KPY2cS5utQM/xMk="},
+	}
+	for _, c := range cases {
+		if got := trader.sign("2024-01-02T03:04:05.678Z", c.method, c.path, c.body); got != c.want {
+			t.Errorf("sign(%s %s) = %s, want %s", c.method, c.path, got, c.want)
+		}
+	}
+}
+
+func TestOKXRequestHeaders(t *testing.T) {
+	trader, srv := newTestOKXTrader(t, okxRoutes())
+	if _, err := trader.GetBalance(context.Background()); err != nil {
+		t.Fatalf("GetBalance失败: %v", err)
+	}
+
+	reqs := srv.requestsTo("GET", "/api/v5/account/balance")
+	if len(reqs) != 1 {
+		t.Fatalf("余额请求次数 = %d, want 1", len(reqs))
+	}
+	h := reqs[0].Header
+	// 签名覆盖带 querystring 的请求路径
+	want := trader.sign(h.Get("OK-ACCESS-TIMESTAMP"), "GET", "/api/v5/account/balance?"+reqs[0].Query, "")
+	if h.Get("OK-ACCESS-SIGN") != want {
+		t.Errorf("OK-ACCESS-SIGN = %s, want %s", h.Get("OK-ACCESS-SIGN"), want)
+	}
+	if h.Get("OK-ACCESS-KEY") != "okx-key" || h.Get("OK-ACCESS-PASSPHRASE") != "okx-pass" {
+		t.Errorf("鉴权请求头错误: %v", h)
+	}
+	if h.Get("x-simulated-trading") != "1" {
+		t.Error("模拟盘请求缺少 x-simulated-trading 请求头")
+	}
+}
+
+func TestOKXGetBalance(t *testing.T) {
+	trader, _ := newTestOKXTrader(t, okxRoutes())
+
+	balance, err := trader.GetBalance(context.Background())
+	if err != nil {
+		t.Fatalf("GetBalance失败: %v", err)
+	}
+	if balance.WalletBalance != 1000.25 || balance.AvailableBalance != 812.5 || balance.UnrealizedPnL != 12.05 {
+		t.Errorf("余额解析错误: %+v", balance)
+	}
+	if math.Abs(balance.TotalEquity-1012.3) > 1e-9 {
+		t.Errorf("TotalEquity = %v, want 1012.3", balance.TotalEquity)
+	}
+}
+
+func TestOKXGetPositions(t *testing.T) {
+	trader, _ := newTestOKXTrader(t, okxRoutes())
+
+	positions, err := trader.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions失败: %v", err)
+	}
+	// 空仓位与非USDT永续被跳过
+	if len(positions) != 2 {
+		t.Fatalf("持仓数量 = %d, want 2: %+v", len(positions), positions)
+	}
+
+	btc, ok := FindPosition(positions, "BTCUSDT", SideLong)
+	if !ok {
+		t.Fatalf("未找到 BTCUSDT 多仓: %+v", positions)
+	}
+	// 5 张 × 面值 0.01
+	if math.Abs(btc.Quantity-0.05) > 1e-9 || btc.EntryPrice != 42150.5 || btc.Leverage != 5 || btc.LiquidationPrice != 34012.3 {
+		t.Errorf("BTC持仓解析错误: %+v", btc)
+	}
+
+	// 单向持仓按张数正负判断方向
+	eth, ok := FindPosition(positions, "ETHUSDT", SideShort)
+	if !ok {
+		t.Fatalf("未找到 ETHUSDT 空仓: %+v", positions)
+	}
+	if math.Abs(eth.Quantity-0.3) > 1e-9 || eth.UnrealizedPnL != 3.09 {
+		t.Errorf("ETH持仓解析错误: %+v", eth)
+	}
+}
+
+func TestOKXGetInstrument(t *testing.T) {
+	trader, srv := newTestOKXTrader(t, okxRoutes())
+	ctx := context.Background()
+
+	inst, err := trader.getInstrument(ctx, "ETHUSDT")
+	if err != nil {
+		t.Fatalf("getInstrument失败: %v", err)
+	}
+	if inst.CtVal != 0.1 || inst.LotSz != 0.01 || inst.MinSz != 0.01 || inst.TickSz != 0.01 || inst.PricePrecision != 2 {
+		t.Errorf("合约信息解析错误: %+v", inst)
+	}
+
+	// 合约信息缓存后不再请求
+	if _, err := trader.getInstrument(ctx, "ETHUSDT"); err != nil {
+		t.Fatalf("getInstrument失败: %v", err)
+	}
+	if n := len(srv.requestsTo("GET", "/api/v5/public/instruments")); n != 1 {
+		t.Errorf("合约信息请求次数 = %d, want 1", n)
+	}
+
+	if _, err := trader.getInstrument(ctx, "SOLUSDT"); err == nil {
+		t.Error("未知合约应返回错误")
+	}
+}
+
+func TestOKXPlaceOrderHedgeMode(t *testing.T) {
+	trader, srv := newTestOKXTrader(t, okxRoutes())
+	if trader.getPosMode() != okxLongShortMode {
+		t.Fatalf("posMode = %s, want %s", trader.getPosMode(), okxLongShortMode)
+	}
+
+	// 0.05678 BTC = 5.678 张，向下取整为 5.67 张；返回取整后的币数量
+	result, err := trader.placeOrder(context.Background(), "BTCUSDT", "buy", SideLong, 0.05678, false)
+	if err != nil {
+		t.Fatalf("placeOrder失败: %v", err)
+	}
+	if result.OrderID != "312269865356374016" {
+		t.Errorf("OrderID = %s", result.OrderID)
+	}
+	if math.Abs(result.Quantity-0.0567) > 1e-9 {
+		t.Errorf("Quantity = %v, want 0.0567（按张数取整后的数量）", result.Quantity)
+	}
+
+	reqs := srv.requestsTo("POST", "/api/v5/trade/order")
+	body := decodeBody(t, reqs[len(reqs)-1])
+	if body["posSide"] != "long" || body["sz"] != "5.67" || body["instId"] != "BTC-USDT-SWAP" || body["tdMode"] != "cross" {
+		t.Errorf("下单参数错误: %v", body)
+	}
+	if _, ok := body["reduceOnly"]; ok {
+		t.Errorf("双向持仓不应发送 reduceOnly: %v", body)
+	}
+}
+
+func TestOKXNetModeOmitsPosSide(t *testing.T) {
+	routes := okxRoutes()
+	routes["POST /api/v5/account/set-position-mode"] = "set_position_mode_59000.json"
+	trader, srv := newTestOKXTrader(t, routes)
+	ctx := context.Background()
+
+	if trader.getPosMode() != okxNetMode {
+		t.Fatalf("posMode = %s, want %s", trader.getPosMode(), okxNetMode)
+	}
+
+	if _, err := trader.placeOrder(ctx, "BTCUSDT", "buy", SideLong, 0.05, false); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if _, err := trader.placeOrder(ctx, "BTCUSDT", "sell", SideLong, 0.05, true); err != nil {
+		t.Fatalf("平仓失败: %v", err)
+	}
+	if err := trader.SetStopLoss(ctx, "BTCUSDT", SideLong, 0.05, 40000); err != nil {
+		t.Fatalf("止损单失败: %v", err)
+	}
+
+	orders := srv.requestsTo("POST", "/api/v5/trade/order")
+	if len(orders) != 2 {
+		t.Fatalf("下单请求次数 = %d, want 2", len(orders))
+	}
+	open, closing := decodeBody(t, orders[0]), decodeBody(t, orders[1])
+	if _, ok := open["posSide"]; ok {
+		t.Errorf("单向持仓开仓不应发送 posSide: %v", open)
+	}
+	if _, ok := open["reduceOnly"]; ok {
+		t.Errorf("开仓不应发送 reduceOnly: %v", open)
+	}
+	if _, ok := closing["posSide"]; ok || closing["reduceOnly"] != "true" {
+		t.Errorf("单向持仓平仓应只发送 reduceOnly: %v", closing)
+	}
+
+	algos := srv.requestsTo("POST", "/api/v5/trade/order-algo")
+	if len(algos) != 1 {
+		t.Fatalf("条件单请求次数 = %d, want 1", len(algos))
+	}
+	algo := decodeBody(t, algos[0])
+	if _, ok := algo["posSide"]; ok || algo["reduceOnly"] != "true" || algo["slTriggerPx"] != "40000.0" {
+		t.Errorf("单向持仓条件单参数错误: %v", algo)
+	}
+}
+
+func TestOKXOrderRejected(t *testing.T) {
+	trader, srv := newTestOKXTrader(t, okxRoutes())
+	srv.setRoute("POST /api/v5/trade/order", "order_rejected.json")
+
+	_, err := trader.placeOrder(context.Background(), "BTCUSDT", "buy", SideLong, 0.05, false)
+	if err == nil || !strings.Contains(err.Error(), "51008") {
+		t.Errorf("应返回 sCode 51008 错误, got %v", err)
+	}
+}
+
+func TestOKXQuantityBelowMinimum(t *testing.T) {
+	trader, srv := newTestOKXTrader(t, okxRoutes())
+
+	if _, err := trader.placeOrder(context.Background(), "BTCUSDT", "buy", SideLong, 0.00005, false); err == nil {
+		t.Error("不足最小下单量时应返回错误")
+	}
+	if n := len(srv.requestsTo("POST", "/api/v5/trade/order")); n != 0 {
+		t.Errorf("不足最小下单量时不应下单, 请求次数 = %d", n)
+	}
+}
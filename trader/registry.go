@@ -0,0 +1,159 @@
+package trader
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CredentialField 交易所凭证字段定义
+type CredentialField struct {
+	Key       string   `json:"key"`                  // 凭证键名（加密JSON中的key）
+	Label     string   `json:"label"`                // 显示名称
+	Secret    bool     `json:"secret"`               // 是否为敏感字段（私钥/Secret等）
+	Required  bool     `json:"required"`             // 是否必填
+	Pattern   string   `json:"pattern,omitempty"`    // 格式校验正则（可选）
+	Options   []string `json:"options,omitempty"`    // 可选值（非空时为下拉选择字段）
+	LegacyKey string   `json:"legacy_key,omitempty"` // 旧版平铺字段名（兼容旧前端请求和旧数据）
+}
+
+// ExchangeCapabilities 交易所能力标记
+type ExchangeCapabilities struct {
+	HedgeMode         bool `json:"hedge_mode"`         // 支持双向持仓
+	ConditionalOrders bool `json:"conditional_orders"` // 支持条件单（止损止盈）
+	IsolatedMargin    bool `json:"isolated_margin"`    // 支持逐仓
+	MarketOrders      bool `json:"market_orders"`      // 支持原生市价单（否则用限价单模拟）
+	Testnet           bool `json:"testnet"`            // 支持测试网/模拟盘
+}
+
+// ExchangeFactory 根据凭证创建交易器
+type ExchangeFactory func(creds map[string]string, testnet bool) (Trader, error)
+
+// ExchangeAdapter 交易所适配器（由各交易器在init中自注册）
+type ExchangeAdapter struct {
+	ID           string               `json:"id"`            // 交易所类型标识，如 "binance"
+	DisplayName  string               `json:"display_name"`  // 显示名称
+	Type         string               `json:"type"`          // "cex" 或 "dex"
+	MarketSource string               `json:"market_source"` // 行情数据源（见 market.NewProvider）
+	Fields       []CredentialField    `json:"fields"`        // 凭证字段定义
+	Capabilities ExchangeCapabilities `json:"capabilities"`  // 能力标记
+	Factory      ExchangeFactory      `json:"-"`
+	// Validate 字段之间的组合校验（可选，在逐字段校验通过后调用）
+	Validate func(creds map[string]string) error `json:"-"`
+}
+
+var (
+	exchangeRegistry   = make(map[string]*ExchangeAdapter)
+	exchangeRegistryMu sync.RWMutex
+)
+
+// RegisterExchange 注册交易所适配器（重复注册或缺少工厂函数会panic，仅应在init中调用）
+func RegisterExchange(adapter ExchangeAdapter) {
+	if adapter.ID == "" || adapter.Factory == nil {
+		panic("trader: RegisterExchange 需要ID和Factory")
+	}
+	for _, f := range adapter.Fields {
+		if f.Pattern != "" {
+			regexp.MustCompile(f.Pattern)
+		}
+	}
+
+	exchangeRegistryMu.Lock()
+	defer exchangeRegistryMu.Unlock()
+	if _, exists := exchangeRegistry[adapter.ID]; exists {
+		panic("trader: 重复注册交易所 " + adapter.ID)
+	}
+	exchangeRegistry[adapter.ID] = &adapter
+}
+
+// GetExchangeAdapter 获取交易所适配器
+func GetExchangeAdapter(id string) (*ExchangeAdapter, error) {
+	exchangeRegistryMu.RLock()
+	defer exchangeRegistryMu.RUnlock()
+	adapter, ok := exchangeRegistry[id]
+	if !ok {
+		return nil, fmt.Errorf("不支持的交易平台: %s", id)
+	}
+	return adapter, nil
+}
+
+// ListExchangeAdapters 获取所有已注册的交易所（按ID排序）
+func ListExchangeAdapters() []ExchangeAdapter {
+	exchangeRegistryMu.RLock()
+	defer exchangeRegistryMu.RUnlock()
+
+	adapters := make([]ExchangeAdapter, 0, len(exchangeRegistry))
+	for _, a := range exchangeRegistry {
+		adapters = append(adapters, *a)
+	}
+	sort.Slice(adapters, func(i, j int) bool { return adapters[i].ID < adapters[j].ID })
+	return adapters
+}
+
+// NormalizeCredentials 只保留已定义的凭证字段，并把旧版字段名映射到新键名
+func (a *ExchangeAdapter) NormalizeCredentials(raw map[string]string) map[string]string {
+	creds := make(map[string]string, len(a.Fields))
+	for _, f := range a.Fields {
+		value := strings.TrimSpace(raw[f.Key])
+		if value == "" && f.LegacyKey != "" {
+			value = strings.TrimSpace(raw[f.LegacyKey])
+		}
+		if value != "" {
+			creds[f.Key] = value
+		}
+	}
+	return creds
+}
+
+// ValidateCredentials 校验凭证（必填项和格式）
+func (a *ExchangeAdapter) ValidateCredentials(creds map[string]string) error {
+	for _, f := range a.Fields {
+		value := creds[f.Key]
+		if value == "" {
+			if f.Required {
+				return fmt.Errorf("使用%s时必须配置%s (%s)", a.DisplayName, f.Label, f.Key)
+			}
+			continue
+		}
+		if f.Pattern != "" && !regexp.MustCompile(f.Pattern).MatchString(value) {
+			return fmt.Errorf("%s的%s格式不正确", a.DisplayName, f.Label)
+		}
+		if len(f.Options) > 0 && !containsString(f.Options, value) {
+			return fmt.Errorf("%s的%s必须是以下之一: %s", a.DisplayName, f.Label, strings.Join(f.Options, ", "))
+		}
+	}
+	if a.Validate != nil {
+		return a.Validate(creds)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NewExchangeTrader 通过注册表创建交易器（接口调用会记录交易所API指标）
+func NewExchangeTrader(id string, raw map[string]string, testnet bool) (Trader, error) {
+	adapter, err := GetExchangeAdapter(id)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := adapter.NormalizeCredentials(raw)
+	if err := adapter.ValidateCredentials(creds); err != nil {
+		return nil, err
+	}
+
+	trader, err := adapter.Factory(creds, testnet)
+	if err != nil {
+		return nil, fmt.Errorf("初始化%s交易器失败: %w", adapter.DisplayName, err)
+	}
+	return instrument(adapter.ID, trader), nil
+}
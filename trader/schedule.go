@@ -0,0 +1,320 @@
+package trader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 决策周期调度模式
+const (
+	ScheduleInterval = "interval" // 按扫描间隔固定执行（默认）
+	ScheduleCandle   = "candle"   // 对齐到K线收盘（N分钟整点 + 偏移）
+	ScheduleCron     = "cron"     // 按 cron 表达式执行
+	ScheduleEvent    = "event"    // 只在事件触发时执行（需配置 events）
+)
+
+// 交易窗口动作
+const (
+	WindowNoOpen = "no_open" // 窗口内不开新仓（平仓照常执行）
+	WindowPause  = "pause"   // 窗口内不执行决策周期
+)
+
+// Schedule 交易员的决策周期调度配置
+type Schedule struct {
+	Mode                 string          `json:"mode"`                   // interval/candle/cron/event，为空表示 interval
+	CandleMinutes        int             `json:"candle_minutes"`         // candle 模式的K线周期（分钟，需整除1440）
+	OffsetSeconds        int             `json:"offset_seconds"`         // candle 模式在收盘后延迟的秒数（等待K线数据落地）
+	Cron                 string          `json:"cron"`                   // cron 模式的表达式（分 时 日 月 周）
+	Timezone             string          `json:"timezone"`               // cron 表达式和交易窗口使用的时区（IANA名称，默认UTC）
+	Windows              []TradingWindow `json:"windows,omitempty"`      // 交易窗口（如周末不开新仓）
+	FundingPauseMinutes  int             `json:"funding_pause_minutes"`  // 资金费率结算前后各暂停的分钟数，0表示不暂停
+	FundingIntervalHours int             `json:"funding_interval_hours"` // 资金费率结算间隔（小时，从UTC 0点起算），0表示默认8
+	Events               *EventTrigger   `json:"events,omitempty"`       // 事件触发配置（可与任意模式组合）
+
+	cron *cronExpr      // Validate 解析后的 cron 表达式
+	loc  *time.Location // Validate 解析后的时区
+}
+
+// TradingWindow 交易窗口：在指定星期的时间段内限制交易
+type TradingWindow struct {
+	Action string   `json:"action"` // no_open/pause
+	Days   []string `json:"days"`   // 星期（mon/tue/wed/thu/fri/sat/sun），为空表示每天
+	Start  string   `json:"start"`  // 开始时间 HH:MM，为空表示 00:00
+	End    string   `json:"end"`    // 结束时间 HH:MM（不含），为空表示 24:00；早于开始时间表示跨越午夜
+
+	days       uint8 // 星期位图（bit0=周日）
+	start, end int   // 分钟数
+}
+
+// EventTrigger 事件触发配置：关注的币种价格异动或触及止损时立即执行决策周期
+type EventTrigger struct {
+	Symbols         []string `json:"symbols"`          // 关注的币种，为空表示关注本交易员设置了止损的持仓
+	MovePct         float64  `json:"move_pct"`         // 相对上一周期价格变动超过该百分比时触发，0表示不按涨跌幅触发
+	OnStopLoss      bool     `json:"on_stop_loss"`     // 持仓价格触及止损价时触发
+	PollSeconds     int      `json:"poll_seconds"`     // 价格轮询间隔（秒），0表示默认30
+	CooldownSeconds int      `json:"cooldown_seconds"` // 距上一周期开始的最短间隔（秒），0表示默认120
+}
+
+// 调度默认值
+const (
+	defaultFundingIntervalHours = 8
+	defaultEventPollSeconds     = 30
+	defaultEventCooldownSeconds = 120
+)
+
+// DefaultSchedule 默认调度（按扫描间隔固定执行，与之前的行为一致）
+func DefaultSchedule() Schedule {
+	return Schedule{Mode: ScheduleInterval}
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate 校验调度配置（同时解析 cron 表达式和时区，调度前必须调用）
+func (s *Schedule) Validate() error {
+	switch s.Mode {
+	case "", ScheduleInterval:
+	case ScheduleCandle:
+		if s.CandleMinutes <= 0 || 1440%s.CandleMinutes != 0 {
+			return fmt.Errorf("candle_minutes 必须整除1440（如 1/3/5/15/30/60/240）: %d", s.CandleMinutes)
+		}
+		if s.OffsetSeconds < 0 || s.OffsetSeconds >= s.CandleMinutes*60 {
+			return fmt.Errorf("offset_seconds 必须在 0 到K线周期之间: %d", s.OffsetSeconds)
+		}
+	case ScheduleCron:
+		expr, err := parseCron(s.Cron)
+		if err != nil {
+			return err
+		}
+		s.cron = expr
+	case ScheduleEvent:
+		if s.Events == nil {
+			return fmt.Errorf("event 模式需要配置 events")
+		}
+	default:
+		return fmt.Errorf("未知的调度模式: %s（支持 %s/%s/%s/%s）", s.Mode, ScheduleInterval, ScheduleCandle, ScheduleCron, ScheduleEvent)
+	}
+
+	s.loc = time.UTC
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("时区无效: %s", s.Timezone)
+		}
+		s.loc = loc
+	}
+
+	for i := range s.Windows {
+		if err := s.Windows[i].parse(); err != nil {
+			return fmt.Errorf("交易窗口 #%d 无效: %w", i+1, err)
+		}
+	}
+
+	if s.FundingPauseMinutes < 0 {
+		return fmt.Errorf("funding_pause_minutes 不能为负数: %d", s.FundingPauseMinutes)
+	}
+	if s.FundingIntervalHours < 0 || (s.FundingIntervalHours > 0 && 24%s.FundingIntervalHours != 0) {
+		return fmt.Errorf("funding_interval_hours 必须整除24: %d", s.FundingIntervalHours)
+	}
+	if time.Duration(s.FundingPauseMinutes*2)*time.Minute >= s.fundingInterval() {
+		return fmt.Errorf("funding_pause_minutes 过大，会暂停全部时间: %d", s.FundingPauseMinutes)
+	}
+
+	if s.Events != nil {
+		e := s.Events
+		if e.MovePct < 0 || e.PollSeconds < 0 || e.CooldownSeconds < 0 {
+			return fmt.Errorf("events 的 move_pct/poll_seconds/cooldown_seconds 不能为负数")
+		}
+		if e.MovePct == 0 && !e.OnStopLoss {
+			return fmt.Errorf("events 需要设置 move_pct 或 on_stop_loss")
+		}
+		for i, symbol := range e.Symbols {
+			e.Symbols[i] = strings.ToUpper(strings.TrimSpace(symbol))
+		}
+	}
+	return nil
+}
+
+// parse 解析交易窗口的星期和时间
+func (w *TradingWindow) parse() error {
+	if w.Action != WindowNoOpen && w.Action != WindowPause {
+		return fmt.Errorf("未知的窗口动作: %s（支持 %s/%s）", w.Action, WindowNoOpen, WindowPause)
+	}
+	w.days = 0
+	for _, day := range w.Days {
+		key := strings.ToLower(strings.TrimSpace(day))
+		if len(key) > 3 {
+			key = key[:3] // 兼容 monday/sunday 等全称
+		}
+		wd, ok := weekdayNames[key]
+		if !ok {
+			return fmt.Errorf("星期无效: %s", day)
+		}
+		w.days |= 1 << uint(wd)
+	}
+	if w.days == 0 {
+		w.days = 0x7f
+	}
+
+	var err error
+	if w.start, err = parseClock(w.Start, 0); err != nil {
+		return err
+	}
+	if w.end, err = parseClock(w.End, 24*60); err != nil {
+		return err
+	}
+	if w.start == w.end {
+		return fmt.Errorf("开始时间和结束时间相同: %s", w.Start)
+	}
+	return nil
+}
+
+// parseClock 解析 HH:MM 为当天的分钟数（为空时返回默认值）
+func parseClock(s string, def int) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	h, m, ok := strings.Cut(s, ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || hour > 24 || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("时间格式无效（需要 HH:MM）: %s", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// contains 窗口是否包含时间 t（t 已转换到配置时区）
+func (w *TradingWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := w.days&(1<<uint(t.Weekday())) != 0
+	if w.start < w.end {
+		return today && minute >= w.start && minute < w.end
+	}
+	// 跨越午夜：开始当天的 start 之后，或次日的 end 之前
+	yesterday := w.days&(1<<uint((t.Weekday()+6)%7)) != 0
+	return (today && minute >= w.start) || (yesterday && minute < w.end)
+}
+
+// fundingInterval 资金费率结算间隔
+func (s *Schedule) fundingInterval() time.Duration {
+	if s.FundingIntervalHours > 0 {
+		return time.Duration(s.FundingIntervalHours) * time.Hour
+	}
+	return defaultFundingIntervalHours * time.Hour
+}
+
+// location 配置的时区（未校验时为UTC）
+func (s *Schedule) location() *time.Location {
+	if s.loc != nil {
+		return s.loc
+	}
+	return time.UTC
+}
+
+// Next 计算下一个周期的执行时间；last 为上一周期开始时间（零值表示尚未执行）
+// 返回零值表示没有定时周期（event 模式）
+func (s *Schedule) Next(now, last time.Time, interval time.Duration) time.Time {
+	switch s.Mode {
+	case ScheduleCandle:
+		step := time.Duration(s.CandleMinutes) * time.Minute
+		offset := time.Duration(s.OffsetSeconds) * time.Second
+		// time.Truncate 以UTC零点为基准对齐，与交易所K线的收盘时间一致
+		next := now.Truncate(step).Add(offset)
+		if !next.After(now) {
+			next = next.Add(step)
+		}
+		return next
+	case ScheduleCron:
+		if s.cron == nil {
+			return time.Time{}
+		}
+		return s.cron.Next(now.In(s.location()))
+	case ScheduleEvent:
+		return time.Time{}
+	default:
+		if last.IsZero() {
+			return now
+		}
+		return last.Add(interval)
+	}
+}
+
+// ScheduleGate 某一时刻的交易限制
+type ScheduleGate struct {
+	Pause  bool   `json:"pause"`   // 不执行决策周期
+	NoOpen bool   `json:"no_open"` // 不开新仓
+	Reason string `json:"reason"`  // 限制原因
+}
+
+// Gate 返回时间 t 所处的交易窗口限制（pause 优先于 no_open）
+func (s *Schedule) Gate(t time.Time) ScheduleGate {
+	var gate ScheduleGate
+	if s.FundingPauseMinutes > 0 {
+		interval := s.fundingInterval()
+		pause := time.Duration(s.FundingPauseMinutes) * time.Minute
+		utc := t.UTC()
+		prev := utc.Truncate(interval)
+		if utc.Sub(prev) < pause || prev.Add(interval).Sub(utc) <= pause {
+			return ScheduleGate{Pause: true, Reason: fmt.Sprintf("资金费率结算前后 %d 分钟暂停", s.FundingPauseMinutes)}
+		}
+	}
+
+	local := t.In(s.location())
+	for _, w := range s.Windows {
+		if !w.contains(local) {
+			continue
+		}
+		days := "每天"
+		if len(w.Days) > 0 {
+			days = strings.Join(w.Days, ",")
+		}
+		reason := fmt.Sprintf("交易窗口 %s %02d:%02d-%02d:%02d", days, w.start/60, w.start%60, w.end/60, w.end%60)
+		if w.Action == WindowPause {
+			return ScheduleGate{Pause: true, Reason: reason + " 暂停"}
+		}
+		if !gate.NoOpen {
+			gate = ScheduleGate{NoOpen: true, Reason: reason + " 不开新仓"}
+		}
+	}
+	return gate
+}
+
+// pollInterval 事件轮询间隔
+func (e *EventTrigger) pollInterval() time.Duration {
+	if e.PollSeconds > 0 {
+		return time.Duration(e.PollSeconds) * time.Second
+	}
+	return defaultEventPollSeconds * time.Second
+}
+
+// cooldown 事件触发的最短间隔
+func (e *EventTrigger) cooldown() time.Duration {
+	if e.CooldownSeconds > 0 {
+		return time.Duration(e.CooldownSeconds) * time.Second
+	}
+	return defaultEventCooldownSeconds * time.Second
+}
+
+// Describe 调度配置的简短描述（用于日志）
+func (s *Schedule) Describe(interval time.Duration) string {
+	var desc string
+	switch s.Mode {
+	case ScheduleCandle:
+		desc = fmt.Sprintf("%d分钟K线收盘后 %ds", s.CandleMinutes, s.OffsetSeconds)
+	case ScheduleCron:
+		desc = fmt.Sprintf("cron %q (%s)", s.Cron, s.location())
+	case ScheduleEvent:
+		desc = "仅事件触发"
+	default:
+		desc = fmt.Sprintf("每 %v", interval)
+	}
+	if s.Events != nil && s.Mode != ScheduleEvent {
+		desc += " + 事件触发"
+	}
+	return desc
+}
@@ -0,0 +1,200 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func mustValidateSchedule(t *testing.T, s Schedule) Schedule {
+	t.Helper()
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate失败: %v", err)
+	}
+	return s
+}
+
+func TestScheduleValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		s       Schedule
+		wantErr bool
+	}{
+		{"默认", Schedule{}, false},
+		{"K线周期", Schedule{Mode: ScheduleCandle, CandleMinutes: 15, OffsetSeconds: 5}, false},
+		{"K线周期不整除1440", Schedule{Mode: ScheduleCandle, CandleMinutes: 7}, true},
+		{"偏移超过K线周期", Schedule{Mode: ScheduleCandle, CandleMinutes: 1, OffsetSeconds: 60}, true},
+		{"cron", Schedule{Mode: ScheduleCron, Cron: "*/5 * * * *", Timezone: "Asia/Shanghai"}, false},
+		{"cron表达式无效", Schedule{Mode: ScheduleCron, Cron: "* * *"}, true},
+		{"时区无效", Schedule{Mode: ScheduleCron, Cron: "* * * * *", Timezone: "Mars/Olympus"}, true},
+		{"event缺少配置", Schedule{Mode: ScheduleEvent}, true},
+		{"event未设置触发条件", Schedule{Mode: ScheduleEvent, Events: &EventTrigger{}}, true},
+		{"未知模式", Schedule{Mode: "daily"}, true},
+		{"窗口动作无效", Schedule{Windows: []TradingWindow{{Action: "close"}}}, true},
+		{"窗口星期无效", Schedule{Windows: []TradingWindow{{Action: WindowPause, Days: []string{"funday"}}}}, true},
+		{"窗口时间无效", Schedule{Windows: []TradingWindow{{Action: WindowPause, Start: "25:00"}}}, true},
+		{"窗口开始等于结束", Schedule{Windows: []TradingWindow{{Action: WindowPause, Start: "08:00", End: "08:00"}}}, true},
+		{"资金费率间隔不整除24", Schedule{FundingPauseMinutes: 5, FundingIntervalHours: 5}, true},
+		{"资金费率暂停覆盖全部时间", Schedule{FundingPauseMinutes: 240}, true},
+		{"资金费率暂停为负", Schedule{FundingPauseMinutes: -1}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.s.Validate()
+			if (err != nil) != c.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleNextCandle(t *testing.T) {
+	s := mustValidateSchedule(t, Schedule{Mode: ScheduleCandle, CandleMinutes: 15, OffsetSeconds: 5})
+	cases := []struct {
+		now, want time.Time
+	}{
+		{time.Date(2025, 1, 1, 10, 7, 0, 0, time.UTC), time.Date(2025, 1, 1, 10, 15, 5, 0, time.UTC)},
+		{time.Date(2025, 1, 1, 10, 15, 3, 0, time.UTC), time.Date(2025, 1, 1, 10, 15, 5, 0, time.UTC)},  // 收盘后等待偏移
+		{time.Date(2025, 1, 1, 10, 15, 5, 0, time.UTC), time.Date(2025, 1, 1, 10, 30, 5, 0, time.UTC)},  // 不含当前时刻
+		{time.Date(2025, 1, 1, 23, 50, 0, 0, time.UTC), time.Date(2025, 1, 2, 0, 0, 5, 0, time.UTC)},    // 跨天
+		{time.Date(2025, 1, 1, 10, 15, 10, 0, time.UTC), time.Date(2025, 1, 1, 10, 30, 5, 0, time.UTC)}, // 已过偏移
+	}
+	for _, c := range cases {
+		if got := s.Next(c.now, time.Time{}, time.Minute); !got.Equal(c.want) {
+			t.Errorf("Next(%v) = %v, want %v", c.now, got, c.want)
+		}
+	}
+
+	// 4小时K线按UTC零点对齐，与 now 所在时区无关
+	h4 := mustValidateSchedule(t, Schedule{Mode: ScheduleCandle, CandleMinutes: 240, Timezone: "Asia/Kolkata"})
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, kolkata) // UTC 04:30
+	if got := h4.Next(now, time.Time{}, time.Minute); !got.Equal(time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("4小时K线 Next = %v, want UTC 08:00", got.UTC())
+	}
+}
+
+func TestScheduleNextModes(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 7, 30, 0, time.UTC)
+
+	interval := mustValidateSchedule(t, Schedule{})
+	if got := interval.Next(now, time.Time{}, 3*time.Minute); !got.Equal(now) {
+		t.Errorf("首个周期应立即执行, Next = %v", got)
+	}
+	last := now.Add(-time.Minute)
+	if got := interval.Next(now, last, 3*time.Minute); !got.Equal(last.Add(3 * time.Minute)) {
+		t.Errorf("interval Next = %v", got)
+	}
+
+	event := mustValidateSchedule(t, Schedule{Mode: ScheduleEvent, Events: &EventTrigger{OnStopLoss: true}})
+	if got := event.Next(now, last, 3*time.Minute); !got.IsZero() {
+		t.Errorf("event 模式没有定时周期, Next = %v", got)
+	}
+
+	// cron 按配置时区计算：上海时间每天 08:00 = UTC 00:00
+	cron := mustValidateSchedule(t, Schedule{Mode: ScheduleCron, Cron: "0 8 * * *", Timezone: "Asia/Shanghai"})
+	if got := cron.Next(now, last, 3*time.Minute); !got.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("cron Next = %v", got.UTC())
+	}
+}
+
+func TestScheduleGateWindows(t *testing.T) {
+	s := mustValidateSchedule(t, Schedule{
+		Timezone: "America/New_York",
+		Windows: []TradingWindow{
+			// 周五 22:00 到次日 02:00 不开新仓（跨越午夜）
+			{Action: WindowNoOpen, Days: []string{"friday"}, Start: "22:00", End: "02:00"},
+			// 每天 12:00-13:00 暂停
+			{Action: WindowPause, Start: "12:00", End: "13:00"},
+			// 周六全天不开新仓
+			{Action: WindowNoOpen, Days: []string{"sat"}},
+		},
+	})
+	ny := mustLoadLocation(t, "America/New_York")
+
+	// 2025-01-03 是周五
+	cases := []struct {
+		name       string
+		at         time.Time
+		pause      bool
+		noOpen     bool
+		wantReason string
+	}{
+		{"周五窗口前", time.Date(2025, 1, 3, 21, 59, 0, 0, ny), false, false, ""},
+		{"周五窗口开始", time.Date(2025, 1, 3, 22, 0, 0, 0, ny), false, true, "交易窗口 friday 22:00-02:00 不开新仓"},
+		{"跨越午夜到周六", time.Date(2025, 1, 4, 1, 59, 0, 0, ny), false, true, "交易窗口 friday 22:00-02:00 不开新仓"},
+		{"周六由全天窗口限制", time.Date(2025, 1, 4, 2, 0, 0, 0, ny), false, true, "交易窗口 sat 00:00-24:00 不开新仓"},
+		{"周日凌晨不受周五窗口影响", time.Date(2025, 1, 5, 1, 0, 0, 0, ny), false, false, ""},
+		{"周四跨午夜部分不生效", time.Date(2025, 1, 3, 1, 0, 0, 0, ny), false, false, ""},
+		{"暂停窗口", time.Date(2025, 1, 2, 12, 30, 0, 0, ny), true, false, "交易窗口 每天 12:00-13:00 暂停"},
+		{"暂停优先于不开新仓", time.Date(2025, 1, 4, 12, 0, 0, 0, ny), true, false, "交易窗口 每天 12:00-13:00 暂停"},
+		{"暂停窗口结束不含", time.Date(2025, 1, 2, 13, 0, 0, 0, ny), false, false, ""},
+		// 按配置时区判断：UTC 03:30 周六 = 纽约周五 22:30
+		{"UTC时间转换到配置时区", time.Date(2025, 1, 4, 3, 30, 0, 0, time.UTC), false, true, "交易窗口 friday 22:00-02:00 不开新仓"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gate := s.Gate(c.at)
+			if gate.Pause != c.pause || gate.NoOpen != c.noOpen || gate.Reason != c.wantReason {
+				t.Errorf("Gate(%v) = %+v, want pause=%v noOpen=%v reason=%q", c.at, gate, c.pause, c.noOpen, c.wantReason)
+			}
+		})
+	}
+}
+
+func TestScheduleGateWindowDST(t *testing.T) {
+	// 每天 09:00-10:00（纽约时间）暂停：夏令时前后对应的UTC时间不同
+	s := mustValidateSchedule(t, Schedule{
+		Timezone: "America/New_York",
+		Windows:  []TradingWindow{{Action: WindowPause, Start: "09:00", End: "10:00"}},
+	})
+	cases := []struct {
+		at    time.Time
+		pause bool
+	}{
+		{time.Date(2025, 3, 7, 14, 30, 0, 0, time.UTC), true},   // EST: 09:30
+		{time.Date(2025, 3, 7, 13, 30, 0, 0, time.UTC), false},  // EST: 08:30
+		{time.Date(2025, 3, 10, 13, 30, 0, 0, time.UTC), true},  // EDT: 09:30
+		{time.Date(2025, 3, 10, 14, 30, 0, 0, time.UTC), false}, // EDT: 10:30
+	}
+	for _, c := range cases {
+		if got := s.Gate(c.at).Pause; got != c.pause {
+			t.Errorf("Gate(%v).Pause = %v, want %v", c.at, got, c.pause)
+		}
+	}
+}
+
+func TestScheduleGateFundingPause(t *testing.T) {
+	s := mustValidateSchedule(t, Schedule{FundingPauseMinutes: 10})
+	settle := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		at    time.Time
+		pause bool
+	}{
+		{"结算前暂停开始", settle.Add(-10 * time.Minute), true},
+		{"结算前暂停之前", settle.Add(-10*time.Minute - time.Second), false},
+		{"结算时刻", settle, true},
+		{"结算后暂停结束前", settle.Add(10*time.Minute - time.Second), true},
+		{"结算后暂停结束", settle.Add(10 * time.Minute), false},
+		{"两次结算之间", settle.Add(4 * time.Hour), false},
+		{"UTC零点结算", time.Date(2025, 1, 2, 0, 5, 0, 0, time.UTC), true},
+		// 其他时区的时间按UTC结算时间判断：上海 16:05 = UTC 08:05
+		{"非UTC时区", time.Date(2025, 1, 1, 16, 5, 0, 0, mustLoadLocation(t, "Asia/Shanghai")), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := s.Gate(c.at); got.Pause != c.pause {
+				t.Errorf("Gate(%v) = %+v, want pause=%v", c.at, got, c.pause)
+			}
+		})
+	}
+
+	// 4小时结算间隔
+	h4 := mustValidateSchedule(t, Schedule{FundingPauseMinutes: 5, FundingIntervalHours: 4})
+	if !h4.Gate(time.Date(2025, 1, 1, 4, 3, 0, 0, time.UTC)).Pause {
+		t.Error("4小时间隔的 04:00 结算应暂停")
+	}
+	if s.Gate(time.Date(2025, 1, 1, 4, 3, 0, 0, time.UTC)).Pause {
+		t.Error("8小时间隔的 04:00 不是结算时间")
+	}
+}
@@ -0,0 +1,139 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// PositionSide 持仓方向
+type PositionSide string
+
+const (
+	SideLong  PositionSide = "long"
+	SideShort PositionSide = "short"
+)
+
+// Balance 账户余额（单位均为 USDT）
+type Balance struct {
+	TotalEquity      float64 `json:"total_equity"`      // 账户净值（含未实现盈亏）
+	WalletBalance    float64 `json:"wallet_balance"`    // 钱包余额（不含未实现盈亏，交易所未提供时为0）
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`    // 未实现盈亏
+	AvailableBalance float64 `json:"available_balance"` // 可用余额
+}
+
+// Position 持仓（价格和盈亏单位为 USDT，数量单位为币）
+type Position struct {
+	Symbol           string       `json:"symbol"` // 统一为 BTCUSDT 格式
+	Side             PositionSide `json:"side"`
+	Quantity         float64      `json:"quantity"` // 持仓数量（始终为正数）
+	EntryPrice       float64      `json:"entry_price"`
+	MarkPrice        float64      `json:"mark_price"`
+	UnrealizedPnL    float64      `json:"unrealized_pnl"`
+	LiquidationPrice float64      `json:"liquidation_price"` // 0 表示交易所未返回
+	Leverage         int          `json:"leverage"`          // 0 表示交易所未返回
+}
+
+// defaultLeverage 交易所未返回杠杆时的估算值
+const defaultLeverage = 10
+
+// Key 持仓唯一标识（symbol_side）
+func (p Position) Key() string {
+	return p.Symbol + "_" + string(p.Side)
+}
+
+// EffectiveLeverage 杠杆倍数（未知时按默认值估算）
+func (p Position) EffectiveLeverage() int {
+	if p.Leverage > 0 {
+		return p.Leverage
+	}
+	return defaultLeverage
+}
+
+// Notional 持仓名义价值（按标记价格）
+func (p Position) Notional() float64 {
+	return p.Quantity * p.MarkPrice
+}
+
+// MarginUsed 占用保证金（估算）
+func (p Position) MarginUsed() float64 {
+	return p.Notional() / float64(p.EffectiveLeverage())
+}
+
+// PriceChangePct 相对开仓价的价格变动百分比（按持仓方向，盈利为正）
+func (p Position) PriceChangePct() float64 {
+	if p.EntryPrice == 0 {
+		return 0
+	}
+	if p.Side == SideLong {
+		return (p.MarkPrice - p.EntryPrice) / p.EntryPrice * 100
+	}
+	return (p.EntryPrice - p.MarkPrice) / p.EntryPrice * 100
+}
+
+// FindPosition 查找指定币种和方向的持仓
+func FindPosition(positions []Position, symbol string, side PositionSide) (Position, bool) {
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			return pos, true
+		}
+	}
+	return Position{}, false
+}
+
+// OrderRequest 下单请求
+type OrderRequest struct {
+	Symbol     string
+	Side       PositionSide // 持仓方向（开多/平多均为 long）
+	Quantity   float64      // 数量（币），平仓时为0表示全部平仓
+	Leverage   int          // 开仓杠杆，平仓时忽略
+	ReduceOnly bool         // true=平仓
+}
+
+// Validate 校验下单请求
+func (r OrderRequest) Validate() error {
+	if r.Symbol == "" {
+		return fmt.Errorf("币种不能为空")
+	}
+	if r.Side != SideLong && r.Side != SideShort {
+		return fmt.Errorf("未知的持仓方向: %s", r.Side)
+	}
+	if r.Quantity < 0 || (!r.ReduceOnly && r.Quantity == 0) {
+		return fmt.Errorf("下单数量无效: %v", r.Quantity)
+	}
+	if !r.ReduceOnly && r.Leverage <= 0 {
+		return fmt.Errorf("杠杆倍数无效: %d", r.Leverage)
+	}
+	return nil
+}
+
+// OrderResult 下单结果
+type OrderResult struct {
+	OrderID  string  `json:"order_id"` // 交易所订单ID（部分交易所不返回时为空）
+	Symbol   string  `json:"symbol"`
+	Status   string  `json:"status,omitempty"`
+	Quantity float64 `json:"quantity"` // 实际提交的数量（币）
+}
+
+// NumericID 数字形式的订单ID（非数字ID返回0）
+func (r *OrderResult) NumericID() int64 {
+	id, _ := strconv.ParseInt(r.OrderID, 10, 64)
+	return id
+}
+
+// PlaceOrder 按下单请求调用对应的开平仓方法
+func PlaceOrder(ctx context.Context, t Trader, req OrderRequest) (*OrderResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.Side == SideLong && !req.ReduceOnly:
+		return t.OpenLong(ctx, req.Symbol, req.Quantity, req.Leverage)
+	case req.Side == SideShort && !req.ReduceOnly:
+		return t.OpenShort(ctx, req.Symbol, req.Quantity, req.Leverage)
+	case req.Side == SideLong:
+		return t.CloseLong(ctx, req.Symbol, req.Quantity)
+	default:
+		return t.CloseShort(ctx, req.Symbol, req.Quantity)
+	}
+}